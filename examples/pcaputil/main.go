@@ -0,0 +1,166 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+// The pcaputil binary merges and splits capture files, like mergecap and
+// editcap:
+//
+//	pcaputil merge -w merged.pcapng eth0.pcap wlan0.pcapng.gz
+//	pcaputil split -c 10000 -i 1m -F pcapng big.pcap.gz out.pcapng
+//
+// Input files can be pcap, pcapng or snoop files, optionally gzip
+// compressed. Output files are compressed if their name ends in ".gz".
+package main
+
+import (
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/gopacket/gopacket/pcapgo"
+	"github.com/gopacket/gopacket/pcapgo/pcaputil"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, `Usage:
+  %[1]s merge -w OUTFILE INFILE...
+  %[1]s split [-c PACKETS] [-s BYTES] [-i DURATION] [-F pcap|pcapng|snoop] INFILE OUTFILE
+`, os.Args[0])
+	os.Exit(2)
+}
+
+// open opens a capture file. pcapng files are opened with an NgReader
+// returning all their interfaces, to keep them apart when merging.
+func open(path string) (pcaputil.Source, io.Closer, error) {
+	r, err := pcapgo.OpenFile(path)
+	if err != nil {
+		return pcaputil.Source{}, nil, err
+	}
+	if r.Format() != pcapgo.FileFormatPcapNg {
+		return pcaputil.Source{
+			PacketDataSource: r,
+			Interface:        pcapgo.NgInterface{Name: path, LinkType: r.LinkType()},
+		}, r, nil
+	}
+	r.Close()
+	f, err := os.Open(path)
+	if err != nil {
+		return pcaputil.Source{}, nil, err
+	}
+	ng, err := pcapgo.NewNgReader(f, pcapgo.NgReaderOptions{WantMixedLinkType: true})
+	if err != nil {
+		f.Close()
+		return pcaputil.Source{}, nil, err
+	}
+	return pcaputil.Source{PacketDataSource: ng}, f, nil
+}
+
+// gzipFile compresses the data written to a file.
+type gzipFile struct {
+	*gzip.Writer
+	f *os.File
+}
+
+func newGzipFile(f *os.File) *gzipFile {
+	return &gzipFile{gzip.NewWriter(f), f}
+}
+
+func (g *gzipFile) Close() error {
+	if err := g.Writer.Close(); err != nil {
+		g.f.Close()
+		return err
+	}
+	return g.f.Close()
+}
+
+func merge(args []string) {
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	out := fs.String("w", "", "Output pcapng file")
+	fs.Parse(args)
+	if *out == "" || fs.NArg() == 0 {
+		usage()
+	}
+
+	var sources []pcaputil.Source
+	for _, path := range fs.Args() {
+		s, c, err := open(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer c.Close()
+		sources = append(sources, s)
+	}
+
+	var w io.WriteCloser
+	f, err := os.Create(*out)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w = f
+	if strings.HasSuffix(*out, ".gz") {
+		w = newGzipFile(f)
+	}
+	n, err := pcaputil.Merge(w, sources...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("merged %d packets from %d files into %s", n, len(sources), *out)
+}
+
+func split(args []string) {
+	fs := flag.NewFlagSet("split", flag.ExitOnError)
+	var options pcaputil.SplitOptions
+	fs.IntVar(&options.MaxPackets, "c", 0, "Maximum number of packets per file")
+	fs.Int64Var(&options.MaxBytes, "s", 0, "Maximum size of the packets per file, in bytes")
+	fs.DurationVar(&options.MaxDuration, "i", 0, "Time window per file")
+	format := fs.String("F", "pcap", "Output format: pcap, pcapng or snoop")
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		usage()
+	}
+	switch *format {
+	case "pcap":
+		options.File.Format = pcapgo.FileFormatPcap
+	case "pcapng":
+		options.File.Format = pcapgo.FileFormatPcapNg
+	case "snoop":
+		options.File.Format = pcapgo.FileFormatSnoop
+	default:
+		usage()
+	}
+	options.File.Gzip = strings.HasSuffix(fs.Arg(1), ".gz")
+
+	r, err := pcapgo.OpenFile(fs.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer r.Close()
+	n, err := pcaputil.Split(r, r.LinkType(), options, pcaputil.NumberedFiles(fs.Arg(1)))
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("wrote %d files", n)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	switch os.Args[1] {
+	case "merge":
+		merge(os.Args[2:])
+	case "split":
+		split(os.Args[2:])
+	default:
+		usage()
+	}
+}
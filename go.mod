@@ -3,8 +3,11 @@ module github.com/gopacket/gopacket
 go 1.23.0
 
 require (
+	github.com/klauspost/compress v1.18.0
 	github.com/vishvananda/netlink v1.1.0
 	github.com/vishvananda/netns v0.0.0-20211101163701-50045581ed74
 	golang.org/x/net v0.36.0
 	golang.org/x/sys v0.30.0
 )
+
+require golang.org/x/text v0.22.0 // indirect
@@ -0,0 +1,377 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+// Package ip6defrag implements a IPv6 defragmenter
+package ip6defrag
+
+import (
+	"bytes"
+	"container/list"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+)
+
+// Quick and Easy to use debug code to trace
+// how defrag works.
+var debug debugging = false // or flip to true
+type debugging bool
+
+func (d debugging) Printf(format string, args ...interface{}) {
+	if d {
+		log.Printf(format, args...)
+	}
+}
+
+// Constants determining how to handle fragments.
+// Reference RFC 8200, section 4.5
+const (
+	IPv6MinimumFragmentSize    = 8     // Minimum size of a single fragment
+	IPv6MaximumSize            = 65535 // Maximum size of the reassembled payload (2^16)
+	IPv6MaximumFragmentOffset  = 8191  // Maximum offset of a fragment
+	IPv6MaximumFragmentListLen = 8192  // Back out if we get more than this many fragments
+)
+
+// ErrOverlappingFragment is returned when a fragment overlaps data
+// already received for the same packet. As required by RFC 5722, the
+// whole packet is then discarded.
+var ErrOverlappingFragment = errors.New("defrag: overlapping fragment, discarding packet (RFC 5722)")
+
+// DefragIPv6 takes in an IPv6 packet and its fragment extension header.
+//
+// It does not modify the layers in place, 'ip6' and 'frag' remain
+// untouched.
+//
+// If frag is nil, the packet is not fragmented and ip6 is immediately
+// returned.
+//
+// If frag is an atomic fragment (offset zero and no more fragments),
+// it is processed in isolation as described in RFC 6946: a new IPv6
+// layer is returned without touching any pending reassembly.
+//
+// If the fragment is not the last one needed, it will return nil and
+// store whatever internal information it needs to eventually defrag
+// the packet.
+//
+// If the fragment is the last one needed to reconstruct the packet, a
+// new IPv6 layer will be returned, whose NextHeader is the one carried
+// in the fragment header and whose payload is the reassembled
+// fragmentable part. Extension headers placed before the fragment
+// header (the unfragmentable part) are not carried over.
+//
+// Usage example:
+//
+//	func HandlePacket(p gopacket.Packet) error {
+//	    ip6, _ := p.Layer(layers.LayerTypeIPv6).(*layers.IPv6)
+//	    frag, _ := p.Layer(layers.LayerTypeIPv6Fragment).(*layers.IPv6Fragment)
+//	    out, err := defragger.DefragIPv6(ip6, frag)
+//	    if err != nil {
+//	        return err
+//	    } else if out == nil {
+//	        return nil // packet fragment, we don't have whole packet yet.
+//	    }
+//	    ... do stuff to 'out' ...
+//	}
+func (d *IPv6Defragmenter) DefragIPv6(ip6 *layers.IPv6, frag *layers.IPv6Fragment) (*layers.IPv6, error) {
+	return d.DefragIPv6WithTimestamp(ip6, frag, time.Now())
+}
+
+// DefragIPv6WithTimestamp provides functionality of DefragIPv6 with
+// an additional timestamp parameter which is used for discarding
+// old fragments instead of time.Now()
+//
+// This is useful when operating on pcap files instead of live captured data
+func (d *IPv6Defragmenter) DefragIPv6WithTimestamp(ip6 *layers.IPv6, frag *layers.IPv6Fragment, t time.Time) (*layers.IPv6, error) {
+	// check if we need to defrag
+	if frag == nil {
+		debug.Printf("defrag: do nothing, do not need anything")
+		return ip6, nil
+	}
+	// atomic fragments are handled on their own, RFC 6946
+	if frag.FragmentOffset == 0 && !frag.MoreFragments {
+		debug.Printf("defrag: atomic fragment, processing in isolation")
+		return newReassembled(ip6, frag.NextHeader, frag.Payload), nil
+	}
+	// perfom security checks
+	if err := d.securityChecks(frag); err != nil {
+		debug.Printf("defrag: alert security check")
+		return nil, err
+	}
+
+	// ok, got a fragment
+	debug.Printf("defrag: got a new fragment frag.Identification=%d frag.FragmentOffset=%d frag.MoreFragments=%v\n",
+		frag.Identification, frag.FragmentOffset*8, frag.MoreFragments)
+
+	// have we already seen a flow between src/dst with that Id?
+	ipf := newIPv6(ip6, frag)
+	var fl *fragmentList
+	var exist bool
+	d.Lock()
+	fl, exist = d.ipFlows[ipf]
+	if !exist {
+		debug.Printf("defrag: unknown flow, creating a new one\n")
+		fl = new(fragmentList)
+		d.ipFlows[ipf] = fl
+	}
+	d.Unlock()
+	// insert, and if final build it
+	out, err2 := fl.insert(ip6, frag, t)
+
+	// overlapping fragments invalidate the whole packet
+	if err2 == ErrOverlappingFragment {
+		d.flush(ipf)
+		return nil, err2
+	}
+
+	// at last, if we hit the maximum frag list len
+	// without any defrag success, we just drop everything and
+	// raise an error
+	if out == nil && fl.List.Len()+1 > IPv6MaximumFragmentListLen {
+		d.flush(ipf)
+		return nil, fmt.Errorf("defrag: Fragment List hits its maximum"+
+			"size(%d), without success. Flushing the list",
+			IPv6MaximumFragmentListLen)
+	}
+
+	// if we got a packet, it's a new one, and he is defragmented
+	if out != nil {
+		// when defrag is done for a flow between two ip
+		// clean the list
+		d.flush(ipf)
+		return out, nil
+	}
+	return nil, err2
+}
+
+// DiscardOlderThan forgets all packets without any activity since
+// time t. It returns the number of FragmentList aka number of
+// fragment packets it has discarded.
+func (d *IPv6Defragmenter) DiscardOlderThan(t time.Time) int {
+	var nb int
+	d.Lock()
+	for k, v := range d.ipFlows {
+		if v.LastSeen.Before(t) {
+			nb = nb + 1
+			delete(d.ipFlows, k)
+		}
+	}
+	d.Unlock()
+	return nb
+}
+
+// flush the fragment list for a particular flow
+func (d *IPv6Defragmenter) flush(ipf ipv6) {
+	d.Lock()
+	delete(d.ipFlows, ipf)
+	d.Unlock()
+}
+
+// securityChecks performs the needed security checks
+func (d *IPv6Defragmenter) securityChecks(frag *layers.IPv6Fragment) error {
+	fragSize := len(frag.Payload)
+
+	// don't allow small fragments outside of specification
+	if frag.MoreFragments && fragSize < IPv6MinimumFragmentSize {
+		return fmt.Errorf("defrag: fragment too small "+
+			"(handcrafted? %d < %d)", fragSize, IPv6MinimumFragmentSize)
+	}
+
+	// all but the last fragment must be a multiple of 8 bytes long
+	if frag.MoreFragments && fragSize%8 != 0 {
+		return fmt.Errorf("defrag: fragment length not a multiple of 8 "+
+			"(handcrafted? %d)", fragSize)
+	}
+
+	// don't allow too big fragment offset
+	if frag.FragmentOffset > IPv6MaximumFragmentOffset {
+		return fmt.Errorf("defrag: fragment offset too big "+
+			"(handcrafted? %d > %d)", frag.FragmentOffset, IPv6MaximumFragmentOffset)
+	}
+	fragOffset := int(frag.FragmentOffset) * 8
+
+	// don't allow fragment that would oversize an IP packet
+	if fragOffset+fragSize > IPv6MaximumSize {
+		return fmt.Errorf("defrag: fragment will overrun "+
+			"(handcrafted? %d > %d)", fragOffset+fragSize, IPv6MaximumSize)
+	}
+
+	return nil
+}
+
+// fragment is a single received fragment. Its data is copied so that
+// callers are free to reuse their decoding layers.
+type fragment struct {
+	Offset int
+	Data   []byte
+}
+
+// fragmentList holds a container/list used to contains IPv6
+// fragments.  It stores internal counters to track the
+// maximum total of byte, and the current length it has received.
+// It also stores a flag to know if he has seen the last packet.
+type fragmentList struct {
+	List          list.List
+	Highest       int
+	Current       int
+	FinalReceived bool
+	LastSeen      time.Time
+	first         *layers.IPv6
+	nextHeader    layers.IPProtocol
+}
+
+// insert inserts an IPv6 fragment into the Fragment List, ordered by
+// offset. Exact duplicates are silently dropped, while any other
+// overlap returns ErrOverlappingFragment, as mandated by RFC 5722.
+func (f *fragmentList) insert(ip6 *layers.IPv6, frag *layers.IPv6Fragment, t time.Time) (*layers.IPv6, error) {
+	fragOffset := int(frag.FragmentOffset) * 8
+	fragEnd := fragOffset + len(frag.Payload)
+	in := &fragment{
+		Offset: fragOffset,
+		Data:   append([]byte(nil), frag.Payload...),
+	}
+
+	var mark *list.Element
+	for e := f.List.Front(); e != nil; e = e.Next() {
+		cur := e.Value.(*fragment)
+		curEnd := cur.Offset + len(cur.Data)
+		if cur.Offset == fragOffset && curEnd == fragEnd && bytes.Equal(cur.Data, in.Data) {
+			debug.Printf("defrag: ignoring frag %d as we already have it (duplicate)\n",
+				fragOffset)
+			f.LastSeen = t
+			return nil, nil
+		}
+		if fragOffset < curEnd && cur.Offset < fragEnd {
+			debug.Printf("defrag: frag %d-%d overlaps existing frag %d-%d\n",
+				fragOffset, fragEnd, cur.Offset, curEnd)
+			return nil, ErrOverlappingFragment
+		}
+		if mark == nil && fragOffset < cur.Offset {
+			mark = e
+		}
+	}
+	if !frag.MoreFragments && f.FinalReceived {
+		debug.Printf("defrag: second final fragment at %d\n", fragOffset)
+		return nil, ErrOverlappingFragment
+	}
+	if f.FinalReceived && fragEnd > f.Highest {
+		debug.Printf("defrag: frag %d-%d is beyond final fragment\n", fragOffset, fragEnd)
+		return nil, ErrOverlappingFragment
+	}
+	if !frag.MoreFragments && fragEnd < f.Highest {
+		debug.Printf("defrag: final frag %d-%d ends before existing data\n", fragOffset, fragEnd)
+		return nil, ErrOverlappingFragment
+	}
+	if mark != nil {
+		f.List.InsertBefore(in, mark)
+	} else {
+		f.List.PushBack(in)
+	}
+
+	f.LastSeen = t
+	if fragOffset == 0 || f.first == nil {
+		// keep our own copy, the caller may reuse its layers
+		first := *ip6
+		first.SrcIP = append(net.IP(nil), ip6.SrcIP...)
+		first.DstIP = append(net.IP(nil), ip6.DstIP...)
+		f.first = &first
+		f.nextHeader = frag.NextHeader
+	}
+
+	// After inserting the Fragment, we update the counters
+	if f.Highest < fragEnd {
+		f.Highest = fragEnd
+	}
+	f.Current = f.Current + len(in.Data)
+
+	debug.Printf("defrag: insert ListLen: %d Highest:%d Current:%d\n",
+		f.List.Len(),
+		f.Highest, f.Current)
+
+	// Final Fragment ?
+	if !frag.MoreFragments {
+		f.FinalReceived = true
+	}
+	// Ready to try defrag ?
+	if f.FinalReceived && f.Highest == f.Current {
+		return f.build()
+	}
+	return nil, nil
+}
+
+// build builds the final datagram. Since overlaps are rejected on
+// insertion, fragments are simply concatenated in offset order.
+func (f *fragmentList) build() (*layers.IPv6, error) {
+	final := make([]byte, 0, f.Highest)
+	var currentOffset int
+
+	debug.Printf("defrag: building the datagram \n")
+	for e := f.List.Front(); e != nil; e = e.Next() {
+		frag := e.Value.(*fragment)
+		if frag.Offset != currentOffset {
+			// Houston - we have an hole !
+			debug.Printf("defrag: hole found while building, " +
+				"stopping the defrag process\n")
+			return nil, errors.New("defrag: building - hole found")
+		}
+		debug.Printf("defrag: building - adding %d\n", frag.Offset)
+		final = append(final, frag.Data...)
+		currentOffset += len(frag.Data)
+	}
+
+	return newReassembled(f.first, f.nextHeader, final), nil
+}
+
+// newReassembled returns a new IPv6 layer built from the addressing
+// fields of in, carrying payload as its upper-layer data.
+func newReassembled(in *layers.IPv6, next layers.IPProtocol, payload []byte) *layers.IPv6 {
+	out := &layers.IPv6{
+		Version:      in.Version,
+		TrafficClass: in.TrafficClass,
+		FlowLabel:    in.FlowLabel,
+		Length:       uint16(len(payload)),
+		NextHeader:   next,
+		HopLimit:     in.HopLimit,
+		SrcIP:        in.SrcIP,
+		DstIP:        in.DstIP,
+	}
+	out.Payload = payload
+	return out
+}
+
+// ipv6 is a struct to be used as a key.
+type ipv6 struct {
+	ip6 gopacket.Flow
+	id  uint32
+}
+
+// newIPv6 returns a new initialized IPv6 Flow
+func newIPv6(ip *layers.IPv6, frag *layers.IPv6Fragment) ipv6 {
+	return ipv6{
+		ip6: ip.NetworkFlow(),
+		id:  frag.Identification,
+	}
+}
+
+// IPv6Defragmenter is a struct which embedded a map of
+// all fragment/packet.
+type IPv6Defragmenter struct {
+	sync.RWMutex
+	ipFlows map[ipv6]*fragmentList
+}
+
+// NewIPv6Defragmenter returns a new IPv6Defragmenter
+// with an initialized map.
+func NewIPv6Defragmenter() *IPv6Defragmenter {
+	return &IPv6Defragmenter{
+		ipFlows: make(map[ipv6]*fragmentList),
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package ip6defrag
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+)
+
+var (
+	testSrcIP = net.ParseIP("2001:db8::1")
+	testDstIP = net.ParseIP("2001:db8::2")
+)
+
+// testPayload returns n bytes of recognizable data.
+func testPayload(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i)
+	}
+	return b
+}
+
+// buildFragment serializes an IPv6 packet carrying data at the given
+// fragment offset (in bytes) and decodes it back, so the layers look
+// exactly like those coming from a capture.
+func buildFragment(t *testing.T, id uint32, offset int, more bool, data []byte) (*layers.IPv6, *layers.IPv6Fragment) {
+	ip6 := &layers.IPv6{
+		Version:    6,
+		HopLimit:   64,
+		NextHeader: layers.IPProtocolIPv6Fragment,
+		SrcIP:      testSrcIP,
+		DstIP:      testDstIP,
+	}
+	frag := &layers.IPv6Fragment{
+		NextHeader:     layers.IPProtocolUDP,
+		FragmentOffset: uint16(offset / 8),
+		MoreFragments:  more,
+		Identification: id,
+	}
+	buf := gopacket.NewSerializeBuffer()
+	err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{FixLengths: true},
+		ip6, frag, gopacket.Payload(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := gopacket.NewPacket(buf.Bytes(), layers.LayerTypeIPv6, gopacket.Default)
+	outIP, _ := p.Layer(layers.LayerTypeIPv6).(*layers.IPv6)
+	outFrag, _ := p.Layer(layers.LayerTypeIPv6Fragment).(*layers.IPv6Fragment)
+	if outIP == nil || outFrag == nil {
+		t.Fatalf("failed to decode fragment: %v", p)
+	}
+	return outIP, outFrag
+}
+
+func TestNotFrag(t *testing.T) {
+	ip := &layers.IPv6{Version: 6, SrcIP: testSrcIP, DstIP: testDstIP}
+	defrag := NewIPv6Defragmenter()
+
+	out, err := defrag.DefragIPv6(ip, nil)
+	if out != ip || err != nil {
+		t.Errorf("defrag: this packet do not need to be defrag ['%v']", err)
+	}
+}
+
+func TestAtomicFragment(t *testing.T) {
+	defrag := NewIPv6Defragmenter()
+	payload := testPayload(100)
+
+	// a pending fragment with the same identification must not be
+	// disturbed by an atomic fragment
+	ip, frag := buildFragment(t, 42, 0, true, payload[:48])
+	if out, err := defrag.DefragIPv6(ip, frag); out != nil || err != nil {
+		t.Fatalf("defrag: unexpected result %v %v", out, err)
+	}
+	ip, frag = buildFragment(t, 42, 0, false, payload)
+	out, err := defrag.DefragIPv6(ip, frag)
+	if err != nil || out == nil {
+		t.Fatalf("defrag: atomic fragment not returned: %v", err)
+	}
+	if !bytes.Equal(out.Payload, payload) || out.NextHeader != layers.IPProtocolUDP {
+		t.Errorf("defrag: bad atomic fragment output %v", out)
+	}
+	ip, frag = buildFragment(t, 42, 48, false, payload[48:])
+	out, err = defrag.DefragIPv6(ip, frag)
+	if err != nil || out == nil {
+		t.Fatalf("defrag: pending packet lost after atomic fragment: %v", err)
+	}
+	if !bytes.Equal(out.Payload, payload) {
+		t.Errorf("defrag: payload is not correctly defragmented")
+	}
+}
+
+func TestDefragInOrderAndReversed(t *testing.T) {
+	payload := testPayload(3000)
+	offsets := []int{0, 1232, 2464}
+	for _, reversed := range []bool{false, true} {
+		defrag := NewIPv6Defragmenter()
+		var out *layers.IPv6
+		for i := range offsets {
+			idx := i
+			if reversed {
+				idx = len(offsets) - 1 - i
+			}
+			end := len(payload)
+			if idx+1 < len(offsets) {
+				end = offsets[idx+1]
+			}
+			ip, frag := buildFragment(t, 7, offsets[idx], idx+1 < len(offsets), payload[offsets[idx]:end])
+			var err error
+			out, err = defrag.DefragIPv6(ip, frag)
+			if err != nil {
+				t.Fatalf("defrag: %v", err)
+			}
+			if (out != nil) != (i == len(offsets)-1) {
+				t.Fatalf("defrag: fragment %d not detected (reversed=%v)", i, reversed)
+			}
+		}
+		if !bytes.Equal(out.Payload, payload) {
+			t.Errorf("defrag: payload is not correctly defragmented (reversed=%v)", reversed)
+		}
+		if int(out.Length) != len(payload) || out.NextHeader != layers.IPProtocolUDP {
+			t.Errorf("defrag: bad header %+v", out)
+		}
+		if !out.SrcIP.Equal(testSrcIP) || !out.DstIP.Equal(testDstIP) {
+			t.Errorf("defrag: bad addresses %v -> %v", out.SrcIP, out.DstIP)
+		}
+		if n := defrag.DiscardOlderThan(time.Now()); n != 0 {
+			t.Errorf("defrag: discarded more fragments then expected: %d", n)
+		}
+	}
+}
+
+func TestDefragDuplicate(t *testing.T) {
+	defrag := NewIPv6Defragmenter()
+	payload := testPayload(64)
+
+	ip, frag := buildFragment(t, 1, 0, true, payload[:32])
+	for i := 0; i < 3; i++ {
+		if out, err := defrag.DefragIPv6(ip, frag); out != nil || err != nil {
+			t.Fatalf("defrag: duplicate should be dropped silently: %v %v", out, err)
+		}
+	}
+	ip, frag = buildFragment(t, 1, 32, false, payload[32:])
+	out, err := defrag.DefragIPv6(ip, frag)
+	if err != nil || out == nil {
+		t.Fatalf("defrag: %v", err)
+	}
+	if !bytes.Equal(out.Payload, payload) {
+		t.Errorf("defrag: payload is not correctly defragmented")
+	}
+}
+
+func TestDefragOverlap(t *testing.T) {
+	defrag := NewIPv6Defragmenter()
+	payload := testPayload(64)
+
+	ip, frag := buildFragment(t, 1, 0, true, payload[:32])
+	if _, err := defrag.DefragIPv6(ip, frag); err != nil {
+		t.Fatal(err)
+	}
+	ip, frag = buildFragment(t, 1, 24, false, payload[24:])
+	if _, err := defrag.DefragIPv6(ip, frag); err != ErrOverlappingFragment {
+		t.Fatalf("defrag: expected overlap error, got %v", err)
+	}
+	// the whole packet must have been discarded
+	if n := defrag.DiscardOlderThan(time.Now().Add(time.Hour)); n != 0 {
+		t.Errorf("defrag: overlapping packet was not flushed (%d lists left)", n)
+	}
+	ip, frag = buildFragment(t, 1, 32, false, payload[32:])
+	if out, err := defrag.DefragIPv6(ip, frag); out != nil || err != nil {
+		t.Errorf("defrag: packet completed from discarded fragments: %v %v", out, err)
+	}
+}
+
+func TestSecurityChecks(t *testing.T) {
+	defrag := NewIPv6Defragmenter()
+
+	ip, frag := buildFragment(t, 1, 0, true, testPayload(4))
+	if _, err := defrag.DefragIPv6(ip, frag); err == nil {
+		t.Error("defrag: too small fragment accepted")
+	}
+	ip, frag = buildFragment(t, 1, 0, true, testPayload(20))
+	if _, err := defrag.DefragIPv6(ip, frag); err == nil {
+		t.Error("defrag: fragment not multiple of 8 accepted")
+	}
+	ip, frag = buildFragment(t, 1, 65528, false, testPayload(16))
+	if _, err := defrag.DefragIPv6(ip, frag); err == nil {
+		t.Error("defrag: overrunning fragment accepted")
+	}
+}
+
+func TestDiscardOlderThan(t *testing.T) {
+	defrag := NewIPv6Defragmenter()
+	now := time.Now()
+
+	ip, frag := buildFragment(t, 1, 0, true, testPayload(16))
+	if _, err := defrag.DefragIPv6WithTimestamp(ip, frag, now.Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	ip, frag = buildFragment(t, 2, 0, true, testPayload(16))
+	if _, err := defrag.DefragIPv6WithTimestamp(ip, frag, now); err != nil {
+		t.Fatal(err)
+	}
+	if n := defrag.DiscardOlderThan(now.Add(-time.Second)); n != 1 {
+		t.Errorf("defrag: expected 1 discarded list, got %d", n)
+	}
+}
@@ -657,3 +657,52 @@ func checkCDPTLVLen(v CiscoDiscoveryValue, l int) (err error) {
 	}
 	return
 }
+
+// cdpChecksum computes the CDP checksum of data. It is the Internet
+// checksum, except that Cisco devices handle an odd trailing byte as the
+// low byte of the last word, added as a signed value.
+func cdpChecksum(data []byte) uint16 {
+	if len(data)%2 == 0 {
+		return gopacket.FoldChecksum(gopacket.ComputeChecksum(data, 0))
+	}
+	n := len(data) - 1
+	csum := gopacket.ComputeChecksum(data[:n], 0)
+	last := uint32(data[n])
+	if last&0x80 != 0 {
+		// the sign extension of the last byte, off by one
+		last += 0xfeff
+	}
+	return gopacket.FoldChecksum(csum + last)
+}
+
+// SerializeTo writes the serialized form of this layer into the
+// SerializationBuffer, implementing gopacket.SerializableLayer.
+// See the docs for gopacket.SerializableLayer for more info.
+func (c *CiscoDiscovery) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOptions) error {
+	length := 4
+	for i := range c.Values {
+		if opts.FixLengths {
+			c.Values[i].Length = uint16(len(c.Values[i].Value) + 4)
+		}
+		length += len(c.Values[i].Value) + 4
+	}
+	bytes, err := b.PrependBytes(length)
+	if err != nil {
+		return err
+	}
+	bytes[0] = c.Version
+	bytes[1] = c.TTL
+	offset := 4
+	for _, v := range c.Values {
+		binary.BigEndian.PutUint16(bytes[offset:], uint16(v.Type))
+		binary.BigEndian.PutUint16(bytes[offset+2:], v.Length)
+		copy(bytes[offset+4:], v.Value)
+		offset += len(v.Value) + 4
+	}
+	if opts.ComputeChecksums {
+		bytes[2], bytes[3] = 0, 0
+		c.Checksum = cdpChecksum(bytes)
+	}
+	binary.BigEndian.PutUint16(bytes[2:4], c.Checksum)
+	return nil
+}
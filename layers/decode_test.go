@@ -753,6 +753,25 @@ func TestDecodeCiscoDiscovery(t *testing.T) {
 	if !reflect.DeepEqual(info, want) {
 		t.Errorf("Values mismatch, \ngot  %#v\nwant %#v\n", info, want)
 	}
+
+	// CiscoDiscoveryInfo only details the values of CiscoDiscovery,
+	// which serializes the whole CDP packet
+	slayers := []gopacket.SerializableLayer{}
+	for _, l := range p.Layers()[:4] {
+		slayers = append(slayers, l.(gopacket.SerializableLayer))
+	}
+	for _, opts := range []gopacket.SerializeOptions{
+		{},
+		{FixLengths: true, ComputeChecksums: true},
+	} {
+		buf := gopacket.NewSerializeBuffer()
+		if err := gopacket.SerializeLayers(buf, opts, slayers...); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), data) {
+			t.Errorf("serialization with opts %#v:\ngot  %x\nwant %x", opts, buf.Bytes(), data)
+		}
+	}
 }
 
 func TestDecodeLinkLayerDiscovery(t *testing.T) {
@@ -986,6 +1005,19 @@ func TestDecodeNortelDiscovery(t *testing.T) {
 	if !reflect.DeepEqual(info, want) {
 		t.Errorf("Values mismatch, \ngot  %#v\nwant %#v\n", info, want)
 	}
+
+	// the Ethernet trailer is not zero, only the 802.3 payload is compared
+	slayers := []gopacket.SerializableLayer{}
+	for _, l := range p.Layers()[1:] {
+		slayers = append(slayers, l.(gopacket.SerializableLayer))
+	}
+	buf := gopacket.NewSerializeBuffer()
+	if err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{}, slayers...); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), data[14:33]) {
+		t.Errorf("serialization:\ngot  %x\nwant %x", buf.Bytes(), data[14:33])
+	}
 }
 
 func TestDecodeIPv6Jumbogram(t *testing.T) {
@@ -1285,6 +1317,9 @@ func TestPFLogUDP(t *testing.T) {
 		LayerTypeUDP,
 		gopacket.LayerTypePayload,
 	}, t)
+	// The UDP checksum of the capture was left to the offload.
+	testSerializationWithOpts(t, p, testPFLogUDP, gopacket.SerializeOptions{})
+	testSerializationWithOpts(t, p, testPFLogUDP, gopacket.SerializeOptions{FixLengths: true})
 }
 
 func TestRegressionDot1QPriority(t *testing.T) {
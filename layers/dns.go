@@ -52,30 +52,42 @@ type DNSType uint16
 
 // DNSType known values.
 const (
-	DNSTypeA      DNSType = 1   // a host address
-	DNSTypeNS     DNSType = 2   // an authoritative name server
-	DNSTypeMD     DNSType = 3   // a mail destination (Obsolete - use MX)
-	DNSTypeMF     DNSType = 4   // a mail forwarder (Obsolete - use MX)
-	DNSTypeCNAME  DNSType = 5   // the canonical name for an alias
-	DNSTypeSOA    DNSType = 6   // marks the start of a zone of authority
-	DNSTypeMB     DNSType = 7   // a mailbox domain name (EXPERIMENTAL)
-	DNSTypeMG     DNSType = 8   // a mail group member (EXPERIMENTAL)
-	DNSTypeMR     DNSType = 9   // a mail rename domain name (EXPERIMENTAL)
-	DNSTypeNULL   DNSType = 10  // a null RR (EXPERIMENTAL)
-	DNSTypeWKS    DNSType = 11  // a well known service description
-	DNSTypePTR    DNSType = 12  // a domain name pointer
-	DNSTypeHINFO  DNSType = 13  // host information
-	DNSTypeMINFO  DNSType = 14  // mailbox or mail list information
-	DNSTypeMX     DNSType = 15  // mail exchange
-	DNSTypeTXT    DNSType = 16  // text strings
-	DNSTypeAAAA   DNSType = 28  // a IPv6 host address [RFC3596]
-	DNSTypeSRV    DNSType = 33  // server discovery [RFC2782] [RFC6195]
-	DNSTypeOPT    DNSType = 41  // OPT Pseudo-RR [RFC6891]
-	DNSTypeRRSIG  DNSType = 46  // RRSIG RR [RFC4034][RFC3755]
-	DNSTypeDNSKEY DNSType = 48  // DNSKEY RR [RFC4034][RFC3755]
-	DNSTypeSVCB   DNSType = 64  // SVCB DNS RR [RFC9460]
-	DNSTypeHTTPS  DNSType = 65  // HTTPS RR [RFC9460]
-	DNSTypeURI    DNSType = 256 // URI RR [RFC7553]
+	DNSTypeA          DNSType = 1   // a host address
+	DNSTypeNS         DNSType = 2   // an authoritative name server
+	DNSTypeMD         DNSType = 3   // a mail destination (Obsolete - use MX)
+	DNSTypeMF         DNSType = 4   // a mail forwarder (Obsolete - use MX)
+	DNSTypeCNAME      DNSType = 5   // the canonical name for an alias
+	DNSTypeSOA        DNSType = 6   // marks the start of a zone of authority
+	DNSTypeMB         DNSType = 7   // a mailbox domain name (EXPERIMENTAL)
+	DNSTypeMG         DNSType = 8   // a mail group member (EXPERIMENTAL)
+	DNSTypeMR         DNSType = 9   // a mail rename domain name (EXPERIMENTAL)
+	DNSTypeNULL       DNSType = 10  // a null RR (EXPERIMENTAL)
+	DNSTypeWKS        DNSType = 11  // a well known service description
+	DNSTypePTR        DNSType = 12  // a domain name pointer
+	DNSTypeHINFO      DNSType = 13  // host information
+	DNSTypeMINFO      DNSType = 14  // mailbox or mail list information
+	DNSTypeMX         DNSType = 15  // mail exchange
+	DNSTypeTXT        DNSType = 16  // text strings
+	DNSTypeAAAA       DNSType = 28  // a IPv6 host address [RFC3596]
+	DNSTypeSRV        DNSType = 33  // server discovery [RFC2782] [RFC6195]
+	DNSTypeNAPTR      DNSType = 35  // Naming Authority Pointer [RFC3403]
+	DNSTypeOPT        DNSType = 41  // OPT Pseudo-RR [RFC6891]
+	DNSTypeDS         DNSType = 43  // Delegation Signer [RFC4034]
+	DNSTypeSSHFP      DNSType = 44  // SSH Key Fingerprint [RFC4255]
+	DNSTypeRRSIG      DNSType = 46  // RRSIG RR [RFC4034][RFC3755]
+	DNSTypeNSEC       DNSType = 47  // NSEC RR [RFC4034][RFC3755]
+	DNSTypeDNSKEY     DNSType = 48  // DNSKEY RR [RFC4034][RFC3755]
+	DNSTypeNSEC3      DNSType = 50  // NSEC3 [RFC5155]
+	DNSTypeNSEC3PARAM DNSType = 51  // NSEC3PARAM [RFC5155]
+	DNSTypeTLSA       DNSType = 52  // TLSA [RFC6698]
+	DNSTypeCDS        DNSType = 59  // Child DS [RFC7344]
+	DNSTypeCDNSKEY    DNSType = 60  // DNSKEY(s) the Child wants reflected in DS [RFC7344]
+	DNSTypeZONEMD     DNSType = 63  // Message Digest Over Zone Data [RFC8976]
+	DNSTypeSVCB       DNSType = 64  // SVCB DNS RR [RFC9460]
+	DNSTypeHTTPS      DNSType = 65  // HTTPS RR [RFC9460]
+	DNSTypeTSIG       DNSType = 250 // Transaction Signature [RFC8945]
+	DNSTypeURI        DNSType = 256 // URI RR [RFC7553]
+	DNSTypeCAA        DNSType = 257 // Certification Authority Restriction [RFC8659]
 )
 
 func (dt DNSType) String() string {
@@ -118,18 +130,42 @@ func (dt DNSType) String() string {
 		return "AAAA"
 	case DNSTypeSRV:
 		return "SRV"
+	case DNSTypeNAPTR:
+		return "NAPTR"
 	case DNSTypeOPT:
 		return "OPT"
+	case DNSTypeDS:
+		return "DS"
+	case DNSTypeSSHFP:
+		return "SSHFP"
 	case DNSTypeRRSIG:
 		return "RRSIG"
+	case DNSTypeNSEC:
+		return "NSEC"
 	case DNSTypeDNSKEY:
 		return "DNSKEY"
+	case DNSTypeNSEC3:
+		return "NSEC3"
+	case DNSTypeNSEC3PARAM:
+		return "NSEC3PARAM"
+	case DNSTypeTLSA:
+		return "TLSA"
+	case DNSTypeCDS:
+		return "CDS"
+	case DNSTypeCDNSKEY:
+		return "CDNSKEY"
+	case DNSTypeZONEMD:
+		return "ZONEMD"
 	case DNSTypeSVCB:
 		return "SVCB"
 	case DNSTypeHTTPS:
 		return "HTTPS"
+	case DNSTypeTSIG:
+		return "TSIG"
 	case DNSTypeURI:
 		return "URI"
+	case DNSTypeCAA:
+		return "CAA"
 	}
 }
 
@@ -290,6 +326,21 @@ type DNS struct {
 	Authorities []DNSResourceRecord
 	Additionals []DNSResourceRecord
 
+	// TCP is set for messages carried over TCP, which are preceded by a
+	// two byte length (RFC 1035, section 4.2.2).  When decoding, Contents
+	// holds the length and the message, and the messages that follow in
+	// the same segment are left in the Payload of the BaseLayer.  When
+	// serializing, the length is prepended to the message.
+	TCP bool
+
+	// Compress enables name compression (RFC 1035, section 4.1.4) when
+	// serializing: a name, or its trailing labels, already written in
+	// the message is replaced by a pointer to it.  Question and owner
+	// names are compressed, and so are the names in the RDATA of the
+	// NS, CNAME, PTR, SOA and MX records (RFC 3597, section 4).  Names
+	// are compared byte for byte.
+	Compress bool
+
 	// buffer for doing name decoding.  We use a single reusable buffer to avoid
 	// name decoding on a single object via multiple DecodeFromBytes calls
 	// requiring constant allocation of small byte slices.
@@ -312,18 +363,55 @@ func decodeDNS(data []byte, p gopacket.PacketBuilder) error {
 	return nil
 }
 
-// DecodeFromBytes decodes the slice into the DNS struct.
+// decodeDNSOverTCP decodes the first length-prefixed DNS message of the
+// byte slice, and the messages following it.
+func decodeDNSOverTCP(data []byte, p gopacket.PacketBuilder) error {
+	d := &DNS{TCP: true}
+	err := d.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(d)
+	p.SetApplicationLayer(d)
+	if len(d.BaseLayer.Payload) == 0 {
+		return nil
+	}
+	// not through NextLayerType, which would make an initialization loop
+	// with LayerTypeDNSOverTCP
+	return p.NextDecoder(gopacket.DecodeFunc(decodeDNSOverTCP))
+}
+
+// DecodeFromBytes decodes the slice into the DNS struct.  If TCP is set,
+// the slice must start with the length of the message.
 func (d *DNS) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
 	d.buffer = d.buffer[:0]
 
+	if d.TCP {
+		if len(data) < 2 {
+			df.SetTruncated()
+			return errDNSPacketTooShort
+		}
+		n := 2 + int(binary.BigEndian.Uint16(data))
+		if len(data) < n {
+			df.SetTruncated()
+			return errDNSPacketTooShort
+		}
+		d.BaseLayer = BaseLayer{Contents: data[:n], Payload: data[n:]}
+		// names are compressed relative to the start of the message
+		return d.decodeMessage(data[2:n], df)
+	}
+	// since there are no further layers, the baselayer's content is
+	// pointing to this layer
+	d.BaseLayer = BaseLayer{Contents: data[:len(data)]}
+	return d.decodeMessage(data, df)
+}
+
+func (d *DNS) decodeMessage(data []byte, df gopacket.DecodeFeedback) error {
 	if len(data) < 12 {
 		df.SetTruncated()
 		return errDNSPacketTooShort
 	}
 
-	// since there are no further layers, the baselayer's content is
-	// pointing to this layer
-	d.BaseLayer = BaseLayer{Contents: data[:len(data)]}
 	d.ID = binary.BigEndian.Uint16(data[:2])
 	d.QR = data[2]&0x80 != 0
 	d.OpCode = DNSOpCode(data[2]>>3) & 0x0F
@@ -403,13 +491,24 @@ func (d *DNS) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
 	return nil
 }
 
-// CanDecode implements gopacket.DecodingLayer.
+// CanDecode implements gopacket.DecodingLayer.  It returns
+// LayerTypeDNSOverTCP if TCP is set.
 func (d *DNS) CanDecode() gopacket.LayerClass {
+	if d.TCP {
+		return LayerTypeDNSOverTCP
+	}
 	return LayerTypeDNS
 }
 
-// NextLayerType implements gopacket.DecodingLayer.
+// NextLayerType implements gopacket.DecodingLayer.  It returns
+// LayerTypeDNSOverTCP if more TCP messages follow this one.
 func (d *DNS) NextLayerType() gopacket.LayerType {
+	if d.TCP {
+		if len(d.BaseLayer.Payload) > 0 {
+			return LayerTypeDNSOverTCP
+		}
+		return gopacket.LayerTypeZero
+	}
 	return gopacket.LayerTypePayload
 }
 
@@ -425,6 +524,7 @@ func b2i(b bool) int {
 	return 0
 }
 
+// recSize returns the size of the RDATA of rr, uncompressed.
 func recSize(rr *DNSResourceRecord) int {
 	switch rr.Type {
 	case DNSTypeA:
@@ -432,15 +532,15 @@ func recSize(rr *DNSResourceRecord) int {
 	case DNSTypeAAAA:
 		return 16
 	case DNSTypeNS:
-		return len(rr.NS) + 2
+		return nameSize(rr.NS)
 	case DNSTypeCNAME:
-		return len(rr.CNAME) + 2
+		return nameSize(rr.CNAME)
 	case DNSTypePTR:
-		return len(rr.PTR) + 2
+		return nameSize(rr.PTR)
 	case DNSTypeSOA:
-		return len(rr.SOA.MName) + 2 + len(rr.SOA.RName) + 2 + 20
+		return nameSize(rr.SOA.MName) + nameSize(rr.SOA.RName) + 20
 	case DNSTypeMX:
-		return 2 + len(rr.MX.Name) + 2
+		return 2 + nameSize(rr.MX.Name)
 	case DNSTypeTXT:
 		l := len(rr.TXTs)
 		for _, txt := range rr.TXTs {
@@ -448,7 +548,7 @@ func recSize(rr *DNSResourceRecord) int {
 		}
 		return l
 	case DNSTypeSRV:
-		return 6 + len(rr.SRV.Name) + 2
+		return 6 + nameSize(rr.SRV.Name)
 	case DNSTypeURI:
 		return 4 + len(rr.URI.Target)
 	case DNSTypeOPT:
@@ -459,27 +559,41 @@ func recSize(rr *DNSResourceRecord) int {
 		return l
 	case DNSTypeRRSIG:
 		return rr.RRSIG.size()
-	case DNSTypeDNSKEY:
+	case DNSTypeDNSKEY, DNSTypeCDNSKEY:
 		return rr.DNSKEY.size()
 	case DNSTypeSVCB, DNSTypeHTTPS:
 		return rr.SVCB.size()
+	case DNSTypeDS, DNSTypeCDS:
+		return rr.DS.size()
+	case DNSTypeNSEC:
+		return rr.NSEC.size()
+	case DNSTypeNSEC3:
+		return rr.NSEC3.size()
+	case DNSTypeNSEC3PARAM:
+		return rr.NSEC3PARAM.size()
+	case DNSTypeCAA:
+		return rr.CAA.size()
+	case DNSTypeTLSA:
+		return rr.TLSA.size()
+	case DNSTypeNAPTR:
+		return rr.NAPTR.size()
+	case DNSTypeSSHFP:
+		return rr.SSHFP.size()
+	case DNSTypeZONEMD:
+		return rr.ZONEMD.size()
+	case DNSTypeTSIG:
+		return rr.TSIG.size()
 	}
 
 	return 0
 }
 
+// computeSize returns the size of recs, uncompressed, which bounds their
+// compressed size.
 func computeSize(recs []DNSResourceRecord) int {
 	sz := 0
 	for _, rr := range recs {
-		v := len(rr.Name)
-
-		if v == 0 {
-			sz += v + 11
-		} else {
-			sz += v + 12
-		}
-
-		sz += recSize(&rr)
+		sz += nameSize(rr.Name) + 10 + recSize(&rr)
 	}
 	return sz
 }
@@ -489,19 +603,30 @@ func computeSize(recs []DNSResourceRecord) int {
 func (d *DNS) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOptions) error {
 	dsz := 0
 	for _, q := range d.Questions {
-		dsz += len(q.Name) + 6
+		dsz += nameSize(q.Name) + 4
 	}
 	dsz += computeSize(d.Answers)
 	dsz += computeSize(d.Authorities)
 	dsz += computeSize(d.Additionals)
 
-	bytes, err := b.PrependBytes(12 + dsz)
-	if err != nil {
-		return err
+	var bytes []byte
+	var compression dnsCompression
+	if d.Compress {
+		// the size computed above is the uncompressed one, so the
+		// message is built aside before being copied in b
+		bytes = make([]byte, 12+dsz)
+		compression = make(dnsCompression)
+	} else {
+		var err error
+		if bytes, err = b.PrependBytes(12 + dsz); err != nil {
+			return err
+		}
 	}
 	binary.BigEndian.PutUint16(bytes, d.ID)
 	bytes[2] = byte((b2i(d.QR) << 7) | (int(d.OpCode) << 3) | (b2i(d.AA) << 2) | (b2i(d.TC) << 1) | b2i(d.RD))
-	bytes[3] = byte((b2i(d.RA) << 7) | (int(d.Z) << 4) | int(d.ResponseCode))
+	// the upper bits of an extended RCODE go in the OPT record, see
+	// SetExtendedResponseCode
+	bytes[3] = byte((b2i(d.RA) << 7) | (int(d.Z) << 4) | int(d.ResponseCode&0xf))
 
 	if opts.FixLengths {
 		d.QDCount = uint16(len(d.Questions))
@@ -516,7 +641,7 @@ func (d *DNS) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOpt
 
 	off := 12
 	for _, qd := range d.Questions {
-		n := qd.encode(bytes, off)
+		n := qd.encode(bytes, off, compression)
 		off += n
 	}
 
@@ -524,7 +649,7 @@ func (d *DNS) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOpt
 		// done this way so we can modify DNSResourceRecord to fix
 		// lengths if requested
 		qa := &d.Answers[i]
-		n, err := qa.encode(bytes, off, opts)
+		n, err := qa.encode(bytes, off, opts, compression)
 		if err != nil {
 			return err
 		}
@@ -533,7 +658,7 @@ func (d *DNS) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOpt
 
 	for i := range d.Authorities {
 		qa := &d.Authorities[i]
-		n, err := qa.encode(bytes, off, opts)
+		n, err := qa.encode(bytes, off, opts, compression)
 		if err != nil {
 			return err
 		}
@@ -541,13 +666,31 @@ func (d *DNS) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOpt
 	}
 	for i := range d.Additionals {
 		qa := &d.Additionals[i]
-		n, err := qa.encode(bytes, off, opts)
+		n, err := qa.encode(bytes, off, opts, compression)
 		if err != nil {
 			return err
 		}
 		off += n
 	}
 
+	if d.Compress {
+		message, err := b.PrependBytes(off)
+		if err != nil {
+			return err
+		}
+		copy(message, bytes[:off])
+		bytes = message
+	}
+	if d.TCP {
+		if len(bytes) > 0xffff {
+			return errDNSMessageTooLong
+		}
+		length, err := b.PrependBytes(2)
+		if err != nil {
+			return err
+		}
+		binary.BigEndian.PutUint16(length, uint16(len(bytes)))
+	}
 	return nil
 }
 
@@ -668,8 +811,8 @@ func (q *DNSQuestion) decode(data []byte, offset int, df gopacket.DecodeFeedback
 	return endq + 4, nil
 }
 
-func (q *DNSQuestion) encode(data []byte, offset int) int {
-	noff := encodeName(q.Name, data, offset)
+func (q *DNSQuestion) encode(data []byte, offset int, compression dnsCompression) int {
+	noff := compression.encodeName(q.Name, data, offset)
 	nSz := noff - offset
 	binary.BigEndian.PutUint16(data[noff:], uint16(q.Type))
 	binary.BigEndian.PutUint16(data[noff+2:], uint16(q.Class))
@@ -717,11 +860,21 @@ type DNSResourceRecord struct {
 	SOA            DNSSOA
 	SRV            DNSSRV
 	MX             DNSMX
-	OPT            []DNSOPT // See RFC 6891, section 6.1.2
-	RRSIG          DNSRRSIG // See RFC 4034, section 3.1
-	DNSKEY         DNSKEY   // See RFC 4034, section 2.1
-	SVCB           DNSSVCB  // See RFC 9460, this contains both SVCB and HTTPS
-	URI            DNSURI
+	OPT            []DNSOPT      // See RFC 6891, section 6.1.2
+	RRSIG          DNSRRSIG      // See RFC 4034, section 3.1
+	DNSKEY         DNSKEY        // See RFC 4034, section 2.1, this contains both DNSKEY and CDNSKEY
+	SVCB           DNSSVCB       // See RFC 9460, this contains both SVCB and HTTPS
+	URI            DNSURI        // See RFC 7553
+	DS             DNSDS         // See RFC 4034, section 5.1, this contains both DS and CDS
+	NSEC           DNSNSEC       // See RFC 4034, section 4.1
+	NSEC3          DNSNSEC3      // See RFC 5155, section 3
+	NSEC3PARAM     DNSNSEC3PARAM // See RFC 5155, section 4
+	CAA            DNSCAA        // See RFC 8659, section 4.1
+	TLSA           DNSTLSA       // See RFC 6698, section 2.1
+	NAPTR          DNSNAPTR      // See RFC 3403, section 4.1
+	SSHFP          DNSSSHFP      // See RFC 4255, section 3.1
+	ZONEMD         DNSZONEMD     // See RFC 8976, section 2.2
+	TSIG           DNSTSIG       // See RFC 8945, section 4.2
 
 	// Undecoded TXT for backward compatibility
 	TXT []byte
@@ -759,6 +912,10 @@ func (rr *DNSResourceRecord) decode(data []byte, offset int, df gopacket.DecodeF
 }
 
 func encodeName(name []byte, data []byte, offset int) int {
+	if isRootName(name) {
+		data[offset] = 0x00 // terminal
+		return offset + 1
+	}
 	l := 0
 	for i := range name {
 		if name[i] == '.' {
@@ -771,21 +928,63 @@ func encodeName(name []byte, data []byte, offset int) int {
 		}
 	}
 
-	if len(name) == 0 {
-		data[offset] = 0x00 // terminal
-		return offset + 1
-	}
-
 	// length for final portion
 	data[offset+len(name)-l] = byte(l)
 	data[offset+len(name)+1] = 0x00 // terminal
 	return offset + len(name) + 2
 }
 
-func (rr *DNSResourceRecord) encode(data []byte, offset int, opts gopacket.SerializeOptions) (int, error) {
+// dnsCompression maps the names written in a message, and their
+// trailing labels, to their offset in the message.  A nil dnsCompression
+// writes names uncompressed.
+type dnsCompression map[string]int
+
+// encodeName writes name at offset, replacing its longest suffix already
+// written by a pointer, and returns the offset following it.
+func (c dnsCompression) encodeName(name []byte, data []byte, offset int) int {
+	if c == nil || isRootName(name) {
+		return encodeName(name, data, offset)
+	}
+	// i is the start of the suffix found in c, or len(name) if none
+	i, ptr := 0, -1
+	for ; i < len(name); i++ {
+		if i == 0 || name[i-1] == '.' {
+			if o, ok := c[string(name[i:])]; ok {
+				ptr = o
+				break
+			}
+		}
+	}
+	var end int
+	switch {
+	case ptr < 0:
+		end = encodeName(name, data, offset)
+	case i == 0:
+		end = offset
+	default:
+		// the labels before the suffix, whose terminal is overwritten
+		// by the pointer
+		end = encodeName(name[:i-1], data, offset) - 1
+	}
+	if ptr >= 0 {
+		binary.BigEndian.PutUint16(data[end:], 0xc000|uint16(ptr))
+		end += 2
+	}
+	// the label starting at name[j] is written at offset+j
+	for j := 0; j < i; j++ {
+		if (j == 0 || name[j-1] == '.') && offset+j < 0x4000 {
+			c[string(name[j:])] = offset + j
+		}
+	}
+	return end
+}
+
+func (rr *DNSResourceRecord) encode(data []byte, offset int, opts gopacket.SerializeOptions, compression dnsCompression) (int, error) {
 
-	noff := encodeName(rr.Name, data, offset)
+	noff := compression.encodeName(rr.Name, data, offset)
 	nSz := noff - offset
+	// the RDATA size, if names in it may be compressed
+	dSz := -1
 
 	binary.BigEndian.PutUint16(data[noff:], uint16(rr.Type))
 	binary.BigEndian.PutUint16(data[noff+2:], uint16(rr.Class))
@@ -797,22 +996,23 @@ func (rr *DNSResourceRecord) encode(data []byte, offset int, opts gopacket.Seria
 	case DNSTypeAAAA:
 		copy(data[noff+10:], rr.IP)
 	case DNSTypeNS:
-		encodeName(rr.NS, data, noff+10)
+		dSz = compression.encodeName(rr.NS, data, noff+10) - noff - 10
 	case DNSTypeCNAME:
-		encodeName(rr.CNAME, data, noff+10)
+		dSz = compression.encodeName(rr.CNAME, data, noff+10) - noff - 10
 	case DNSTypePTR:
-		encodeName(rr.PTR, data, noff+10)
+		dSz = compression.encodeName(rr.PTR, data, noff+10) - noff - 10
 	case DNSTypeSOA:
-		noff2 := encodeName(rr.SOA.MName, data, noff+10)
-		noff2 = encodeName(rr.SOA.RName, data, noff2)
+		noff2 := compression.encodeName(rr.SOA.MName, data, noff+10)
+		noff2 = compression.encodeName(rr.SOA.RName, data, noff2)
 		binary.BigEndian.PutUint32(data[noff2:], rr.SOA.Serial)
 		binary.BigEndian.PutUint32(data[noff2+4:], rr.SOA.Refresh)
 		binary.BigEndian.PutUint32(data[noff2+8:], rr.SOA.Retry)
 		binary.BigEndian.PutUint32(data[noff2+12:], rr.SOA.Expire)
 		binary.BigEndian.PutUint32(data[noff2+16:], rr.SOA.Minimum)
+		dSz = noff2 + 20 - noff - 10
 	case DNSTypeMX:
 		binary.BigEndian.PutUint16(data[noff+10:], rr.MX.Preference)
-		encodeName(rr.MX.Name, data, noff+12)
+		dSz = compression.encodeName(rr.MX.Name, data, noff+12) - noff - 10
 	case DNSTypeTXT:
 		noff2 := noff + 10
 		for _, txt := range rr.TXTs {
@@ -839,16 +1039,38 @@ func (rr *DNSResourceRecord) encode(data []byte, offset int, opts gopacket.Seria
 		}
 	case DNSTypeRRSIG:
 		rr.RRSIG.encode(data, noff+10)
-	case DNSTypeDNSKEY:
+	case DNSTypeDNSKEY, DNSTypeCDNSKEY:
 		rr.DNSKEY.encode(data, noff+10)
 	case DNSTypeSVCB, DNSTypeHTTPS:
 		rr.SVCB.encode(data, noff+10)
+	case DNSTypeDS, DNSTypeCDS:
+		rr.DS.encode(data, noff+10)
+	case DNSTypeNSEC:
+		rr.NSEC.encode(data, noff+10)
+	case DNSTypeNSEC3:
+		rr.NSEC3.encode(data, noff+10)
+	case DNSTypeNSEC3PARAM:
+		rr.NSEC3PARAM.encode(data, noff+10)
+	case DNSTypeCAA:
+		rr.CAA.encode(data, noff+10)
+	case DNSTypeTLSA:
+		rr.TLSA.encode(data, noff+10)
+	case DNSTypeNAPTR:
+		rr.NAPTR.encode(data, noff+10)
+	case DNSTypeSSHFP:
+		rr.SSHFP.encode(data, noff+10)
+	case DNSTypeZONEMD:
+		rr.ZONEMD.encode(data, noff+10)
+	case DNSTypeTSIG:
+		rr.TSIG.encode(data, noff+10)
 	default:
 		return 0, fmt.Errorf("serializing resource record of type %v not supported", rr.Type)
 	}
 
 	// DataLength
-	dSz := recSize(rr)
+	if dSz < 0 {
+		dSz = recSize(rr)
+	}
 	binary.BigEndian.PutUint16(data[noff+8:], uint16(dSz))
 
 	if opts.FixLengths {
@@ -870,6 +1092,32 @@ func (rr *DNSResourceRecord) String() string {
 	if rr.Type == DNSTypeURI {
 		return fmt.Sprintf("URI %d %d %s", rr.URI.Priority, rr.URI.Weight, string(rr.URI.Target))
 	}
+	var rdata fmt.Stringer
+	switch rr.Type {
+	case DNSTypeDS, DNSTypeCDS:
+		rdata = rr.DS
+	case DNSTypeNSEC:
+		rdata = rr.NSEC
+	case DNSTypeNSEC3:
+		rdata = rr.NSEC3
+	case DNSTypeNSEC3PARAM:
+		rdata = rr.NSEC3PARAM
+	case DNSTypeCAA:
+		rdata = rr.CAA
+	case DNSTypeTLSA:
+		rdata = rr.TLSA
+	case DNSTypeNAPTR:
+		rdata = rr.NAPTR
+	case DNSTypeSSHFP:
+		rdata = rr.SSHFP
+	case DNSTypeZONEMD:
+		rdata = rr.ZONEMD
+	case DNSTypeTSIG:
+		rdata = rr.TSIG
+	}
+	if rdata != nil {
+		return rr.Type.String() + " " + rdata.String()
+	}
 	if rr.Class == DNSClassIN {
 		switch rr.Type {
 		case DNSTypeA, DNSTypeAAAA:
@@ -1061,7 +1309,7 @@ func (rr *DNSResourceRecord) decodeRData(data []byte, offset int, buffer *[]byte
 		if err != nil {
 			return err
 		}
-	case DNSTypeDNSKEY:
+	case DNSTypeDNSKEY, DNSTypeCDNSKEY:
 		err := rr.DNSKEY.decode(data, offset)
 		if err != nil {
 			return err
@@ -1072,6 +1320,27 @@ func (rr *DNSResourceRecord) decodeRData(data []byte, offset int, buffer *[]byte
 			return err
 		}
 		rr.SVCB = svcb
+	case DNSTypeDS, DNSTypeCDS:
+		return rr.DS.decode(rr.Data)
+	case DNSTypeNSEC:
+		return rr.NSEC.decode(data, offset, buffer)
+	case DNSTypeNSEC3:
+		return rr.NSEC3.decode(rr.Data)
+	case DNSTypeNSEC3PARAM:
+		_, err := rr.NSEC3PARAM.decode(rr.Data)
+		return err
+	case DNSTypeCAA:
+		return rr.CAA.decode(rr.Data)
+	case DNSTypeTLSA:
+		return rr.TLSA.decode(rr.Data)
+	case DNSTypeNAPTR:
+		return rr.NAPTR.decode(data, offset, buffer)
+	case DNSTypeSSHFP:
+		return rr.SSHFP.decode(rr.Data)
+	case DNSTypeZONEMD:
+		return rr.ZONEMD.decode(rr.Data)
+	case DNSTypeTSIG:
+		return rr.TSIG.decode(data, offset, buffer)
 	}
 	return nil
 }
@@ -1221,8 +1490,8 @@ type DNSRRSIG struct {
 }
 
 func (rrsig DNSRRSIG) size() int {
-	// 18 bytes for the fixed fields, 2 bytes for the first Label Length, and ending 0x00 byte.
-	return 18 + len(rrsig.SignerName) + 2 + len(rrsig.Signature)
+	// 18 bytes for the fixed fields
+	return 18 + nameSize(rrsig.SignerName) + len(rrsig.Signature)
 }
 
 func (rrsig DNSRRSIG) String() string {
@@ -1405,6 +1674,8 @@ func (doc DNSOptionCode) String() string {
 		return "CodeChain"
 	case DNSOptionCodeEDNSKeyTag:
 		return "CodeEDNSKeyTag"
+	case DNSOptionCodeExtendedDNSError:
+		return "ExtendedDNSError"
 	case DNSOptionCodeEDNSClientTag:
 		return "EDNSClientTag"
 	case DNSOptionCodeEDNSServerTag:
@@ -1427,6 +1698,7 @@ const (
 	DNSOptionCodePadding          DNSOptionCode = 12
 	DNSOptionCodeChain            DNSOptionCode = 13
 	DNSOptionCodeEDNSKeyTag       DNSOptionCode = 14
+	DNSOptionCodeExtendedDNSError DNSOptionCode = 15 // RFC 8914
 	DNSOptionCodeEDNSClientTag    DNSOptionCode = 16
 	DNSOptionCodeEDNSServerTag    DNSOptionCode = 17
 	DNSOptionCodeDeviceID         DNSOptionCode = 26946
@@ -1448,6 +1720,7 @@ var (
 	errDNSNameOffsetTooHigh    = errors.New("dns name offset too high")
 	errDNSNameOffsetNegative   = errors.New("dns name offset is negative")
 	errDNSPacketTooShort       = errors.New("DNS packet too short")
+	errDNSMessageTooLong       = errors.New("DNS message too long for TCP")
 	errDNSNameTooLong          = errors.New("dns name is too long")
 	errDNSNameInvalidIndex     = errors.New("dns name uncomputable: invalid index")
 	errDNSPointerOffsetTooHigh = errors.New("dns offset pointer too high")
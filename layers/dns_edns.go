@@ -0,0 +1,365 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package layers
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// DefaultEDNSUDPSize is the UDP payload size advertised by the OPT
+// records the DNS helpers add, as recommended by the DNS flag day 2020.
+const DefaultEDNSUDPSize = 1232
+
+// The TTL of an OPT record holds the upper bits of the RCODE, the EDNS
+// version and the flags, see RFC 6891, section 6.1.3.
+const (
+	ednsVersionShift = 16
+	ednsRCodeShift   = 24
+	ednsFlagDO       = 1 << 15
+)
+
+// EDNS returns the OPT pseudo-record of the message, or nil if it has
+// none.
+func (d *DNS) EDNS() *DNSResourceRecord {
+	for i := range d.Additionals {
+		if d.Additionals[i].Type == DNSTypeOPT {
+			return &d.Additionals[i]
+		}
+	}
+	return nil
+}
+
+// AddEDNS returns the OPT pseudo-record of the message, adding one
+// advertising udpSize if it has none.
+func (d *DNS) AddEDNS(udpSize uint16) *DNSResourceRecord {
+	if opt := d.EDNS(); opt != nil {
+		return opt
+	}
+	d.Additionals = append(d.Additionals, DNSResourceRecord{Type: DNSTypeOPT, Class: DNSClass(udpSize)})
+	return &d.Additionals[len(d.Additionals)-1]
+}
+
+// EDNSVersion returns the EDNS version of the message.  ok is false if
+// the message has no OPT record.
+func (d *DNS) EDNSVersion() (version uint8, ok bool) {
+	opt := d.EDNS()
+	if opt == nil {
+		return 0, false
+	}
+	return uint8(opt.TTL >> ednsVersionShift), true
+}
+
+// SetEDNSVersion sets the EDNS version of the message, adding an OPT
+// record if needed.
+func (d *DNS) SetEDNSVersion(version uint8) {
+	opt := d.AddEDNS(DefaultEDNSUDPSize)
+	opt.TTL = opt.TTL&^(0xff<<ednsVersionShift) | uint32(version)<<ednsVersionShift
+}
+
+// DNSSECOK reports whether the DO bit of the message is set, i.e. whether
+// DNSSEC records are requested (RFC 3225).
+func (d *DNS) DNSSECOK() bool {
+	opt := d.EDNS()
+	return opt != nil && opt.TTL&ednsFlagDO != 0
+}
+
+// SetDNSSECOK sets the DO bit of the message, adding an OPT record if
+// needed.
+func (d *DNS) SetDNSSECOK(do bool) {
+	opt := d.AddEDNS(DefaultEDNSUDPSize)
+	if do {
+		opt.TTL |= ednsFlagDO
+	} else {
+		opt.TTL &^= ednsFlagDO
+	}
+}
+
+// ExtendedResponseCode returns the response code of the message, made of
+// the RCODE of the header and the upper bits held by the OPT record.
+// Decoding already sets ResponseCode to it.
+func (d *DNS) ExtendedResponseCode() DNSResponseCode {
+	rcode := d.ResponseCode & 0xf
+	if opt := d.EDNS(); opt != nil {
+		rcode |= DNSResponseCode(opt.TTL >> ednsRCodeShift << 4)
+	}
+	return rcode
+}
+
+// SetExtendedResponseCode sets ResponseCode to rcode, and the upper bits
+// of the OPT record to the ones of rcode.  An OPT record is added if the
+// message has none and rcode does not fit in the header.
+func (d *DNS) SetExtendedResponseCode(rcode DNSResponseCode) {
+	d.ResponseCode = rcode
+	opt := d.EDNS()
+	if opt == nil {
+		if rcode <= 0xf {
+			return
+		}
+		opt = d.AddEDNS(DefaultEDNSUDPSize)
+	}
+	opt.TTL = opt.TTL&^(0xff<<ednsRCodeShift) | uint32(rcode>>4)<<ednsRCodeShift
+}
+
+var errDNSOptionCode = errors.New("DNS option of another type")
+
+// DNSOptionClientSubnet is an EDNS Client Subnet option, see RFC 7871,
+// section 6.
+type DNSOptionClientSubnet struct {
+	// Family is the address family, 1 for IPv4 and 2 for IPv6.
+	Family             uint16
+	SourcePrefixLength uint8
+	ScopePrefixLength  uint8
+	// Address is the address of the subnet, with the bits past
+	// SourcePrefixLength cleared.
+	Address net.IP
+}
+
+// ClientSubnet decodes an EDNS Client Subnet option.
+func (opt DNSOPT) ClientSubnet() (DNSOptionClientSubnet, error) {
+	var ecs DNSOptionClientSubnet
+	if opt.Code != DNSOptionCodeEDNSClientSubnet {
+		return ecs, errDNSOptionCode
+	}
+	if len(opt.Data) < 4 {
+		return ecs, errors.New("EDNS Client Subnet option too small")
+	}
+	ecs.Family = binary.BigEndian.Uint16(opt.Data)
+	ecs.SourcePrefixLength = opt.Data[2]
+	ecs.ScopePrefixLength = opt.Data[3]
+	var size int
+	switch ecs.Family {
+	case 1:
+		size = net.IPv4len
+	case 2:
+		size = net.IPv6len
+	default:
+		return ecs, fmt.Errorf("EDNS Client Subnet option with unknown family %d", ecs.Family)
+	}
+	addr := opt.Data[4:]
+	if int(ecs.SourcePrefixLength) > size*8 || len(addr) != (int(ecs.SourcePrefixLength)+7)/8 {
+		return ecs, errors.New("EDNS Client Subnet option with invalid address length")
+	}
+	ecs.Address = make(net.IP, size)
+	copy(ecs.Address, addr)
+	return ecs, nil
+}
+
+// OPT returns the option, with the address truncated to the source
+// prefix length.  Family is set from the address if zero.
+func (ecs DNSOptionClientSubnet) OPT() DNSOPT {
+	family := ecs.Family
+	if family == 0 {
+		family = 2
+		if ecs.Address.To4() != nil {
+			family = 1
+		}
+	}
+	addr := ecs.Address.To16()
+	if family == 1 {
+		addr = ecs.Address.To4()
+	}
+	n := (int(ecs.SourcePrefixLength) + 7) / 8
+	data := make([]byte, 4+n)
+	binary.BigEndian.PutUint16(data, family)
+	data[2] = ecs.SourcePrefixLength
+	data[3] = ecs.ScopePrefixLength
+	copy(data[4:], addr)
+	if bits := ecs.SourcePrefixLength % 8; bits != 0 {
+		data[len(data)-1] &= 0xff << (8 - bits)
+	}
+	return DNSOPT{Code: DNSOptionCodeEDNSClientSubnet, Data: data}
+}
+
+// DNSOptionCookie is a DNS Cookie option, see RFC 7873, section 4.
+type DNSOptionCookie struct {
+	// Client is the 8 bytes client cookie, and Server the 8 to 32 bytes
+	// server cookie, absent from the first query to a server.
+	Client, Server []byte
+}
+
+// Cookie decodes a DNS Cookie option.
+func (opt DNSOPT) Cookie() (DNSOptionCookie, error) {
+	if opt.Code != DNSOptionCodeCookie {
+		return DNSOptionCookie{}, errDNSOptionCode
+	}
+	if n := len(opt.Data); n != 8 && (n < 16 || n > 40) {
+		return DNSOptionCookie{}, fmt.Errorf("DNS Cookie option of invalid length %d", n)
+	}
+	cookie := DNSOptionCookie{Client: opt.Data[:8]}
+	if len(opt.Data) > 8 {
+		cookie.Server = opt.Data[8:]
+	}
+	return cookie, nil
+}
+
+// OPT returns the option.
+func (cookie DNSOptionCookie) OPT() DNSOPT {
+	data := make([]byte, 0, len(cookie.Client)+len(cookie.Server))
+	data = append(data, cookie.Client...)
+	data = append(data, cookie.Server...)
+	return DNSOPT{Code: DNSOptionCodeCookie, Data: data}
+}
+
+// DNSOptionPadding is a Padding option, see RFC 7830.
+type DNSOptionPadding struct {
+	// Length is the number of padding bytes.
+	Length int
+}
+
+// Padding decodes a Padding option.  Its bytes should be zero, but are
+// not checked.
+func (opt DNSOPT) Padding() (DNSOptionPadding, error) {
+	if opt.Code != DNSOptionCodePadding {
+		return DNSOptionPadding{}, errDNSOptionCode
+	}
+	return DNSOptionPadding{Length: len(opt.Data)}, nil
+}
+
+// OPT returns the option, made of zero bytes.
+func (padding DNSOptionPadding) OPT() DNSOPT {
+	return DNSOPT{Code: DNSOptionCodePadding, Data: make([]byte, padding.Length)}
+}
+
+// DNSOptionKeepalive is an edns-tcp-keepalive option, see RFC 7828,
+// section 3.1.
+type DNSOptionKeepalive struct {
+	// HasTimeout is false for the option of queries, which carries no
+	// timeout.
+	HasTimeout bool
+	// Timeout is the idle timeout, in units of 100 milliseconds.
+	Timeout uint16
+}
+
+// Keepalive decodes an edns-tcp-keepalive option.
+func (opt DNSOPT) Keepalive() (DNSOptionKeepalive, error) {
+	if opt.Code != DNSOptionCodeEDNSKeepAlive {
+		return DNSOptionKeepalive{}, errDNSOptionCode
+	}
+	switch len(opt.Data) {
+	case 0:
+		return DNSOptionKeepalive{}, nil
+	case 2:
+		return DNSOptionKeepalive{HasTimeout: true, Timeout: binary.BigEndian.Uint16(opt.Data)}, nil
+	}
+	return DNSOptionKeepalive{}, fmt.Errorf("edns-tcp-keepalive option of invalid length %d", len(opt.Data))
+}
+
+// OPT returns the option.
+func (keepalive DNSOptionKeepalive) OPT() DNSOPT {
+	opt := DNSOPT{Code: DNSOptionCodeEDNSKeepAlive}
+	if keepalive.HasTimeout {
+		opt.Data = binary.BigEndian.AppendUint16(nil, keepalive.Timeout)
+	}
+	return opt
+}
+
+// DNSExtendedErrorCode is the INFO-CODE of an Extended DNS Error option,
+// see RFC 8914, section 4.
+type DNSExtendedErrorCode uint16
+
+// DNSExtendedErrorCode known values.
+const (
+	DNSExtendedErrorCodeOther                      DNSExtendedErrorCode = 0
+	DNSExtendedErrorCodeUnsupportedDNSKEYAlgorithm DNSExtendedErrorCode = 1
+	DNSExtendedErrorCodeUnsupportedDSDigestType    DNSExtendedErrorCode = 2
+	DNSExtendedErrorCodeStaleAnswer                DNSExtendedErrorCode = 3
+	DNSExtendedErrorCodeForgedAnswer               DNSExtendedErrorCode = 4
+	DNSExtendedErrorCodeDNSSECIndeterminate        DNSExtendedErrorCode = 5
+	DNSExtendedErrorCodeDNSSECBogus                DNSExtendedErrorCode = 6
+	DNSExtendedErrorCodeSignatureExpired           DNSExtendedErrorCode = 7
+	DNSExtendedErrorCodeSignatureNotYetValid       DNSExtendedErrorCode = 8
+	DNSExtendedErrorCodeDNSKEYMissing              DNSExtendedErrorCode = 9
+	DNSExtendedErrorCodeRRSIGsMissing              DNSExtendedErrorCode = 10
+	DNSExtendedErrorCodeNoZoneKeyBitSet            DNSExtendedErrorCode = 11
+	DNSExtendedErrorCodeNSECMissing                DNSExtendedErrorCode = 12
+	DNSExtendedErrorCodeCachedError                DNSExtendedErrorCode = 13
+	DNSExtendedErrorCodeNotReady                   DNSExtendedErrorCode = 14
+	DNSExtendedErrorCodeBlocked                    DNSExtendedErrorCode = 15
+	DNSExtendedErrorCodeCensored                   DNSExtendedErrorCode = 16
+	DNSExtendedErrorCodeFiltered                   DNSExtendedErrorCode = 17
+	DNSExtendedErrorCodeProhibited                 DNSExtendedErrorCode = 18
+	DNSExtendedErrorCodeStaleNXDomainAnswer        DNSExtendedErrorCode = 19
+	DNSExtendedErrorCodeNotAuthoritative           DNSExtendedErrorCode = 20
+	DNSExtendedErrorCodeNotSupported               DNSExtendedErrorCode = 21
+	DNSExtendedErrorCodeNoReachableAuthority       DNSExtendedErrorCode = 22
+	DNSExtendedErrorCodeNetworkError               DNSExtendedErrorCode = 23
+	DNSExtendedErrorCodeInvalidData                DNSExtendedErrorCode = 24
+)
+
+var dnsExtendedErrorCodeNames = [...]string{
+	"Other Error",
+	"Unsupported DNSKEY Algorithm",
+	"Unsupported DS Digest Type",
+	"Stale Answer",
+	"Forged Answer",
+	"DNSSEC Indeterminate",
+	"DNSSEC Bogus",
+	"Signature Expired",
+	"Signature Not Yet Valid",
+	"DNSKEY Missing",
+	"RRSIGs Missing",
+	"No Zone Key Bit Set",
+	"NSEC Missing",
+	"Cached Error",
+	"Not Ready",
+	"Blocked",
+	"Censored",
+	"Filtered",
+	"Prohibited",
+	"Stale NXDomain Answer",
+	"Not Authoritative",
+	"Not Supported",
+	"No Reachable Authority",
+	"Network Error",
+	"Invalid Data",
+}
+
+func (code DNSExtendedErrorCode) String() string {
+	if int(code) < len(dnsExtendedErrorCodeNames) {
+		return dnsExtendedErrorCodeNames[code]
+	}
+	return fmt.Sprintf("Unknown(%d)", uint16(code))
+}
+
+// DNSOptionExtendedError is an Extended DNS Error option, see RFC 8914,
+// section 2.
+type DNSOptionExtendedError struct {
+	InfoCode DNSExtendedErrorCode
+	// ExtraText is an UTF-8 text for humans, possibly empty.
+	ExtraText []byte
+}
+
+// ExtendedError decodes an Extended DNS Error option.
+func (opt DNSOPT) ExtendedError() (DNSOptionExtendedError, error) {
+	if opt.Code != DNSOptionCodeExtendedDNSError {
+		return DNSOptionExtendedError{}, errDNSOptionCode
+	}
+	if len(opt.Data) < 2 {
+		return DNSOptionExtendedError{}, errors.New("Extended DNS Error option too small")
+	}
+	ede := DNSOptionExtendedError{InfoCode: DNSExtendedErrorCode(binary.BigEndian.Uint16(opt.Data))}
+	if len(opt.Data) > 2 {
+		ede.ExtraText = opt.Data[2:]
+	}
+	return ede, nil
+}
+
+// OPT returns the option.
+func (ede DNSOptionExtendedError) OPT() DNSOPT {
+	data := binary.BigEndian.AppendUint16(make([]byte, 0, 2+len(ede.ExtraText)), uint16(ede.InfoCode))
+	return DNSOPT{Code: DNSOptionCodeExtendedDNSError, Data: append(data, ede.ExtraText...)}
+}
+
+func (ede DNSOptionExtendedError) String() string {
+	if len(ede.ExtraText) == 0 {
+		return fmt.Sprintf("%d (%v)", ede.InfoCode, ede.InfoCode)
+	}
+	return fmt.Sprintf("%d (%v): %s", ede.InfoCode, ede.InfoCode, ede.ExtraText)
+}
@@ -0,0 +1,183 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package layers
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/gopacket/gopacket"
+)
+
+func TestDNSOptionClientSubnet(t *testing.T) {
+	for _, test := range []struct {
+		ecs  DNSOptionClientSubnet
+		data []byte
+		addr net.IP
+	}{
+		{
+			DNSOptionClientSubnet{SourcePrefixLength: 24, Address: net.IP{192, 0, 2, 77}},
+			[]byte{0, 1, 24, 0, 192, 0, 2},
+			net.IP{192, 0, 2, 0},
+		},
+		{
+			DNSOptionClientSubnet{SourcePrefixLength: 20, ScopePrefixLength: 16, Address: net.IP{198, 51, 100, 1}},
+			[]byte{0, 1, 20, 16, 198, 51, 96},
+			net.IP{198, 51, 96, 0},
+		},
+		{
+			DNSOptionClientSubnet{SourcePrefixLength: 36, Address: net.ParseIP("2001:db8:abcd:1234::1")},
+			[]byte{0, 2, 36, 0, 0x20, 0x01, 0x0d, 0xb8, 0xa0},
+			net.ParseIP("2001:db8:a000::"),
+		},
+		{
+			DNSOptionClientSubnet{Family: 1},
+			[]byte{0, 1, 0, 0},
+			net.IPv4zero.To4(),
+		},
+	} {
+		opt := test.ecs.OPT()
+		if opt.Code != DNSOptionCodeEDNSClientSubnet || !bytes.Equal(opt.Data, test.data) {
+			t.Errorf("%+v: got %v", test.ecs, opt)
+		}
+		ecs, err := opt.ClientSubnet()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ecs.SourcePrefixLength != test.ecs.SourcePrefixLength || ecs.ScopePrefixLength != test.ecs.ScopePrefixLength || !ecs.Address.Equal(test.addr) {
+			t.Errorf("%+v: got %+v", test.ecs, ecs)
+		}
+	}
+
+	for _, data := range [][]byte{
+		{0, 1, 24},
+		{0, 3, 0, 0},
+		{0, 1, 24, 0, 192, 0},
+		{0, 1, 24, 0, 192, 0, 2, 0},
+		{0, 1, 33, 0, 1, 2, 3, 4, 5},
+	} {
+		if _, err := (DNSOPT{Code: DNSOptionCodeEDNSClientSubnet, Data: data}).ClientSubnet(); err == nil {
+			t.Errorf("no error for %x", data)
+		}
+	}
+	if _, err := (DNSOPT{Code: DNSOptionCodeCookie, Data: []byte{0, 1, 0, 0}}).ClientSubnet(); err == nil {
+		t.Error("no error for a cookie option")
+	}
+}
+
+func TestDNSOptionCookie(t *testing.T) {
+	client := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	server := []byte{9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23, 24}
+	for _, want := range []DNSOptionCookie{{Client: client}, {Client: client, Server: server}} {
+		got, err := want.OPT().Cookie()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	}
+	for _, n := range []int{0, 7, 9, 15, 41} {
+		if _, err := (DNSOPT{Code: DNSOptionCodeCookie, Data: make([]byte, n)}).Cookie(); err == nil {
+			t.Errorf("no error for %d bytes", n)
+		}
+	}
+}
+
+func TestDNSOptionPaddingAndKeepalive(t *testing.T) {
+	opt := DNSOptionPadding{Length: 5}.OPT()
+	if !bytes.Equal(opt.Data, make([]byte, 5)) {
+		t.Errorf("got padding %x", opt.Data)
+	}
+	if padding, err := opt.Padding(); err != nil || padding.Length != 5 {
+		t.Errorf("got padding %+v, error %v", padding, err)
+	}
+
+	for _, want := range []DNSOptionKeepalive{{}, {HasTimeout: true, Timeout: 1200}} {
+		opt := want.OPT()
+		if got, err := opt.Keepalive(); err != nil || got != want {
+			t.Errorf("got %+v, error %v, want %+v", got, err, want)
+		}
+	}
+	if _, err := (DNSOPT{Code: DNSOptionCodeEDNSKeepAlive, Data: []byte{1}}).Keepalive(); err == nil {
+		t.Error("no error for 1 byte")
+	}
+}
+
+func TestDNSOptionExtendedError(t *testing.T) {
+	want := DNSOptionExtendedError{InfoCode: DNSExtendedErrorCodeSignatureExpired, ExtraText: []byte("example.com/DNSKEY")}
+	opt := want.OPT()
+	if !bytes.Equal(opt.Data[:2], []byte{0, 7}) {
+		t.Errorf("got %x", opt.Data)
+	}
+	got, err := opt.ExtendedError()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v", got)
+	}
+	if s := got.String(); s != "7 (Signature Expired): example.com/DNSKEY" {
+		t.Errorf("got %q", s)
+	}
+	if s := (DNSOptionExtendedError{InfoCode: 100}).String(); s != "100 (Unknown(100))" {
+		t.Errorf("got %q", s)
+	}
+	if _, err := (DNSOPT{Code: DNSOptionCodeExtendedDNSError, Data: []byte{0}}).ExtendedError(); err == nil {
+		t.Error("no error for 1 byte")
+	}
+}
+
+func TestDNSEDNSHelpers(t *testing.T) {
+	dns := &DNS{ID: 1, QR: true}
+	if _, ok := dns.EDNSVersion(); ok || dns.DNSSECOK() || dns.EDNS() != nil {
+		t.Fatal("EDNS reported without an OPT record")
+	}
+	dns.SetExtendedResponseCode(DNSResponseCodeNXDomain)
+	if dns.EDNS() != nil || dns.ResponseCode != DNSResponseCodeNXDomain {
+		t.Fatal("OPT record added for a header RCODE")
+	}
+	dns.SetDNSSECOK(true)
+	dns.SetEDNSVersion(1)
+	dns.SetExtendedResponseCode(DNSResponseCodeBadCookie)
+	opt := dns.EDNS()
+	if opt == nil || opt.Class != DefaultEDNSUDPSize || opt.TTL != 0x01018000 {
+		t.Fatalf("got OPT record %+v", opt)
+	}
+	opt.OPT = append(opt.OPT, DNSOptionExtendedError{InfoCode: DNSExtendedErrorCodeBlocked}.OPT())
+
+	buf := gopacket.NewSerializeBuffer()
+	if err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{FixLengths: true}, dns); err != nil {
+		t.Fatal(err)
+	}
+	// the header holds the lower bits of BADCOOKIE
+	if rcode := buf.Bytes()[3]; rcode != 0x07 {
+		t.Errorf("got header RCODE %d", rcode)
+	}
+	p := gopacket.NewPacket(buf.Bytes(), LayerTypeDNS, testDecodeOptions)
+	dns2, ok := p.Layer(LayerTypeDNS).(*DNS)
+	if !ok {
+		t.Fatal(p.ErrorLayer().Error())
+	}
+	if version, ok := dns2.EDNSVersion(); !ok || version != 1 || !dns2.DNSSECOK() {
+		t.Errorf("got version %d, DO %v", version, dns2.DNSSECOK())
+	}
+	if dns2.ResponseCode != DNSResponseCodeBadCookie || dns2.ExtendedResponseCode() != DNSResponseCodeBadCookie {
+		t.Errorf("got RCODE %d, extended %d", dns2.ResponseCode, dns2.ExtendedResponseCode())
+	}
+	if ede, err := dns2.EDNS().OPT[0].ExtendedError(); err != nil || ede.InfoCode != DNSExtendedErrorCodeBlocked {
+		t.Errorf("got %+v, error %v", ede, err)
+	}
+
+	dns2.SetDNSSECOK(false)
+	dns2.SetExtendedResponseCode(DNSResponseCodeNoErr)
+	if dns2.DNSSECOK() || dns2.EDNS().TTL != 0x00010000 {
+		t.Errorf("got OPT TTL %x", dns2.EDNS().TTL)
+	}
+}
@@ -0,0 +1,593 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package layers
+
+import (
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The String methods of the records below return their RDATA in the
+// presentation format of their RFC, without the type mnemonic, since DS
+// and CDS, and DNSKEY and CDNSKEY, share the same record.
+
+// presentationName returns name as a fully qualified domain name.
+func presentationName(name []byte) string {
+	if len(name) == 0 {
+		return "."
+	}
+	return string(name) + "."
+}
+
+// presentationHex returns data as upper case hexadecimal, as DNSSEC
+// digests are usually written.
+func presentationHex(data []byte) string {
+	return strings.ToUpper(hex.EncodeToString(data))
+}
+
+// presentationString returns s as a quoted <character-string>.
+func presentationString(s []byte) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, c := range s {
+		switch {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < ' ' || c > '~':
+			fmt.Fprintf(&b, "\\%03d", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// mnemonic returns the name of dt in presentation format, or TYPEn for
+// the types without a name (RFC 3597, section 5).
+func (dt DNSType) mnemonic() string {
+	if s := dt.String(); s != "Unknown" {
+		return s
+	}
+	return "TYPE" + strconv.Itoa(int(dt))
+}
+
+// nameSize returns the size of name, uncompressed.
+func nameSize(name []byte) int {
+	if isRootName(name) {
+		return 1
+	}
+	return len(name) + 2
+}
+
+// isRootName reports whether name is the root, written either empty, as
+// decoded, or as ".".
+func isRootName(name []byte) bool {
+	return len(name) == 0 || len(name) == 1 && name[0] == '.'
+}
+
+// decodeCharacterString decodes the <character-string> at offset, and
+// returns the offset following it.
+func decodeCharacterString(data []byte, offset int) ([]byte, int, error) {
+	if offset >= len(data) {
+		return nil, 0, errCharStringMissData
+	}
+	end := offset + 1 + int(data[offset])
+	if end > len(data) {
+		return nil, 0, errCharStringMissData
+	}
+	return data[offset+1 : end], end, nil
+}
+
+func encodeCharacterString(s []byte, data []byte, offset int) int {
+	data[offset] = byte(len(s))
+	copy(data[offset+1:], s)
+	return offset + 1 + len(s)
+}
+
+// DNSTypeBitmap is the set of types of the NSEC and NSEC3 records, see
+// RFC 4034, section 4.1.2.  Types are kept in increasing order.
+type DNSTypeBitmap []DNSType
+
+func decodeTypeBitmap(data []byte) (DNSTypeBitmap, error) {
+	var types DNSTypeBitmap
+	last := -1
+	for len(data) > 0 {
+		if len(data) < 2 {
+			return nil, errors.New("type bitmap truncated")
+		}
+		window, length := int(data[0]), int(data[1])
+		if window <= last || length == 0 || length > 32 || len(data) < 2+length {
+			return nil, errors.New("invalid type bitmap")
+		}
+		for i, b := range data[2 : 2+length] {
+			for bit := 0; bit < 8; bit++ {
+				if b&(0x80>>bit) != 0 {
+					types = append(types, DNSType(window<<8|i<<3|bit))
+				}
+			}
+		}
+		last = window
+		data = data[2+length:]
+	}
+	return types, nil
+}
+
+// windows returns, for every window of the types, its number and the
+// length of its bitmap.
+func (types DNSTypeBitmap) windows() [][2]int {
+	sorted := append(DNSTypeBitmap(nil), types...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	var windows [][2]int
+	for _, t := range sorted {
+		window, length := int(t>>8), int(t&0xff)/8+1
+		if n := len(windows); n > 0 && windows[n-1][0] == window {
+			windows[n-1][1] = length
+		} else {
+			windows = append(windows, [2]int{window, length})
+		}
+	}
+	return windows
+}
+
+func (types DNSTypeBitmap) size() int {
+	sz := 0
+	for _, w := range types.windows() {
+		sz += 2 + w[1]
+	}
+	return sz
+}
+
+func (types DNSTypeBitmap) encode(data []byte, offset int) int {
+	for _, w := range types.windows() {
+		data[offset] = byte(w[0])
+		data[offset+1] = byte(w[1])
+		bitmap := data[offset+2 : offset+2+w[1]]
+		for i := range bitmap {
+			bitmap[i] = 0
+		}
+		for _, t := range types {
+			if int(t>>8) == w[0] {
+				bitmap[t&0xff/8] |= 0x80 >> (t & 7)
+			}
+		}
+		offset += 2 + w[1]
+	}
+	return offset
+}
+
+func (types DNSTypeBitmap) String() string {
+	s := make([]string, len(types))
+	for i, t := range types {
+		s[i] = t.mnemonic()
+	}
+	return strings.Join(s, " ")
+}
+
+// DNSDSDigestType is the digest algorithm of a DS record, see RFC 4034,
+// section 5.1.3.
+type DNSDSDigestType uint8
+
+// DNSDSDigestType known values.
+const (
+	DNSDSDigestTypeSHA1   DNSDSDigestType = 1 // [RFC3658]
+	DNSDSDigestTypeSHA256 DNSDSDigestType = 2 // [RFC4509]
+	DNSDSDigestTypeGOST   DNSDSDigestType = 3 // [RFC5933]
+	DNSDSDigestTypeSHA384 DNSDSDigestType = 4 // [RFC6605]
+)
+
+// DNSDS is a DS record, see RFC 4034, section 5.1.  It also holds CDS
+// records (RFC 7344).
+type DNSDS struct {
+	KeyTag     uint16
+	Algorithm  DNSSECAlgorithm
+	DigestType DNSDSDigestType
+	Digest     []byte
+}
+
+func (ds *DNSDS) decode(data []byte) error {
+	if len(data) < 4 {
+		return errors.New("DS too small")
+	}
+	ds.KeyTag = binary.BigEndian.Uint16(data)
+	ds.Algorithm = DNSSECAlgorithm(data[2])
+	ds.DigestType = DNSDSDigestType(data[3])
+	ds.Digest = data[4:]
+	return nil
+}
+
+func (ds DNSDS) size() int {
+	return 4 + len(ds.Digest)
+}
+
+func (ds DNSDS) encode(data []byte, offset int) {
+	binary.BigEndian.PutUint16(data[offset:], ds.KeyTag)
+	data[offset+2] = uint8(ds.Algorithm)
+	data[offset+3] = uint8(ds.DigestType)
+	copy(data[offset+4:], ds.Digest)
+}
+
+func (ds DNSDS) String() string {
+	return fmt.Sprintf("%d %d %d %s", ds.KeyTag, ds.Algorithm, ds.DigestType, presentationHex(ds.Digest))
+}
+
+// DNSNSEC is a NSEC record, see RFC 4034, section 4.1.
+type DNSNSEC struct {
+	NextDomain []byte
+	Types      DNSTypeBitmap
+}
+
+func (nsec *DNSNSEC) decode(data []byte, offset int, buffer *[]byte) error {
+	name, offset, err := decodeName(data, offset, buffer, 1)
+	if err != nil {
+		return err
+	}
+	nsec.NextDomain = name
+	nsec.Types, err = decodeTypeBitmap(data[offset:])
+	return err
+}
+
+func (nsec DNSNSEC) size() int {
+	return nameSize(nsec.NextDomain) + nsec.Types.size()
+}
+
+func (nsec DNSNSEC) encode(data []byte, offset int) {
+	// the next domain is never compressed, RFC 4034, section 4.1.1
+	offset = encodeName(nsec.NextDomain, data, offset)
+	nsec.Types.encode(data, offset)
+}
+
+func (nsec DNSNSEC) String() string {
+	if len(nsec.Types) == 0 {
+		return presentationName(nsec.NextDomain)
+	}
+	return presentationName(nsec.NextDomain) + " " + nsec.Types.String()
+}
+
+// DNSNSEC3Flag is a flag of the NSEC3 and NSEC3PARAM records, see RFC
+// 5155, section 3.1.2.
+type DNSNSEC3Flag uint8
+
+// DNSNSEC3FlagOptOut is set on the NSEC3 records that may cover unsigned
+// delegations.
+const DNSNSEC3FlagOptOut DNSNSEC3Flag = 1
+
+// DNSNSEC3PARAM is a NSEC3PARAM record, see RFC 5155, section 4.1.
+type DNSNSEC3PARAM struct {
+	HashAlgorithm uint8
+	Flags         DNSNSEC3Flag
+	Iterations    uint16
+	Salt          []byte
+}
+
+// decode decodes the record, and returns the offset following it.
+func (p *DNSNSEC3PARAM) decode(data []byte) (int, error) {
+	if len(data) < 5 {
+		return 0, errors.New("NSEC3PARAM too small")
+	}
+	p.HashAlgorithm = data[0]
+	p.Flags = DNSNSEC3Flag(data[1])
+	p.Iterations = binary.BigEndian.Uint16(data[2:])
+	var offset int
+	var err error
+	if p.Salt, offset, err = decodeCharacterString(data, 4); err != nil {
+		return 0, err
+	}
+	return offset, nil
+}
+
+func (p DNSNSEC3PARAM) size() int {
+	return 5 + len(p.Salt)
+}
+
+func (p DNSNSEC3PARAM) encode(data []byte, offset int) int {
+	data[offset] = p.HashAlgorithm
+	data[offset+1] = uint8(p.Flags)
+	binary.BigEndian.PutUint16(data[offset+2:], p.Iterations)
+	return encodeCharacterString(p.Salt, data, offset+4)
+}
+
+func (p DNSNSEC3PARAM) String() string {
+	salt := "-"
+	if len(p.Salt) > 0 {
+		salt = hex.EncodeToString(p.Salt)
+	}
+	return fmt.Sprintf("%d %d %d %s", p.HashAlgorithm, p.Flags, p.Iterations, salt)
+}
+
+// nsec3Base32 is the encoding of the hashed owner names.
+var nsec3Base32 = base32.HexEncoding.WithPadding(base32.NoPadding)
+
+// DNSNSEC3 is a NSEC3 record, see RFC 5155, section 3.
+type DNSNSEC3 struct {
+	DNSNSEC3PARAM
+	NextHashedOwner []byte
+	Types           DNSTypeBitmap
+}
+
+func (nsec3 *DNSNSEC3) decode(data []byte) error {
+	offset, err := nsec3.DNSNSEC3PARAM.decode(data)
+	if err != nil {
+		return err
+	}
+	if nsec3.NextHashedOwner, offset, err = decodeCharacterString(data, offset); err != nil {
+		return err
+	}
+	nsec3.Types, err = decodeTypeBitmap(data[offset:])
+	return err
+}
+
+func (nsec3 DNSNSEC3) size() int {
+	return nsec3.DNSNSEC3PARAM.size() + 1 + len(nsec3.NextHashedOwner) + nsec3.Types.size()
+}
+
+func (nsec3 DNSNSEC3) encode(data []byte, offset int) {
+	offset = nsec3.DNSNSEC3PARAM.encode(data, offset)
+	offset = encodeCharacterString(nsec3.NextHashedOwner, data, offset)
+	nsec3.Types.encode(data, offset)
+}
+
+func (nsec3 DNSNSEC3) String() string {
+	s := nsec3.DNSNSEC3PARAM.String() + " " + strings.ToLower(nsec3Base32.EncodeToString(nsec3.NextHashedOwner))
+	if len(nsec3.Types) > 0 {
+		s += " " + nsec3.Types.String()
+	}
+	return s
+}
+
+// DNSCAA is a CAA record, see RFC 8659, section 4.1.
+type DNSCAA struct {
+	// Flags has the issuer critical flag as its most significant bit.
+	Flags uint8
+	Tag   []byte
+	Value []byte
+}
+
+func (caa *DNSCAA) decode(data []byte) error {
+	if len(data) < 2 || len(data) < 2+int(data[1]) {
+		return errors.New("CAA too small")
+	}
+	caa.Flags = data[0]
+	caa.Tag = data[2 : 2+data[1]]
+	caa.Value = data[2+data[1]:]
+	return nil
+}
+
+func (caa DNSCAA) size() int {
+	return 2 + len(caa.Tag) + len(caa.Value)
+}
+
+func (caa DNSCAA) encode(data []byte, offset int) {
+	data[offset] = caa.Flags
+	offset = encodeCharacterString(caa.Tag, data, offset+1)
+	copy(data[offset:], caa.Value)
+}
+
+func (caa DNSCAA) String() string {
+	return fmt.Sprintf("%d %s %s", caa.Flags, caa.Tag, presentationString(caa.Value))
+}
+
+// DNSTLSA is a TLSA record, see RFC 6698, section 2.1.
+type DNSTLSA struct {
+	Usage        uint8
+	Selector     uint8
+	MatchingType uint8
+	Certificate  []byte
+}
+
+func (tlsa *DNSTLSA) decode(data []byte) error {
+	if len(data) < 3 {
+		return errors.New("TLSA too small")
+	}
+	tlsa.Usage = data[0]
+	tlsa.Selector = data[1]
+	tlsa.MatchingType = data[2]
+	tlsa.Certificate = data[3:]
+	return nil
+}
+
+func (tlsa DNSTLSA) size() int {
+	return 3 + len(tlsa.Certificate)
+}
+
+func (tlsa DNSTLSA) encode(data []byte, offset int) {
+	data[offset] = tlsa.Usage
+	data[offset+1] = tlsa.Selector
+	data[offset+2] = tlsa.MatchingType
+	copy(data[offset+3:], tlsa.Certificate)
+}
+
+func (tlsa DNSTLSA) String() string {
+	return fmt.Sprintf("%d %d %d %s", tlsa.Usage, tlsa.Selector, tlsa.MatchingType, presentationHex(tlsa.Certificate))
+}
+
+// DNSNAPTR is a NAPTR record, see RFC 3403, section 4.1.
+type DNSNAPTR struct {
+	Order, Preference      uint16
+	Flags, Service, Regexp []byte
+	Replacement            []byte
+}
+
+func (naptr *DNSNAPTR) decode(data []byte, offset int, buffer *[]byte) error {
+	if len(data) < offset+4 {
+		return errors.New("NAPTR too small")
+	}
+	naptr.Order = binary.BigEndian.Uint16(data[offset:])
+	naptr.Preference = binary.BigEndian.Uint16(data[offset+2:])
+	offset += 4
+	var err error
+	for _, s := range []*[]byte{&naptr.Flags, &naptr.Service, &naptr.Regexp} {
+		if *s, offset, err = decodeCharacterString(data, offset); err != nil {
+			return err
+		}
+	}
+	naptr.Replacement, _, err = decodeName(data, offset, buffer, 1)
+	return err
+}
+
+func (naptr DNSNAPTR) size() int {
+	return 4 + 3 + len(naptr.Flags) + len(naptr.Service) + len(naptr.Regexp) + nameSize(naptr.Replacement)
+}
+
+func (naptr DNSNAPTR) encode(data []byte, offset int) {
+	binary.BigEndian.PutUint16(data[offset:], naptr.Order)
+	binary.BigEndian.PutUint16(data[offset+2:], naptr.Preference)
+	offset += 4
+	for _, s := range [][]byte{naptr.Flags, naptr.Service, naptr.Regexp} {
+		offset = encodeCharacterString(s, data, offset)
+	}
+	encodeName(naptr.Replacement, data, offset)
+}
+
+func (naptr DNSNAPTR) String() string {
+	return fmt.Sprintf("%d %d %s %s %s %s", naptr.Order, naptr.Preference,
+		presentationString(naptr.Flags), presentationString(naptr.Service),
+		presentationString(naptr.Regexp), presentationName(naptr.Replacement))
+}
+
+// DNSSSHFP is a SSHFP record, see RFC 4255, section 3.1.
+type DNSSSHFP struct {
+	Algorithm       uint8
+	FingerprintType uint8
+	Fingerprint     []byte
+}
+
+func (sshfp *DNSSSHFP) decode(data []byte) error {
+	if len(data) < 2 {
+		return errors.New("SSHFP too small")
+	}
+	sshfp.Algorithm = data[0]
+	sshfp.FingerprintType = data[1]
+	sshfp.Fingerprint = data[2:]
+	return nil
+}
+
+func (sshfp DNSSSHFP) size() int {
+	return 2 + len(sshfp.Fingerprint)
+}
+
+func (sshfp DNSSSHFP) encode(data []byte, offset int) {
+	data[offset] = sshfp.Algorithm
+	data[offset+1] = sshfp.FingerprintType
+	copy(data[offset+2:], sshfp.Fingerprint)
+}
+
+func (sshfp DNSSSHFP) String() string {
+	return fmt.Sprintf("%d %d %s", sshfp.Algorithm, sshfp.FingerprintType, presentationHex(sshfp.Fingerprint))
+}
+
+// DNSZONEMD is a ZONEMD record, see RFC 8976, section 2.2.
+type DNSZONEMD struct {
+	Serial        uint32
+	Scheme        uint8
+	HashAlgorithm uint8
+	Digest        []byte
+}
+
+func (z *DNSZONEMD) decode(data []byte) error {
+	if len(data) < 6 {
+		return errors.New("ZONEMD too small")
+	}
+	z.Serial = binary.BigEndian.Uint32(data)
+	z.Scheme = data[4]
+	z.HashAlgorithm = data[5]
+	z.Digest = data[6:]
+	return nil
+}
+
+func (z DNSZONEMD) size() int {
+	return 6 + len(z.Digest)
+}
+
+func (z DNSZONEMD) encode(data []byte, offset int) {
+	binary.BigEndian.PutUint32(data[offset:], z.Serial)
+	data[offset+4] = z.Scheme
+	data[offset+5] = z.HashAlgorithm
+	copy(data[offset+6:], z.Digest)
+}
+
+func (z DNSZONEMD) String() string {
+	return fmt.Sprintf("%d %d %d %s", z.Serial, z.Scheme, z.HashAlgorithm, presentationHex(z.Digest))
+}
+
+// DNSTSIG is a TSIG record, see RFC 8945, section 4.2.
+type DNSTSIG struct {
+	Algorithm []byte
+	// TimeSigned is the signing time in seconds since the epoch, on 48
+	// bits.
+	TimeSigned uint64
+	Fudge      uint16
+	MAC        []byte
+	OriginalID uint16
+	// Error is the extended RCODE of the TSIG processing, e.g. 16 for
+	// BADSIG.
+	Error     uint16
+	OtherData []byte
+}
+
+func (tsig *DNSTSIG) decode(data []byte, offset int, buffer *[]byte) error {
+	name, offset, err := decodeName(data, offset, buffer, 1)
+	if err != nil {
+		return err
+	}
+	tsig.Algorithm = name
+	if len(data) < offset+10 {
+		return errors.New("TSIG too small")
+	}
+	tsig.TimeSigned = uint64(binary.BigEndian.Uint16(data[offset:]))<<32 | uint64(binary.BigEndian.Uint32(data[offset+2:]))
+	tsig.Fudge = binary.BigEndian.Uint16(data[offset+6:])
+	macSize := int(binary.BigEndian.Uint16(data[offset+8:]))
+	offset += 10
+	if len(data) < offset+macSize+6 {
+		return errors.New("TSIG too small")
+	}
+	tsig.MAC = data[offset : offset+macSize]
+	offset += macSize
+	tsig.OriginalID = binary.BigEndian.Uint16(data[offset:])
+	tsig.Error = binary.BigEndian.Uint16(data[offset+2:])
+	otherLen := int(binary.BigEndian.Uint16(data[offset+4:]))
+	offset += 6
+	if len(data) != offset+otherLen {
+		return errors.New("TSIG other data length mismatch")
+	}
+	tsig.OtherData = data[offset:]
+	return nil
+}
+
+func (tsig DNSTSIG) size() int {
+	return nameSize(tsig.Algorithm) + 16 + len(tsig.MAC) + len(tsig.OtherData)
+}
+
+func (tsig DNSTSIG) encode(data []byte, offset int) {
+	offset = encodeName(tsig.Algorithm, data, offset)
+	binary.BigEndian.PutUint16(data[offset:], uint16(tsig.TimeSigned>>32))
+	binary.BigEndian.PutUint32(data[offset+2:], uint32(tsig.TimeSigned))
+	binary.BigEndian.PutUint16(data[offset+6:], tsig.Fudge)
+	binary.BigEndian.PutUint16(data[offset+8:], uint16(len(tsig.MAC)))
+	offset += 10 + copy(data[offset+10:], tsig.MAC)
+	binary.BigEndian.PutUint16(data[offset:], tsig.OriginalID)
+	binary.BigEndian.PutUint16(data[offset+2:], tsig.Error)
+	binary.BigEndian.PutUint16(data[offset+4:], uint16(len(tsig.OtherData)))
+	copy(data[offset+6:], tsig.OtherData)
+}
+
+func (tsig DNSTSIG) String() string {
+	s := fmt.Sprintf("%s %d %d %d %s %d %d %d", presentationName(tsig.Algorithm), tsig.TimeSigned, tsig.Fudge,
+		len(tsig.MAC), base64.StdEncoding.EncodeToString(tsig.MAC), tsig.OriginalID, tsig.Error, len(tsig.OtherData))
+	if len(tsig.OtherData) > 0 {
+		s += " " + base64.StdEncoding.EncodeToString(tsig.OtherData)
+	}
+	return s
+}
@@ -0,0 +1,187 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package layers
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gopacket/gopacket"
+)
+
+// The NSEC record of RFC 4034, section 4.3.
+func TestParseDNSTypeNSEC(t *testing.T) {
+	rdata := []byte{
+		0x04, 'h', 'o', 's', 't', 0x07, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 0x03, 'c', 'o', 'm', 0x00,
+		0x00, 0x06, 0x40, 0x01, 0x00, 0x00, 0x00, 0x03,
+		0x04, 0x1b, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x20,
+	}
+	rr := DNSResourceRecord{Type: DNSTypeNSEC, Data: rdata}
+	var buffer []byte
+	if err := rr.decodeRData(rdata, 0, &buffer); err != nil {
+		t.Fatal(err)
+	}
+	if want := (DNSTypeBitmap{DNSTypeA, DNSTypeMX, DNSTypeRRSIG, DNSTypeNSEC, 1234}); !reflect.DeepEqual(rr.NSEC.Types, want) {
+		t.Errorf("got types %v", rr.NSEC.Types)
+	}
+	if got, want := rr.String(), "NSEC host.example.com. A MX RRSIG NSEC TYPE1234"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if rr.NSEC.size() != len(rdata) {
+		t.Errorf("got size %d, want %d", rr.NSEC.size(), len(rdata))
+	}
+	data := make([]byte, len(rdata))
+	rr.NSEC.encode(data, 0)
+	if !bytes.Equal(data, rdata) {
+		t.Errorf("got encoding %x", data)
+	}
+
+	for _, bitmap := range [][]byte{
+		{0x00},
+		{0x00, 0x00},
+		{0x00, 0x21},
+		{0x00, 0x02, 0x40},
+		{0x01, 0x01, 0x40, 0x00, 0x01, 0x40},
+	} {
+		if _, err := decodeTypeBitmap(bitmap); err == nil {
+			t.Errorf("no error for bitmap %x", bitmap)
+		}
+	}
+}
+
+func TestDNSRDataRoundTrip(t *testing.T) {
+	// the NSEC3 record of RFC 5155, appendix A
+	nextHashed, err := nsec3Base32.DecodeString(strings.ToUpper("2t7b4g4vsa5smi47k61mv5bv1a22bojr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := []byte{0x2b, 0xb1, 0x83, 0xaf, 0x5f, 0x22, 0x58, 0x81, 0x79, 0xa5, 0x3b, 0x0a, 0x98, 0x63, 0x1f, 0xad, 0x1a, 0x29, 0x21, 0x18}
+	for _, test := range []struct {
+		rr     DNSResourceRecord
+		field  string
+		string string
+	}{
+		{
+			DNSResourceRecord{Type: DNSTypeDS, DS: DNSDS{KeyTag: 60485, Algorithm: DNSSECAlgorithmRSASHA1, DigestType: DNSDSDigestTypeSHA1, Digest: digest}},
+			"DS", "DS 60485 5 1 2BB183AF5F22588179A53B0A98631FAD1A292118",
+		},
+		{
+			DNSResourceRecord{Type: DNSTypeCDS, DS: DNSDS{KeyTag: 1, Algorithm: DNSSECAlgorithmED25519, DigestType: DNSDSDigestTypeSHA256, Digest: []byte{0xab}}},
+			"DS", "CDS 1 15 2 AB",
+		},
+		{
+			DNSResourceRecord{Type: DNSTypeCDNSKEY, DNSKEY: DNSKEY{Flags: DNSKEYFlagSecureEntryPoint, Protocol: DNSKEYProtocolValue, Algorithm: DNSSECAlgorithmED25519, PublicKey: []byte{1, 2, 3}}},
+			"DNSKEY", "",
+		},
+		{
+			DNSResourceRecord{Type: DNSTypeNSEC, NSEC: DNSNSEC{NextDomain: []byte("b.example"), Types: DNSTypeBitmap{DNSTypeNS, DNSTypeSOA, DNSTypeRRSIG, DNSTypeNSEC, DNSTypeDNSKEY, DNSTypeCAA}}},
+			"NSEC", "NSEC b.example. NS SOA RRSIG NSEC DNSKEY CAA",
+		},
+		{
+			DNSResourceRecord{Type: DNSTypeNSEC3, NSEC3: DNSNSEC3{
+				DNSNSEC3PARAM:   DNSNSEC3PARAM{HashAlgorithm: 1, Flags: DNSNSEC3FlagOptOut, Iterations: 12, Salt: []byte{0xaa, 0xbb, 0xcc, 0xdd}},
+				NextHashedOwner: nextHashed,
+				Types:           DNSTypeBitmap{DNSTypeNS, DNSTypeSOA, DNSTypeMX, DNSTypeRRSIG, DNSTypeDNSKEY, DNSTypeNSEC3PARAM},
+			}},
+			"NSEC3", "NSEC3 1 1 12 aabbccdd 2t7b4g4vsa5smi47k61mv5bv1a22bojr NS SOA MX RRSIG DNSKEY NSEC3PARAM",
+		},
+		{
+			DNSResourceRecord{Type: DNSTypeNSEC3PARAM, NSEC3PARAM: DNSNSEC3PARAM{HashAlgorithm: 1, Iterations: 12, Salt: []byte{0xaa, 0xbb, 0xcc, 0xdd}}},
+			"NSEC3PARAM", "NSEC3PARAM 1 0 12 aabbccdd",
+		},
+		{
+			DNSResourceRecord{Type: DNSTypeCAA, CAA: DNSCAA{Flags: 128, Tag: []byte("issue"), Value: []byte(`ca.example.net; account="230123"`)}},
+			"CAA", `CAA 128 issue "ca.example.net; account=\"230123\""`,
+		},
+		{
+			DNSResourceRecord{Type: DNSTypeTLSA, TLSA: DNSTLSA{Usage: 3, Selector: 1, MatchingType: 1, Certificate: digest}},
+			"TLSA", "TLSA 3 1 1 2BB183AF5F22588179A53B0A98631FAD1A292118",
+		},
+		{
+			DNSResourceRecord{Type: DNSTypeNAPTR, NAPTR: DNSNAPTR{Order: 100, Preference: 10, Flags: []byte("S"), Service: []byte("SIP+D2U"), Regexp: []byte{}, Replacement: []byte("_sip._udp.example.com")}},
+			"NAPTR", `NAPTR 100 10 "S" "SIP+D2U" "" _sip._udp.example.com.`,
+		},
+		{
+			DNSResourceRecord{Type: DNSTypeNAPTR, NAPTR: DNSNAPTR{Order: 100, Preference: 50, Flags: []byte("u"), Service: []byte("E2U+sip"), Regexp: []byte(`!^.*$!sip:info@example.com!`)}},
+			"NAPTR", `NAPTR 100 50 "u" "E2U+sip" "!^.*$!sip:info@example.com!" .`,
+		},
+		{
+			DNSResourceRecord{Type: DNSTypeSSHFP, SSHFP: DNSSSHFP{Algorithm: 4, FingerprintType: 2, Fingerprint: digest}},
+			"SSHFP", "SSHFP 4 2 2BB183AF5F22588179A53B0A98631FAD1A292118",
+		},
+		{
+			DNSResourceRecord{Type: DNSTypeZONEMD, ZONEMD: DNSZONEMD{Serial: 2018031900, Scheme: 1, HashAlgorithm: 1, Digest: digest}},
+			"ZONEMD", "ZONEMD 2018031900 1 1 2BB183AF5F22588179A53B0A98631FAD1A292118",
+		},
+		{
+			DNSResourceRecord{Type: DNSTypeTSIG, Class: DNSClassAny, TSIG: DNSTSIG{Algorithm: []byte("hmac-sha256"), TimeSigned: 1<<40 | 5, Fudge: 300, MAC: []byte{1, 2, 3}, OriginalID: 1234, Error: 18, OtherData: []byte{0, 0, 0x5f, 0x5e, 0x10, 0}}},
+			"TSIG", "TSIG hmac-sha256. 1099511627781 300 3 AQID 1234 18 6 AABfXhAA",
+		},
+	} {
+		test.rr.Name = []byte("example.com")
+		if test.rr.Class == 0 {
+			test.rr.Class = DNSClassIN
+		}
+		dns := &DNS{ID: 1, QR: true, Answers: []DNSResourceRecord{test.rr}}
+		buf := gopacket.NewSerializeBuffer()
+		if err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{FixLengths: true}, dns); err != nil {
+			t.Fatalf("%v: %v", test.rr.Type, err)
+		}
+		if got, want := len(buf.Bytes()), 12+13+10+recSize(&test.rr); got != want {
+			t.Errorf("%v: got %d bytes, want %d", test.rr.Type, got, want)
+		}
+		p := gopacket.NewPacket(buf.Bytes(), LayerTypeDNS, testDecodeOptions)
+		if p.ErrorLayer() != nil {
+			t.Fatalf("%v: %v", test.rr.Type, p.ErrorLayer().Error())
+		}
+		got := p.Layer(LayerTypeDNS).(*DNS).Answers[0]
+		want := reflect.ValueOf(test.rr).FieldByName(test.field).Interface()
+		if field := reflect.ValueOf(got).FieldByName(test.field).Interface(); !reflect.DeepEqual(field, want) {
+			t.Errorf("%v: got %+v, want %+v", test.rr.Type, field, want)
+		}
+		if test.string != "" && got.String() != test.string {
+			t.Errorf("%v: got %q, want %q", test.rr.Type, got.String(), test.string)
+		}
+	}
+}
+
+func TestDNSRDataTruncated(t *testing.T) {
+	for _, test := range []struct {
+		rr DNSResourceRecord
+		// min is the size below which decoding fails, the size of the
+		// record if any truncation does
+		min int
+	}{
+		{DNSResourceRecord{Type: DNSTypeDS, DS: DNSDS{Digest: []byte{1, 2}}}, 4},
+		{DNSResourceRecord{Type: DNSTypeNSEC3PARAM, NSEC3PARAM: DNSNSEC3PARAM{Salt: []byte{1, 2}}}, 7},
+		{DNSResourceRecord{Type: DNSTypeCAA, CAA: DNSCAA{Tag: []byte("issue"), Value: []byte("ca")}}, 7},
+		{DNSResourceRecord{Type: DNSTypeTLSA, TLSA: DNSTLSA{Certificate: []byte{1}}}, 3},
+		{DNSResourceRecord{Type: DNSTypeNAPTR, NAPTR: DNSNAPTR{Flags: []byte("S"), Replacement: []byte("example.com")}}, 21},
+		{DNSResourceRecord{Type: DNSTypeSSHFP, SSHFP: DNSSSHFP{Fingerprint: []byte{1}}}, 2},
+		{DNSResourceRecord{Type: DNSTypeZONEMD, ZONEMD: DNSZONEMD{Digest: []byte{1}}}, 6},
+		{DNSResourceRecord{Type: DNSTypeTSIG, TSIG: DNSTSIG{Algorithm: []byte("hmac-sha256"), MAC: []byte{1, 2}, OtherData: []byte{3}}}, 32},
+	} {
+		data := make([]byte, 1+10+recSize(&test.rr))
+		if _, err := test.rr.encode(data, 0, gopacket.SerializeOptions{}, nil); err != nil {
+			t.Fatal(err)
+		}
+		rdata := data[11:]
+		for i := 0; i <= len(rdata); i++ {
+			rr := DNSResourceRecord{Type: test.rr.Type, Data: rdata[:i]}
+			var buffer []byte
+			err := rr.decodeRData(rdata[:i], 0, &buffer)
+			if i < test.min && err == nil {
+				t.Errorf("%v: no error for %d bytes", test.rr.Type, i)
+			} else if i >= test.min && err != nil {
+				t.Errorf("%v: error for %d bytes: %v", test.rr.Type, i, err)
+			}
+		}
+	}
+}
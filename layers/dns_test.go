@@ -370,7 +370,7 @@ func TestEncodeDNSTypeRRSIG(t *testing.T) {
 		},
 	}
 	wireData := make([]byte, len(testParseDNSTypeRRSIG))
-	rr.encode(wireData, 0, gopacket.SerializeOptions{})
+	rr.encode(wireData, 0, gopacket.SerializeOptions{}, nil)
 	if !bytes.Equal(wireData, testParseDNSTypeRRSIG) {
 		t.Errorf("Incorrect RRSIG wire format, expected \n%v,\ngot \n%v\n", testParseDNSTypeRRSIG, wireData)
 	}
@@ -442,7 +442,7 @@ func TestEncodeDNSTypeDNSKEY(t *testing.T) {
 		},
 	}
 	wireData := make([]byte, len(testParseDNSTypeDNSKEY))
-	rr.encode(wireData, 0, gopacket.SerializeOptions{})
+	rr.encode(wireData, 0, gopacket.SerializeOptions{}, nil)
 	if !bytes.Equal(wireData, testParseDNSTypeDNSKEY) {
 		t.Errorf("Incorrect DNSKEY wire format, expected \n%v,\ngot \n%v\n", testParseDNSTypeDNSKEY, wireData)
 	}
@@ -893,6 +893,175 @@ func TestDNSEncodeResponse(t *testing.T) {
 	testDNSEqual(t, dns, dns2)
 }
 
+func TestDNSEncodeCompressed(t *testing.T) {
+	newResponse := func() *DNS {
+		dns := &DNS{ID: 1234, QR: true, AA: true, RD: true, RA: true}
+		dns.Questions = []DNSQuestion{{Name: []byte("www.example.com"), Type: DNSTypeA, Class: DNSClassIN}}
+		dns.Answers = []DNSResourceRecord{
+			{Name: []byte("www.example.com"), Type: DNSTypeCNAME, Class: DNSClassIN, TTL: 60, CNAME: []byte("web.example.com")},
+			{Name: []byte("web.example.com"), Type: DNSTypeA, Class: DNSClassIN, TTL: 60, IP: net.IP{192, 0, 2, 1}},
+		}
+		dns.Authorities = []DNSResourceRecord{
+			{Name: []byte("example.com"), Type: DNSTypeNS, Class: DNSClassIN, TTL: 3600, NS: []byte("ns1.example.com")},
+			{Name: []byte("example.com"), Type: DNSTypeSOA, Class: DNSClassIN, TTL: 3600, SOA: DNSSOA{
+				MName: []byte("ns1.example.com"), RName: []byte("hostmaster.example.com"),
+				Serial: 1, Refresh: 2, Retry: 3, Expire: 4, Minimum: 5,
+			}},
+		}
+		dns.Additionals = []DNSResourceRecord{
+			{Name: []byte("example.com"), Type: DNSTypeMX, Class: DNSClassIN, TTL: 3600, MX: DNSMX{Preference: 10, Name: []byte("mail.example.org")}},
+			// names in SRV records are never compressed
+			{Name: []byte("_sip._udp.example.com"), Type: DNSTypeSRV, Class: DNSClassIN, TTL: 60, SRV: DNSSRV{Port: 5060, Name: []byte("web.example.com")}},
+			{Name: []byte(""), Type: DNSTypeOPT, Class: 4096},
+		}
+		return dns
+	}
+	opts := gopacket.SerializeOptions{FixLengths: true}
+	plain := newResponse()
+	buf := gopacket.NewSerializeBuffer()
+	if err := gopacket.SerializeLayers(buf, opts, plain); err != nil {
+		t.Fatal(err)
+	}
+	plainLen := len(buf.Bytes())
+
+	for _, tcp := range []bool{false, true} {
+		dns := newResponse()
+		dns.Compress = true
+		dns.TCP = tcp
+		buf := gopacket.NewSerializeBuffer()
+		if err := gopacket.SerializeLayers(buf, opts, dns); err != nil {
+			t.Fatal(err)
+		}
+		data := buf.Bytes()
+		if tcp {
+			data = data[2:]
+		}
+		// "example" remains in the question, in the MX name under
+		// example.org, and in the uncompressed SRV target
+		if strings.Count(string(data), "example") != 3 || len(data) >= plainLen {
+			t.Errorf("TCP %v: got %d bytes, %d uncompressed: %x", tcp, len(data), plainLen, data)
+		}
+		// the question is followed by the answer owner name, a pointer to
+		// it, and the CNAME, pointing to example.com in the question
+		if cname := data[12+21+2+10:][:6]; !bytes.Equal(cname, []byte{3, 'w', 'e', 'b', 0xc0, 16}) {
+			t.Errorf("TCP %v: got CNAME %x", tcp, cname)
+		}
+		decodeAs := LayerTypeDNS
+		if tcp {
+			decodeAs = LayerTypeDNSOverTCP
+		}
+		p := gopacket.NewPacket(buf.Bytes(), decodeAs, testDecodeOptions)
+		if p.ErrorLayer() != nil {
+			t.Fatal(p.ErrorLayer().Error())
+		}
+		dns2 := p.Layer(LayerTypeDNS).(*DNS)
+		// DataLength was fixed to the compressed sizes
+		testDNSEqual(t, dns, dns2)
+		// ns1.example.com is a pointer to the NS record, hostmaster a label
+		// and a pointer
+		if got := dns.Authorities[1].DataLength; got != 2+1+10+2+20 {
+			t.Errorf("TCP %v: got SOA data length %d", tcp, got)
+		}
+	}
+}
+
+func TestDNSEncodeRootNames(t *testing.T) {
+	// the root is written empty, as decoded, or as "."
+	dns := &DNS{ID: 1234, QR: true, AA: true}
+	dns.Questions = []DNSQuestion{{Name: []byte(""), Type: DNSTypeNS, Class: DNSClassIN}}
+	dns.Answers = []DNSResourceRecord{
+		{Name: []byte(""), Type: DNSTypeNS, Class: DNSClassIN, NS: []byte("")},
+		{Name: []byte("."), Type: DNSTypeCNAME, Class: DNSClassIN, CNAME: []byte(".")},
+		{Name: []byte(""), Type: DNSTypePTR, Class: DNSClassIN, PTR: []byte("")},
+		{Name: []byte(""), Type: DNSTypeSOA, Class: DNSClassIN, SOA: DNSSOA{MName: []byte(""), RName: []byte("."), Serial: 1}},
+		// a null MX (RFC 7505)
+		{Name: []byte("example.com"), Type: DNSTypeMX, Class: DNSClassIN, MX: DNSMX{Name: []byte(".")}},
+		{Name: []byte("_sip._udp.example.com"), Type: DNSTypeSRV, Class: DNSClassIN, SRV: DNSSRV{Port: 5060, Name: []byte("")}},
+	}
+	buf := gopacket.NewSerializeBuffer()
+	if err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{FixLengths: true}, dns); err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []uint16{1, 1, 1, 22, 3, 7} {
+		if got := dns.Answers[i].DataLength; got != want {
+			t.Errorf("answer %d: got data length %d, want %d", i, got, want)
+		}
+	}
+
+	p := gopacket.NewPacket(buf.Bytes(), LayerTypeDNS, testDecodeOptions)
+	if p.ErrorLayer() != nil {
+		t.Fatal(p.ErrorLayer().Error())
+	}
+	dns2 := p.Layer(LayerTypeDNS).(*DNS)
+	if len(dns2.Questions) != 1 || len(dns2.Answers) != len(dns.Answers) {
+		t.Fatalf("got %d questions and %d answers", len(dns2.Questions), len(dns2.Answers))
+	}
+	n := 12 + nameSize(dns2.Questions[0].Name) + 4
+	for _, rr := range dns2.Answers {
+		n += nameSize(rr.Name) + 10 + int(rr.DataLength)
+	}
+	if len(buf.Bytes()) != n {
+		t.Errorf("got %d bytes, decoded %d: %x", len(buf.Bytes()), n, buf.Bytes())
+	}
+	rrs := dns2.Answers
+	for i, name := range [][]byte{dns2.Questions[0].Name, rrs[0].Name, rrs[1].Name, rrs[0].NS, rrs[1].CNAME, rrs[2].PTR,
+		rrs[3].SOA.MName, rrs[3].SOA.RName, rrs[4].MX.Name, rrs[5].SRV.Name} {
+		if len(name) != 0 {
+			t.Errorf("name %d: got %q, want the root", i, name)
+		}
+	}
+	if rrs[3].SOA.Serial != 1 || rrs[5].SRV.Port != 5060 {
+		t.Errorf("got SOA %v, SRV %v", rrs[3].SOA, rrs[5].SRV)
+	}
+}
+
+func TestDNSOverTCP(t *testing.T) {
+	if got := TCPPort(53).LayerType(); got != LayerTypeDNSOverTCP {
+		t.Errorf("TCP port 53: got %v", got)
+	}
+	query := &DNS{ID: 1, RD: true, TCP: true}
+	query.Questions = append(query.Questions, DNSQuestion{Name: []byte("example.com"), Type: DNSTypeA, Class: DNSClassIN})
+	buf := gopacket.NewSerializeBuffer()
+	if err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{FixLengths: true}, query); err != nil {
+		t.Fatal(err)
+	}
+	data := append([]byte(nil), buf.Bytes()...)
+	if n := int(data[0])<<8 | int(data[1]); n != len(data)-2 {
+		t.Fatalf("got length prefix %d for %d bytes", n, len(data)-2)
+	}
+	// a response whose answer points back to the name of the question,
+	// which only decodes if the offset is relative to the message
+	response := []byte{
+		0, 45, 0, 1, 0x81, 0x80, 0, 1, 0, 1, 0, 0, 0, 0,
+		7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 3, 'c', 'o', 'm', 0, 0, 1, 0, 1,
+		0xc0, 0x0c, 0, 1, 0, 1, 0, 0, 0, 60, 0, 4, 192, 0, 2, 1,
+	}
+	data = append(data, response...)
+
+	p := gopacket.NewPacket(data, LayerTypeDNSOverTCP, testDecodeOptions)
+	if p.ErrorLayer() != nil {
+		t.Fatal(p.ErrorLayer().Error())
+	}
+	var msgs []*DNS
+	for _, l := range p.Layers() {
+		msgs = append(msgs, l.(*DNS))
+	}
+	if len(msgs) != 2 {
+		t.Fatalf("got %d layers, want 2", len(msgs))
+	}
+	testDNSEqual(t, query, msgs[0])
+	if got := msgs[1]; !got.QR || len(got.Answers) != 1 || string(got.Answers[0].Name) != "example.com" ||
+		!got.Answers[0].IP.Equal(net.IP{192, 0, 2, 1}) || !bytes.Equal(got.Contents, response) {
+		t.Errorf("got response %+v", got)
+	}
+
+	// a message spanning the end of the data
+	p = gopacket.NewPacket(data[:len(data)-1], LayerTypeDNSOverTCP, testDecodeOptions)
+	if len(p.Layers()) != 2 || p.ErrorLayer() == nil || !p.Metadata().Truncated {
+		t.Errorf("got layers %v, truncated %v", p.Layers(), p.Metadata().Truncated)
+	}
+}
+
 // testDNSMalformedPacket is the packet:
 //
 //	10:30:00.389666 IP 10.77.43.131.60718 > 10.1.0.17.53: 18245 updateD [b2&3=0x5420] [18516a] [12064q] [21584n] [12081au][|domain]
@@ -44,3 +44,15 @@ func decodeEtherIP(data []byte, p gopacket.PacketBuilder) error {
 	e := &EtherIP{}
 	return decodingLayerDecoder(e, data, p)
 }
+
+// SerializeTo writes the serialized form of this layer into the
+// SerializationBuffer, implementing gopacket.SerializableLayer.
+// See the docs for gopacket.SerializableLayer for more info.
+func (e *EtherIP) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOptions) error {
+	bytes, err := b.PrependBytes(2)
+	if err != nil {
+		return err
+	}
+	binary.BigEndian.PutUint16(bytes, uint16(e.Version)<<12|e.Reserved&0x0fff)
+	return nil
+}
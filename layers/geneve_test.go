@@ -144,6 +144,9 @@ func TestDecodeGeneve1(t *testing.T) {
 			t.Errorf("Geneve layer mismatch, \nwant %#v\ngot  %#v\n", want, got)
 		}
 	}
+	// The outer UDP checksum is zero, as allowed over IPv4.
+	testSerializationWithOpts(t, p, testPacketGeneve1, gopacket.SerializeOptions{})
+	testSerializationWithOpts(t, p, testPacketGeneve1, gopacket.SerializeOptions{FixLengths: true})
 }
 
 func TestDecodeGeneve2(t *testing.T) {
@@ -10,6 +10,7 @@ package layers
 import (
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"net"
 	"time"
 
@@ -253,9 +254,9 @@ func igmpTimeDecode(t uint8) time.Duration {
 	if t&0x80 == 0 {
 		return time.Millisecond * 100 * time.Duration(t)
 	}
-	mant := (t & 0x70) >> 4
-	exp := t & 0x0F
-	return time.Millisecond * 100 * time.Duration((mant|0x10)<<(exp+3))
+	exp := (t & 0x70) >> 4
+	mant := t & 0x0F
+	return time.Millisecond * 100 * time.Duration(mant|0x10) << (exp + 3)
 }
 
 // LayerType returns LayerTypeIGMP for the V1,2,3 message protocol formats.
@@ -355,3 +356,131 @@ func decodeIGMP(data []byte, p gopacket.PacketBuilder) error {
 
 	return errors.New("Unable to determine IGMP type.")
 }
+
+// igmpTimeEncode encodes a duration in the format decoded by
+// igmpTimeDecode, rounding down to the closest representable value.
+func igmpTimeEncode(d time.Duration) uint8 {
+	t := d / (100 * time.Millisecond)
+	if t < 0x80 {
+		return uint8(t)
+	}
+	for exp := uint8(0); exp < 8; exp++ {
+		if mant := t >> (exp + 3); mant < 0x20 {
+			return 0x80 | exp<<4 | uint8(mant&0x0F)
+		}
+	}
+	return 0xFF
+}
+
+// SerializeTo writes the serialized form of this layer into the
+// SerializationBuffer, implementing gopacket.SerializableLayer.
+// See the docs for gopacket.SerializableLayer for more info.
+func (i *IGMPv1or2) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOptions) error {
+	bytes, err := b.PrependBytes(8)
+	if err != nil {
+		return err
+	}
+	bytes[0] = byte(i.Type)
+	bytes[1] = igmpTimeEncode(i.MaxResponseTime)
+	if err := igmpPutIP(bytes[4:8], i.GroupAddress); err != nil {
+		return err
+	}
+	if opts.ComputeChecksums {
+		bytes[2], bytes[3] = 0, 0
+		i.Checksum = gopacket.FoldChecksum(gopacket.ComputeChecksum(b.Bytes(), 0))
+	}
+	binary.BigEndian.PutUint16(bytes[2:4], i.Checksum)
+	return nil
+}
+
+// igmpPutIP writes an IPv4 address, the unspecified address if ip is nil.
+func igmpPutIP(bytes []byte, ip net.IP) error {
+	if ip == nil {
+		copy(bytes, net.IPv4zero.To4())
+		return nil
+	}
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return fmt.Errorf("invalid IGMP IPv4 address %v", ip)
+	}
+	copy(bytes, ip4)
+	return nil
+}
+
+// SerializeTo writes the serialized form of this layer into the
+// SerializationBuffer, implementing gopacket.SerializableLayer.
+// See the docs for gopacket.SerializableLayer for more info.
+func (i *IGMP) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOptions) error {
+	var bytes []byte
+	var err error
+	switch i.Type {
+	case IGMPMembershipQuery:
+		if opts.FixLengths {
+			i.NumberOfSources = uint16(len(i.SourceAddresses))
+		}
+		bytes, err = b.PrependBytes(12 + 4*len(i.SourceAddresses))
+		if err != nil {
+			return err
+		}
+		bytes[1] = igmpTimeEncode(i.MaxResponseTime)
+		if err := igmpPutIP(bytes[4:8], i.GroupAddress); err != nil {
+			return err
+		}
+		bytes[8] = i.RobustnessValue & 0x7
+		if i.SupressRouterProcessing {
+			bytes[8] |= 0x8
+		}
+		bytes[9] = igmpTimeEncode(i.IntervalTime)
+		binary.BigEndian.PutUint16(bytes[10:12], i.NumberOfSources)
+		for j, addr := range i.SourceAddresses {
+			if err := igmpPutIP(bytes[12+j*4:16+j*4], addr); err != nil {
+				return err
+			}
+		}
+	case IGMPMembershipReportV3:
+		length := 8
+		for _, gr := range i.GroupRecords {
+			length += 8 + 4*len(gr.SourceAddresses)
+		}
+		if opts.FixLengths {
+			i.NumberOfGroupRecords = uint16(len(i.GroupRecords))
+		}
+		bytes, err = b.PrependBytes(length)
+		if err != nil {
+			return err
+		}
+		bytes[1] = 0
+		binary.BigEndian.PutUint16(bytes[4:6], 0)
+		binary.BigEndian.PutUint16(bytes[6:8], i.NumberOfGroupRecords)
+		offset := 8
+		for j := range i.GroupRecords {
+			gr := &i.GroupRecords[j]
+			if opts.FixLengths {
+				gr.NumberOfSources = uint16(len(gr.SourceAddresses))
+				gr.AuxDataLen = 0
+			}
+			bytes[offset] = byte(gr.Type)
+			bytes[offset+1] = gr.AuxDataLen
+			binary.BigEndian.PutUint16(bytes[offset+2:offset+4], gr.NumberOfSources)
+			if err := igmpPutIP(bytes[offset+4:offset+8], gr.MulticastAddress); err != nil {
+				return err
+			}
+			offset += 8
+			for _, addr := range gr.SourceAddresses {
+				if err := igmpPutIP(bytes[offset:offset+4], addr); err != nil {
+					return err
+				}
+				offset += 4
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported IGMP type %v", i.Type)
+	}
+	bytes[0] = byte(i.Type)
+	if opts.ComputeChecksums {
+		bytes[2], bytes[3] = 0, 0
+		i.Checksum = gopacket.FoldChecksum(gopacket.ComputeChecksum(b.Bytes(), 0))
+	}
+	binary.BigEndian.PutUint16(bytes[2:4], i.Checksum)
+	return nil
+}
@@ -8,6 +8,7 @@ package layers
 
 import (
 	"testing"
+	"time"
 
 	"github.com/gopacket/gopacket"
 )
@@ -70,6 +71,7 @@ func TestIGMPv2MembershipQuery(t *testing.T) {
 	if igmp.Type != IGMPMembershipQuery {
 		t.Fatal("Invalid IGMP type")
 	}
+	testSerialization(t, p, igmpv2MembershipQueryPacket)
 }
 func BenchmarkDecodeigmpv2MembershipQueryPacket(b *testing.B) {
 	for i := 0; i < b.N; i++ {
@@ -102,6 +104,7 @@ func TestIGMPv2MembershipReport(t *testing.T) {
 	if igmp.Type != IGMPMembershipReportV2 {
 		t.Fatal("Invalid IGMP type")
 	}
+	testSerialization(t, p, igmpv2MembershipReportPacket)
 }
 func BenchmarkDecodeigmpv2MembershipReportPacket(b *testing.B) {
 	for i := 0; i < b.N; i++ {
@@ -134,6 +137,7 @@ func TestIGMPv3MembershipQuery(t *testing.T) {
 	if igmp.Type != IGMPMembershipQuery {
 		t.Fatal("Invalid IGMP type")
 	}
+	testSerialization(t, p, igmp3v3MembershipQueryPacket)
 }
 
 func BenchmarkDecodeigmp3v3MembershipQueryPacket(b *testing.B) {
@@ -167,6 +171,7 @@ func TestIGMPv3MembershipReport2Records(t *testing.T) {
 	if igmp.Type != IGMPMembershipReportV3 {
 		t.Fatal("Invalid IGMP type")
 	}
+	testSerialization(t, p, igmpv3MembershipReport2Records)
 }
 
 func BenchmarkDecodeigmpv3MembershipReport2Records(b *testing.B) {
@@ -174,3 +179,34 @@ func BenchmarkDecodeigmpv3MembershipReport2Records(b *testing.B) {
 		gopacket.NewPacket(igmpv3MembershipReport2Records, LinkTypeEthernet, gopacket.NoCopy)
 	}
 }
+
+func TestIGMPTimeDecode(t *testing.T) {
+	// RFC 3376, section 4.1.1: 1|exp|mant is (mant|0x10) << (exp+3)
+	// units of 100ms
+	for _, test := range []struct {
+		code uint8
+		want time.Duration
+	}{
+		{0x00, 0},
+		{0x64, 10 * time.Second},
+		{0x7f, 12700 * time.Millisecond},
+		{0x80, 12800 * time.Millisecond},
+		{0x8f, 24800 * time.Millisecond},
+		{0x90, 25600 * time.Millisecond},
+		{0xf0, 1638400 * time.Millisecond},
+		{0xff, 3174400 * time.Millisecond},
+	} {
+		if got := igmpTimeDecode(test.code); got != test.want {
+			t.Errorf("code %#x: got %v, want %v", test.code, got, test.want)
+		}
+	}
+}
+
+func TestIGMPTimeEncoding(t *testing.T) {
+	for code := 0; code < 256; code++ {
+		d := igmpTimeDecode(uint8(code))
+		if got := igmpTimeEncode(d); got != uint8(code) {
+			t.Errorf("code %#x: decoded to %v, encoded back to %#x", code, d, got)
+		}
+	}
+}
@@ -9,6 +9,7 @@ package layers
 import (
 	"encoding/binary"
 	"errors"
+	"fmt"
 
 	"github.com/gopacket/gopacket"
 )
@@ -54,6 +55,30 @@ func decodeIPSecAH(data []byte, p gopacket.PacketBuilder) error {
 	return p.NextDecoder(i.NextHeader)
 }
 
+// SerializeTo writes the serialized form of this layer into the
+// SerializationBuffer, implementing gopacket.SerializableLayer.
+// See the docs for gopacket.SerializableLayer for more info.
+func (i *IPSecAH) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOptions) error {
+	length := 12 + len(i.AuthenticationData)
+	if length%4 != 0 {
+		return fmt.Errorf("IPSec AH length %d not a multiple of 4", length)
+	}
+	if opts.FixLengths {
+		i.HeaderLength = uint8(length/4 - 2)
+	}
+	bytes, err := b.PrependBytes(length)
+	if err != nil {
+		return err
+	}
+	bytes[0] = uint8(i.NextHeader)
+	bytes[1] = i.HeaderLength
+	binary.BigEndian.PutUint16(bytes[2:4], i.Reserved)
+	binary.BigEndian.PutUint32(bytes[4:8], i.SPI)
+	binary.BigEndian.PutUint32(bytes[8:12], i.Seq)
+	copy(bytes[12:], i.AuthenticationData)
+	return nil
+}
+
 // IPSecESP is the encapsulating security payload defined in
 // http://tools.ietf.org/html/rfc2406
 type IPSecESP struct {
@@ -76,3 +101,17 @@ func decodeIPSecESP(data []byte, p gopacket.PacketBuilder) error {
 	p.AddLayer(i)
 	return nil
 }
+
+// SerializeTo writes the serialized form of this layer into the
+// SerializationBuffer, implementing gopacket.SerializableLayer.
+// See the docs for gopacket.SerializableLayer for more info.
+func (i *IPSecESP) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOptions) error {
+	bytes, err := b.PrependBytes(8 + len(i.Encrypted))
+	if err != nil {
+		return err
+	}
+	binary.BigEndian.PutUint32(bytes[:4], i.SPI)
+	binary.BigEndian.PutUint32(bytes[4:8], i.Seq)
+	copy(bytes[8:], i.Encrypted)
+	return nil
+}
@@ -56,6 +56,7 @@ func TestPacketIPSecAHTransport(t *testing.T) {
 			t.Errorf("IPSecAH layer mismatch, \nwant %#v\ngot  %#v\n", want, got)
 		}
 	}
+	testSerialization(t, p, testPacketIPSecAHTransport)
 }
 
 func BenchmarkDecodePacketIPSecAHTransport(b *testing.B) {
@@ -109,6 +110,7 @@ func TestPacketIPSecAHTunnel(t *testing.T) {
 			t.Errorf("IPSecAH layer mismatch, \nwant %#v\ngot  %#v\n", want, got)
 		}
 	}
+	testSerialization(t, p, testPacketIPSecAHTunnel)
 }
 
 func BenchmarkDecodePacketIPSecAHTunnel(b *testing.B) {
@@ -149,6 +151,7 @@ func TestPacketIPSecESP(t *testing.T) {
 		t.Error("Failed to decode packet:", p.ErrorLayer().Error())
 	}
 	checkLayers(p, []gopacket.LayerType{LayerTypeEthernet, LayerTypeIPv4, LayerTypeIPSecESP}, t)
+	testSerialization(t, p, testPacketIPSecESP)
 }
 
 func BenchmarkDecodePacketIPSecESP(b *testing.B) {
@@ -150,6 +150,11 @@ var (
 	LayerTypeRADIUS                       = gopacket.RegisterLayerType(146, gopacket.LayerTypeMetadata{Name: "RADIUS", Decoder: gopacket.DecodeFunc(decodeRADIUS)})
 	LayerTypeLinuxSLL2                    = gopacket.RegisterLayerType(276, gopacket.LayerTypeMetadata{Name: "Linux SLL2", Decoder: gopacket.DecodeFunc(decodeLinuxSLL2)})
 	LayerTypeMDP                          = gopacket.RegisterLayerType(147, gopacket.LayerTypeMetadata{Name: "MDP", Decoder: gopacket.DecodeFunc(decodeMDP)})
+	LayerTypeQUIC                         = gopacket.RegisterLayerType(148, gopacket.LayerTypeMetadata{Name: "QUIC", Decoder: gopacket.DecodeFunc(decodeQUIC)})
+	LayerTypeDNSOverTCP                   = gopacket.RegisterLayerType(149, gopacket.LayerTypeMetadata{Name: "DNSOverTCP", Decoder: gopacket.DecodeFunc(decodeDNSOverTCP)})
+	LayerTypeMDNS                         = gopacket.RegisterLayerType(150, gopacket.LayerTypeMetadata{Name: "MDNS", Decoder: gopacket.DecodeFunc(decodeMDNS)})
+	LayerTypeLLMNR                        = gopacket.RegisterLayerType(151, gopacket.LayerTypeMetadata{Name: "LLMNR", Decoder: gopacket.DecodeFunc(decodeLLMNR)})
+	LayerTypeNBNS                         = gopacket.RegisterLayerType(152, gopacket.LayerTypeMetadata{Name: "NBNS", Decoder: gopacket.DecodeFunc(decodeNBNS)})
 )
 
 var (
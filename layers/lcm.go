@@ -217,3 +217,46 @@ func (lcm LCM) Payload() []byte {
 func (lcm LCM) Fingerprint() LCMFingerprint {
 	return lcm.fingerprint
 }
+
+// SerializeTo writes the serialized form of this layer into the
+// SerializationBuffer, implementing gopacket.SerializableLayer.
+// See the docs for gopacket.SerializableLayer for more info.
+//
+// The channel name is written for unfragmented messages and for the first
+// fragment only. With FixLengths, Magic is set according to Fragmented.
+func (lcm *LCM) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOptions) error {
+	if opts.FixLengths {
+		if lcm.Fragmented {
+			lcm.Magic = LCMFragmentedHeaderMagic
+		} else {
+			lcm.Magic = LCMShortHeaderMagic
+		}
+	}
+	length := 8
+	if lcm.Fragmented {
+		length += 12
+	}
+	withChannel := !lcm.Fragmented || lcm.FragmentNumber == 0
+	if withChannel {
+		length += len(lcm.ChannelName) + 1
+	}
+	bytes, err := b.PrependBytes(length)
+	if err != nil {
+		return err
+	}
+	binary.BigEndian.PutUint32(bytes[0:4], lcm.Magic)
+	binary.BigEndian.PutUint32(bytes[4:8], lcm.SequenceNumber)
+	offset := 8
+	if lcm.Fragmented {
+		binary.BigEndian.PutUint32(bytes[8:12], lcm.PayloadSize)
+		binary.BigEndian.PutUint32(bytes[12:16], lcm.FragmentOffset)
+		binary.BigEndian.PutUint16(bytes[16:18], lcm.FragmentNumber)
+		binary.BigEndian.PutUint16(bytes[18:20], lcm.TotalFragments)
+		offset = 20
+	}
+	if withChannel {
+		copy(bytes[offset:], lcm.ChannelName)
+		bytes[length-1] = 0
+	}
+	return nil
+}
@@ -7,6 +7,7 @@
 package layers
 
 import (
+	"bytes"
 	"encoding/hex"
 	"testing"
 
@@ -154,3 +155,22 @@ func testInvalidLCM(t *testing.T) {
 		t.Fatal("Did not detect LCM decode error.")
 	}
 }
+
+func TestLCMSerialize(t *testing.T) {
+	for _, data := range [][]byte{shortPacket, fragmentedPacket} {
+		lcm := &LCM{}
+		if err := lcm.DecodeFromBytes(data, gopacket.NilDecodeFeedback); err != nil {
+			t.Fatal(err)
+		}
+		for _, opts := range []gopacket.SerializeOptions{{}, {FixLengths: true}} {
+			buf := gopacket.NewSerializeBuffer()
+			if err := gopacket.SerializeLayers(buf, opts, lcm, gopacket.Payload(lcm.Payload())); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(buf.Bytes(), data) {
+				t.Errorf("LCM serialization with %+v: expected\n%sbut got\n%s",
+					opts, hex.Dump(data), hex.Dump(buf.Bytes()))
+			}
+		}
+	}
+}
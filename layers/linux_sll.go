@@ -96,3 +96,26 @@ func decodeLinuxSLL(data []byte, p gopacket.PacketBuilder) error {
 	p.SetLinkLayer(sll)
 	return p.NextDecoder(sll.EthernetType)
 }
+
+// SerializeTo writes the serialized form of this layer into the
+// SerializationBuffer, implementing gopacket.SerializableLayer.
+// See the docs for gopacket.SerializableLayer for more info.
+func (sll *LinuxSLL) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOptions) error {
+	if len(sll.Addr) > 8 {
+		return fmt.Errorf("Linux SLL address too long: %d bytes", len(sll.Addr))
+	}
+	if opts.FixLengths {
+		sll.AddrLen = uint16(len(sll.Addr))
+	}
+	bytes, err := b.PrependBytes(16)
+	if err != nil {
+		return err
+	}
+	binary.BigEndian.PutUint16(bytes[0:2], uint16(sll.PacketType))
+	binary.BigEndian.PutUint16(bytes[2:4], sll.AddrType)
+	binary.BigEndian.PutUint16(bytes[4:6], sll.AddrLen)
+	copy(bytes[6:14], lotsOfZeros[:8])
+	copy(bytes[6:14], sll.Addr)
+	binary.BigEndian.PutUint16(bytes[14:16], uint16(sll.EthernetType))
+	return nil
+}
@@ -174,3 +174,28 @@ func decodeLinuxSLL2(data []byte, p gopacket.PacketBuilder) error {
 	p.SetLinkLayer(sll)
 	return p.NextDecoder(sll.NextLayerType())
 }
+
+// SerializeTo writes the serialized form of this layer into the
+// SerializationBuffer, implementing gopacket.SerializableLayer.
+// See the docs for gopacket.SerializableLayer for more info.
+func (sll *LinuxSLL2) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOptions) error {
+	if len(sll.Addr) > 8 {
+		return fmt.Errorf("Linux SLL2 address too long: %d bytes", len(sll.Addr))
+	}
+	if opts.FixLengths {
+		sll.AddrLength = uint8(len(sll.Addr))
+	}
+	bytes, err := b.PrependBytes(20)
+	if err != nil {
+		return err
+	}
+	binary.BigEndian.PutUint16(bytes[0:2], uint16(sll.ProtocolType))
+	binary.BigEndian.PutUint16(bytes[2:4], 0) // reserved
+	binary.BigEndian.PutUint32(bytes[4:8], sll.InterfaceIndex)
+	binary.BigEndian.PutUint16(bytes[8:10], uint16(sll.ARPHardwareType))
+	bytes[10] = uint8(sll.PacketType)
+	bytes[11] = sll.AddrLength
+	copy(bytes[12:20], lotsOfZeros[:8])
+	copy(bytes[12:20], sll.Addr)
+	return nil
+}
@@ -25,4 +25,7 @@ func TestParseLinkTypeLinuxSLL2(t *testing.T) {
 		t.Error("Failed to decode packet:", p.ErrorLayer().Error())
 	}
 	checkLayers(p, []gopacket.LayerType{LayerTypeLinuxSLL2, LayerTypeIPv4, LayerTypeTCP}, t)
+	// Captured on loopback, the TCP checksum was left to the offload.
+	testSerializationWithOpts(t, p, testParseLinkTypeLinuxSLL2, gopacket.SerializeOptions{})
+	testSerializationWithOpts(t, p, testParseLinkTypeLinuxSLL2, gopacket.SerializeOptions{FixLengths: true})
 }
@@ -0,0 +1,49 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package layers
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/gopacket/gopacket"
+)
+
+// testPacketLinuxSLLARP is an outgoing ARP request captured on the "any"
+// interface.
+var testPacketLinuxSLLARP = []byte{
+	0x00, 0x04, 0x00, 0x01, 0x00, 0x06, 0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x00, 0x00, 0x08, 0x06,
+	0x00, 0x01, 0x08, 0x00, 0x06, 0x04, 0x00, 0x01, 0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0xc0, 0xa8,
+	0x01, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xc0, 0xa8, 0x01, 0x01,
+}
+
+func TestLinuxSLL(t *testing.T) {
+	p := gopacket.NewPacket(testPacketLinuxSLLARP, LinkTypeLinuxSLL, testDecodeOptions)
+	if p.ErrorLayer() != nil {
+		t.Fatal("Failed to decode packet:", p.ErrorLayer().Error())
+	}
+	checkLayers(p, []gopacket.LayerType{LayerTypeLinuxSLL, LayerTypeARP}, t)
+	got := p.Layer(LayerTypeLinuxSLL).(*LinuxSLL)
+	want := &LinuxSLL{
+		BaseLayer:    BaseLayer{Contents: testPacketLinuxSLLARP[:16], Payload: testPacketLinuxSLLARP[16:]},
+		PacketType:   LinuxSLLPacketTypeOutgoing,
+		AddrLen:      6,
+		Addr:         net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+		EthernetType: EthernetTypeARP,
+		AddrType:     1,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LinuxSLL mismatch:\ngot  %#v\nwant %#v", got, want)
+	}
+	testSerialization(t, p, testPacketLinuxSLLARP)
+
+	got.Addr = make(net.HardwareAddr, 9)
+	if err := got.SerializeTo(gopacket.NewSerializeBuffer(), gopacket.SerializeOptions{}); err == nil {
+		t.Error("no error for a 9 byte address")
+	}
+}
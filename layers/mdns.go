@@ -0,0 +1,200 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package layers
+
+import (
+	"bytes"
+	"net"
+	"strings"
+
+	"github.com/gopacket/gopacket"
+)
+
+// dnsClassMDNSBit is the top bit of the class of mDNS questions and
+// records, see RFC 6762, sections 5.4 and 10.2.
+const dnsClassMDNSBit DNSClass = 0x8000
+
+// MDNSClass returns dc without the bit that mDNS uses as the
+// unicast-response bit of questions and the cache-flush bit of records.
+func (dc DNSClass) MDNSClass() DNSClass {
+	return dc &^ dnsClassMDNSBit
+}
+
+// UnicastResponse reports whether the QU bit of an mDNS question is set,
+// i.e. whether a unicast response is preferred (RFC 6762, section 5.4).
+func (q *DNSQuestion) UnicastResponse() bool {
+	return q.Class&dnsClassMDNSBit != 0
+}
+
+// CacheFlush reports whether the cache-flush bit of an mDNS record is
+// set, i.e. whether the record replaces the cached ones of the same name,
+// type and class (RFC 6762, section 10.2).
+func (rr *DNSResourceRecord) CacheFlush() bool {
+	return rr.Type != DNSTypeOPT && rr.Class&dnsClassMDNSBit != 0
+}
+
+// MDNS is a multicast DNS message, see RFC 6762.  Its wire format is the
+// one of DNS, except for the top bit of the classes, see
+// DNSQuestion.UnicastResponse and DNSResourceRecord.CacheFlush.
+type MDNS struct {
+	DNS
+}
+
+// LayerType returns LayerTypeMDNS.
+func (m *MDNS) LayerType() gopacket.LayerType { return LayerTypeMDNS }
+
+// CanDecode implements gopacket.DecodingLayer.
+func (m *MDNS) CanDecode() gopacket.LayerClass { return LayerTypeMDNS }
+
+func decodeMDNS(data []byte, p gopacket.PacketBuilder) error {
+	m := &MDNS{}
+	if err := m.DecodeFromBytes(data, p); err != nil {
+		return err
+	}
+	p.AddLayer(m)
+	p.SetApplicationLayer(m)
+	return nil
+}
+
+// LLMNR is a Link-Local Multicast Name Resolution message, see RFC 4795.
+// Its wire format is the one of DNS, except for the flags: the C
+// (conflict) flag takes the place of AA, and the T (tentative) flag the
+// place of RD.
+type LLMNR struct {
+	DNS
+}
+
+// LayerType returns LayerTypeLLMNR.
+func (l *LLMNR) LayerType() gopacket.LayerType { return LayerTypeLLMNR }
+
+// CanDecode implements gopacket.DecodingLayer.
+func (l *LLMNR) CanDecode() gopacket.LayerClass { return LayerTypeLLMNR }
+
+// Conflict reports whether the C flag is set: on a query, the sender
+// detected that the name is not unique; on a response, the name is not
+// considered unique by the responder.
+func (l *LLMNR) Conflict() bool { return l.AA }
+
+// Tentative reports whether the T flag of a response is set, i.e. whether
+// the responder has not verified the uniqueness of the name yet.
+func (l *LLMNR) Tentative() bool { return l.RD }
+
+func decodeLLMNR(data []byte, p gopacket.PacketBuilder) error {
+	l := &LLMNR{}
+	if err := l.DecodeFromBytes(data, p); err != nil {
+		return err
+	}
+	p.AddLayer(l)
+	p.SetApplicationLayer(l)
+	return nil
+}
+
+// DNSSDService is a service instance advertised with DNS-Based Service
+// Discovery, see RFC 6763.
+type DNSSDService struct {
+	// Instance is the user-friendly name of the instance, e.g.
+	// "Office Printer", Service its type, e.g. "_ipp._tcp", and Domain
+	// the domain it is registered in, e.g. "local".
+	Instance, Service, Domain []byte
+	// Host, Port, Priority and Weight come from the SRV record of the
+	// instance, if the message has it.
+	Host                   []byte
+	Port, Priority, Weight uint16
+	// Attributes come from the TXT record of the instance, if the message
+	// has it.
+	Attributes []DNSSDAttribute
+	// IPs are the addresses of Host found in the message.
+	IPs []net.IP
+}
+
+// DNSSDAttribute is a key/value pair of the TXT record of a DNS-SD
+// service instance, see RFC 6763, section 6.
+type DNSSDAttribute struct {
+	Key string
+	// Value is nil for a boolean attribute, one without '='.
+	Value []byte
+}
+
+// DNSSDAttributes parses the strings of the TXT record of a DNS-SD
+// service instance.  Empty strings, and strings with an empty key, are
+// skipped; only the first occurrence of a key is kept, keys being case
+// insensitive.
+func DNSSDAttributes(txts [][]byte) []DNSSDAttribute {
+	var attrs []DNSSDAttribute
+next:
+	for _, txt := range txts {
+		attr := DNSSDAttribute{Key: string(txt)}
+		if i := bytes.IndexByte(txt, '='); i >= 0 {
+			attr = DNSSDAttribute{Key: string(txt[:i]), Value: txt[i+1:]}
+		}
+		if attr.Key == "" {
+			continue
+		}
+		for _, a := range attrs {
+			if strings.EqualFold(a.Key, attr.Key) {
+				continue next
+			}
+		}
+		attrs = append(attrs, attr)
+	}
+	return attrs
+}
+
+// dnssdServicesEnumeration is the name browsed for the service types of a
+// domain, whose PTR records do not point to instances.
+var dnssdServicesEnumeration = []byte("_services._dns-sd._udp.")
+
+// DNSSDServices returns the service instances pointed to by the PTR
+// records of the message, from any section, completed with the SRV, TXT,
+// A and AAAA records of the message.  Names are compared byte for byte.
+func (d *DNS) DNSSDServices() []DNSSDService {
+	var records []*DNSResourceRecord
+	for _, section := range [][]DNSResourceRecord{d.Answers, d.Authorities, d.Additionals} {
+		for i := range section {
+			records = append(records, &section[i])
+		}
+	}
+	var services []DNSSDService
+	for _, ptr := range records {
+		if ptr.Type != DNSTypePTR || bytes.HasPrefix(ptr.Name, dnssdServicesEnumeration) {
+			continue
+		}
+		// the PTR record of "_ipp._tcp.local" points to
+		// "Office Printer._ipp._tcp.local"
+		instance, ok := bytes.CutSuffix(ptr.PTR, append([]byte{'.'}, ptr.Name...))
+		if !ok || len(instance) == 0 {
+			continue
+		}
+		s := DNSSDService{Instance: instance, Service: ptr.Name}
+		// the service type is made of two labels, the domain follows
+		if i := bytes.Index(ptr.Name, []byte("._tcp.")); i >= 0 {
+			s.Service, s.Domain = ptr.Name[:i+5], ptr.Name[i+6:]
+		} else if i := bytes.Index(ptr.Name, []byte("._udp.")); i >= 0 {
+			s.Service, s.Domain = ptr.Name[:i+5], ptr.Name[i+6:]
+		}
+		for _, rr := range records {
+			if !bytes.Equal(rr.Name, ptr.PTR) {
+				continue
+			}
+			switch rr.Type {
+			case DNSTypeSRV:
+				s.Host, s.Port, s.Priority, s.Weight = rr.SRV.Name, rr.SRV.Port, rr.SRV.Priority, rr.SRV.Weight
+			case DNSTypeTXT:
+				s.Attributes = DNSSDAttributes(rr.TXTs)
+			}
+		}
+		if s.Host != nil {
+			for _, rr := range records {
+				if (rr.Type == DNSTypeA || rr.Type == DNSTypeAAAA) && bytes.Equal(rr.Name, s.Host) {
+					s.IPs = append(s.IPs, rr.IP)
+				}
+			}
+		}
+		services = append(services, s)
+	}
+	return services
+}
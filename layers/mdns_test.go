@@ -0,0 +1,119 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package layers
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/gopacket/gopacket"
+)
+
+// serializeOverUDP returns a UDP datagram between ports port carrying l.
+func serializeOverUDP(t *testing.T, port UDPPort, l gopacket.SerializableLayer) []byte {
+	t.Helper()
+	buf := gopacket.NewSerializeBuffer()
+	udp := &UDP{SrcPort: port, DstPort: port}
+	if err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{FixLengths: true}, udp, l); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestMDNS(t *testing.T) {
+	instance := []byte("Office Printer._ipp._tcp.local")
+	host := []byte("printer.local")
+	cacheFlushIN := DNSClassIN | dnsClassMDNSBit
+	response := &MDNS{DNS{
+		QR: true, AA: true, Compress: true,
+		Questions: []DNSQuestion{{Name: []byte("_ipp._tcp.local"), Type: DNSTypePTR, Class: cacheFlushIN}},
+		Answers: []DNSResourceRecord{
+			{Name: []byte("_services._dns-sd._udp.local"), Type: DNSTypePTR, Class: DNSClassIN, TTL: 4500, PTR: []byte("_ipp._tcp.local")},
+			{Name: []byte("_ipp._tcp.local"), Type: DNSTypePTR, Class: DNSClassIN, TTL: 4500, PTR: instance},
+		},
+		Additionals: []DNSResourceRecord{
+			{Name: instance, Type: DNSTypeSRV, Class: cacheFlushIN, TTL: 120, SRV: DNSSRV{Port: 631, Name: host}},
+			{Name: instance, Type: DNSTypeTXT, Class: cacheFlushIN, TTL: 4500, TXTs: [][]byte{[]byte("txtvers=1"), []byte("rp=ipp/print"), []byte("Color=T")}},
+			{Name: host, Type: DNSTypeA, Class: cacheFlushIN, TTL: 120, IP: net.IP{192, 168, 1, 20}},
+			{Name: host, Type: DNSTypeAAAA, Class: cacheFlushIN, TTL: 120, IP: net.ParseIP("fe80::20")},
+		},
+	}}
+
+	p := gopacket.NewPacket(serializeOverUDP(t, 5353, response), LayerTypeUDP, testDecodeOptions)
+	if p.ErrorLayer() != nil {
+		t.Fatal(p.ErrorLayer().Error())
+	}
+	checkLayers(p, []gopacket.LayerType{LayerTypeUDP, LayerTypeMDNS}, t)
+	mdns := p.ApplicationLayer().(*MDNS)
+	if q := mdns.Questions[0]; !q.UnicastResponse() || q.Class.MDNSClass() != DNSClassIN {
+		t.Errorf("got question class %v", q.Class)
+	}
+	if rr := mdns.Answers[0]; rr.CacheFlush() {
+		t.Errorf("got cache flush for shared record %s", rr.Name)
+	}
+	if rr := mdns.Additionals[0]; !rr.CacheFlush() || rr.Class.MDNSClass() != DNSClassIN {
+		t.Errorf("got record class %v", rr.Class)
+	}
+
+	want := []DNSSDService{{
+		Instance: []byte("Office Printer"),
+		Service:  []byte("_ipp._tcp"),
+		Domain:   []byte("local"),
+		Host:     host,
+		Port:     631,
+		Attributes: []DNSSDAttribute{
+			{Key: "txtvers", Value: []byte("1")},
+			{Key: "rp", Value: []byte("ipp/print")},
+			{Key: "Color", Value: []byte("T")},
+		},
+		IPs: []net.IP{net.IP{192, 168, 1, 20}, net.ParseIP("fe80::20")},
+	}}
+	if got := mdns.DNSSDServices(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got services %+v, want %+v", got, want)
+	}
+}
+
+func TestDNSSDAttributes(t *testing.T) {
+	var txts [][]byte
+	for _, s := range []string{"txtvers=1", "", "duplex", "=x", "note=", "Paper=A4", "paper=letter", "pdl=a=b"} {
+		txts = append(txts, []byte(s))
+	}
+	want := []DNSSDAttribute{
+		{Key: "txtvers", Value: []byte("1")},
+		{Key: "duplex"},
+		{Key: "note", Value: []byte{}},
+		{Key: "Paper", Value: []byte("A4")},
+		{Key: "pdl", Value: []byte("a=b")},
+	}
+	if got := DNSSDAttributes(txts); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// An LLMNR response to a query for "host", with the conflict and tentative
+// flags set.
+var testPacketLLMNR = []byte{
+	0x9c, 0x2a, 0x85, 0x00, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00,
+	0x04, 'h', 'o', 's', 't', 0x00, 0x00, 0x01, 0x00, 0x01,
+	0xc0, 0x0c, 0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x00, 0x1e, 0x00, 0x04, 0xa9, 0xfe, 0x01, 0x02,
+}
+
+func TestLLMNR(t *testing.T) {
+	p := gopacket.NewPacket(serializeOverUDP(t, 5355, gopacket.Payload(testPacketLLMNR)), LayerTypeUDP, testDecodeOptions)
+	if p.ErrorLayer() != nil {
+		t.Fatal(p.ErrorLayer().Error())
+	}
+	checkLayers(p, []gopacket.LayerType{LayerTypeUDP, LayerTypeLLMNR}, t)
+	llmnr := p.ApplicationLayer().(*LLMNR)
+	if !llmnr.QR || !llmnr.Conflict() || !llmnr.Tentative() {
+		t.Errorf("got flags QR %v, C %v, T %v", llmnr.QR, llmnr.Conflict(), llmnr.Tentative())
+	}
+	if len(llmnr.Answers) != 1 || !llmnr.Answers[0].IP.Equal(net.IP{169, 254, 1, 2}) {
+		t.Errorf("got answers %+v", llmnr.Answers)
+	}
+}
@@ -153,3 +153,24 @@ func (d *ModbusTCP) Payload() []byte {
 func (s *ModbusTCP) CanDecode() gopacket.LayerClass {
 	return LayerTypeModbusTCP
 }
+
+//******************************************************************************
+
+// SerializeTo writes the serialized form of this layer into the
+// SerializationBuffer, implementing gopacket.SerializableLayer.
+// See the docs for gopacket.SerializableLayer for more info.
+func (d *ModbusTCP) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOptions) error {
+	if opts.FixLengths {
+		// UnitIdentifier plus the PDU already in the buffer
+		d.Length = uint16(len(b.Bytes()) + 1)
+	}
+	bytes, err := b.PrependBytes(mbapRecordSizeInBytes)
+	if err != nil {
+		return err
+	}
+	binary.BigEndian.PutUint16(bytes[:2], d.TransactionIdentifier)
+	binary.BigEndian.PutUint16(bytes[2:4], uint16(d.ProtocolIdentifier))
+	binary.BigEndian.PutUint16(bytes[4:6], d.Length)
+	bytes[6] = d.UnitIdentifier
+	return nil
+}
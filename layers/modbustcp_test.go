@@ -0,0 +1,32 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package layers
+
+import (
+	"testing"
+
+	"github.com/gopacket/gopacket"
+)
+
+// testPacketModbusTCP is a Read Holding Registers request for 10
+// registers starting at address 0, sent to unit 1.
+var testPacketModbusTCP = []byte{
+	0x00, 0x01, 0x00, 0x00, 0x00, 0x06, 0x01, 0x03, 0x00, 0x00, 0x00, 0x0a,
+}
+
+func TestPacketModbusTCP(t *testing.T) {
+	p := gopacket.NewPacket(testPacketModbusTCP, LayerTypeModbusTCP, gopacket.Default)
+	if p.ErrorLayer() != nil {
+		t.Error("Failed to decode packet:", p.ErrorLayer().Error())
+	}
+	checkLayers(p, []gopacket.LayerType{LayerTypeModbusTCP, gopacket.LayerTypePayload}, t)
+	m := p.Layer(LayerTypeModbusTCP).(*ModbusTCP)
+	if m.TransactionIdentifier != 1 || m.Length != 6 || m.UnitIdentifier != 1 {
+		t.Errorf("unexpected MBAP header %+v", m)
+	}
+	testSerialization(t, p, testPacketModbusTCP)
+}
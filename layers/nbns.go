@@ -0,0 +1,584 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package layers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/gopacket/gopacket"
+)
+
+// NBNSOpCode is the operation of a NetBIOS Name Service message.
+type NBNSOpCode uint8
+
+// NBNSOpCode known values, see RFC 1002, section 4.2.1.1.
+const (
+	NBNSOpCodeQuery        NBNSOpCode = 0
+	NBNSOpCodeRegistration NBNSOpCode = 5
+	NBNSOpCodeRelease      NBNSOpCode = 6
+	NBNSOpCodeWACK         NBNSOpCode = 7
+	NBNSOpCodeRefresh      NBNSOpCode = 8
+	NBNSOpCodeMultiHomed   NBNSOpCode = 15 // multi-homed name registration
+)
+
+func (op NBNSOpCode) String() string {
+	switch op {
+	default:
+		return fmt.Sprintf("Unknown(%d)", uint8(op))
+	case NBNSOpCodeQuery:
+		return "Query"
+	case NBNSOpCodeRegistration:
+		return "Registration"
+	case NBNSOpCodeRelease:
+		return "Release"
+	case NBNSOpCodeWACK:
+		return "WACK"
+	case NBNSOpCodeRefresh:
+		return "Refresh"
+	case NBNSOpCodeMultiHomed:
+		return "Multi-Homed Registration"
+	}
+}
+
+// NBNSResponseCode is the result of a NetBIOS Name Service request.
+type NBNSResponseCode uint8
+
+// NBNSResponseCode known values, see RFC 1002, section 4.2.
+const (
+	NBNSResponseCodeNoError     NBNSResponseCode = 0
+	NBNSResponseCodeFormatError NBNSResponseCode = 1
+	NBNSResponseCodeServerError NBNSResponseCode = 2
+	NBNSResponseCodeNameError   NBNSResponseCode = 3
+	NBNSResponseCodeUnsupported NBNSResponseCode = 4
+	NBNSResponseCodeRefused     NBNSResponseCode = 5
+	NBNSResponseCodeActive      NBNSResponseCode = 6
+	NBNSResponseCodeConflict    NBNSResponseCode = 7
+)
+
+func (rc NBNSResponseCode) String() string {
+	switch rc {
+	default:
+		return fmt.Sprintf("Unknown(%d)", uint8(rc))
+	case NBNSResponseCodeNoError:
+		return "No Error"
+	case NBNSResponseCodeFormatError:
+		return "Format Error"
+	case NBNSResponseCodeServerError:
+		return "Server Failure"
+	case NBNSResponseCodeNameError:
+		return "Name Error"
+	case NBNSResponseCodeUnsupported:
+		return "Unsupported Request"
+	case NBNSResponseCodeRefused:
+		return "Refused"
+	case NBNSResponseCodeActive:
+		return "Active Error"
+	case NBNSResponseCodeConflict:
+		return "Name In Conflict"
+	}
+}
+
+// NBNSType is the type of a NetBIOS Name Service question or record.
+type NBNSType uint16
+
+// NBNSType known values, see RFC 1002, section 4.2.
+const (
+	NBNSTypeA      NBNSType = 0x0001
+	NBNSTypeNS     NBNSType = 0x0002
+	NBNSTypeNULL   NBNSType = 0x000a
+	NBNSTypeNB     NBNSType = 0x0020
+	NBNSTypeNBSTAT NBNSType = 0x0021
+)
+
+func (t NBNSType) String() string {
+	switch t {
+	default:
+		return fmt.Sprintf("Unknown(%d)", uint16(t))
+	case NBNSTypeA:
+		return "A"
+	case NBNSTypeNS:
+		return "NS"
+	case NBNSTypeNULL:
+		return "NULL"
+	case NBNSTypeNB:
+		return "NB"
+	case NBNSTypeNBSTAT:
+		return "NBSTAT"
+	}
+}
+
+// NBNSNodeType is the name resolution mode of a NetBIOS node.
+type NBNSNodeType uint8
+
+// NBNSNodeType known values, see RFC 1001, section 10.
+const (
+	NBNSNodeTypeB NBNSNodeType = 0 // broadcast
+	NBNSNodeTypeP NBNSNodeType = 1 // point-to-point
+	NBNSNodeTypeM NBNSNodeType = 2 // mixed
+	NBNSNodeTypeH NBNSNodeType = 3 // hybrid, a Microsoft extension
+)
+
+func (nt NBNSNodeType) String() string {
+	switch nt {
+	default:
+		return fmt.Sprintf("Unknown(%d)", uint8(nt))
+	case NBNSNodeTypeB:
+		return "B-node"
+	case NBNSNodeTypeP:
+		return "P-node"
+	case NBNSNodeTypeM:
+		return "M-node"
+	case NBNSNodeTypeH:
+		return "H-node"
+	}
+}
+
+// NetBIOS name suffixes, the 16th byte of a name telling the service
+// registering it.
+const (
+	NetBIOSSuffixWorkstation   uint8 = 0x00
+	NetBIOSSuffixMessenger     uint8 = 0x03
+	NetBIOSSuffixDomainMaster  uint8 = 0x1b
+	NetBIOSSuffixDomainControl uint8 = 0x1c
+	NetBIOSSuffixMasterBrowser uint8 = 0x1d
+	NetBIOSSuffixBrowserElect  uint8 = 0x1e
+	NetBIOSSuffixFileServer    uint8 = 0x20
+)
+
+var (
+	errNBNSPacketTooShort = errors.New("NBNS packet too short")
+	errNBNSNameLength     = errors.New("NBNS name is not 32 bytes long")
+	errNBNSNameEncoding   = errors.New("NBNS name is not first-level encoded")
+)
+
+// EncodeNetBIOSName returns the first-level encoding of a NetBIOS name,
+// see RFC 1001, section 14.1: name is padded to 15 bytes and followed by
+// suffix, then each byte is written as two letters from 'A' to 'P'.
+// name is truncated to 15 bytes, and padded with spaces, or NULs for
+// the "*" wildcard.
+func EncodeNetBIOSName(name string, suffix uint8) []byte {
+	var raw [16]byte
+	pad := byte(' ')
+	if name == "*" {
+		pad = 0
+	}
+	for i := range raw[:15] {
+		if i < len(name) {
+			raw[i] = name[i]
+		} else {
+			raw[i] = pad
+		}
+	}
+	raw[15] = suffix
+	encoded := make([]byte, 32)
+	for i, b := range raw {
+		encoded[2*i] = 'A' + b>>4
+		encoded[2*i+1] = 'A' + b&0xf
+	}
+	return encoded
+}
+
+// DecodeNetBIOSName decodes the first-level encoding of a NetBIOS name,
+// returning the name without its padding and its suffix.
+func DecodeNetBIOSName(encoded []byte) (name string, suffix uint8, err error) {
+	if len(encoded) != 32 {
+		return "", 0, errNBNSNameLength
+	}
+	var raw [16]byte
+	for i := range raw {
+		hi, lo := encoded[2*i]-'A', encoded[2*i+1]-'A'
+		if hi > 0xf || lo > 0xf {
+			return "", 0, errNBNSNameEncoding
+		}
+		raw[i] = hi<<4 | lo
+	}
+	return string(bytes.TrimRight(raw[:15], " \x00")), raw[15], nil
+}
+
+// NBNSName is a NetBIOS name, as found in the questions and records of
+// NetBIOS Name Service messages.
+type NBNSName struct {
+	Name   string
+	Suffix uint8
+	// Scope is the NetBIOS scope, a domain name appended to the encoded
+	// name, usually empty.
+	Scope []byte
+}
+
+func (n *NBNSName) decode(data []byte, offset int, buffer *[]byte) (int, error) {
+	name, end, err := decodeName(data, offset, buffer, 1)
+	if err != nil {
+		return 0, err
+	}
+	encoded, scope, _ := bytes.Cut(name, []byte{'.'})
+	if n.Name, n.Suffix, err = DecodeNetBIOSName(encoded); err != nil {
+		return 0, err
+	}
+	n.Scope = scope
+	return end, nil
+}
+
+func (n *NBNSName) size() int {
+	return 1 + 32 + nameSize(n.Scope)
+}
+
+func (n *NBNSName) encode(data []byte, offset int) int {
+	data[offset] = 32
+	copy(data[offset+1:], EncodeNetBIOSName(n.Name, n.Suffix))
+	return encodeName(n.Scope, data, offset+33)
+}
+
+// NBNSQuestion is a question of a NetBIOS Name Service request.
+type NBNSQuestion struct {
+	NBNSName
+	Type  NBNSType
+	Class DNSClass
+}
+
+func (q *NBNSQuestion) decode(data []byte, offset int, buffer *[]byte) (int, error) {
+	end, err := q.NBNSName.decode(data, offset, buffer)
+	if err != nil {
+		return 0, err
+	}
+	if len(data) < end+4 {
+		return 0, errors.New("NBNS question too small")
+	}
+	q.Type = NBNSType(binary.BigEndian.Uint16(data[end:]))
+	q.Class = DNSClass(binary.BigEndian.Uint16(data[end+2:]))
+	return end + 4, nil
+}
+
+// NBNSAddress is an address of a NetBIOS name, from an NB record.
+type NBNSAddress struct {
+	Group    bool
+	NodeType NBNSNodeType
+	IP       net.IP
+}
+
+// NBNSNodeName is a name registered by a node, from an NBSTAT record.
+type NBNSNodeName struct {
+	Name   string
+	Suffix uint8
+	Group  bool
+	// NodeType, Deregistering, Conflict, Active and Permanent give the
+	// state of the name on the node, see RFC 1002, section 4.2.18.
+	NodeType      NBNSNodeType
+	Deregistering bool
+	Conflict      bool
+	Active        bool
+	Permanent     bool
+}
+
+// NBNSResourceRecord is a record of a NetBIOS Name Service message.
+type NBNSResourceRecord struct {
+	NBNSName
+	Type  NBNSType
+	Class DNSClass
+	TTL   uint32
+
+	// RDATA Raw Values
+	DataLength uint16
+	Data       []byte
+
+	// RDATA Decoded Values
+	Addresses []NBNSAddress  // NB, but for WACK responses
+	NodeNames []NBNSNodeName // NBSTAT
+	// Statistics are the bytes following the node names of an NBSTAT
+	// record, starting with the unit ID, usually a MAC address.
+	Statistics []byte
+}
+
+func (rr *NBNSResourceRecord) decode(data []byte, offset int, buffer *[]byte, wack bool) (int, error) {
+	end, err := rr.NBNSName.decode(data, offset, buffer)
+	if err != nil {
+		return 0, err
+	}
+	if len(data) < end+10 {
+		return 0, errors.New("NBNS record too small")
+	}
+	rr.Type = NBNSType(binary.BigEndian.Uint16(data[end:]))
+	rr.Class = DNSClass(binary.BigEndian.Uint16(data[end+2:]))
+	rr.TTL = binary.BigEndian.Uint32(data[end+4:])
+	rr.DataLength = binary.BigEndian.Uint16(data[end+8:])
+	end += 10
+	if len(data) < end+int(rr.DataLength) {
+		return 0, errDecodeRecordLength
+	}
+	rr.Data = data[end : end+int(rr.DataLength)]
+	rr.Addresses, rr.NodeNames, rr.Statistics = nil, nil, nil
+
+	switch rr.Type {
+	case NBNSTypeNB:
+		// the RDATA of a WACK holds the flags of the request
+		if wack {
+			break
+		}
+		if len(rr.Data)%6 != 0 {
+			return 0, fmt.Errorf("NBNS NB record length %d is not a multiple of 6", len(rr.Data))
+		}
+		for d := rr.Data; len(d) > 0; d = d[6:] {
+			rr.Addresses = append(rr.Addresses, NBNSAddress{
+				Group:    d[0]&0x80 != 0,
+				NodeType: NBNSNodeType(d[0] >> 5 & 0x3),
+				IP:       net.IP(d[2:6]),
+			})
+		}
+	case NBNSTypeNBSTAT:
+		if len(rr.Data) < 1 || len(rr.Data) < 1+18*int(rr.Data[0]) {
+			return 0, errors.New("NBNS NBSTAT record too small")
+		}
+		d := rr.Data[1:]
+		for i := 0; i < int(rr.Data[0]); i++ {
+			rr.NodeNames = append(rr.NodeNames, NBNSNodeName{
+				Name:          string(bytes.TrimRight(d[:15], " \x00")),
+				Suffix:        d[15],
+				Group:         d[16]&0x80 != 0,
+				NodeType:      NBNSNodeType(d[16] >> 5 & 0x3),
+				Deregistering: d[16]&0x10 != 0,
+				Conflict:      d[16]&0x08 != 0,
+				Active:        d[16]&0x04 != 0,
+				Permanent:     d[16]&0x02 != 0,
+			})
+			d = d[18:]
+		}
+		rr.Statistics = d
+	}
+	return end + int(rr.DataLength), nil
+}
+
+// rdataSize returns the size of the RDATA of rr, encoded from its decoded
+// values if it has any, or from Data otherwise.
+func (rr *NBNSResourceRecord) rdataSize() int {
+	switch {
+	case rr.Type == NBNSTypeNB && len(rr.Addresses) > 0:
+		return 6 * len(rr.Addresses)
+	case rr.Type == NBNSTypeNBSTAT && (len(rr.NodeNames) > 0 || len(rr.Statistics) > 0):
+		return 1 + 18*len(rr.NodeNames) + len(rr.Statistics)
+	}
+	return len(rr.Data)
+}
+
+func (rr *NBNSResourceRecord) encode(data []byte, offset int, opts gopacket.SerializeOptions) (int, error) {
+	end := rr.NBNSName.encode(data, offset)
+	sz := rr.rdataSize()
+	if opts.FixLengths {
+		rr.DataLength = uint16(sz)
+	}
+	binary.BigEndian.PutUint16(data[end:], uint16(rr.Type))
+	binary.BigEndian.PutUint16(data[end+2:], uint16(rr.Class))
+	binary.BigEndian.PutUint32(data[end+4:], rr.TTL)
+	binary.BigEndian.PutUint16(data[end+8:], rr.DataLength)
+	rdata := data[end+10 : end+10+sz]
+
+	switch {
+	case rr.Type == NBNSTypeNB && len(rr.Addresses) > 0:
+		for i, a := range rr.Addresses {
+			ip := a.IP.To4()
+			if ip == nil {
+				return 0, fmt.Errorf("NBNS address %v is not IPv4", a.IP)
+			}
+			rdata[6*i] = byte(b2i(a.Group)<<7) | byte(a.NodeType&0x3)<<5
+			rdata[6*i+1] = 0
+			copy(rdata[6*i+2:], ip)
+		}
+	case rr.Type == NBNSTypeNBSTAT && (len(rr.NodeNames) > 0 || len(rr.Statistics) > 0):
+		if len(rr.NodeNames) > 0xff {
+			return 0, fmt.Errorf("NBNS NBSTAT record has %d names", len(rr.NodeNames))
+		}
+		rdata[0] = byte(len(rr.NodeNames))
+		d := rdata[1:]
+		for _, n := range rr.NodeNames {
+			copy(d, fmt.Sprintf("%-15.15s", n.Name))
+			d[15] = n.Suffix
+			d[16] = byte(b2i(n.Group)<<7|b2i(n.Deregistering)<<4|b2i(n.Conflict)<<3|b2i(n.Active)<<2|b2i(n.Permanent)<<1) | byte(n.NodeType&0x3)<<5
+			d[17] = 0
+			d = d[18:]
+		}
+		copy(d, rr.Statistics)
+	default:
+		copy(rdata, rr.Data)
+	}
+	return end + 10 + sz - offset, nil
+}
+
+//	NBNS header, see RFC 1002, section 4.2.1.1
+//	0  1  2  3  4  5  6  7  8  9  0  1  2  3  4  5
+//	+--+--+--+--+--+--+--+--+--+--+--+--+--+--+--+--+
+//	|                  NAME_TRN_ID                  |
+//	+--+--+--+--+--+--+--+--+--+--+--+--+--+--+--+--+
+//	|R |  OPCODE   |AA|TC|RD|RA| 0| 0|B |   RCODE   |
+//	+--+--+--+--+--+--+--+--+--+--+--+--+--+--+--+--+
+//	|                    QDCOUNT                    |
+//	+--+--+--+--+--+--+--+--+--+--+--+--+--+--+--+--+
+//	|                    ANCOUNT                    |
+//	+--+--+--+--+--+--+--+--+--+--+--+--+--+--+--+--+
+//	|                    NSCOUNT                    |
+//	+--+--+--+--+--+--+--+--+--+--+--+--+--+--+--+--+
+//	|                    ARCOUNT                    |
+//	+--+--+--+--+--+--+--+--+--+--+--+--+--+--+--+--+
+
+// NBNS is a NetBIOS Name Service message, see RFC 1002, section 4.2.  Its
+// layout is the one of DNS, with names first-level encoded, see
+// EncodeNetBIOSName.
+type NBNS struct {
+	BaseLayer
+
+	ID           uint16
+	Response     bool
+	OpCode       NBNSOpCode
+	AA           bool // authoritative answer
+	TC           bool // truncated
+	RD           bool // recursion desired
+	RA           bool // recursion available
+	Broadcast    bool
+	ResponseCode NBNSResponseCode
+
+	QDCount uint16
+	ANCount uint16
+	NSCount uint16
+	ARCount uint16
+
+	Questions   []NBNSQuestion
+	Answers     []NBNSResourceRecord
+	Authorities []NBNSResourceRecord
+	Additionals []NBNSResourceRecord
+
+	// buffer for doing name decoding, like DNS
+	buffer []byte
+}
+
+// LayerType returns LayerTypeNBNS.
+func (n *NBNS) LayerType() gopacket.LayerType { return LayerTypeNBNS }
+
+// CanDecode implements gopacket.DecodingLayer.
+func (n *NBNS) CanDecode() gopacket.LayerClass { return LayerTypeNBNS }
+
+// NextLayerType implements gopacket.DecodingLayer.
+func (n *NBNS) NextLayerType() gopacket.LayerType { return gopacket.LayerTypeZero }
+
+// Payload returns nil, NBNS messages have no payload.
+func (n *NBNS) Payload() []byte { return nil }
+
+func decodeNBNS(data []byte, p gopacket.PacketBuilder) error {
+	n := &NBNS{}
+	if err := n.DecodeFromBytes(data, p); err != nil {
+		return err
+	}
+	p.AddLayer(n)
+	p.SetApplicationLayer(n)
+	return nil
+}
+
+// DecodeFromBytes decodes the slice into the NBNS struct.
+func (n *NBNS) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if len(data) < 12 {
+		df.SetTruncated()
+		return errNBNSPacketTooShort
+	}
+	n.BaseLayer = BaseLayer{Contents: data}
+	n.buffer = n.buffer[:0]
+
+	n.ID = binary.BigEndian.Uint16(data)
+	n.Response = data[2]&0x80 != 0
+	n.OpCode = NBNSOpCode(data[2] >> 3 & 0xf)
+	n.AA = data[2]&0x04 != 0
+	n.TC = data[2]&0x02 != 0
+	n.RD = data[2]&0x01 != 0
+	n.RA = data[3]&0x80 != 0
+	n.Broadcast = data[3]&0x10 != 0
+	n.ResponseCode = NBNSResponseCode(data[3] & 0xf)
+	n.QDCount = binary.BigEndian.Uint16(data[4:])
+	n.ANCount = binary.BigEndian.Uint16(data[6:])
+	n.NSCount = binary.BigEndian.Uint16(data[8:])
+	n.ARCount = binary.BigEndian.Uint16(data[10:])
+
+	n.Questions = n.Questions[:0]
+	offset := 12
+	var err error
+	for i := 0; i < int(n.QDCount); i++ {
+		n.Questions = append(n.Questions, NBNSQuestion{})
+		if offset, err = n.Questions[i].decode(data, offset, &n.buffer); err != nil {
+			n.Questions = n.Questions[:i]
+			return err
+		}
+	}
+	wack := n.OpCode == NBNSOpCodeWACK
+	for _, section := range []struct {
+		records *[]NBNSResourceRecord
+		count   uint16
+	}{
+		{&n.Answers, n.ANCount},
+		{&n.Authorities, n.NSCount},
+		{&n.Additionals, n.ARCount},
+	} {
+		*section.records = (*section.records)[:0]
+		for i := 0; i < int(section.count); i++ {
+			*section.records = append(*section.records, NBNSResourceRecord{})
+			if offset, err = (*section.records)[i].decode(data, offset, &n.buffer, wack); err != nil {
+				*section.records = (*section.records)[:i]
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SerializeTo writes the serialized form of this layer into the
+// SerializationBuffer, implementing gopacket.SerializableLayer.  Names are
+// written uncompressed.
+func (n *NBNS) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOptions) error {
+	sz := 12
+	for i := range n.Questions {
+		sz += n.Questions[i].size() + 4
+	}
+	for _, records := range [][]NBNSResourceRecord{n.Answers, n.Authorities, n.Additionals} {
+		for i := range records {
+			sz += records[i].size() + 10 + records[i].rdataSize()
+		}
+	}
+	data, err := b.PrependBytes(sz)
+	if err != nil {
+		return err
+	}
+
+	if opts.FixLengths {
+		n.QDCount = uint16(len(n.Questions))
+		n.ANCount = uint16(len(n.Answers))
+		n.NSCount = uint16(len(n.Authorities))
+		n.ARCount = uint16(len(n.Additionals))
+	}
+	binary.BigEndian.PutUint16(data, n.ID)
+	data[2] = byte(b2i(n.Response)<<7|b2i(n.AA)<<2|b2i(n.TC)<<1|b2i(n.RD)) | byte(n.OpCode&0xf)<<3
+	data[3] = byte(b2i(n.RA)<<7|b2i(n.Broadcast)<<4) | byte(n.ResponseCode&0xf)
+	binary.BigEndian.PutUint16(data[4:], n.QDCount)
+	binary.BigEndian.PutUint16(data[6:], n.ANCount)
+	binary.BigEndian.PutUint16(data[8:], n.NSCount)
+	binary.BigEndian.PutUint16(data[10:], n.ARCount)
+
+	offset := 12
+	for i := range n.Questions {
+		q := &n.Questions[i]
+		offset = q.encode(data, offset)
+		binary.BigEndian.PutUint16(data[offset:], uint16(q.Type))
+		binary.BigEndian.PutUint16(data[offset+2:], uint16(q.Class))
+		offset += 4
+	}
+	for _, records := range [][]NBNSResourceRecord{n.Answers, n.Authorities, n.Additionals} {
+		for i := range records {
+			l, err := records[i].encode(data, offset, opts)
+			if err != nil {
+				return err
+			}
+			offset += l
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package layers
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/gopacket/gopacket"
+)
+
+func TestNetBIOSName(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		suffix  uint8
+		encoded string
+	}{
+		// RFC 1001, section 14.1
+		{"FRED", NetBIOSSuffixFileServer, "EGFCEFEECACACACACACACACACACACACA"},
+		{"*", NetBIOSSuffixWorkstation, "CKAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"},
+		{"WORKGROUP", NetBIOSSuffixMasterBrowser, "FHEPFCELEHFCEPFFFACACACACACACABN"},
+	} {
+		encoded := EncodeNetBIOSName(test.name, test.suffix)
+		if string(encoded) != test.encoded {
+			t.Errorf("%s<%02x>: got %s", test.name, test.suffix, encoded)
+		}
+		name, suffix, err := DecodeNetBIOSName(encoded)
+		if err != nil || name != test.name || suffix != test.suffix {
+			t.Errorf("%s<%02x>: got %q<%02x>, error %v", test.name, test.suffix, name, suffix, err)
+		}
+	}
+	for _, encoded := range []string{"EGFCEFEE", "EGFCEFEECACACACACACACACACACACACQ"} {
+		if _, _, err := DecodeNetBIOSName([]byte(encoded)); err == nil {
+			t.Errorf("no error for %s", encoded)
+		}
+	}
+}
+
+// A broadcast node status query for "*".
+var testPacketNBNSNodeStatusQuery = []byte{
+	0x81, 0x2c, 0x00, 0x10, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x20, 'C', 'K', 'A', 'A', 'A', 'A', 'A', 'A', 'A', 'A', 'A', 'A', 'A', 'A', 'A', 'A',
+	'A', 'A', 'A', 'A', 'A', 'A', 'A', 'A', 'A', 'A', 'A', 'A', 'A', 'A', 'A', 'A', 0x00,
+	0x00, 0x21, 0x00, 0x01,
+}
+
+func TestNBNSNodeStatusQuery(t *testing.T) {
+	p := gopacket.NewPacket(serializeOverUDP(t, 137, gopacket.Payload(testPacketNBNSNodeStatusQuery)), LayerTypeUDP, testDecodeOptions)
+	if p.ErrorLayer() != nil {
+		t.Fatal(p.ErrorLayer().Error())
+	}
+	checkLayers(p, []gopacket.LayerType{LayerTypeUDP, LayerTypeNBNS}, t)
+	nbns := p.ApplicationLayer().(*NBNS)
+	if nbns.ID != 0x812c || nbns.Response || nbns.OpCode != NBNSOpCodeQuery || !nbns.Broadcast {
+		t.Errorf("got header %+v", nbns)
+	}
+	want := []NBNSQuestion{{NBNSName: NBNSName{Name: "*"}, Type: NBNSTypeNBSTAT, Class: DNSClassIN}}
+	if !reflect.DeepEqual(nbns.Questions, want) {
+		t.Errorf("got questions %+v", nbns.Questions)
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	if err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{}, nbns); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), testPacketNBNSNodeStatusQuery) {
+		t.Errorf("got %x", buf.Bytes())
+	}
+}
+
+func TestNBNSRoundTrip(t *testing.T) {
+	for _, nbns := range []*NBNS{
+		{
+			ID: 1, Response: true, AA: true, RD: true,
+			Answers: []NBNSResourceRecord{{
+				NBNSName: NBNSName{Name: "*"}, Type: NBNSTypeNBSTAT, Class: DNSClassIN,
+				NodeNames: []NBNSNodeName{
+					{Name: "FILESERVER", Suffix: NetBIOSSuffixFileServer, NodeType: NBNSNodeTypeH, Active: true},
+					{Name: "WORKGROUP", Suffix: NetBIOSSuffixWorkstation, Group: true, NodeType: NBNSNodeTypeH, Active: true},
+				},
+				Statistics: append([]byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}, make([]byte, 40)...),
+			}},
+		},
+		{
+			ID: 2, Response: true, AA: true, RD: true, RA: true,
+			Answers: []NBNSResourceRecord{{
+				NBNSName: NBNSName{Name: "FILESERVER", Suffix: NetBIOSSuffixFileServer, Scope: []byte("corp.example")},
+				Type:     NBNSTypeNB, Class: DNSClassIN, TTL: 300000,
+				Addresses: []NBNSAddress{
+					{NodeType: NBNSNodeTypeH, IP: net.IP{10, 1, 2, 3}},
+					{NodeType: NBNSNodeTypeH, IP: net.IP{10, 4, 5, 6}},
+				},
+			}},
+		},
+		{
+			ID: 3, Response: true, OpCode: NBNSOpCodeWACK, AA: true,
+			Answers: []NBNSResourceRecord{{
+				NBNSName: NBNSName{Name: "LAPTOP"}, Type: NBNSTypeNB, Class: DNSClassIN, TTL: 2,
+				Data: []byte{0x29, 0x10},
+			}},
+		},
+		{
+			ID: 4, OpCode: NBNSOpCodeRegistration, RD: true, Broadcast: true,
+			Questions: []NBNSQuestion{{NBNSName: NBNSName{Name: "LAPTOP"}, Type: NBNSTypeNB, Class: DNSClassIN}},
+			Additionals: []NBNSResourceRecord{{
+				NBNSName: NBNSName{Name: "LAPTOP"}, Type: NBNSTypeNB, Class: DNSClassIN, TTL: 300000,
+				Addresses: []NBNSAddress{{NodeType: NBNSNodeTypeB, IP: net.IP{192, 168, 1, 7}}},
+			}},
+		},
+	} {
+		buf := gopacket.NewSerializeBuffer()
+		if err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{FixLengths: true}, nbns); err != nil {
+			t.Fatal(err)
+		}
+		p := gopacket.NewPacket(buf.Bytes(), LayerTypeNBNS, testDecodeOptions)
+		if p.ErrorLayer() != nil {
+			t.Fatalf("%d: %v", nbns.ID, p.ErrorLayer().Error())
+		}
+		got := p.Layer(LayerTypeNBNS).(*NBNS)
+		if got.OpCode != nbns.OpCode || got.Broadcast != nbns.Broadcast || got.RA != nbns.RA || !reflect.DeepEqual(got.Questions, nbns.Questions) {
+			t.Errorf("%d: got %+v", nbns.ID, got)
+		}
+		for i, rr := range append(got.Answers, got.Additionals...) {
+			want := append(nbns.Answers, nbns.Additionals...)[i]
+			if rr.NBNSName.Name != want.Name || rr.Suffix != want.Suffix || !bytes.Equal(rr.Scope, want.Scope) || rr.TTL != want.TTL {
+				t.Errorf("%d: got record %+v", nbns.ID, rr)
+			}
+			if !reflect.DeepEqual(rr.Addresses, want.Addresses) || !reflect.DeepEqual(rr.NodeNames, want.NodeNames) || !bytes.Equal(rr.Statistics, want.Statistics) {
+				t.Errorf("%d: got RDATA %+v, want %+v", nbns.ID, rr, want)
+			}
+			if want.Data != nil && !bytes.Equal(rr.Data, want.Data) {
+				t.Errorf("%d: got data %x", nbns.ID, rr.Data)
+			}
+		}
+	}
+}
+
+func TestNBNSTruncated(t *testing.T) {
+	for i := range testPacketNBNSNodeStatusQuery {
+		var nbns NBNS
+		if err := nbns.DecodeFromBytes(testPacketNBNSNodeStatusQuery[:i], gopacket.NilDecodeFeedback); err == nil {
+			t.Errorf("no error for %d bytes", i)
+		}
+	}
+}
@@ -239,6 +239,30 @@ func decodeNortelDiscovery(data []byte, p gopacket.PacketBuilder) error {
 	return nil
 }
 
+// SerializeTo writes the serialized form of this layer into the
+// SerializationBuffer, implementing gopacket.SerializableLayer.
+// See the docs for gopacket.SerializableLayer for more info.
+func (c *NortelDiscovery) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOptions) error {
+	ip := c.IPAddress.To4()
+	if ip == nil {
+		return fmt.Errorf("invalid NortelDiscovery IPv4 address %v", c.IPAddress)
+	}
+	if len(c.SegmentID) != 3 {
+		return fmt.Errorf("invalid NortelDiscovery segment ID length %d", len(c.SegmentID))
+	}
+	bytes, err := b.PrependBytes(11)
+	if err != nil {
+		return err
+	}
+	copy(bytes[0:4], ip)
+	copy(bytes[4:7], c.SegmentID)
+	bytes[7] = uint8(c.Chassis)
+	bytes[8] = uint8(c.Backplane)
+	bytes[9] = uint8(c.State)
+	bytes[10] = c.NumLinks
+	return nil
+}
+
 func (t NDPChassisType) String() (s string) {
 	switch t {
 	case NDPChassisother:
@@ -0,0 +1,40 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package layers
+
+import (
+	"net"
+	"testing"
+
+	"github.com/gopacket/gopacket"
+)
+
+var testPacketNortelDiscovery = []byte{
+	0xac, 0x13, 0x58, 0x03, 0x00, 0x04, 0x15, 0x30, 0x0c, 0x02, 0x01,
+}
+
+func TestNortelDiscovery(t *testing.T) {
+	p := gopacket.NewPacket(testPacketNortelDiscovery, LayerTypeNortelDiscovery, testDecodeOptions)
+	if p.ErrorLayer() != nil {
+		t.Fatal("Failed to decode packet:", p.ErrorLayer().Error())
+	}
+	got := p.Layer(LayerTypeNortelDiscovery).(*NortelDiscovery)
+	if !got.IPAddress.Equal(net.IP{172, 19, 88, 3}) || got.Chassis != NDPChassisBayStack450101001000Switches ||
+		got.Backplane != NDPBackplaneEthernetFastEthernetGigabitEthernet || got.State != NDPStateHeartbeat || got.NumLinks != 1 {
+		t.Errorf("NortelDiscovery mismatch: %#v", got)
+	}
+	testSerialization(t, p, testPacketNortelDiscovery)
+
+	for _, bad := range []*NortelDiscovery{
+		{IPAddress: net.ParseIP("2001:db8::1"), SegmentID: []byte{0, 0, 0}},
+		{IPAddress: net.IP{10, 0, 0, 1}, SegmentID: []byte{0, 0}},
+	} {
+		if err := bad.SerializeTo(gopacket.NewSerializeBuffer(), gopacket.SerializeOptions{}); err == nil {
+			t.Errorf("no error for %#v", bad)
+		}
+	}
+}
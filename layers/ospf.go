@@ -251,6 +251,7 @@ type OSPFv3 struct {
 	OSPF
 	Instance uint8
 	Reserved uint8
+	tcpipchecksum
 }
 
 // getLSAsv2 parses the LSA information from the packet for OSPFv2
@@ -714,3 +715,312 @@ func decodeOSPF(data []byte, p gopacket.PacketBuilder) error {
 
 	return fmt.Errorf("Unable to determine OSPF type.")
 }
+
+// ospfPrefix returns an address prefix padded to a multiple of 32 bits,
+// as carried by OSPFv3 LSAs (RFC 5340 A.4.1).
+func ospfPrefix(prefix []byte) []byte {
+	padded := make([]byte, (len(prefix)+3)/4*4)
+	copy(padded, prefix)
+	return padded
+}
+
+// appendUint24 appends the low 24 bits of v, preceded by b.
+func appendUint24(buf []byte, b uint8, v uint32) []byte {
+	return append(buf, b, byte(v>>16), byte(v>>8), byte(v))
+}
+
+// appendLSAheader appends the 20 byte LSA header, in its OSPFv2 or
+// OSPFv3 form.
+func appendLSAheader(buf []byte, h *LSAheader, v2 bool) []byte {
+	buf = binary.BigEndian.AppendUint16(buf, h.LSAge)
+	if v2 {
+		buf = append(buf, h.LSOptions, uint8(h.LSType))
+	} else {
+		buf = binary.BigEndian.AppendUint16(buf, h.LSType)
+	}
+	buf = binary.BigEndian.AppendUint32(buf, h.LinkStateID)
+	buf = binary.BigEndian.AppendUint32(buf, h.AdvRouter)
+	buf = binary.BigEndian.AppendUint32(buf, h.LSSeqNumber)
+	buf = binary.BigEndian.AppendUint16(buf, h.LSChecksum)
+	return binary.BigEndian.AppendUint16(buf, h.Length)
+}
+
+// lsaChecksum computes the Fletcher checksum of an LSA (RFC 2328
+// section 12.1.7), skipping the LS age field.
+func lsaChecksum(lsa []byte) uint16 {
+	const offset = 14 // checksum offset, once the LS age is skipped
+	data := lsa[2:]
+	var c0, c1 int
+	for i, b := range data {
+		if i == offset || i == offset+1 {
+			b = 0
+		}
+		c0 = (c0 + int(b)) % 255
+		c1 = (c1 + c0) % 255
+	}
+	x := ((len(data)-offset-1)*c0 - c1) % 255
+	if x <= 0 {
+		x += 255
+	}
+	y := 510 - c0 - x
+	if y > 255 {
+		y -= 255
+	}
+	return uint16(x)<<8 | uint16(y)
+}
+
+// serializeLSA appends an LSA, header included.
+func serializeLSA(buf []byte, lsa *LSA, v2 bool, opts gopacket.SerializeOptions) ([]byte, error) {
+	var body []byte
+	switch c := lsa.Content.(type) {
+	case RouterLSAV2:
+		if opts.FixLengths {
+			c.Links = uint16(len(c.Routers))
+			lsa.Content = c
+		}
+		body = append(body, c.Flags, 0)
+		body = binary.BigEndian.AppendUint16(body, c.Links)
+		for _, r := range c.Routers {
+			body = binary.BigEndian.AppendUint32(body, r.LinkID)
+			body = binary.BigEndian.AppendUint32(body, r.LinkData)
+			body = append(body, r.Type, 0)
+			body = binary.BigEndian.AppendUint16(body, r.Metric)
+		}
+	case ASExternalLSAV2:
+		var e uint8
+		if c.ExternalBit != 0 {
+			e = 0x80
+		}
+		body = binary.BigEndian.AppendUint32(body, c.NetworkMask)
+		body = appendUint24(body, e, c.Metric)
+		body = binary.BigEndian.AppendUint32(body, c.ForwardingAddress)
+		body = binary.BigEndian.AppendUint32(body, c.ExternalRouteTag)
+	case NetworkLSAV2:
+		body = binary.BigEndian.AppendUint32(body, c.NetworkMask)
+		for _, r := range c.AttachedRouter {
+			body = binary.BigEndian.AppendUint32(body, r)
+		}
+	case RouterLSA:
+		body = appendUint24(body, c.Flags, c.Options)
+		for _, r := range c.Routers {
+			body = append(body, r.Type, 0)
+			body = binary.BigEndian.AppendUint16(body, r.Metric)
+			body = binary.BigEndian.AppendUint32(body, r.InterfaceID)
+			body = binary.BigEndian.AppendUint32(body, r.NeighborInterfaceID)
+			body = binary.BigEndian.AppendUint32(body, r.NeighborRouterID)
+		}
+	case NetworkLSA:
+		body = appendUint24(body, 0, c.Options)
+		for _, r := range c.AttachedRouter {
+			body = binary.BigEndian.AppendUint32(body, r)
+		}
+	case InterAreaPrefixLSA:
+		body = appendUint24(body, 0, c.Metric)
+		body = append(body, c.PrefixLength, c.PrefixOptions, 0, 0)
+		body = append(body, ospfPrefix(c.AddressPrefix)...)
+	case InterAreaRouterLSA:
+		body = appendUint24(body, 0, c.Options)
+		body = appendUint24(body, 0, c.Metric)
+		body = binary.BigEndian.AppendUint32(body, c.DestinationRouterID)
+	case ASExternalLSA:
+		body = appendUint24(body, c.Flags, c.Metric)
+		// PrefixLength is decoded in bytes
+		body = append(body, c.PrefixLength*8, c.PrefixOptions)
+		body = binary.BigEndian.AppendUint16(body, c.RefLSType)
+		body = append(body, ospfPrefix(c.AddressPrefix)...)
+		if c.Flags&0x02 != 0 {
+			fwd := make([]byte, 16)
+			copy(fwd, c.ForwardingAddress)
+			body = append(body, fwd...)
+		}
+		if c.Flags&0x01 != 0 {
+			body = binary.BigEndian.AppendUint32(body, c.ExternalRouteTag)
+		}
+		if c.RefLSType != 0 {
+			body = binary.BigEndian.AppendUint32(body, c.RefLinkStateID)
+		}
+	case LinkLSA:
+		if opts.FixLengths {
+			c.NumOfPrefixes = uint32(len(c.Prefixes))
+			lsa.Content = c
+		}
+		body = appendUint24(body, c.RtrPriority, c.Options)
+		addr := make([]byte, 16)
+		copy(addr, c.LinkLocalAddress)
+		body = append(body, addr...)
+		body = binary.BigEndian.AppendUint32(body, c.NumOfPrefixes)
+		for _, p := range c.Prefixes {
+			body = append(body, p.PrefixLength, p.PrefixOptions, 0, 0)
+			body = append(body, ospfPrefix(p.AddressPrefix)...)
+		}
+	case IntraAreaPrefixLSA:
+		if opts.FixLengths {
+			c.NumOfPrefixes = uint16(len(c.Prefixes))
+			lsa.Content = c
+		}
+		body = binary.BigEndian.AppendUint16(body, c.NumOfPrefixes)
+		body = binary.BigEndian.AppendUint16(body, c.RefLSType)
+		body = binary.BigEndian.AppendUint32(body, c.RefLinkStateID)
+		body = binary.BigEndian.AppendUint32(body, c.RefAdvRouter)
+		for _, p := range c.Prefixes {
+			body = append(body, p.PrefixLength, p.PrefixOptions)
+			body = binary.BigEndian.AppendUint16(body, p.Metric)
+			body = append(body, ospfPrefix(p.AddressPrefix)...)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported LSA content %T", lsa.Content)
+	}
+	if opts.FixLengths {
+		lsa.Length = uint16(20 + len(body))
+	}
+	start := len(buf)
+	buf = appendLSAheader(buf, &lsa.LSAheader, v2)
+	buf = append(buf, body...)
+	if opts.ComputeChecksums {
+		lsa.LSChecksum = lsaChecksum(buf[start:])
+		binary.BigEndian.PutUint16(buf[start+16:], lsa.LSChecksum)
+	}
+	return buf, nil
+}
+
+// serializeContent returns the serialized packet body, following the
+// common header.
+func (ospf *OSPF) serializeContent(v2 bool, opts gopacket.SerializeOptions) ([]byte, error) {
+	var body []byte
+	switch c := ospf.Content.(type) {
+	case HelloPkgV2:
+		if !v2 {
+			return nil, errors.New("OSPFv3 cannot carry an OSPFv2 Hello")
+		}
+		body = binary.BigEndian.AppendUint32(body, c.NetworkMask)
+		body = binary.BigEndian.AppendUint16(body, c.HelloInterval)
+		body = append(body, uint8(c.Options), c.RtrPriority)
+		body = binary.BigEndian.AppendUint32(body, c.RouterDeadInterval)
+		body = binary.BigEndian.AppendUint32(body, c.DesignatedRouterID)
+		body = binary.BigEndian.AppendUint32(body, c.BackupDesignatedRouterID)
+		for _, n := range c.NeighborID {
+			body = binary.BigEndian.AppendUint32(body, n)
+		}
+	case HelloPkg:
+		if v2 {
+			return nil, errors.New("OSPFv2 Hello must be a HelloPkgV2")
+		}
+		body = binary.BigEndian.AppendUint32(body, c.InterfaceID)
+		body = appendUint24(body, c.RtrPriority, c.Options)
+		body = binary.BigEndian.AppendUint16(body, c.HelloInterval)
+		body = binary.BigEndian.AppendUint16(body, uint16(c.RouterDeadInterval))
+		body = binary.BigEndian.AppendUint32(body, c.DesignatedRouterID)
+		body = binary.BigEndian.AppendUint32(body, c.BackupDesignatedRouterID)
+		for _, n := range c.NeighborID {
+			body = binary.BigEndian.AppendUint32(body, n)
+		}
+	case DbDescPkg:
+		if v2 {
+			body = binary.BigEndian.AppendUint16(body, c.InterfaceMTU)
+			body = append(body, uint8(c.Options), uint8(c.Flags))
+		} else {
+			body = appendUint24(body, 0, c.Options)
+			body = binary.BigEndian.AppendUint16(body, c.InterfaceMTU)
+			body = binary.BigEndian.AppendUint16(body, c.Flags)
+		}
+		body = binary.BigEndian.AppendUint32(body, c.DDSeqNumber)
+		for i := range c.LSAinfo {
+			body = appendLSAheader(body, &c.LSAinfo[i], v2)
+		}
+	case []LSReq:
+		for _, r := range c {
+			body = append(body, 0, 0)
+			body = binary.BigEndian.AppendUint16(body, r.LSType)
+			body = binary.BigEndian.AppendUint32(body, r.LSID)
+			body = binary.BigEndian.AppendUint32(body, r.AdvRouter)
+		}
+	case LSUpdate:
+		if opts.FixLengths {
+			c.NumOfLSAs = uint32(len(c.LSAs))
+			ospf.Content = c
+		}
+		body = binary.BigEndian.AppendUint32(body, c.NumOfLSAs)
+		for i := range c.LSAs {
+			var err error
+			if body, err = serializeLSA(body, &c.LSAs[i], v2, opts); err != nil {
+				return nil, err
+			}
+		}
+	case []LSAheader:
+		for i := range c {
+			body = appendLSAheader(body, &c[i], v2)
+		}
+	case nil:
+	default:
+		return nil, fmt.Errorf("unsupported OSPF content %T", ospf.Content)
+	}
+	return body, nil
+}
+
+// serializeHeader writes the header fields common to both versions.
+func (ospf *OSPF) serializeHeader(bytes []byte, opts gopacket.SerializeOptions) {
+	if opts.FixLengths {
+		ospf.PacketLength = uint16(len(bytes))
+	}
+	bytes[0] = ospf.Version
+	bytes[1] = uint8(ospf.Type)
+	binary.BigEndian.PutUint16(bytes[2:4], ospf.PacketLength)
+	binary.BigEndian.PutUint32(bytes[4:8], ospf.RouterID)
+	binary.BigEndian.PutUint32(bytes[8:12], ospf.AreaID)
+	binary.BigEndian.PutUint16(bytes[12:14], ospf.Checksum)
+}
+
+// SerializeTo writes the serialized form of this layer into the
+// SerializationBuffer, implementing gopacket.SerializableLayer.
+// See the docs for gopacket.SerializableLayer for more info.
+func (ospf *OSPFv2) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOptions) error {
+	body, err := ospf.serializeContent(true, opts)
+	if err != nil {
+		return err
+	}
+	bytes, err := b.PrependBytes(24 + len(body))
+	if err != nil {
+		return err
+	}
+	copy(bytes[24:], body)
+	ospf.serializeHeader(bytes, opts)
+	binary.BigEndian.PutUint16(bytes[14:16], ospf.AuType)
+	binary.BigEndian.PutUint64(bytes[16:24], ospf.Authentication)
+	if opts.ComputeChecksums {
+		// the checksum skips the authentication field
+		bytes[12], bytes[13] = 0, 0
+		csum := gopacket.ComputeChecksum(bytes[:16], 0)
+		ospf.Checksum = gopacket.FoldChecksum(gopacket.ComputeChecksum(bytes[24:], csum))
+		binary.BigEndian.PutUint16(bytes[12:14], ospf.Checksum)
+	}
+	return nil
+}
+
+// SerializeTo writes the serialized form of this layer into the
+// SerializationBuffer, implementing gopacket.SerializableLayer.
+// See the docs for gopacket.SerializableLayer for more info.
+func (ospf *OSPFv3) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOptions) error {
+	body, err := ospf.serializeContent(false, opts)
+	if err != nil {
+		return err
+	}
+	bytes, err := b.PrependBytes(16 + len(body))
+	if err != nil {
+		return err
+	}
+	copy(bytes[16:], body)
+	ospf.serializeHeader(bytes, opts)
+	bytes[14] = ospf.Instance
+	bytes[15] = ospf.Reserved
+	if opts.ComputeChecksums {
+		// like ICMPv6, OSPFv3 checksums the IPv6 pseudo header
+		bytes[12], bytes[13] = 0, 0
+		csum, err := ospf.computeChecksum(bytes, IPProtocolOSPF)
+		if err != nil {
+			return err
+		}
+		ospf.Checksum = gopacket.FoldChecksum(csum)
+		binary.BigEndian.PutUint16(bytes[12:14], ospf.Checksum)
+	}
+	return nil
+}
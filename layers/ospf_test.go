@@ -70,6 +70,7 @@ func TestPacketOSPF2Hello(t *testing.T) {
 	} else {
 		t.Error("No OSPF layer type found in packet")
 	}
+	testSerialization(t, p, testPacketOSPF2Hello)
 }
 func BenchmarkDecodePacketPacket5(b *testing.B) {
 	for i := 0; i < b.N; i++ {
@@ -134,6 +135,7 @@ func TestPacketOSPF3Hello(t *testing.T) {
 	} else {
 		t.Error("No OSPF layer type found in packet")
 	}
+	testSerialization(t, p, testPacketOSPF3Hello)
 }
 func BenchmarkDecodePacketPacket0(b *testing.B) {
 	for i := 0; i < b.N; i++ {
@@ -186,6 +188,7 @@ func TestPacketOSPF2DBDesc(t *testing.T) {
 	} else {
 		t.Error("No OSPF layer type found in packet")
 	}
+	testSerialization(t, p, testPacketOSPF2DBDesc)
 }
 
 // 192.168.87.1 > 192.168.87.9: OSPFv2, Database Description, length 52
@@ -251,6 +254,7 @@ func TestPacketOSPF2DBDescWithLSAheader(t *testing.T) {
 	} else {
 		t.Error("No OSPF layer type found in packet")
 	}
+	testSerialization(t, p, testPacketOSPF2DBDescWithLSAheader)
 }
 
 func BenchmarkDecodePacketPacket6(b *testing.B) {
@@ -308,6 +312,7 @@ func TestPacketOSPF3DBDesc(t *testing.T) {
 	} else {
 		t.Error("No OSPF layer type found in packet")
 	}
+	testSerialization(t, p, testPacketOSPF3DBDesc)
 }
 func BenchmarkDecodePacketPacket1(b *testing.B) {
 	for i := 0; i < b.N; i++ {
@@ -361,6 +366,7 @@ func TestPacketOSPF2LSRequest(t *testing.T) {
 	} else {
 		t.Error("No OSPF layer type found in packet")
 	}
+	testSerialization(t, p, testPacketOSPF2LSRequest)
 }
 func BenchmarkDecodePacketPacket7(b *testing.B) {
 	for i := 0; i < b.N; i++ {
@@ -456,6 +462,7 @@ func TestPacketOSPF3LSRequest(t *testing.T) {
 	} else {
 		t.Error("No OSPF layer type found in packet")
 	}
+	testSerialization(t, p, testPacketOSPF3LSRequest)
 }
 func BenchmarkDecodePacketPacket2(b *testing.B) {
 	for i := 0; i < b.N; i++ {
@@ -683,6 +690,7 @@ func TestPacketOSPF2LSUpdate(t *testing.T) {
 	} else {
 		t.Error("No OSPF layer type found in packet")
 	}
+	testSerialization(t, p, testPacketOSPF2LSUpdate)
 }
 func BenchmarkDecodePacketPacket8(b *testing.B) {
 	for i := 0; i < b.N; i++ {
@@ -787,6 +795,9 @@ func TestPacketOSPF2LSUpdateLSA2(t *testing.T) {
 	} else {
 		t.Error("No OSPF layer type found in packet")
 	}
+	// the capture is truncated and its OSPF checksum is zero: only the
+	// fields as decoded can be serialized back
+	testSerializationWithOpts(t, p, testPacketOSPF2LSUpdateLSA2, gopacket.SerializeOptions{})
 }
 
 // testPacketOSPF2LSUpdateLSA7 is the packet:
@@ -879,6 +890,9 @@ func TestPacketOSPF2LSUpdateLSA7(t *testing.T) {
 	} else {
 		t.Error("No OSPF layer type found in packet")
 	}
+	// the capture is truncated and its OSPF checksum is zero: only the
+	// fields as decoded can be serialized back
+	testSerializationWithOpts(t, p, testPacketOSPF2LSUpdateLSA7, gopacket.SerializeOptions{})
 }
 
 // testPacketOSPF3LSUpdate is the packet:
@@ -1092,6 +1106,7 @@ func TestPacketOSPF3LSUpdate(t *testing.T) {
 	} else {
 		t.Error("No OSPF layer type found in packet")
 	}
+	testSerialization(t, p, testPacketOSPF3LSUpdate)
 }
 func BenchmarkDecodePacketPacket3(b *testing.B) {
 	for i := 0; i < b.N; i++ {
@@ -1150,6 +1165,7 @@ func TestPacketOSPF2LSAck(t *testing.T) {
 	} else {
 		t.Error("No OSPF layer type found in packet")
 	}
+	testSerialization(t, p, testPacketOSPF2LSAck)
 }
 func BenchmarkDecodePacketPacket9(b *testing.B) {
 	for i := 0; i < b.N; i++ {
@@ -1268,6 +1284,7 @@ func TestPacketOSPF3LSAck(t *testing.T) {
 	} else {
 		t.Error("No OSPF layer type found in packet")
 	}
+	testSerialization(t, p, testPacketOSPF3LSAck)
 }
 
 var testPacketOSPFInvalidLSA = []byte{
@@ -82,3 +82,41 @@ func decodePFLog(data []byte, p gopacket.PacketBuilder) error {
 	pf := &PFLog{}
 	return decodingLayerDecoder(pf, data, p)
 }
+
+// SerializeTo writes the serialized form of this layer into the
+// SerializationBuffer, implementing gopacket.SerializableLayer.
+// See the docs for gopacket.SerializableLayer for more info.
+func (pf *PFLog) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOptions) error {
+	if len(pf.IFName) > 16 || len(pf.Ruleset) > 16 {
+		return errors.New("PFLog interface or ruleset name longer than 16 bytes")
+	}
+	if opts.FixLengths {
+		pf.Length = 61
+	}
+	if pf.Length < 61 {
+		return fmt.Errorf("PFLog length %d less than 61", pf.Length)
+	}
+	actualLength := int(pf.Length)
+	if pf.Length%4 == 1 {
+		actualLength += 3
+	}
+	bytes, err := b.PrependBytes(actualLength)
+	if err != nil {
+		return err
+	}
+	copy(bytes, lotsOfZeros[:actualLength])
+	bytes[0] = pf.Length
+	bytes[1] = uint8(pf.Family)
+	bytes[2] = pf.Action
+	bytes[3] = pf.Reason
+	copy(bytes[4:20], pf.IFName)
+	copy(bytes[20:36], pf.Ruleset)
+	binary.BigEndian.PutUint32(bytes[36:40], pf.RuleNum)
+	binary.BigEndian.PutUint32(bytes[40:44], pf.SubruleNum)
+	binary.BigEndian.PutUint32(bytes[44:48], pf.UID)
+	binary.BigEndian.PutUint32(bytes[48:52], uint32(pf.PID))
+	binary.BigEndian.PutUint32(bytes[52:56], pf.RuleUID)
+	binary.BigEndian.PutUint32(bytes[56:60], uint32(pf.RulePID))
+	bytes[60] = uint8(pf.Direction)
+	return nil
+}
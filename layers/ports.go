@@ -57,7 +57,7 @@ func (a TCPPort) LayerType() gopacket.LayerType {
 	}
 	switch a {
 	case 53:
-		return LayerTypeDNS
+		return LayerTypeDNSOverTCP
 	case 443: // https
 		return LayerTypeTLS
 	case 502: // modbustcp
@@ -121,6 +121,10 @@ func (a UDPPort) LayerType() gopacket.LayerType {
 		return LayerTypeDHCPv4
 	case 123:
 		return LayerTypeNTP
+	case 137:
+		return LayerTypeNBNS
+	case 443:
+		return LayerTypeQUIC
 	case 546:
 		return LayerTypeDHCPv6
 	case 547:
@@ -137,6 +141,10 @@ func (a UDPPort) LayerType() gopacket.LayerType {
 		return LayerTypeVXLAN
 	case 5060:
 		return LayerTypeSIP
+	case 5353:
+		return LayerTypeMDNS
+	case 5355:
+		return LayerTypeLLMNR
 	case 6081:
 		return LayerTypeGeneve
 	case 6343:
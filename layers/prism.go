@@ -144,3 +144,37 @@ func (m *PrismHeader) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) e
 
 func (m *PrismHeader) CanDecode() gopacket.LayerClass    { return LayerTypePrismHeader }
 func (m *PrismHeader) NextLayerType() gopacket.LayerType { return LayerTypeDot11 }
+
+// SerializeTo writes the serialized form of this layer into the
+// SerializationBuffer, implementing gopacket.SerializableLayer.
+// See the docs for gopacket.SerializableLayer for more info.
+func (m *PrismHeader) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOptions) error {
+	if len(m.DeviceName) > 16 {
+		return errors.New("Prism device name longer than 16 bytes")
+	}
+	length := 24 + 12*len(m.Values)
+	if opts.FixLengths {
+		m.Length = uint16(length)
+	}
+	bytes, err := b.PrependBytes(length)
+	if err != nil {
+		return err
+	}
+	binary.LittleEndian.PutUint32(bytes[0:4], uint32(m.Code))
+	binary.LittleEndian.PutUint32(bytes[4:8], uint32(m.Length))
+	copy(bytes[8:24], lotsOfZeros[:16])
+	copy(bytes[8:24], m.DeviceName)
+	offset := 24
+	for _, pv := range m.Values {
+		if len(pv.Data) > 4 {
+			return errors.New("Prism value data longer than 4 bytes")
+		}
+		binary.LittleEndian.PutUint32(bytes[offset:], uint32(pv.DID))
+		binary.LittleEndian.PutUint16(bytes[offset+4:], pv.Status)
+		binary.LittleEndian.PutUint16(bytes[offset+6:], pv.Length)
+		copy(bytes[offset+8:offset+12], lotsOfZeros[:4])
+		copy(bytes[offset+8:offset+12], pv.Data)
+		offset += 12
+	}
+	return nil
+}
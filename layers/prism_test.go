@@ -7,6 +7,7 @@
 package layers
 
 import (
+	"bytes"
 	"net"
 	"reflect"
 	"testing"
@@ -111,6 +112,19 @@ func TestPacketPrism(t *testing.T) {
 			t.Errorf("Dot11 packet processing failed:\ngot  :\n%#v\n\nwant :\n%#v\n\n", got, want)
 		}
 	}
+
+	// Dot11MgmtProbeReq is not serializable, the 802.11 frame is used as
+	// payload
+	prism := p.Layer(LayerTypePrismHeader).(*PrismHeader)
+	for _, opts := range []gopacket.SerializeOptions{{}, {FixLengths: true}} {
+		buf := gopacket.NewSerializeBuffer()
+		if err := gopacket.SerializeLayers(buf, opts, prism, gopacket.Payload(prism.Payload)); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Bytes(), testPacketPrism) {
+			t.Errorf("serialization with opts %#v:\ngot  %x\nwant %x", opts, buf.Bytes(), testPacketPrism)
+		}
+	}
 }
 
 func BenchmarkDecodePacketPrism(b *testing.B) {
@@ -0,0 +1,719 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package layers
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gopacket/gopacket"
+)
+
+// QUICVersion is the version of a QUIC long header packet.
+type QUICVersion uint32
+
+// QUICVersion known values.
+const (
+	QUICVersionNegotiation QUICVersion = 0
+	QUICVersion1           QUICVersion = 0x00000001 // RFC 9000
+	QUICVersion2           QUICVersion = 0x6b3343cf // RFC 9369
+)
+
+func (v QUICVersion) String() string {
+	switch {
+	case v == QUICVersionNegotiation:
+		return "Negotiation"
+	case v == QUICVersion1:
+		return "v1"
+	case v == QUICVersion2:
+		return "v2"
+	case v&0xffffff00 == 0xff000000:
+		return fmt.Sprintf("draft-%d", uint8(v))
+	}
+	return fmt.Sprintf("0x%08x", uint32(v))
+}
+
+// QUICPacketType is the type of a QUIC packet.
+type QUICPacketType uint8
+
+// QUICPacketType known values.  The values do not match the wire
+// encoding of the type, which differs between QUIC versions.
+const (
+	QUICPacketInitial QUICPacketType = iota
+	QUICPacket0RTT
+	QUICPacketHandshake
+	QUICPacketRetry
+	QUICPacketVersionNegotiation
+	// QUICPacket1RTT is the only packet with a short header.
+	QUICPacket1RTT
+	// QUICPacketUnknown is a long header packet of an unknown version,
+	// of which only the version and connection IDs can be read.
+	QUICPacketUnknown
+)
+
+func (t QUICPacketType) String() string {
+	switch t {
+	case QUICPacketInitial:
+		return "Initial"
+	case QUICPacket0RTT:
+		return "0-RTT"
+	case QUICPacketHandshake:
+		return "Handshake"
+	case QUICPacketRetry:
+		return "Retry"
+	case QUICPacketVersionNegotiation:
+		return "Version Negotiation"
+	case QUICPacket1RTT:
+		return "1-RTT"
+	}
+	return "Unknown"
+}
+
+// QUICFrameType is the type of a QUIC frame (RFC 9000, section 12.4).
+type QUICFrameType uint64
+
+// QUICFrameType values of the frames allowed in Initial packets.
+const (
+	QUICFramePadding            QUICFrameType = 0x00
+	QUICFramePing               QUICFrameType = 0x01
+	QUICFrameAck                QUICFrameType = 0x02
+	QUICFrameAckECN             QUICFrameType = 0x03
+	QUICFrameCrypto             QUICFrameType = 0x06
+	QUICFrameConnectionClose    QUICFrameType = 0x1c
+	QUICFrameConnectionCloseApp QUICFrameType = 0x1d
+)
+
+func (t QUICFrameType) String() string {
+	switch t {
+	case QUICFramePadding:
+		return "PADDING"
+	case QUICFramePing:
+		return "PING"
+	case QUICFrameAck:
+		return "ACK"
+	case QUICFrameAckECN:
+		return "ACK_ECN"
+	case QUICFrameCrypto:
+		return "CRYPTO"
+	case QUICFrameConnectionClose, QUICFrameConnectionCloseApp:
+		return "CONNECTION_CLOSE"
+	}
+	return fmt.Sprintf("0x%x", uint64(t))
+}
+
+// QUICAckRange is a range of acknowledged packet numbers.
+type QUICAckRange struct {
+	Smallest, Largest uint64
+}
+
+// QUICFrame is a frame of a decrypted Initial packet.  Only the fields of
+// its Type are set.
+type QUICFrame struct {
+	Type QUICFrameType
+	// Offset and Data are the offset and data of a CRYPTO frame.  Data
+	// holds the padding of a PADDING frame, which covers consecutive
+	// padding bytes.
+	Offset uint64
+	Data   []byte
+	// AckDelay, AckRanges and ECNCounts are the fields of an ACK frame.
+	// AckRanges are sorted in decreasing order, and ECNCounts are the
+	// ECT(0), ECT(1) and ECN-CE counts of an ACK_ECN frame.
+	AckDelay  uint64
+	AckRanges []QUICAckRange
+	ECNCounts [3]uint64
+	// ErrorCode, FrameType and Reason are the fields of a
+	// CONNECTION_CLOSE frame.  FrameType is only sent by the transport.
+	ErrorCode uint64
+	FrameType QUICFrameType
+	Reason    []byte
+}
+
+// QUIC is a QUIC packet, RFC 9000 for version 1 and RFC 9369 for version 2.
+//
+// A UDP datagram may carry several coalesced QUIC packets: each one is a
+// QUIC layer, whose LayerPayload holds the following packets.  The packets are
+// encrypted, except for version negotiation and Retry packets, but the
+// keys of Initial packets derive from the Destination Connection ID of the
+// client's first Initial packet (RFC 9001, section 5.2).  The Initial
+// packets of clients are thus decrypted by the decoder, and their CRYPTO
+// frames give the TLS ClientHello.  Use DecryptInitial for the Initial
+// packets of servers, and those of clients after a Retry.
+//
+// Each packet is decoded on its own, so the hellos are only decoded from
+// the packets holding them whole.  Use a QUICInitialReassembler for the
+// hellos spanning several Initial packets.
+//
+// The length of the Destination Connection ID of a short header packet is
+// not carried by the packet, so DestConnID is not set for them.
+type QUIC struct {
+	BaseLayer
+	LongHeader bool
+	// FixedBit is always set, unless the endpoints agreed to grease it
+	// (RFC 9287).
+	FixedBit bool
+	// SpinBit is the latency spin bit of a short header packet.
+	SpinBit    bool
+	PacketType QUICPacketType
+	Version    QUICVersion
+	DestConnID []byte
+	SrcConnID  []byte
+	// SupportedVersions are the versions of a version negotiation packet.
+	SupportedVersions []QUICVersion
+	// Token is the token of an Initial or Retry packet.
+	Token []byte
+	// RetryIntegrityTag is the integrity tag of a Retry packet.
+	RetryIntegrityTag []byte
+	// Length is the length of the packet number and the payload of an
+	// Initial, 0-RTT or Handshake packet.
+	Length uint64
+	// Protected holds the packet number and the payload of a packet, still
+	// protected.
+	Protected []byte
+
+	// The following fields are set once an Initial packet is decrypted.
+	Decrypted    bool
+	PacketNumber uint64
+	Frames       []QUICFrame
+	// Crypto holds the data of the CRYPTO frames contiguous from offset
+	// 0, which is the start of the TLS handshake messages for the first
+	// Initial packet of each endpoint, or of all the Initial packets of
+	// the endpoint once a QUICInitialReassembler completed the hello.
+	Crypto []byte
+	// ClientHello and ServerHello are decoded from Crypto, if it holds a
+	// whole message.  Their HandshakeType is 0 otherwise.
+	ClientHello TLSHandshakeRecordClientHello
+	ServerHello TLSHandshakeRecordServerHello
+
+	// pnOffset is the offset of the packet number in Contents.
+	pnOffset int
+}
+
+// LayerType returns LayerTypeQUIC.
+func (q *QUIC) LayerType() gopacket.LayerType { return LayerTypeQUIC }
+
+// CanDecode returns LayerTypeQUIC.
+func (q *QUIC) CanDecode() gopacket.LayerClass { return LayerTypeQUIC }
+
+// NextLayerType returns LayerTypeQUIC for coalesced packets, and
+// gopacket.LayerTypePayload for the padding that may follow the packets
+// of a datagram.
+func (q *QUIC) NextLayerType() gopacket.LayerType {
+	switch {
+	case len(q.BaseLayer.Payload) == 0:
+		return gopacket.LayerTypeZero
+	case q.BaseLayer.Payload[0]&0x40 != 0:
+		return LayerTypeQUIC
+	}
+	return gopacket.LayerTypePayload
+}
+
+func decodeQUIC(data []byte, p gopacket.PacketBuilder) error {
+	q := &QUIC{}
+	if err := q.DecodeFromBytes(data, p); err != nil {
+		return err
+	}
+	p.AddLayer(q)
+	p.SetApplicationLayer(q)
+	// not through NextLayerType, which would make an initialization loop
+	// with LayerTypeQUIC
+	switch {
+	case len(q.BaseLayer.Payload) == 0:
+		return nil
+	case q.BaseLayer.Payload[0]&0x40 != 0:
+		return p.NextDecoder(gopacket.DecodeFunc(decodeQUIC))
+	}
+	return p.NextDecoder(gopacket.LayerTypePayload)
+}
+
+var errQUICTruncated = errors.New("QUIC packet truncated")
+
+// quicVarint decodes a variable-length integer (RFC 9000, section 16), and
+// returns its length, or 0 if data is too short.
+func quicVarint(data []byte) (uint64, int) {
+	if len(data) == 0 {
+		return 0, 0
+	}
+	n := 1 << (data[0] >> 6)
+	if len(data) < n {
+		return 0, 0
+	}
+	v := uint64(data[0] & 0x3f)
+	for _, b := range data[1:n] {
+		v = v<<8 | uint64(b)
+	}
+	return v, n
+}
+
+// quicLongPacketType returns the type of a long header packet with the
+// type bits typ.
+func quicLongPacketType(v QUICVersion, typ byte) QUICPacketType {
+	switch {
+	case v == QUICVersion2:
+		// version 2 rotates the types, RFC 9369 section 3.2
+		return [4]QUICPacketType{QUICPacketRetry, QUICPacketInitial, QUICPacket0RTT, QUICPacketHandshake}[typ]
+	case v == QUICVersion1, v&0xffffff00 == 0xff000000:
+		return QUICPacketType(typ)
+	}
+	return QUICPacketUnknown
+}
+
+// DecodeFromBytes decodes the first QUIC packet of data.
+func (q *QUIC) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	*q = QUIC{
+		SupportedVersions: q.SupportedVersions[:0],
+		Frames:            q.Frames[:0],
+	}
+	if len(data) < 1 {
+		df.SetTruncated()
+		return errQUICTruncated
+	}
+	q.LongHeader = data[0]&0x80 != 0
+	q.FixedBit = data[0]&0x40 != 0
+	if !q.LongHeader {
+		q.PacketType = QUICPacket1RTT
+		q.SpinBit = data[0]&0x20 != 0
+		q.Protected = data[1:]
+		q.BaseLayer = BaseLayer{Contents: data}
+		return nil
+	}
+
+	// the invariants, RFC 8999 section 5.1
+	if len(data) < 6 {
+		df.SetTruncated()
+		return errQUICTruncated
+	}
+	q.Version = QUICVersion(binary.BigEndian.Uint32(data[1:5]))
+	c := tlsCursor{data: data[5:]}
+	q.DestConnID = c.vec8()
+	q.SrcConnID = c.vec8()
+	if c.err != nil {
+		df.SetTruncated()
+		return errQUICTruncated
+	}
+
+	if q.Version == QUICVersionNegotiation {
+		q.PacketType = QUICPacketVersionNegotiation
+		if len(c.data)%4 != 0 {
+			return errors.New("QUIC version negotiation packet with invalid version list")
+		}
+		for !c.empty() {
+			q.SupportedVersions = append(q.SupportedVersions, QUICVersion(c.u32()))
+		}
+		q.BaseLayer = BaseLayer{Contents: data}
+		return nil
+	}
+	q.PacketType = quicLongPacketType(q.Version, data[0]>>4&0x03)
+	switch q.PacketType {
+	case QUICPacketUnknown:
+		q.Protected = c.data
+		q.BaseLayer = BaseLayer{Contents: data}
+		return nil
+	case QUICPacketRetry:
+		if len(c.data) < 16 {
+			df.SetTruncated()
+			return errQUICTruncated
+		}
+		q.Token = c.data[:len(c.data)-16]
+		q.RetryIntegrityTag = c.data[len(c.data)-16:]
+		q.BaseLayer = BaseLayer{Contents: data}
+		return nil
+	case QUICPacketInitial:
+		length, n := quicVarint(c.data)
+		c.bytes(n)
+		q.Token = c.bytes(int(length))
+		if n == 0 || length > uint64(len(data)) {
+			c.err = errQUICTruncated
+		}
+	}
+	length, n := quicVarint(c.data)
+	c.bytes(n)
+	if n == 0 || length > uint64(len(data)) {
+		c.err = errQUICTruncated
+	}
+	q.Length = length
+	q.pnOffset = len(data) - len(c.data)
+	q.Protected = c.bytes(int(length))
+	if c.err != nil {
+		df.SetTruncated()
+		return errQUICTruncated
+	}
+	end := len(data) - len(c.data)
+	q.BaseLayer = BaseLayer{Contents: data[:end], Payload: data[end:]}
+
+	if q.PacketType == QUICPacketInitial && (q.Version == QUICVersion1 || q.Version == QUICVersion2) {
+		// fails for the packets of servers, which are left encrypted
+		q.DecryptInitial(q.DestConnID, false)
+	}
+	return nil
+}
+
+// quicInitialSalts are the salts of the Initial secrets, RFC 9001 section
+// 5.2 and RFC 9369 section 3.3.1.
+var quicInitialSalts = map[QUICVersion][]byte{
+	QUICVersion1: {0x38, 0x76, 0x2c, 0xf7, 0xf5, 0x59, 0x34, 0xb3, 0x4d, 0x17, 0x9a, 0xe6, 0xa4, 0xc8, 0x0c, 0xad, 0xcc, 0xbb, 0x7f, 0x0a},
+	QUICVersion2: {0x0d, 0xed, 0xe3, 0xde, 0xf7, 0x00, 0xa6, 0xdb, 0x81, 0x93, 0x81, 0xbe, 0x6e, 0x26, 0x9d, 0xcb, 0xf9, 0xbd, 0x2e, 0xd9},
+}
+
+// quicRetryKeys are the keys and nonces of the Retry integrity tags, RFC
+// 9001 section 5.8 and RFC 9369 section 3.3.3.
+var quicRetryKeys = map[QUICVersion][2][]byte{
+	QUICVersion1: {
+		{0xbe, 0x0c, 0x69, 0x0b, 0x9f, 0x66, 0x57, 0x5a, 0x1d, 0x76, 0x6b, 0x54, 0xe3, 0x68, 0xc8, 0x4e},
+		{0x46, 0x15, 0x99, 0xd3, 0x5d, 0x63, 0x2b, 0xf2, 0x23, 0x98, 0x25, 0xbb},
+	},
+	QUICVersion2: {
+		{0x8f, 0xb4, 0xb0, 0x1b, 0x56, 0xac, 0x48, 0xe2, 0x60, 0xfb, 0xcb, 0xce, 0xad, 0x7c, 0xcc, 0x92},
+		{0xd8, 0x69, 0x69, 0xbc, 0x2d, 0x7c, 0x6d, 0x99, 0x90, 0xef, 0xb0, 0x4a},
+	},
+}
+
+// hkdfExtract is HKDF-Extract with SHA-256, RFC 5869.
+func hkdfExtract(salt, secret []byte) []byte {
+	h := hmac.New(sha256.New, salt)
+	h.Write(secret)
+	return h.Sum(nil)
+}
+
+// hkdfExpandLabel is HKDF-Expand-Label with SHA-256 and an empty context,
+// RFC 8446 section 7.1.
+func hkdfExpandLabel(secret []byte, label string, length int) []byte {
+	info := []byte{byte(length >> 8), byte(length), byte(len("tls13 ") + len(label))}
+	info = append(info, "tls13 "...)
+	info = append(info, label...)
+	info = append(info, 0)
+	var out, t []byte
+	for i := byte(1); len(out) < length; i++ {
+		h := hmac.New(sha256.New, secret)
+		h.Write(t)
+		h.Write(info)
+		h.Write([]byte{i})
+		t = h.Sum(nil)
+		out = append(out, t...)
+	}
+	return out[:length]
+}
+
+// quicInitialKeys are the keys protecting the Initial packets of an
+// endpoint.
+type quicInitialKeys struct {
+	key, iv, hp []byte
+}
+
+// newQUICInitialKeys derives the Initial keys from the Destination
+// Connection ID of the client's first Initial packet.
+func newQUICInitialKeys(v QUICVersion, dcid []byte, server bool) (quicInitialKeys, error) {
+	salt, ok := quicInitialSalts[v]
+	if !ok {
+		return quicInitialKeys{}, fmt.Errorf("QUIC version %v not supported", v)
+	}
+	label := "client in"
+	if server {
+		label = "server in"
+	}
+	secret := hkdfExpandLabel(hkdfExtract(salt, dcid), label, 32)
+	prefix := "quic "
+	if v == QUICVersion2 {
+		prefix = "quicv2 "
+	}
+	return quicInitialKeys{
+		key: hkdfExpandLabel(secret, prefix+"key", 16),
+		iv:  hkdfExpandLabel(secret, prefix+"iv", 12),
+		hp:  hkdfExpandLabel(secret, prefix+"hp", 16),
+	}, nil
+}
+
+// DecryptInitial removes the protection of an Initial packet, and decodes
+// its frames.  dcid is the Destination Connection ID of the first Initial
+// packet sent by the client, or of the first one after a Retry, and server
+// selects the keys of the packets sent by the server.  The packet data is
+// left untouched: the decrypted frames are copies.
+func (q *QUIC) DecryptInitial(dcid []byte, server bool) error {
+	if q.PacketType != QUICPacketInitial {
+		return errors.New("not a QUIC Initial packet")
+	}
+	keys, err := newQUICInitialKeys(q.Version, dcid, server)
+	if err != nil {
+		return err
+	}
+	block, err := aes.NewCipher(keys.key)
+	if err != nil {
+		return err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+	hp, err := aes.NewCipher(keys.hp)
+	if err != nil {
+		return err
+	}
+
+	// header protection, RFC 9001 section 5.4: the sample starts 4 bytes
+	// after the packet number, whatever its length
+	if len(q.Protected) < 4+aes.BlockSize {
+		return errQUICTruncated
+	}
+	var mask [aes.BlockSize]byte
+	hp.Encrypt(mask[:], q.Protected[4:4+aes.BlockSize])
+	first := q.Contents[0] ^ mask[0]&0x0f
+	pnLen := int(first&0x03) + 1
+	header := make([]byte, q.pnOffset+pnLen)
+	copy(header, q.Contents)
+	header[0] = first
+	var pn uint64
+	for i := 0; i < pnLen; i++ {
+		header[q.pnOffset+i] ^= mask[1+i]
+		pn = pn<<8 | uint64(header[q.pnOffset+i])
+	}
+	nonce := make([]byte, len(keys.iv))
+	copy(nonce, keys.iv)
+	for i := 0; i < 8; i++ {
+		nonce[len(nonce)-1-i] ^= byte(pn >> (8 * i))
+	}
+	plaintext, err := aead.Open(nil, nonce, q.Protected[pnLen:], header)
+	if err != nil {
+		return fmt.Errorf("QUIC Initial packet decryption: %v", err)
+	}
+
+	// the truncated packet number is the full one for the first packets
+	q.PacketNumber = pn
+	if err := q.decodeFrames(plaintext); err != nil {
+		return err
+	}
+	q.Decrypted = true
+	return q.decodeCrypto()
+}
+
+// decodeFrames decodes the frames allowed in Initial packets.
+func (q *QUIC) decodeFrames(data []byte) error {
+	q.Frames = q.Frames[:0]
+	truncated := false
+	next := func() uint64 {
+		v, n := quicVarint(data)
+		if n == 0 {
+			truncated = true
+		}
+		data = data[n:]
+		return v
+	}
+	bytes := func(length uint64) []byte {
+		if length > uint64(len(data)) {
+			truncated = true
+			length = uint64(len(data))
+		}
+		b := data[:length]
+		data = data[length:]
+		return b
+	}
+	for len(data) > 0 && !truncated {
+		f := QUICFrame{Type: QUICFrameType(next())}
+		switch f.Type {
+		case QUICFramePadding:
+			n := 0
+			for n < len(data) && data[n] == 0 {
+				n++
+			}
+			f.Data = bytes(uint64(n))
+		case QUICFramePing:
+		case QUICFrameAck, QUICFrameAckECN:
+			largest := next()
+			f.AckDelay = next()
+			count := next()
+			first := next()
+			if first > largest {
+				return errors.New("QUIC ACK frame with invalid range")
+			}
+			smallest := largest - first
+			f.AckRanges = append(f.AckRanges, QUICAckRange{smallest, largest})
+			for i := uint64(0); i < count && !truncated; i++ {
+				gap, length := next(), next()
+				if smallest < gap+2 || smallest-gap-2 < length {
+					return errors.New("QUIC ACK frame with invalid range")
+				}
+				largest = smallest - gap - 2
+				smallest = largest - length
+				f.AckRanges = append(f.AckRanges, QUICAckRange{smallest, largest})
+			}
+			if f.Type == QUICFrameAckECN {
+				f.ECNCounts = [3]uint64{next(), next(), next()}
+			}
+		case QUICFrameCrypto:
+			f.Offset = next()
+			f.Data = bytes(next())
+		case QUICFrameConnectionClose, QUICFrameConnectionCloseApp:
+			f.ErrorCode = next()
+			if f.Type == QUICFrameConnectionClose {
+				f.FrameType = QUICFrameType(next())
+			}
+			f.Reason = bytes(next())
+		default:
+			return fmt.Errorf("QUIC frame type %v not allowed in Initial packets", f.Type)
+		}
+		if truncated {
+			return errQUICTruncated
+		}
+		q.Frames = append(q.Frames, f)
+	}
+	return nil
+}
+
+// decodeCrypto gathers the CRYPTO data contiguous from offset 0, which may
+// be sent out of order, and decodes the TLS hello it starts with.
+func (q *QUIC) decodeCrypto() error {
+	return q.decodeHello(quicCryptoData(q.Frames))
+}
+
+// quicCryptoData returns the data of the CRYPTO frames contiguous from
+// offset 0.
+func quicCryptoData(frames []QUICFrame) []byte {
+	var crypto []byte
+	for progress := true; progress; {
+		progress = false
+		for _, f := range frames {
+			have := uint64(len(crypto))
+			if f.Type == QUICFrameCrypto && f.Offset <= have && f.Offset+uint64(len(f.Data)) > have {
+				crypto = append(crypto, f.Data[have-f.Offset:]...)
+				progress = true
+			}
+		}
+	}
+	return crypto
+}
+
+// quicHelloComplete reports whether crypto holds a whole handshake
+// message.
+func quicHelloComplete(crypto []byte) bool {
+	return len(crypto) >= 4 && len(crypto) >= 4+(int(crypto[1])<<16|int(crypto[2])<<8|int(crypto[3]))
+}
+
+// decodeHello sets Crypto, and decodes the TLS hello it starts with.
+func (q *QUIC) decodeHello(crypto []byte) error {
+	q.Crypto = crypto
+	if !quicHelloComplete(crypto) {
+		return nil
+	}
+	switch crypto[0] {
+	case TLSHandshakeClientHello:
+		return q.ClientHello.decodeFromBytes(crypto, gopacket.NilDecodeFeedback)
+	case TLSHandshakeServerHello:
+		return q.ServerHello.decodeFromBytes(crypto, gopacket.NilDecodeFeedback)
+	}
+	return nil
+}
+
+// quicMaxCryptoBytes bounds the CRYPTO data a QUICInitialReassembler
+// buffers for an endpoint, far above the size of real hellos.
+const quicMaxCryptoBytes = 1 << 16
+
+// QUICInitialReassembler gathers the CRYPTO frames of the Initial packets
+// of QUIC connections, to decode the TLS hellos spanning several packets.
+// ClientHellos with post-quantum key shares typically do.  Connections are
+// identified by the Destination Connection ID of the client's first Initial
+// packet.  A QUICInitialReassembler is not safe for concurrent use.
+type QUICInitialReassembler struct {
+	streams map[quicCryptoKey]*quicCryptoStream
+}
+
+type quicCryptoKey struct {
+	dcid   string
+	server bool
+}
+
+// quicCryptoStream holds the CRYPTO frames of an endpoint.
+type quicCryptoStream struct {
+	frames   []QUICFrame
+	bytes    int
+	lastSeen time.Time
+}
+
+// NewQUICInitialReassembler returns a new QUICInitialReassembler.
+func NewQUICInitialReassembler() *QUICInitialReassembler {
+	return &QUICInitialReassembler{streams: make(map[quicCryptoKey]*quicCryptoStream)}
+}
+
+// Add adds the CRYPTO frames of a decrypted Initial packet seen at ts.
+// dcid is the Destination Connection ID of the client's first Initial
+// packet, and server is set for the packets of the server.  Once the
+// CRYPTO data of the endpoint is contiguous from offset 0 to the end of
+// the first handshake message, Crypto and ClientHello or ServerHello of q
+// are set from it, the frames of the endpoint are released and Add
+// returns true.
+func (r *QUICInitialReassembler) Add(q *QUIC, dcid []byte, server bool, ts time.Time) (bool, error) {
+	if q.PacketType != QUICPacketInitial || !q.Decrypted {
+		return false, errors.New("not a decrypted QUIC Initial packet")
+	}
+	k := quicCryptoKey{string(dcid), server}
+	st := r.streams[k]
+	if st == nil {
+		st = &quicCryptoStream{}
+		r.streams[k] = st
+	}
+	st.lastSeen = ts
+	for _, f := range q.Frames {
+		if f.Type == QUICFrameCrypto {
+			// the data of decrypted frames is not shared with q
+			st.frames = append(st.frames, f)
+			st.bytes += len(f.Data)
+		}
+	}
+	if st.bytes > quicMaxCryptoBytes {
+		delete(r.streams, k)
+		return false, fmt.Errorf("QUIC CRYPTO data larger than %d bytes", quicMaxCryptoBytes)
+	}
+	crypto := quicCryptoData(st.frames)
+	if !quicHelloComplete(crypto) {
+		return false, nil
+	}
+	delete(r.streams, k)
+	return true, q.decodeHello(crypto)
+}
+
+// DiscardOlderThan forgets the endpoints whose last packet was seen before
+// t, and returns the number of endpoints forgotten.
+func (r *QUICInitialReassembler) DiscardOlderThan(t time.Time) int {
+	n := 0
+	for k, st := range r.streams {
+		if st.lastSeen.Before(t) {
+			delete(r.streams, k)
+			n++
+		}
+	}
+	return n
+}
+
+// VerifyRetry checks the integrity tag of a Retry packet (RFC 9001,
+// section 5.8).  odcid is the Destination Connection ID of the packet the
+// Retry answers.
+func (q *QUIC) VerifyRetry(odcid []byte) bool {
+	keys, ok := quicRetryKeys[q.Version]
+	if q.PacketType != QUICPacketRetry || !ok || len(odcid) > 255 {
+		return false
+	}
+	block, err := aes.NewCipher(keys[0])
+	if err != nil {
+		return false
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return false
+	}
+	pseudo := append([]byte{byte(len(odcid))}, odcid...)
+	pseudo = append(pseudo, q.Contents[:len(q.Contents)-16]...)
+	tag := aead.Seal(nil, keys[1], nil, pseudo)
+	return subtle.ConstantTimeCompare(tag, q.RetryIntegrityTag) == 1
+}
+
+// Payload returns the protected payload of the packet.  The coalesced
+// packets following it are in LayerPayload.
+func (q *QUIC) Payload() []byte { return q.Protected }
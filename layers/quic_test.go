@@ -0,0 +1,297 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package layers
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gopacket/gopacket"
+)
+
+func unhex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// The keys of RFC 9001, appendix A.1, and RFC 9369, appendix A.1.
+func TestQUICInitialKeys(t *testing.T) {
+	for _, test := range []struct {
+		version     QUICVersion
+		server      bool
+		key, iv, hp string
+	}{
+		{QUICVersion1, false, "1f369613dd76d5467730efcbe3b1a22d", "fa044b2f42a3fd3b46fb255c", "9f50449e04a0e810283a1e9933adedd2"},
+		{QUICVersion1, true, "cf3a5331653c364c88f0f379b6067e37", "0ac1493ca1905853b0bba03e", "c206b8d9b9f0f37644430b490eeaa314"},
+		{QUICVersion2, false, "8b1a0bc121284290a29e0971b5cd045d", "91f73e2351d8fa91660e909f", "45b95e15235d6f45a6b19cbcb0294ba9"},
+		{QUICVersion2, true, "82db637861d55e1d011f19ea71d5d2a7", "dd13c276499c0249d3310652", "edf6d05c83121201b436e16877593c3a"},
+	} {
+		keys, err := newQUICInitialKeys(test.version, unhex(t, "8394c8f03e515708"), test.server)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := [3]string{hex.EncodeToString(keys.key), hex.EncodeToString(keys.iv), hex.EncodeToString(keys.hp)}; got != [3]string{test.key, test.iv, test.hp} {
+			t.Errorf("%v, server %v: got %q", test.version, test.server, got)
+		}
+	}
+}
+
+// protectQUICInitial returns an Initial packet with a 1 byte packet number,
+// protected with the keys of keyID, the connection ID of the client's first
+// Initial packet.
+func protectQUICInitial(t *testing.T, v QUICVersion, keyID, dcid, scid []byte, server bool, pn byte, frames []byte) []byte {
+	t.Helper()
+	keys, err := newQUICInitialKeys(v, keyID, server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := byte(0xc0)
+	if v == QUICVersion2 {
+		first |= 1 << 4
+	}
+	length := 1 + len(frames) + 16
+	header := append([]byte{first, byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}, tlsVec(1, dcid)...)
+	header = append(header, tlsVec(1, scid)...)
+	header = append(header, 0, 0x40|byte(length>>8), byte(length), pn)
+	block, _ := aes.NewCipher(keys.key)
+	aead, _ := cipher.NewGCM(block)
+	nonce := append([]byte(nil), keys.iv...)
+	nonce[len(nonce)-1] ^= pn
+	sealed := aead.Seal(nil, nonce, frames, header)
+	hp, _ := aes.NewCipher(keys.hp)
+	var mask [16]byte
+	hp.Encrypt(mask[:], sealed[3:19])
+	header[0] ^= mask[0] & 0x0f
+	header[len(header)-1] ^= mask[1]
+	return append(header, sealed...)
+}
+
+func quicCryptoFrame(offset int, data []byte) []byte {
+	return append([]byte{byte(QUICFrameCrypto), 0x40 | byte(offset>>8), byte(offset), 0x40 | byte(len(data)>>8), byte(len(data))}, data...)
+}
+
+func TestQUICClientInitial(t *testing.T) {
+	if got := UDPPort(443).LayerType(); got != LayerTypeQUIC {
+		t.Errorf("UDP port 443: got %v", got)
+	}
+	hello := chromeLikeClientHello()
+	dcid, scid := unhex(t, "8394c8f03e515708"), []byte{1, 2, 3, 4}
+	for _, v := range []QUICVersion{QUICVersion1, QUICVersion2} {
+		// the ClientHello is split and sent out of order, as Chrome does
+		var frames []byte
+		frames = append(frames, quicCryptoFrame(100, hello[100:])...)
+		frames = append(frames, byte(QUICFramePing))
+		frames = append(frames, quicCryptoFrame(0, hello[:100])...)
+		frames = append(frames, make([]byte, 50)...)
+		data := protectQUICInitial(t, v, dcid, dcid, scid, false, 2, frames)
+		initialLen := len(data)
+		// a coalesced Handshake packet, then the padding of the datagram
+		handshake := []byte{0xe0, byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v), 1, 9, 0, 20}
+		if v == QUICVersion2 {
+			handshake[0] = 0xf0
+		}
+		data = append(data, handshake...)
+		data = append(data, make([]byte, 20+10)...)
+
+		p := gopacket.NewPacket(data, LayerTypeQUIC, gopacket.Default)
+		if p.ErrorLayer() != nil {
+			t.Fatal(p.ErrorLayer().Error())
+		}
+		var types []gopacket.LayerType
+		for _, l := range p.Layers() {
+			types = append(types, l.LayerType())
+		}
+		if want := []gopacket.LayerType{LayerTypeQUIC, LayerTypeQUIC, gopacket.LayerTypePayload}; !reflect.DeepEqual(types, want) {
+			t.Fatalf("%v: got layers %v", v, types)
+		}
+		q := p.Layers()[0].(*QUIC)
+		if !q.LongHeader || !q.FixedBit || q.Version != v || q.PacketType != QUICPacketInitial ||
+			!bytes.Equal(q.DestConnID, dcid) || !bytes.Equal(q.SrcConnID, scid) || len(q.Token) != 0 {
+			t.Errorf("%v: got header %+v", v, q)
+		}
+		if len(q.Contents) != initialLen || !q.Decrypted || q.PacketNumber != 2 {
+			t.Errorf("%v: got length %d, decrypted %v, packet number %d", v, len(q.Contents), q.Decrypted, q.PacketNumber)
+		}
+		var frameTypes []QUICFrameType
+		for _, f := range q.Frames {
+			frameTypes = append(frameTypes, f.Type)
+		}
+		if want := []QUICFrameType{QUICFrameCrypto, QUICFramePing, QUICFrameCrypto, QUICFramePadding}; !reflect.DeepEqual(frameTypes, want) {
+			t.Errorf("%v: got frames %v", v, frameTypes)
+		}
+		if !bytes.Equal(q.Crypto, hello) {
+			t.Errorf("%v: got crypto data %x", v, q.Crypto)
+		}
+		ch := q.ClientHello
+		if string(ch.SNI) != "example.com" || !reflect.DeepEqual(ch.ALPN, []string{"h2", "http/1.1"}) {
+			t.Errorf("%v: got SNI %q, ALPN %q", v, ch.SNI, ch.ALPN)
+		}
+		if got := ch.JA4QUIC(); got != "q13d1516h2_8daaf6152771_e5627efa2ab1" {
+			t.Errorf("%v: got JA4 %s", v, got)
+		}
+
+		hs := p.Layers()[1].(*QUIC)
+		if hs.PacketType != QUICPacketHandshake || !bytes.Equal(hs.DestConnID, []byte{9}) || len(hs.Protected) != 20 || hs.Decrypted {
+			t.Errorf("%v: got handshake packet %+v", v, hs)
+		}
+	}
+}
+
+func TestQUICInitialReassembler(t *testing.T) {
+	hello := chromeLikeClientHello()
+	dcid, scid := unhex(t, "8394c8f03e515708"), []byte{1, 2, 3, 4}
+	start := time.Unix(1700000000, 0)
+	// the end of the ClientHello is sent first, in the second packet
+	packets := [][]byte{
+		protectQUICInitial(t, QUICVersion1, dcid, dcid, scid, false, 1, quicCryptoFrame(150, hello[150:])),
+		protectQUICInitial(t, QUICVersion1, dcid, dcid, scid, false, 0, quicCryptoFrame(0, hello[:150])),
+	}
+	r := NewQUICInitialReassembler()
+	for i, data := range packets {
+		p := gopacket.NewPacket(data, LayerTypeQUIC, gopacket.Default)
+		if p.ErrorLayer() != nil {
+			t.Fatal(p.ErrorLayer().Error())
+		}
+		q := p.Layer(LayerTypeQUIC).(*QUIC)
+		if q.ClientHello.HandshakeType != 0 {
+			t.Errorf("packet %d: ClientHello decoded from a part of it", i)
+		}
+		done, err := r.Add(q, dcid, false, start.Add(time.Duration(i)*time.Millisecond))
+		if err != nil || done != (i == 1) {
+			t.Fatalf("packet %d: got %v, error %v", i, done, err)
+		}
+		if !done {
+			continue
+		}
+		if !bytes.Equal(q.Crypto, hello) {
+			t.Errorf("got crypto data %x", q.Crypto)
+		}
+		if ch := q.ClientHello; string(ch.SNI) != "example.com" || !reflect.DeepEqual(ch.ALPN, []string{"h2", "http/1.1"}) {
+			t.Errorf("got SNI %q, ALPN %q", ch.SNI, ch.ALPN)
+		}
+	}
+	if n := r.DiscardOlderThan(start.Add(time.Hour)); n != 0 {
+		t.Errorf("got %d endpoints discarded after the hello", n)
+	}
+
+	// a part of a hello is forgotten after a while
+	p := gopacket.NewPacket(packets[0], LayerTypeQUIC, gopacket.Default)
+	if done, err := r.Add(p.Layer(LayerTypeQUIC).(*QUIC), dcid, false, start); done || err != nil {
+		t.Errorf("got %v, error %v", done, err)
+	}
+	if n := r.DiscardOlderThan(start.Add(time.Second)); n != 1 {
+		t.Errorf("got %d endpoints discarded", n)
+	}
+	if _, err := r.Add(&QUIC{PacketType: QUICPacketInitial}, dcid, false, start); err == nil {
+		t.Error("no error for an encrypted packet")
+	}
+}
+
+func TestQUICServerInitial(t *testing.T) {
+	dcid := unhex(t, "8394c8f03e515708")
+	frames := []byte{
+		byte(QUICFrameAck), 5, 0, 1, 1, 0, 0,
+		byte(QUICFrameConnectionClose), 0x0a, 0x06, 3, 'b', 'y', 'e',
+	}
+	data := protectQUICInitial(t, QUICVersion1, dcid, []byte{1, 2, 3, 4}, []byte{5, 6}, true, 0, frames)
+	var q QUIC
+	if err := q.DecodeFromBytes(data, gopacket.NilDecodeFeedback); err != nil {
+		t.Fatal(err)
+	}
+	// the keys do not derive from the connection IDs of the packet
+	if q.Decrypted {
+		t.Fatal("server Initial decrypted without the client's connection ID")
+	}
+	if err := q.DecryptInitial(dcid, false); err == nil {
+		t.Error("decrypted with the keys of the client")
+	}
+	if err := q.DecryptInitial(dcid, true); err != nil {
+		t.Fatal(err)
+	}
+	want := []QUICFrame{
+		{Type: QUICFrameAck, AckRanges: []QUICAckRange{{4, 5}, {2, 2}}},
+		{Type: QUICFrameConnectionClose, ErrorCode: 0x0a, FrameType: QUICFrameCrypto, Reason: []byte("bye")},
+	}
+	if !reflect.DeepEqual(q.Frames, want) {
+		t.Errorf("got frames %+v", q.Frames)
+	}
+}
+
+// The Retry packets of RFC 9001, appendix A.4, and RFC 9369, appendix A.4.
+func TestQUICRetry(t *testing.T) {
+	for _, test := range []struct {
+		version QUICVersion
+		packet  string
+	}{
+		{QUICVersion1, "ff000000010008f067a5502a4262b5746f6b656e04a265ba2eff4d829058fb3f0f2496ba"},
+		{QUICVersion2, "cf6b3343cf0008f067a5502a4262b5746f6b656ec8646ce8bfe33952d955543665dcc7b6"},
+	} {
+		p := gopacket.NewPacket(unhex(t, test.packet), LayerTypeQUIC, gopacket.Default)
+		q, ok := p.Layer(LayerTypeQUIC).(*QUIC)
+		if !ok {
+			t.Fatalf("%v: %v", test.version, p.ErrorLayer().Error())
+		}
+		if q.PacketType != QUICPacketRetry || q.Version != test.version || len(q.DestConnID) != 0 ||
+			!bytes.Equal(q.SrcConnID, unhex(t, "f067a5502a4262b5")) || string(q.Token) != "token" {
+			t.Errorf("%v: got %+v", test.version, q)
+		}
+		if !q.VerifyRetry(unhex(t, "8394c8f03e515708")) {
+			t.Errorf("%v: integrity tag not verified", test.version)
+		}
+		if q.VerifyRetry(unhex(t, "8394c8f03e515709")) {
+			t.Errorf("%v: integrity tag verified for another connection ID", test.version)
+		}
+	}
+}
+
+func TestQUICVersionNegotiation(t *testing.T) {
+	data := []byte{0x80, 0, 0, 0, 0, 2, 1, 2, 1, 3, 0, 0, 0, 1, 0x6b, 0x33, 0x43, 0xcf, 0x1a, 0x2a, 0x3a, 0x4a}
+	var q QUIC
+	if err := q.DecodeFromBytes(data, gopacket.NilDecodeFeedback); err != nil {
+		t.Fatal(err)
+	}
+	if q.PacketType != QUICPacketVersionNegotiation || !bytes.Equal(q.DestConnID, []byte{1, 2}) || !bytes.Equal(q.SrcConnID, []byte{3}) {
+		t.Errorf("got %+v", q)
+	}
+	if want := []QUICVersion{QUICVersion1, QUICVersion2, 0x1a2a3a4a}; !reflect.DeepEqual(q.SupportedVersions, want) {
+		t.Errorf("got versions %v", q.SupportedVersions)
+	}
+	if err := q.DecodeFromBytes(data[:len(data)-1], gopacket.NilDecodeFeedback); err == nil {
+		t.Error("no error for a truncated version list")
+	}
+}
+
+func TestQUICShortHeader(t *testing.T) {
+	data := []byte{0x61, 1, 2, 3, 4, 5}
+	p := gopacket.NewPacket(data, LayerTypeQUIC, gopacket.Default)
+	q, ok := p.Layer(LayerTypeQUIC).(*QUIC)
+	if !ok {
+		t.Fatal(p.ErrorLayer().Error())
+	}
+	if q.LongHeader || q.PacketType != QUICPacket1RTT || !q.SpinBit || !bytes.Equal(q.Payload(), data[1:]) {
+		t.Errorf("got %+v", q)
+	}
+}
+
+func TestQUICTruncated(t *testing.T) {
+	data := protectQUICInitial(t, QUICVersion1, []byte{1}, []byte{1}, nil, false, 0, make([]byte, 40))
+	for i := 0; i < len(data); i++ {
+		var q QUIC
+		if err := q.DecodeFromBytes(data[:i], gopacket.NilDecodeFeedback); err == nil && i > 0 {
+			t.Errorf("no error for %d bytes", i)
+		}
+	}
+}
@@ -92,3 +92,53 @@ func decodeRUDP(data []byte, p gopacket.PacketBuilder) error {
 func (r *RUDP) TransportFlow() gopacket.Flow {
 	return gopacket.NewFlow(EndpointRUDPPort, []byte{byte(r.SrcPort)}, []byte{byte(r.DstPort)})
 }
+
+// SerializeTo writes the serialized form of this layer into the
+// SerializationBuffer, implementing gopacket.SerializableLayer.
+// See the docs for gopacket.SerializableLayer for more info.
+//
+// The variable header area is built from RUDPHeaderSYN or RUDPHeaderEACK
+// when the matching flag is set, and is VariableHeaderArea otherwise. The
+// checksum is written as is.
+func (r *RUDP) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOptions) error {
+	variable := r.VariableHeaderArea
+	switch {
+	case r.SYN && r.RUDPHeaderSYN != nil:
+		variable = make([]byte, 6)
+		binary.BigEndian.PutUint16(variable[:2], r.MaxOutstandingSegments)
+		binary.BigEndian.PutUint16(variable[2:4], r.MaxSegmentSize)
+		binary.BigEndian.PutUint16(variable[4:6], r.OptionFlags)
+	case r.EACK && r.RUDPHeaderEACK != nil:
+		variable = make([]byte, 4*len(r.SeqsReceivedOK))
+		for i, seq := range r.SeqsReceivedOK {
+			binary.BigEndian.PutUint32(variable[i*4:], seq)
+		}
+	}
+	hlen := 18 + len(variable)
+	if hlen%2 != 0 {
+		return fmt.Errorf("RUDP header length %d not a multiple of 2", hlen)
+	}
+	if opts.FixLengths {
+		r.HeaderLength = uint8(hlen / 2)
+		r.DataLength = uint16(len(b.Bytes()))
+	}
+	bytes, err := b.PrependBytes(hlen)
+	if err != nil {
+		return err
+	}
+	bytes[0] = r.Version & 0x3
+	for i, f := range []bool{r.SYN, r.ACK, r.EACK, r.RST, r.NUL} {
+		if f {
+			bytes[0] |= 0x80 >> i
+		}
+	}
+	bytes[1] = r.HeaderLength
+	bytes[2] = uint8(r.SrcPort)
+	bytes[3] = uint8(r.DstPort)
+	binary.BigEndian.PutUint16(bytes[4:6], r.DataLength)
+	binary.BigEndian.PutUint32(bytes[6:10], r.Seq)
+	binary.BigEndian.PutUint32(bytes[10:14], r.Ack)
+	binary.BigEndian.PutUint32(bytes[14:18], r.Checksum)
+	copy(bytes[18:], variable)
+	return nil
+}
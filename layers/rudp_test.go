@@ -0,0 +1,63 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package layers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gopacket/gopacket"
+)
+
+// testPacketRUDPSYN is a SYN+ACK segment with three bytes of data.
+var testPacketRUDPSYN = []byte{
+	0xc1, 0x0c, 0x01, 0x02, 0x00, 0x03, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2a,
+	0x12, 0x34, 0x56, 0x78, 0x00, 0x20, 0x05, 0x78, 0x00, 0x01,
+	'a', 'b', 'c',
+}
+
+// testPacketRUDPEACK acknowledges two out of order segments.
+var testPacketRUDPEACK = []byte{
+	0x61, 0x0d, 0x02, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x2b, 0x00, 0x00, 0x01, 0x00,
+	0x9a, 0xbc, 0xde, 0xf0, 0x00, 0x00, 0x01, 0x02, 0x00, 0x00, 0x01, 0x04,
+}
+
+func TestRUDP(t *testing.T) {
+	for _, test := range []struct {
+		data []byte
+		want *RUDP
+	}{
+		{
+			testPacketRUDPSYN,
+			&RUDP{
+				SYN: true, ACK: true, Version: 1, HeaderLength: 12,
+				SrcPort: 1, DstPort: 2, DataLength: 3, Seq: 0x100, Ack: 0x2a, Checksum: 0x12345678,
+				RUDPHeaderSYN: &RUDPHeaderSYN{MaxOutstandingSegments: 32, MaxSegmentSize: 1400, OptionFlags: 1},
+			},
+		},
+		{
+			testPacketRUDPEACK,
+			&RUDP{
+				ACK: true, EACK: true, Version: 1, HeaderLength: 13,
+				SrcPort: 2, DstPort: 1, Seq: 0x2b, Ack: 0x100, Checksum: 0x9abcdef0,
+				RUDPHeaderEACK: &RUDPHeaderEACK{SeqsReceivedOK: []uint32{0x102, 0x104}},
+			},
+		},
+	} {
+		p := gopacket.NewPacket(test.data, LayerTypeRUDP, testDecodeOptions)
+		if p.ErrorLayer() != nil {
+			t.Fatal("Failed to decode packet:", p.ErrorLayer().Error())
+		}
+		got := p.Layer(LayerTypeRUDP).(*RUDP)
+		test.want.BaseLayer = got.BaseLayer
+		test.want.VariableHeaderArea = got.VariableHeaderArea
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("RUDP mismatch:\ngot  %#v\nwant %#v", got, test.want)
+		}
+		testSerialization(t, p, test.data)
+	}
+}
@@ -45,6 +45,14 @@ func (s *SCTP) TransportFlow() gopacket.Flow {
 	return gopacket.NewFlow(EndpointSCTPPort, s.sPort, s.dPort)
 }
 
+// For testing only
+func (s *SCTP) SetInternalPortsForTesting() {
+	s.sPort = make([]byte, 2)
+	s.dPort = make([]byte, 2)
+	binary.BigEndian.PutUint16(s.sPort, uint16(s.SrcPort))
+	binary.BigEndian.PutUint16(s.dPort, uint16(s.DstPort))
+}
+
 func decodeWithSCTPChunkTypePrefix(data []byte, p gopacket.PacketBuilder) error {
 	chunkType := SCTPChunkType(data[0])
 	return chunkType.Decode(data, p)
@@ -324,10 +332,11 @@ func decodeSCTPData(data []byte, p gopacket.PacketBuilder) error {
 		PayloadProtocol: SCTPPayloadProtocol(binary.BigEndian.Uint32(data[12:16])),
 		Payload:         []byte{},
 	}
-	if l >= 16 {
-		sc.Payload = data[16:l]
+	// Length is the length in bytes of the data, INCLUDING the 16-byte
+	// header, but not the padding.
+	if l >= 16 && chunk.Length >= 16 {
+		sc.Payload = data[16:chunk.Length]
 	}
-	// Length is the length in bytes of the data, INCLUDING the 16-byte header.
 	p.AddLayer(sc)
 	return p.NextDecoder(gopacket.DecodeFunc(decodeWithSCTPChunkTypePrefix))
 }
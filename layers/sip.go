@@ -10,6 +10,8 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"net/textproto"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -590,3 +592,67 @@ func (s *SIP) GetContentLength() int64 {
 func (s *SIP) GetCSeq() int64 {
 	return int64(s.cseq)
 }
+
+// sipHeaderNames holds the canonical form of the header names that
+// textproto.CanonicalMIMEHeaderKey does not spell like RFC 3261.
+var sipHeaderNames = map[string]string{
+	"call-id":          "Call-ID",
+	"cseq":             "CSeq",
+	"mime-version":     "MIME-Version",
+	"www-authenticate": "WWW-Authenticate",
+}
+
+func sipHeaderName(name string) string {
+	if n, ok := sipHeaderNames[name]; ok {
+		return n
+	}
+	if len(name) == 1 {
+		// compact form
+		return name
+	}
+	return textproto.CanonicalMIMEHeaderKey(name)
+}
+
+// SerializeTo writes the serialized form of this layer into the
+// SerializationBuffer, implementing gopacket.SerializableLayer.
+// See the docs for gopacket.SerializableLayer for more info.
+//
+// The message body is the data already in the buffer, i.e. the layers
+// serialized after this one. Headers are written in name order, since the
+// order they were received in is not kept. With FixLengths, the
+// Content-Length header is set to the length of the body.
+func (s *SIP) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOptions) error {
+	if opts.FixLengths {
+		if s.Headers == nil {
+			s.Headers = make(map[string][]string)
+		}
+		delete(s.Headers, compactSipHeadersCorrespondance["content-length"])
+		s.contentLength = len(b.Bytes())
+		s.Headers["content-length"] = []string{strconv.Itoa(s.contentLength)}
+	}
+
+	var buf bytes.Buffer
+	if s.IsResponse {
+		fmt.Fprintf(&buf, "%s %d %s\r\n", s.Version, s.ResponseCode, s.ResponseStatus)
+	} else {
+		fmt.Fprintf(&buf, "%s %s %s\r\n", s.Method, s.RequestURI, s.Version)
+	}
+	names := make([]string, 0, len(s.Headers))
+	for name := range s.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range s.Headers[name] {
+			fmt.Fprintf(&buf, "%s: %s\r\n", sipHeaderName(name), value)
+		}
+	}
+	buf.WriteString("\r\n")
+
+	bytes, err := b.PrependBytes(buf.Len())
+	if err != nil {
+		return err
+	}
+	copy(bytes, buf.Bytes())
+	return nil
+}
@@ -410,6 +410,45 @@ func TestSIPDecode(t *testing.T) {
 
 }
 
+func TestSIPSerialize(t *testing.T) {
+	tests := []struct {
+		name    string
+		decoder gopacket.Decoder
+		data    []byte
+	}{
+		{"sipRequest", LinkTypeEthernet, testPacketSIPRequest},
+		{"sipResponse", LinkTypeEthernet, testPacketSIPResponse},
+		{"compactInvite", LinkTypeEthernet, testPacketSIPCompactInvite},
+		{"inviteWithPayload", LayerTypeSIP, testPacketSIPOnlyInviteWithPayload},
+		{"inviteWithPayloadNoContentLength", LayerTypeSIP, testPacketSIPOnlyInviteWithPayloadNoContentLength},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := gopacket.NewPacket(tt.data, tt.decoder, gopacket.Default)
+			want, ok := p.Layer(LayerTypeSIP).(*SIP)
+			assertTrue(t, ok, "SIP layer not present")
+			body := append([]byte(nil), want.Payload()...)
+
+			buf := gopacket.NewSerializeBuffer()
+			err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{FixLengths: true}, want, gopacket.Payload(body))
+			assertTrue(t, err == nil, "serialization failed: %v", err)
+
+			p = gopacket.NewPacket(buf.Bytes(), LayerTypeSIP, gopacket.Default)
+			assertTrue(t, p.ErrorLayer() == nil, "decoding failed: %v", p.ErrorLayer())
+			got := p.Layer(LayerTypeSIP).(*SIP)
+			assertEqual(t, want.IsResponse, got.IsResponse, "Response")
+			assertEqual(t, want.Version, got.Version, "Version")
+			assertEqual(t, want.Method, got.Method, "METHOD")
+			assertEqual(t, want.RequestURI, got.RequestURI, "URI")
+			assertEqual(t, want.ResponseCode, got.ResponseCode, "Code")
+			assertEqual(t, want.ResponseStatus, got.ResponseStatus, "Status")
+			assertEqual(t, want.Headers, got.Headers, "Headers")
+			assertEqual(t, int64(len(body)), got.GetContentLength(), "Content-Length")
+			assertTrue(t, bytes.Equal(body, got.Payload()), "Payload")
+		})
+	}
+}
+
 func TestSIPGetFirstHeader(t *testing.T) {
 	type args struct {
 		headers map[string][]string
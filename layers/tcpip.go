@@ -52,10 +52,20 @@ func (ip *IPv6) pseudoheaderChecksum() (csum uint32, err error) {
 // out. headerProtocol is the IP protocol number of the upper-layer header.
 // The returned 32bit checksum may need to be folded.
 func (c *tcpipchecksum) computeChecksum(headerAndPayload []byte, headerProtocol IPProtocol) (uint32, error) {
+	csum, err := c.pseudoheaderSum(uint32(len(headerAndPayload)), headerProtocol)
+	if err != nil {
+		return 0, err
+	}
+	csum = gopacket.ComputeChecksum(headerAndPayload, csum)
+	return csum, nil
+}
+
+// pseudoheaderSum returns the unfolded sum of the pseudo-header for a
+// layer 4 packet of the given length.
+func (c *tcpipchecksum) pseudoheaderSum(length uint32, headerProtocol IPProtocol) (uint32, error) {
 	if c.pseudoheader == nil {
 		return 0, errors.New("TCP/IP layer 4 checksum cannot be computed without network layer... call SetNetworkLayerForChecksum to set which layer to use")
 	}
-	length := uint32(len(headerAndPayload))
 	csum, err := c.pseudoheader.pseudoheaderChecksum()
 	if err != nil {
 		return 0, err
@@ -63,8 +73,6 @@ func (c *tcpipchecksum) computeChecksum(headerAndPayload []byte, headerProtocol
 	csum += uint32(headerProtocol)
 	csum += length & 0xffff
 	csum += length >> 16
-
-	csum = gopacket.ComputeChecksum(headerAndPayload, csum)
 	return csum, nil
 }
 
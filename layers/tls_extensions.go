@@ -0,0 +1,340 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package layers
+
+import (
+	"errors"
+	"fmt"
+)
+
+var errTLSTruncated = errors.New("TLS handshake message truncated")
+
+// tlsCursor walks over the length-prefixed vectors used by the TLS
+// presentation language (RFC 8446, section 3), checking bounds at every
+// step. Once a read fails, all later reads fail too, so callers only
+// need to check err once they are done.
+type tlsCursor struct {
+	data []byte
+	err  error
+}
+
+func (c *tlsCursor) empty() bool { return len(c.data) == 0 }
+
+func (c *tlsCursor) bytes(n int) []byte {
+	if c.err != nil || n < 0 || len(c.data) < n {
+		c.err = errTLSTruncated
+		return nil
+	}
+	b := c.data[:n:n]
+	c.data = c.data[n:]
+	return b
+}
+
+func (c *tlsCursor) u8() uint8 {
+	b := c.bytes(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (c *tlsCursor) u16() uint16 {
+	b := c.bytes(2)
+	if b == nil {
+		return 0
+	}
+	return uint16(b[0])<<8 | uint16(b[1])
+}
+
+func (c *tlsCursor) u24() uint32 {
+	b := c.bytes(3)
+	if b == nil {
+		return 0
+	}
+	return uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2])
+}
+
+func (c *tlsCursor) u32() uint32 {
+	b := c.bytes(4)
+	if b == nil {
+		return 0
+	}
+	return uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
+}
+
+// vec8, vec16 and vec24 read a vector prefixed by a 1, 2 or 3 byte length.
+func (c *tlsCursor) vec8() []byte  { return c.bytes(int(c.u8())) }
+func (c *tlsCursor) vec16() []byte { return c.bytes(int(c.u16())) }
+func (c *tlsCursor) vec24() []byte { return c.bytes(int(c.u24())) }
+
+// tlsUint16List decodes a vector of 16-bit values.
+func tlsUint16List(data []byte) ([]uint16, error) {
+	if len(data)%2 != 0 {
+		return nil, errors.New("TLS list of 16-bit values has odd length")
+	}
+	out := make([]uint16, 0, len(data)/2)
+	for i := 0; i < len(data); i += 2 {
+		out = append(out, uint16(data[i])<<8|uint16(data[i+1]))
+	}
+	return out, nil
+}
+
+// String returns the IANA name of the extension.
+func (e TLSExtension) String() string {
+	switch e {
+	case TLSExtServerName:
+		return "server_name"
+	case TLSExtMaxFragLen:
+		return "max_fragment_length"
+	case TLSExtClientCertURL:
+		return "client_certificate_url"
+	case TLSExtTrustedCAKeys:
+		return "trusted_ca_keys"
+	case TLSExtTruncatedHMAC:
+		return "truncated_hmac"
+	case TLSExtStatusRequest:
+		return "status_request"
+	case TLSExtUserMapping:
+		return "user_mapping"
+	case TLSExtClientAuthz:
+		return "client_authz"
+	case TLSExtServerAuthz:
+		return "server_authz"
+	case TLSExtCertType:
+		return "cert_type"
+	case TLSExtSupportedGroups:
+		return "supported_groups"
+	case TLSExtECPointFormats:
+		return "ec_point_formats"
+	case TLSExtSRP:
+		return "srp"
+	case TLSExtSignatureAlgs:
+		return "signature_algorithms"
+	case TLSxtUseSRTP:
+		return "use_srtp"
+	case TLSExtHeartbeat:
+		return "heartbeat"
+	case TLSExtALPN:
+		return "application_layer_protocol_negotiation"
+	case TLSExtStatusRequestV2:
+		return "status_request_v2"
+	case TLSExtSignedCertTS:
+		return "signed_certificate_timestamp"
+	case TLSExtClientCertType:
+		return "client_certificate_type"
+	case TLSExtServerCertType:
+		return "server_certificate_type"
+	case TLSExtPadding:
+		return "padding"
+	case TLSExtEncryptThenMAC:
+		return "encrypt_then_mac"
+	case TLSExtExtendedMasterSecret:
+		return "extended_master_secret"
+	case TLSExtCompressCertificate:
+		return "compress_certificate"
+	case TLSExtRecordSizeLimit:
+		return "record_size_limit"
+	case TLSExtDelegatedCredentials:
+		return "delegated_credentials"
+	case TLSExtSessionTicket:
+		return "session_ticket"
+	case TLSExtPreSharedKey:
+		return "pre_shared_key"
+	case TLSExtEarlyData:
+		return "early_data"
+	case TLSExtSupportedVersions:
+		return "supported_versions"
+	case TLSExtCookie:
+		return "cookie"
+	case TLSExtPSKKeyExchangeModes:
+		return "psk_key_exchange_modes"
+	case TLSExtCertificateAuthorities:
+		return "certificate_authorities"
+	case TLSExtOIDFilters:
+		return "oid_filters"
+	case TLSExtPostHandshakeAuth:
+		return "post_handshake_auth"
+	case TLSExtSignatureAlgsCert:
+		return "signature_algorithms_cert"
+	case TLSExtKeyShare:
+		return "key_share"
+	case TLSExtQUICTransportParams:
+		return "quic_transport_parameters"
+	case TLSExtNPN:
+		return "next_protocol_negotiation"
+	case TLSExtApplicationSettings:
+		return "application_settings"
+	case TLSExtEncryptedClientHello:
+		return "encrypted_client_hello"
+	case TLSExtRenegotiationInfo:
+		return "renegotiation_info"
+	default:
+		if IsTLSGREASE(uint16(e)) {
+			return "GREASE"
+		}
+		return fmt.Sprintf("Unknown(%d)", uint16(e))
+	}
+}
+
+// IsTLSGREASE reports whether v is one of the reserved GREASE values of
+// RFC 8701, which clients sprinkle into cipher suites, extensions,
+// groups and versions to keep servers tolerant of unknown values.
+func IsTLSGREASE(v uint16) bool {
+	return v&0x0f0f == 0x0a0a && v>>8 == v&0xff
+}
+
+// TLSHelloExtension is a single extension carried by a ClientHello or
+// ServerHello, in the order it appeared on the wire.
+type TLSHelloExtension struct {
+	Type TLSExtension
+	Data []byte
+}
+
+// TLSKeyShareEntry is a KeyShareEntry of the TLS 1.3 key_share
+// extension.
+type TLSKeyShareEntry struct {
+	Group       uint16
+	KeyExchange []byte
+}
+
+// decodeTLSExtensionList splits an extensions block into its entries.
+func decodeTLSExtensionList(data []byte) ([]TLSHelloExtension, error) {
+	var exts []TLSHelloExtension
+	c := tlsCursor{data: data}
+	for !c.empty() {
+		typ := c.u16()
+		body := c.vec16()
+		if c.err != nil {
+			return exts, fmt.Errorf("TLS extension list: %v", c.err)
+		}
+		exts = append(exts, TLSHelloExtension{Type: TLSExtension(typ), Data: body})
+	}
+	return exts, nil
+}
+
+// decodeTLSServerName returns the DNS host name of a server_name
+// extension (RFC 6066, section 3).
+func decodeTLSServerName(data []byte) ([]byte, error) {
+	c := tlsCursor{data: data}
+	list := tlsCursor{data: c.vec16()}
+	for c.err == nil && !list.empty() {
+		nameType := list.u8()
+		name := list.vec16()
+		if list.err != nil {
+			return nil, list.err
+		}
+		if nameType == 0 { // 0 = DNS hostname
+			return name, nil
+		}
+	}
+	return nil, c.err
+}
+
+// decodeTLSALPN returns the protocol names of an
+// application_layer_protocol_negotiation extension (RFC 7301).
+func decodeTLSALPN(data []byte) ([]string, error) {
+	c := tlsCursor{data: data}
+	list := tlsCursor{data: c.vec16()}
+	if c.err != nil {
+		return nil, c.err
+	}
+	var protos []string
+	for !list.empty() {
+		p := list.vec8()
+		if list.err != nil {
+			return nil, list.err
+		}
+		protos = append(protos, string(p))
+	}
+	return protos, nil
+}
+
+// decodeTLSKeyShareEntries decodes a list of KeyShareEntry structures.
+func decodeTLSKeyShareEntries(data []byte) ([]TLSKeyShareEntry, error) {
+	var shares []TLSKeyShareEntry
+	c := tlsCursor{data: data}
+	for !c.empty() {
+		group := c.u16()
+		key := c.vec16()
+		if c.err != nil {
+			return nil, c.err
+		}
+		shares = append(shares, TLSKeyShareEntry{Group: group, KeyExchange: key})
+	}
+	return shares, nil
+}
+
+// decodeExtensions fills the typed fields of a ClientHello from its
+// extension list.
+func (t *TLSHandshakeRecordClientHello) decodeExtensions() error {
+	var err error
+	for _, ext := range t.ExtensionList {
+		c := tlsCursor{data: ext.Data}
+		switch ext.Type {
+		case TLSExtServerName:
+			t.SNI, err = decodeTLSServerName(ext.Data)
+		case TLSExtALPN:
+			t.ALPN, err = decodeTLSALPN(ext.Data)
+		case TLSExtSupportedGroups:
+			t.SupportedGroups, err = tlsUint16List(c.vec16())
+		case TLSExtECPointFormats:
+			t.ECPointFormats = c.vec8()
+		case TLSExtSignatureAlgs:
+			t.SignatureAlgorithms, err = tlsUint16List(c.vec16())
+		case TLSExtSupportedVersions:
+			var versions []uint16
+			versions, err = tlsUint16List(c.vec8())
+			t.SupportedVersions = t.SupportedVersions[:0]
+			for _, v := range versions {
+				t.SupportedVersions = append(t.SupportedVersions, TLSVersion(v))
+			}
+		case TLSExtKeyShare:
+			t.KeyShares, err = decodeTLSKeyShareEntries(c.vec16())
+		case TLSExtPSKKeyExchangeModes:
+			t.PSKKeyExchangeModes = c.vec8()
+		}
+		if err == nil {
+			err = c.err
+		}
+		if err != nil {
+			return fmt.Errorf("TLS ClientHello %v extension: %v", ext.Type, err)
+		}
+	}
+	return nil
+}
+
+// decodeExtensions fills the typed fields of a ServerHello from its
+// extension list.
+func (t *TLSHandshakeRecordServerHello) decodeExtensions() error {
+	var err error
+	for _, ext := range t.ExtensionList {
+		c := tlsCursor{data: ext.Data}
+		switch ext.Type {
+		case TLSExtALPN:
+			var protos []string
+			protos, err = decodeTLSALPN(ext.Data)
+			if len(protos) > 0 {
+				t.ALPN = protos[0]
+			}
+		case TLSExtSupportedVersions:
+			t.SupportedVersion = TLSVersion(c.u16())
+		case TLSExtKeyShare:
+			t.KeyShare.Group = c.u16()
+			if !t.HelloRetryRequest {
+				// a HelloRetryRequest only carries the selected group
+				t.KeyShare.KeyExchange = c.vec16()
+			}
+		}
+		if err == nil {
+			err = c.err
+		}
+		if err != nil {
+			return fmt.Errorf("TLS ServerHello %v extension: %v", ext.Type, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package layers
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TLS client and server fingerprints, as described in
+//
+//	https://github.com/salesforce/ja3
+//	https://github.com/FoxIO-LLC/ja4
+//
+// GREASE values (RFC 8701) are ignored everywhere.
+
+// joinDecimal joins the non-GREASE values with '-', as JA3 does.
+func joinDecimal(values []uint16) string {
+	var b strings.Builder
+	for _, v := range values {
+		if IsTLSGREASE(v) {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte('-')
+		}
+		b.WriteString(strconv.Itoa(int(v)))
+	}
+	return b.String()
+}
+
+func extensionTypes(exts []TLSHelloExtension) []uint16 {
+	types := make([]uint16, 0, len(exts))
+	for _, e := range exts {
+		types = append(types, uint16(e.Type))
+	}
+	return types
+}
+
+// JA3String returns the JA3 fingerprint string of the ClientHello:
+// SSLVersion,Ciphers,Extensions,EllipticCurves,EllipticCurvePointFormats.
+func (t *TLSHandshakeRecordClientHello) JA3String() string {
+	formats := make([]uint16, len(t.ECPointFormats))
+	for i, f := range t.ECPointFormats {
+		formats[i] = uint16(f)
+	}
+	return fmt.Sprintf("%d,%s,%s,%s,%s", uint16(t.ProtocolVersion),
+		joinDecimal(t.CipherSuiteList()),
+		joinDecimal(extensionTypes(t.ExtensionList)),
+		joinDecimal(t.SupportedGroups),
+		joinDecimal(formats))
+}
+
+// JA3 returns the JA3 fingerprint of the ClientHello, the MD5 hash of
+// JA3String in hexadecimal.
+func (t *TLSHandshakeRecordClientHello) JA3() string {
+	sum := md5.Sum([]byte(t.JA3String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// JA3SString returns the JA3S fingerprint string of the ServerHello:
+// SSLVersion,Cipher,Extensions.
+func (t *TLSHandshakeRecordServerHello) JA3SString() string {
+	return fmt.Sprintf("%d,%d,%s", uint16(t.ProtocolVersion), t.CipherSuite,
+		joinDecimal(extensionTypes(t.ExtensionList)))
+}
+
+// JA3S returns the JA3S fingerprint of the ServerHello, the MD5 hash of
+// JA3SString in hexadecimal.
+func (t *TLSHandshakeRecordServerHello) JA3S() string {
+	sum := md5.Sum([]byte(t.JA3SString()))
+	return hex.EncodeToString(sum[:])
+}
+
+// ja4Version returns the two character JA4 version code.
+func ja4Version(v TLSVersion) string {
+	switch v {
+	case 0x0304:
+		return "13"
+	case 0x0303:
+		return "12"
+	case 0x0302:
+		return "11"
+	case 0x0301:
+		return "10"
+	case 0x0300:
+		return "s3"
+	case 0x0200:
+		return "s2"
+	case 0xfeff:
+		return "d1"
+	case 0xfefd:
+		return "d2"
+	case 0xfefc:
+		return "d3"
+	}
+	return "00"
+}
+
+func isAlnum(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'
+}
+
+// ja4ALPN returns the first and last characters of the first ALPN value.
+func ja4ALPN(alpn []string) string {
+	if len(alpn) == 0 || len(alpn[0]) == 0 {
+		return "00"
+	}
+	p := alpn[0]
+	if !isAlnum(p[0]) || !isAlnum(p[len(p)-1]) {
+		h := hex.EncodeToString([]byte(p))
+		return h[:1] + h[len(h)-1:]
+	}
+	return p[:1] + p[len(p)-1:]
+}
+
+// ja4Hex returns the sorted or original-order list of non-GREASE values
+// as 4 character hexadecimal strings.
+func ja4Hex(values []uint16, sorted bool, skip ...uint16) []string {
+	out := make([]string, 0, len(values))
+outer:
+	for _, v := range values {
+		if IsTLSGREASE(v) {
+			continue
+		}
+		for _, s := range skip {
+			if v == s {
+				continue outer
+			}
+		}
+		out = append(out, fmt.Sprintf("%04x", v))
+	}
+	if sorted {
+		sort.Strings(out)
+	}
+	return out
+}
+
+func ja4Hash(s string) string {
+	if s == "" {
+		return "000000000000"
+	}
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+func ja4Count(n int) string {
+	if n > 99 {
+		n = 99
+	}
+	return fmt.Sprintf("%02d", n)
+}
+
+// ja4 computes the JA4 fingerprint, with the given transport character:
+// 't' for TCP, 'q' for QUIC or 'd' for DTLS. When raw is set, the
+// cipher and extension lists are returned unhashed (JA4_r).
+func (t *TLSHandshakeRecordClientHello) ja4(transport byte, raw bool) string {
+	version := t.ProtocolVersion
+	var highest TLSVersion
+	for _, v := range t.SupportedVersions {
+		if !IsTLSGREASE(uint16(v)) && v > highest {
+			highest = v
+		}
+	}
+	if highest != 0 {
+		version = highest
+	}
+	sni := byte('i')
+	if len(t.SNI) > 0 {
+		sni = 'd'
+	}
+	exts := extensionTypes(t.ExtensionList)
+	ciphers := ja4Hex(t.CipherSuiteList(), true)
+	a := fmt.Sprintf("%c%s%c%s%s%s", transport, ja4Version(version), sni,
+		ja4Count(len(ciphers)), ja4Count(len(ja4Hex(exts, false))), ja4ALPN(t.ALPN))
+
+	b := strings.Join(ciphers, ",")
+	c := strings.Join(ja4Hex(exts, true, uint16(TLSExtServerName), uint16(TLSExtALPN)), ",")
+	if len(t.SignatureAlgorithms) > 0 {
+		c += "_" + strings.Join(ja4Hex(t.SignatureAlgorithms, false), ",")
+	}
+	if raw {
+		return a + "_" + b + "_" + c
+	}
+	return a + "_" + ja4Hash(b) + "_" + ja4Hash(c)
+}
+
+// JA4 returns the JA4 fingerprint of a ClientHello sent over TCP.
+func (t *TLSHandshakeRecordClientHello) JA4() string { return t.ja4('t', false) }
+
+// JA4Raw returns the unhashed JA4 fingerprint (JA4_r) of a ClientHello
+// sent over TCP.
+func (t *TLSHandshakeRecordClientHello) JA4Raw() string { return t.ja4('t', true) }
+
+// JA4QUIC returns the JA4 fingerprint of a ClientHello carried in QUIC
+// CRYPTO frames.
+func (t *TLSHandshakeRecordClientHello) JA4QUIC() string { return t.ja4('q', false) }
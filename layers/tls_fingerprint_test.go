@@ -0,0 +1,122 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package layers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gopacket/gopacket"
+)
+
+func decodeTestClientHello(t *testing.T, msg []byte) *TLSHandshakeRecordClientHello {
+	var tls TLS
+	if err := tls.DecodeFromBytes(tlsRecord(msg), gopacket.NilDecodeFeedback); err != nil {
+		t.Fatal(err)
+	}
+	return &tls.Handshake[0].ClientHello
+}
+
+func TestJA3(t *testing.T) {
+	// Reference fingerprint from https://github.com/salesforce/ja3
+	suites := []byte{0x00, 0x2f, 0x00, 0x35, 0x00, 0x05, 0x00, 0x0a, 0xc0, 0x09, 0xc0, 0x0a,
+		0xc0, 0x13, 0xc0, 0x14, 0x00, 0x32, 0x00, 0x38, 0x00, 0x13, 0x00, 0x04}
+	msg := append([]byte{TLSHandshakeClientHello}, tlsVec(3,
+		[]byte{0x03, 0x01}, make([]byte, 32), tlsVec(1),
+		tlsVec(2, suites),
+		tlsVec(1, []byte{0}),
+		tlsVec(2,
+			tlsExt(TLSExtServerName, tlsVec(2, []byte{0}, tlsVec(2, []byte("example.com")))),
+			tlsExt(TLSExtSupportedGroups, tlsVec(2, []byte{0x00, 0x17, 0x00, 0x18, 0x00, 0x19})),
+			tlsExt(TLSExtECPointFormats, tlsVec(1, []byte{0})),
+		),
+	)...)
+	ch := decodeTestClientHello(t, msg)
+
+	want := "769,47-53-5-10-49161-49162-49171-49172-50-56-19-4,0-10-11,23-24-25,0"
+	if got := ch.JA3String(); got != want {
+		t.Errorf("JA3 string:\ngot:  %s\nwant: %s", got, want)
+	}
+	if got := ch.JA3(); got != "ada70206e40642a3e4461f35503241d5" {
+		t.Errorf("JA3 hash: got %s", got)
+	}
+}
+
+func TestJA3S(t *testing.T) {
+	p := gopacket.NewPacket(testServerHello, LayerTypeTLS, testTLSDecodeOptions)
+	tls := p.Layer(LayerTypeTLS).(*TLS)
+	sh := tls.Handshake[0].ServerHello
+
+	if got := sh.JA3SString(); got != "769,47,65281-35-15" {
+		t.Errorf("JA3S string: got %s", got)
+	}
+	if got := sh.JA3S(); got != "d34cdf3ab2ca82a6542791bde391a97e" {
+		t.Errorf("JA3S hash: got %s", got)
+	}
+}
+
+// chromeLikeClientHello returns a ClientHello whose JA4 fingerprint is
+// the Chrome example of the JA4 technical details.
+func chromeLikeClientHello() []byte {
+	suites := []byte{0x0a, 0x0a, 0x13, 0x01, 0x13, 0x02, 0x13, 0x03, 0xc0, 0x2b, 0xc0, 0x2f,
+		0xc0, 0x2c, 0xc0, 0x30, 0xcc, 0xa9, 0xcc, 0xa8, 0xc0, 0x13, 0xc0, 0x14, 0x00, 0x9c,
+		0x00, 0x9d, 0x00, 0x2f, 0x00, 0x35}
+	sigAlgs := []byte{0x04, 0x03, 0x08, 0x04, 0x04, 0x01, 0x05, 0x03, 0x08, 0x05, 0x05, 0x01,
+		0x08, 0x06, 0x06, 0x01}
+	return append([]byte{TLSHandshakeClientHello}, tlsVec(3,
+		[]byte{0x03, 0x03}, make([]byte, 32), tlsVec(1, make([]byte, 32)),
+		tlsVec(2, suites),
+		tlsVec(1, []byte{0}),
+		tlsVec(2,
+			tlsExt(0x2a2a),
+			tlsExt(TLSExtServerName, tlsVec(2, []byte{0}, tlsVec(2, []byte("example.com")))),
+			tlsExt(TLSExtExtendedMasterSecret),
+			tlsExt(TLSExtRenegotiationInfo, []byte{0}),
+			tlsExt(TLSExtSupportedGroups, tlsVec(2, []byte{0x3a, 0x3a, 0x00, 0x1d, 0x00, 0x17, 0x00, 0x18})),
+			tlsExt(TLSExtECPointFormats, tlsVec(1, []byte{0})),
+			tlsExt(TLSExtSessionTicket),
+			tlsExt(TLSExtALPN, tlsVec(2, tlsVec(1, []byte("h2")), tlsVec(1, []byte("http/1.1")))),
+			tlsExt(TLSExtStatusRequest, []byte{1, 0, 0, 0, 0}),
+			tlsExt(TLSExtSignatureAlgs, tlsVec(2, sigAlgs)),
+			tlsExt(TLSExtSignedCertTS),
+			tlsExt(TLSExtKeyShare, tlsVec(2, []byte{0x3a, 0x3a}, tlsVec(2, []byte{0}), []byte{0x00, 0x1d}, tlsVec(2, make([]byte, 32)))),
+			tlsExt(TLSExtPSKKeyExchangeModes, tlsVec(1, []byte{1})),
+			tlsExt(TLSExtSupportedVersions, tlsVec(1, []byte{0x7a, 0x7a, 0x03, 0x04, 0x03, 0x03})),
+			tlsExt(TLSExtCompressCertificate, tlsVec(1, []byte{0x00, 0x02})),
+			tlsExt(TLSExtApplicationSettings, tlsVec(2, tlsVec(1, []byte("h2")))),
+			tlsExt(TLSExtPadding, make([]byte, 16)),
+			tlsExt(0x1a1a, []byte{0}),
+		),
+	)...)
+}
+
+func TestJA4(t *testing.T) {
+	ch := decodeTestClientHello(t, chromeLikeClientHello())
+
+	if got := ch.JA4(); got != "t13d1516h2_8daaf6152771_e5627efa2ab1" {
+		t.Errorf("JA4: got %s", got)
+	}
+	if got := ch.JA4QUIC(); got != "q13d1516h2_8daaf6152771_e5627efa2ab1" {
+		t.Errorf("JA4 over QUIC: got %s", got)
+	}
+	wantRaw := "t13d1516h2_002f,0035,009c,009d,1301,1302,1303,c013,c014,c02b,c02c,c02f,c030,cca8,cca9_" +
+		"0005,000a,000b,000d,0012,0015,0017,001b,0023,002b,002d,0033,4469,ff01_0403,0804,0401,0503,0805,0501,0806,0601"
+	if got := ch.JA4Raw(); got != wantRaw {
+		t.Errorf("JA4_r:\ngot:  %s\nwant: %s", got, wantRaw)
+	}
+}
+
+func TestJA4NoALPN(t *testing.T) {
+	p := gopacket.NewPacket(testClientHello, LinkTypeEthernet, testTLSDecodeOptions)
+	tls := p.Layer(LayerTypeTLS).(*TLS)
+	ch := tls.Handshake[0].ClientHello
+
+	got := ch.JA4()
+	if want := "t10d450500_"; !strings.HasPrefix(got, want) {
+		t.Errorf("JA4: got %s, want prefix %s", got, want)
+	}
+}
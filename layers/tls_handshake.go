@@ -7,8 +7,9 @@
 package layers
 
 import (
-	"encoding/binary"
+	"bytes"
 	"errors"
+	"fmt"
 
 	"github.com/gopacket/gopacket"
 )
@@ -17,41 +18,60 @@ import (
 type TLSExtension uint16
 
 const (
-	TLSExtServerName           TLSExtension = 0
-	TLSExtMaxFragLen           TLSExtension = 1
-	TLSExtClientCertURL        TLSExtension = 2
-	TLSExtTrustedCAKeys        TLSExtension = 3
-	TLSExtTruncatedHMAC        TLSExtension = 4
-	TLSExtStatusRequest        TLSExtension = 5
-	TLSExtUserMapping          TLSExtension = 6
-	TLSExtClientAuthz          TLSExtension = 7
-	TLSExtServerAuthz          TLSExtension = 8
-	TLSExtCertType             TLSExtension = 9
-	TLSExtSupportedGroups      TLSExtension = 10
-	TLSExtECPointFormats       TLSExtension = 11
-	TLSExtSRP                  TLSExtension = 12
-	TLSExtSignatureAlgs        TLSExtension = 13
-	TLSxtUseSRTP               TLSExtension = 14
-	TLSExtHeartbeat            TLSExtension = 15
-	TLSExtALPN                 TLSExtension = 16
-	TLSExtStatusRequestV2      TLSExtension = 17
-	TLSExtSignedCertTS         TLSExtension = 18
-	TLSExtClientCertType       TLSExtension = 19
-	TLSExtServerCertType       TLSExtension = 20
-	TLSExtPadding              TLSExtension = 21
-	TLSExtEncryptThenMAC       TLSExtension = 22
-	TLSExtExtendedMasterSecret TLSExtension = 23
-	TLSExtSessionTicket        TLSExtension = 35
-	TLSExtNPN                  TLSExtension = 13172
-	TLSExtRenegotiationInfo    TLSExtension = 65281
+	TLSExtServerName             TLSExtension = 0
+	TLSExtMaxFragLen             TLSExtension = 1
+	TLSExtClientCertURL          TLSExtension = 2
+	TLSExtTrustedCAKeys          TLSExtension = 3
+	TLSExtTruncatedHMAC          TLSExtension = 4
+	TLSExtStatusRequest          TLSExtension = 5
+	TLSExtUserMapping            TLSExtension = 6
+	TLSExtClientAuthz            TLSExtension = 7
+	TLSExtServerAuthz            TLSExtension = 8
+	TLSExtCertType               TLSExtension = 9
+	TLSExtSupportedGroups        TLSExtension = 10
+	TLSExtECPointFormats         TLSExtension = 11
+	TLSExtSRP                    TLSExtension = 12
+	TLSExtSignatureAlgs          TLSExtension = 13
+	TLSxtUseSRTP                 TLSExtension = 14
+	TLSExtHeartbeat              TLSExtension = 15
+	TLSExtALPN                   TLSExtension = 16
+	TLSExtStatusRequestV2        TLSExtension = 17
+	TLSExtSignedCertTS           TLSExtension = 18
+	TLSExtClientCertType         TLSExtension = 19
+	TLSExtServerCertType         TLSExtension = 20
+	TLSExtPadding                TLSExtension = 21
+	TLSExtEncryptThenMAC         TLSExtension = 22
+	TLSExtExtendedMasterSecret   TLSExtension = 23
+	TLSExtCompressCertificate    TLSExtension = 27
+	TLSExtRecordSizeLimit        TLSExtension = 28
+	TLSExtDelegatedCredentials   TLSExtension = 34
+	TLSExtSessionTicket          TLSExtension = 35
+	TLSExtPreSharedKey           TLSExtension = 41
+	TLSExtEarlyData              TLSExtension = 42
+	TLSExtSupportedVersions      TLSExtension = 43
+	TLSExtCookie                 TLSExtension = 44
+	TLSExtPSKKeyExchangeModes    TLSExtension = 45
+	TLSExtCertificateAuthorities TLSExtension = 47
+	TLSExtOIDFilters             TLSExtension = 48
+	TLSExtPostHandshakeAuth      TLSExtension = 49
+	TLSExtSignatureAlgsCert      TLSExtension = 50
+	TLSExtKeyShare               TLSExtension = 51
+	TLSExtQUICTransportParams    TLSExtension = 57
+	TLSExtNPN                    TLSExtension = 13172
+	TLSExtApplicationSettings    TLSExtension = 17513
+	TLSExtEncryptedClientHello   TLSExtension = 65037
+	TLSExtRenegotiationInfo      TLSExtension = 65281
 )
 
-/*refer to https://datatracker.ietf.org/doc/html/rfc5246#appendix-A.4*/
+/*refer to https://datatracker.ietf.org/doc/html/rfc5246#appendix-A.4 and https://datatracker.ietf.org/doc/html/rfc8446#appendix-B.3*/
 const (
 	TLSHandshakeHelloRequest        = 0
 	TLSHandshakeClientHello         = 1
 	TLSHandshakeServerHello         = 2
 	TLSHandsharkHelloVerirfyRequest = 3
+	TLSHandshakeNewSessionTicket    = 4
+	TLSHandshakeEndOfEarlyData      = 5
+	TLSHandshakeEncryptedExtensions = 8
 	TLSHandshakeCertificate         = 11
 	TLSHandshakeServerKeyExchange   = 12
 	TLSHandshakeCertificateRequest  = 13
@@ -59,6 +79,8 @@ const (
 	TLSHandshakeCertificateVerify   = 15
 	TLSHandshakeClientKeyExchange   = 16
 	TLSHandshakeFinished            = 20
+	TLSHandshakeCertificateStatus   = 22
+	TLSHandshakeKeyUpdate           = 24
 )
 
 var handShakeTypeMap = map[uint8]string{
@@ -66,6 +88,9 @@ var handShakeTypeMap = map[uint8]string{
 	TLSHandshakeClientHello:         "Client Hello",
 	TLSHandshakeServerHello:         "Server Hello",
 	TLSHandsharkHelloVerirfyRequest: "Hello Verify Request",
+	TLSHandshakeNewSessionTicket:    "New Session Ticket",
+	TLSHandshakeEndOfEarlyData:      "End Of Early Data",
+	TLSHandshakeEncryptedExtensions: "Encrypted Extensions",
 	TLSHandshakeCertificate:         "Certificate",
 	TLSHandshakeServerKeyExchange:   "Server Key Exchange",
 	TLSHandshakeCertificateRequest:  "Certificate Request",
@@ -73,8 +98,12 @@ var handShakeTypeMap = map[uint8]string{
 	TLSHandshakeCertificateVerify:   "Certificate Verify",
 	TLSHandshakeClientKeyExchange:   "Client Key Exchange",
 	TLSHandshakeFinished:            "Finished",
+	TLSHandshakeCertificateStatus:   "Certificate Status",
+	TLSHandshakeKeyUpdate:           "Key Update",
 }
 
+// TLSHandshakeRecordClientHello is a decoded ClientHello message
+// (RFC 5246, section 7.4.1.2 and RFC 8446, section 4.1.2).
 type TLSHandshakeRecordClientHello struct {
 	HandshakeType            uint8
 	Length                   uint32
@@ -89,67 +118,326 @@ type TLSHandshakeRecordClientHello struct {
 	ExtensionsLength         uint16
 	Extensions               []uint8
 	SNI                      []uint8
+
+	// ExtensionList holds every extension in wire order.
+	ExtensionList []TLSHelloExtension
+	// The following are decoded from their respective extensions, if
+	// present.
+	ALPN                []string
+	SupportedGroups     []uint16
+	ECPointFormats      []uint8
+	SignatureAlgorithms []uint16
+	SupportedVersions   []TLSVersion
+	KeyShares           []TLSKeyShareEntry
+	PSKKeyExchangeModes []uint8
+}
+
+// CipherSuiteList returns the offered cipher suites as 16-bit values.
+func (t *TLSHandshakeRecordClientHello) CipherSuiteList() []uint16 {
+	suites, _ := tlsUint16List(t.CipherSuits)
+	return suites
+}
+
+// tlsHelloRetryRequestRandom is the special ServerHello.random value
+// identifying a HelloRetryRequest, RFC 8446 section 4.1.3.
+var tlsHelloRetryRequestRandom = []byte{
+	0xcf, 0x21, 0xad, 0x74, 0xe5, 0x9a, 0x61, 0x11, 0xbe, 0x1d, 0x8c, 0x02, 0x1e, 0x65, 0xb8, 0x91,
+	0xc2, 0xa2, 0x11, 0x16, 0x7a, 0xbb, 0x8c, 0x5e, 0x07, 0x9e, 0x09, 0xe2, 0xc8, 0xa8, 0x33, 0x9c,
+}
+
+// TLSHandshakeRecordServerHello is a decoded ServerHello message
+// (RFC 5246, section 7.4.1.3 and RFC 8446, section 4.1.3).
+type TLSHandshakeRecordServerHello struct {
+	HandshakeType     uint8
+	Length            uint32
+	ProtocolVersion   TLSVersion
+	Random            []uint8
+	SessionIDLength   uint8
+	SessionID         []uint8
+	CipherSuite       uint16
+	CompressionMethod uint8
+	ExtensionsLength  uint16
+	Extensions        []uint8
+
+	// ExtensionList holds every extension in wire order.
+	ExtensionList []TLSHelloExtension
+	// HelloRetryRequest is set when Random carries the special
+	// HelloRetryRequest value of TLS 1.3.
+	HelloRetryRequest bool
+	// SupportedVersion is the version selected through the
+	// supported_versions extension, TLS 1.3 and later only.
+	SupportedVersion TLSVersion
+	// ALPN is the application protocol selected by the server.
+	ALPN string
+	// KeyShare is the server share of a TLS 1.3 ServerHello. For a
+	// HelloRetryRequest only Group is set.
+	KeyShare TLSKeyShareEntry
+}
+
+// NegotiatedVersion returns the protocol version selected by the
+// server, taking the TLS 1.3 supported_versions extension into account.
+func (t *TLSHandshakeRecordServerHello) NegotiatedVersion() TLSVersion {
+	if t.SupportedVersion != 0 {
+		return t.SupportedVersion
+	}
+	return t.ProtocolVersion
+}
+
+// TLSHandshakeRecordCertificate is a decoded Certificate message. Both
+// the TLS 1.2 (RFC 5246, section 7.4.2) and TLS 1.3 (RFC 8446, section
+// 4.4.2) layouts are understood.
+type TLSHandshakeRecordCertificate struct {
+	HandshakeType uint8
+	Length        uint32
+	// RequestContext is only present in TLS 1.3.
+	RequestContext []byte
+	// Certificates holds the raw ASN.1 DER certificates, sender's
+	// certificate first, ready for crypto/x509.ParseCertificate.
+	Certificates [][]byte
+}
+
+// TLSHandshakeRecordServerKeyExchange is a decoded ServerKeyExchange
+// message (RFC 5246, section 7.4.3). Its layout depends on the key
+// exchange of the negotiated cipher suite; ECDHE parameters with a named
+// curve (RFC 8422, section 5.4) are decoded into the typed fields, any
+// other exchange is only available through Params.
+type TLSHandshakeRecordServerKeyExchange struct {
+	HandshakeType uint8
+	Length        uint32
+	// Params holds the whole message body.
+	Params             []byte
+	CurveType          uint8
+	NamedGroup         uint16
+	PublicKey          []byte
+	SignatureAlgorithm uint16
+	Signature          []byte
+}
+
+// TLSHandshakeRecordNewSessionTicket is a decoded NewSessionTicket
+// message, in either its TLS 1.2 (RFC 5077) or TLS 1.3 (RFC 8446,
+// section 4.6.1) form. AgeAdd, Nonce and Extensions are only present in
+// TLS 1.3.
+type TLSHandshakeRecordNewSessionTicket struct {
+	HandshakeType uint8
+	Length        uint32
+	LifetimeHint  uint32
+	AgeAdd        uint32
+	Nonce         []byte
+	Ticket        []byte
+	Extensions    []byte
 }
 
+// TLSHandshakeRecordClientKeyChange is a decoded ClientKeyExchange
+// message (RFC 5246, section 7.4.7). ExchangeKeys holds either the RSA
+// encrypted premaster secret, the DH public value or the ECDH public
+// point, without its length prefix.
 type TLSHandshakeRecordClientKeyChange struct {
+	HandshakeType uint8
+	Length        uint32
+	ExchangeKeys  []byte
 }
 
-// TLSHandshakeRecord defines the structure of a Handshare Record
+// TLSHandshakeRecord defines the structure of a Handshare Record. A
+// single record may carry several handshake messages; each decoded
+// message fills its own field, with a non-zero HandshakeType.
 type TLSHandshakeRecord struct {
 	TLSRecordHeader
-	ClientHello     TLSHandshakeRecordClientHello
-	ClientKeyChange TLSHandshakeRecordClientKeyChange
+	ClientHello       TLSHandshakeRecordClientHello
+	ServerHello       TLSHandshakeRecordServerHello
+	Certificate       TLSHandshakeRecordCertificate
+	ServerKeyExchange TLSHandshakeRecordServerKeyExchange
+	NewSessionTicket  TLSHandshakeRecordNewSessionTicket
+	ClientKeyChange   TLSHandshakeRecordClientKeyChange
+	// Fragment holds the start of the last handshake message of the
+	// record when it continues in the next record (RFC 5246, section
+	// 6.2.1), e.g. a Certificate following the ServerHello. The messages
+	// before it are decoded.
+	Fragment []byte
+}
+
+// decodeTLSHandshakeHeader reads the type and length of a handshake
+// message and returns its body.
+func decodeTLSHandshakeHeader(data []byte) (uint8, uint32, tlsCursor, error) {
+	c := tlsCursor{data: data}
+	typ := c.u8()
+	length := c.u24()
+	body := c.bytes(int(length))
+	return typ, length, tlsCursor{data: body}, c.err
 }
 
 func (t *TLSHandshakeRecordClientHello) decodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
-	t.HandshakeType = data[0]
-	d := make([]byte, 4)
-	for k, v := range data[1:4] {
-		d[k+1] = v
-	}
-	t.Length = binary.BigEndian.Uint32(d)
-	t.ProtocolVersion = TLSVersion(binary.BigEndian.Uint16(data[4:6]))
-	t.Random = data[6:38]
-	t.SessionIDLength = data[38]
-	t.SessionID = data[39 : 39+t.SessionIDLength]
-	t.CipherSuitsLength = binary.BigEndian.Uint16(data[39+t.SessionIDLength : 39+t.SessionIDLength+2])
-	t.CipherSuits = data[39+t.SessionIDLength+2 : (39 + uint16(t.SessionIDLength) + 2 + t.CipherSuitsLength)]
-	t.CompressionMethodsLength = data[(39 + uint16(t.SessionIDLength) + 2 + t.CipherSuitsLength)]
-	t.CompressionMethods = data[(39+uint16(t.SessionIDLength)+2+t.CipherSuitsLength)+1 : (39+uint16(t.SessionIDLength)+2+t.CipherSuitsLength)+1+uint16(t.CompressionMethodsLength)]
-	t.ExtensionsLength = binary.BigEndian.Uint16(data[(39+uint16(t.SessionIDLength)+2+t.CipherSuitsLength)+1+uint16(t.CompressionMethodsLength) : (39+uint16(t.SessionIDLength)+2+t.CipherSuitsLength)+1+uint16(t.CompressionMethodsLength)+2])
-
-	// extract extension data
-	data = data[((39 + uint16(t.SessionIDLength) + 2 + t.CipherSuitsLength) + 1 + uint16(t.CompressionMethodsLength) + 2) : ((39+uint16(t.SessionIDLength)+2+t.CipherSuitsLength)+1+uint16(t.CompressionMethodsLength)+2)+t.ExtensionsLength]
-	t.Extensions = data
-	for len(data) > 0 {
-		if len(data) < 4 {
-			break
+	var c tlsCursor
+	var err error
+	t.HandshakeType, t.Length, c, err = decodeTLSHandshakeHeader(data)
+	if err != nil {
+		return err
+	}
+	t.ProtocolVersion = TLSVersion(c.u16())
+	t.Random = c.bytes(32)
+	t.SessionIDLength = c.u8()
+	t.SessionID = c.bytes(int(t.SessionIDLength))
+	t.CipherSuitsLength = c.u16()
+	t.CipherSuits = c.bytes(int(t.CipherSuitsLength))
+	t.CompressionMethodsLength = c.u8()
+	t.CompressionMethods = c.bytes(int(t.CompressionMethodsLength))
+	if c.err != nil {
+		return fmt.Errorf("TLS ClientHello: %v", c.err)
+	}
+	// extensions are optional in TLS 1.2 and earlier
+	if c.empty() {
+		return nil
+	}
+	t.ExtensionsLength = c.u16()
+	t.Extensions = c.bytes(int(t.ExtensionsLength))
+	if c.err != nil {
+		return fmt.Errorf("TLS ClientHello: %v", c.err)
+	}
+	if t.ExtensionList, err = decodeTLSExtensionList(t.Extensions); err != nil {
+		return err
+	}
+	return t.decodeExtensions()
+}
+
+func (t *TLSHandshakeRecordServerHello) decodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	var c tlsCursor
+	var err error
+	t.HandshakeType, t.Length, c, err = decodeTLSHandshakeHeader(data)
+	if err != nil {
+		return err
+	}
+	t.ProtocolVersion = TLSVersion(c.u16())
+	t.Random = c.bytes(32)
+	t.SessionIDLength = c.u8()
+	t.SessionID = c.bytes(int(t.SessionIDLength))
+	t.CipherSuite = c.u16()
+	t.CompressionMethod = c.u8()
+	if c.err != nil {
+		return fmt.Errorf("TLS ServerHello: %v", c.err)
+	}
+	t.HelloRetryRequest = bytes.Equal(t.Random, tlsHelloRetryRequestRandom)
+	if c.empty() {
+		return nil
+	}
+	t.ExtensionsLength = c.u16()
+	t.Extensions = c.bytes(int(t.ExtensionsLength))
+	if c.err != nil {
+		return fmt.Errorf("TLS ServerHello: %v", c.err)
+	}
+	if t.ExtensionList, err = decodeTLSExtensionList(t.Extensions); err != nil {
+		return err
+	}
+	return t.decodeExtensions()
+}
+
+func (t *TLSHandshakeRecordCertificate) decodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	var c tlsCursor
+	var err error
+	t.HandshakeType, t.Length, c, err = decodeTLSHandshakeHeader(data)
+	if err != nil {
+		return err
+	}
+	// TLS 1.2 starts straight with the 24-bit certificate list length,
+	// TLS 1.3 prefixes it with the request context.
+	tls13 := len(c.data) < 3 || int(c.data[0])<<16|int(c.data[1])<<8|int(c.data[2]) != len(c.data)-3
+	if tls13 {
+		t.RequestContext = c.vec8()
+	}
+	list := tlsCursor{data: c.vec24()}
+	if c.err != nil {
+		return fmt.Errorf("TLS Certificate: %v", c.err)
+	}
+	for !list.empty() {
+		cert := list.vec24()
+		if tls13 {
+			// per-certificate extensions (OCSP status, SCTs) are skipped
+			list.vec16()
 		}
-		extensionType := binary.BigEndian.Uint16(data[:2])
-		length := binary.BigEndian.Uint16(data[2:4])
-		if len(data) < 4+int(length) {
-			break
+		if list.err != nil {
+			return fmt.Errorf("TLS Certificate: %v", list.err)
 		}
-		switch TLSExtension(extensionType) {
-		case TLSExtServerName:
-			if len(data) > 6 {
-				serverNameExtensionLength := binary.BigEndian.Uint16(data[4:6])
-				entryType := data[6]
-				if serverNameExtensionLength > 0 && entryType == 0 && len(data) > 8 { // 0 = DNS hostname
-					hostnameLength := binary.BigEndian.Uint16(data[7:9])
-					if len(data) > int(8+hostnameLength) {
-						t.SNI = data[9 : 9+hostnameLength]
-					}
-				}
-			}
+		t.Certificates = append(t.Certificates, cert)
+	}
+	return nil
+}
+
+func (t *TLSHandshakeRecordServerKeyExchange) decodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	var c tlsCursor
+	var err error
+	t.HandshakeType, t.Length, c, err = decodeTLSHandshakeHeader(data)
+	if err != nil {
+		return err
+	}
+	t.Params = c.data
+	// 3 = named_curve, the only curve type allowed by RFC 8422
+	if len(c.data) == 0 || c.data[0] != 3 {
+		return nil
+	}
+	// A DHE prime of 768 to 1023 bytes also starts with 3, so the
+	// parameters are only taken as ECDHE ones if they parse exactly.
+	p := c
+	curveType := p.u8()
+	group := p.u16()
+	pub := p.vec8()
+	var sigAlg uint16
+	var sig []byte
+	// anonymous key exchanges have no signature
+	if !p.empty() {
+		// TLS 1.2 prefixes the signature with its algorithm, earlier
+		// versions do not.
+		if len(p.data) >= 4 && int(p.data[2])<<8|int(p.data[3]) == len(p.data)-4 {
+			sigAlg = p.u16()
 		}
-		data = data[4+length:]
+		sig = p.vec16()
+	}
+	if p.err != nil || !p.empty() {
+		// not ECDHE after all, keep the raw parameters only
+		return nil
 	}
+	t.CurveType, t.NamedGroup, t.PublicKey = curveType, group, pub
+	t.SignatureAlgorithm, t.Signature = sigAlg, sig
+	return nil
+}
 
+func (t *TLSHandshakeRecordNewSessionTicket) decodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	var c tlsCursor
+	var err error
+	t.HandshakeType, t.Length, c, err = decodeTLSHandshakeHeader(data)
+	if err != nil {
+		return err
+	}
+	t.LifetimeHint = c.u32()
+	// the TLS 1.2 form is just the lifetime hint and the ticket
+	if len(c.data) >= 2 && int(c.data[0])<<8|int(c.data[1]) == len(c.data)-2 {
+		t.Ticket = c.vec16()
+	} else {
+		t.AgeAdd = c.u32()
+		t.Nonce = c.vec8()
+		t.Ticket = c.vec16()
+		t.Extensions = c.vec16()
+	}
+	if c.err != nil {
+		return fmt.Errorf("TLS NewSessionTicket: %v", c.err)
+	}
 	return nil
 }
+
 func (t *TLSHandshakeRecordClientKeyChange) decodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
-	/*TBD*/
+	var c tlsCursor
+	var err error
+	t.HandshakeType, t.Length, c, err = decodeTLSHandshakeHeader(data)
+	if err != nil {
+		return err
+	}
+	// RSA and DHE use a 16-bit length, ECDHE an 8-bit one. SSL 3.0
+	// RSA has no length at all.
+	switch {
+	case len(c.data) >= 2 && int(c.data[0])<<8|int(c.data[1]) == len(c.data)-2:
+		t.ExchangeKeys = c.vec16()
+	case len(c.data) >= 1 && int(c.data[0]) == len(c.data)-1:
+		t.ExchangeKeys = c.vec8()
+	default:
+		t.ExchangeKeys = c.data
+	}
 	return nil
 }
 
@@ -169,18 +457,19 @@ func (t TLSHandshakeRecord) isEncryptedHandshakeMessage(h TLSRecordHeader, data
 		 */
 		return false
 	}
-	maybeType := data[0]
-	d := make([]byte, 4)
-	for k, v := range data[1:4] {
-		d[k+1] = v
-	}
-	if uint32(h.Length)-binary.BigEndian.Uint32(d) != 4 {
+	/*
+	 * Plaintext records start with the header of a handshake message of
+	 * a known type, fitting in the record. The messages following it may
+	 * continue in the next record, see TLSHandshakeRecord.Fragment.
+	 */
+	if len(data) < 4 {
 		return true
 	}
-	if _, ok := handShakeTypeMap[maybeType]; !ok {
+	if _, ok := handShakeTypeMap[data[0]]; !ok {
 		return true
 	}
-	return false
+	l := int(data[1])<<16 | int(data[2])<<8 | int(data[3])
+	return len(data) < 4+l
 }
 
 // DecodeFromBytes decodes the slice into the TLS struct.
@@ -193,15 +482,45 @@ func (t *TLSHandshakeRecord) decodeFromBytes(h TLSRecordHeader, data []byte, df
 	if t.isEncryptedHandshakeMessage(h, data) {
 		return nil
 	}
-	handshakeType := data[0]
-	switch handshakeType {
-	case TLSHandshakeClientHello:
-		t.ClientHello.decodeFromBytes(data, df)
-	case TLSHandshakeClientKeyExchange:
-		t.ClientKeyChange.decodeFromBytes(data, df)
-	default:
-		return errors.New("Unknown TLS handshake type")
-		// TODO
+	for len(data) > 0 {
+		l := 4
+		if len(data) >= 4 {
+			l += int(data[1])<<16 | int(data[2])<<8 | int(data[3])
+		}
+		if len(data) < l {
+			// the message continues in the next record
+			t.Fragment = data
+			break
+		}
+		msg := data[:l]
+		data = data[l:]
+
+		var err error
+		switch msg[0] {
+		case TLSHandshakeClientHello:
+			err = t.ClientHello.decodeFromBytes(msg, df)
+		case TLSHandshakeServerHello:
+			err = t.ServerHello.decodeFromBytes(msg, df)
+		case TLSHandshakeCertificate:
+			err = t.Certificate.decodeFromBytes(msg, df)
+		case TLSHandshakeServerKeyExchange:
+			err = t.ServerKeyExchange.decodeFromBytes(msg, df)
+		case TLSHandshakeNewSessionTicket:
+			err = t.NewSessionTicket.decodeFromBytes(msg, df)
+		case TLSHandshakeClientKeyExchange:
+			err = t.ClientKeyChange.decodeFromBytes(msg, df)
+		case TLSHandshakeHelloRequest, TLSHandsharkHelloVerirfyRequest,
+			TLSHandshakeCertificateRequest, TLSHandshakeServerHelloDone,
+			TLSHandshakeCertificateVerify, TLSHandshakeFinished,
+			TLSHandshakeEndOfEarlyData, TLSHandshakeEncryptedExtensions,
+			TLSHandshakeCertificateStatus, TLSHandshakeKeyUpdate:
+			// known, but not dissected
+		default:
+			return errors.New("Unknown TLS handshake type")
+		}
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -5,6 +5,8 @@
 package layers
 
 import (
+	"bytes"
+	"crypto/x509"
 	"reflect"
 	"testing"
 
@@ -17,9 +19,9 @@ import (
 // Packet 4 - Client Hello (full packet, from Ethernet to TLS layers)
 var testClientHello = []byte{
 	0x00, 0x0c, 0x29, 0x1f, 0xab, 0x17, 0x00, 0x50, 0x56, 0xc0, 0x00, 0x08, 0x08, 0x00, 0x45, 0x00,
-	0x00, 0xfe, 0x71, 0x42, 0x40, 0x00, 0x80, 0x06, 0x4e, 0xe1, 0xc0, 0xa8, 0xdc, 0x01, 0xc0, 0xa8,
+	0x01, 0x15, 0x71, 0x42, 0x40, 0x00, 0x80, 0x06, 0x4e, 0xca, 0xc0, 0xa8, 0xdc, 0x01, 0xc0, 0xa8,
 	0xdc, 0x83, 0x2f, 0x0e, 0x01, 0xbb, 0x25, 0x6c, 0xbd, 0x3d, 0xcc, 0xce, 0xe1, 0xf7, 0x50, 0x18,
-	0xff, 0xff, 0x7c, 0xaf, 0x00, 0x00, 0x16, 0x03, 0x01, 0x00, 0xd1, 0x01, 0x00, 0x00, 0xcd, 0x03,
+	0xff, 0xff, 0x7c, 0xaf, 0x00, 0x00, 0x16, 0x03, 0x01, 0x00, 0xe8, 0x01, 0x00, 0x00, 0xe4, 0x03,
 	0x01, 0xff, 0xa2, 0x88, 0x97, 0x7c, 0x41, 0xa1, 0x08, 0x34, 0x2c, 0x98, 0xc2, 0x70, 0x04, 0xa0,
 	0x5d, 0x5f, 0x39, 0xef, 0xe0, 0x70, 0xd5, 0x12, 0xf1, 0x35, 0x17, 0xb6, 0x0d, 0xc4, 0xd3, 0x09,
 	0x85, 0x00, 0x00, 0x5a, 0xc0, 0x14, 0xc0, 0x0a, 0x00, 0x39, 0x00, 0x38, 0x00, 0x88, 0x00, 0x87,
@@ -38,7 +40,7 @@ var testClientHello = []byte{
 }
 var testClientHelloDecoded = &TLS{
 	BaseLayer: BaseLayer{
-		Contents: testClientHello[54:268],
+		Contents: testClientHello[54:],
 		Payload:  nil,
 	},
 	ChangeCipherSpec: nil,
@@ -47,11 +49,11 @@ var testClientHelloDecoded = &TLS{
 			TLSRecordHeader: TLSRecordHeader{
 				ContentType: 22,
 				Version:     0x0301,
-				Length:      209,
+				Length:      232,
 			},
 			ClientHello: TLSHandshakeRecordClientHello{
 				HandshakeType:            0x1,
-				Length:                   0xcd,
+				Length:                   0xe4,
 				ProtocolVersion:          0x301,
 				Random:                   []uint8{0xff, 0xa2, 0x88, 0x97, 0x7c, 0x41, 0xa1, 0x8, 0x34, 0x2c, 0x98, 0xc2, 0x70, 0x4, 0xa0, 0x5d, 0x5f, 0x39, 0xef, 0xe0, 0x70, 0xd5, 0x12, 0xf1, 0x35, 0x17, 0xb6, 0xd, 0xc4, 0xd3, 0x9, 0x85},
 				SessionIDLength:          0x0,
@@ -63,6 +65,15 @@ var testClientHelloDecoded = &TLS{
 				ExtensionsLength:         0x60,
 				Extensions:               []uint8{0x0, 0xb, 0x0, 0x4, 0x3, 0x0, 0x1, 0x2, 0x0, 0xa, 0x0, 0x34, 0x0, 0x32, 0x0, 0xe, 0x0, 0xd, 0x0, 0x19, 0x0, 0xb, 0x0, 0xc, 0x0, 0x18, 0x0, 0x9, 0x0, 0xa, 0x0, 0x16, 0x0, 0x17, 0x0, 0x8, 0x0, 0x6, 0x0, 0x7, 0x0, 0x14, 0x0, 0x15, 0x0, 0x4, 0x0, 0x5, 0x0, 0x12, 0x0, 0x13, 0x0, 0x1, 0x0, 0x2, 0x0, 0x3, 0x0, 0xf, 0x0, 0x10, 0x0, 0x11, 0x0, 0x23, 0x0, 0x0, 0x0, 0xf, 0x0, 0x1, 0x1, 0x00, 0x00, 0x00, 0x13, 0x00, 0x11, 0x00, 0x00, 0x0e, 0x77, 0x77, 0x77, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x63, 0x6f, 0x6d},
 				SNI:                      []uint8{0x77, 0x77, 0x77, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x63, 0x6f, 0x6d},
+				ExtensionList: []TLSHelloExtension{
+					{Type: TLSExtECPointFormats, Data: []uint8{0x3, 0x0, 0x1, 0x2}},
+					{Type: TLSExtSupportedGroups, Data: []uint8{0x0, 0x32, 0x0, 0xe, 0x0, 0xd, 0x0, 0x19, 0x0, 0xb, 0x0, 0xc, 0x0, 0x18, 0x0, 0x9, 0x0, 0xa, 0x0, 0x16, 0x0, 0x17, 0x0, 0x8, 0x0, 0x6, 0x0, 0x7, 0x0, 0x14, 0x0, 0x15, 0x0, 0x4, 0x0, 0x5, 0x0, 0x12, 0x0, 0x13, 0x0, 0x1, 0x0, 0x2, 0x0, 0x3, 0x0, 0xf, 0x0, 0x10, 0x0, 0x11}},
+					{Type: TLSExtSessionTicket, Data: []uint8{}},
+					{Type: TLSExtHeartbeat, Data: []uint8{0x1}},
+					{Type: TLSExtServerName, Data: []uint8{0x00, 0x11, 0x00, 0x00, 0x0e, 0x77, 0x77, 0x77, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x63, 0x6f, 0x6d}},
+				},
+				SupportedGroups: []uint16{14, 13, 25, 11, 12, 24, 9, 10, 22, 23, 8, 6, 7, 20, 21, 4, 5, 18, 19, 1, 2, 3, 15, 16, 17},
+				ECPointFormats:  []uint8{0x0, 0x1, 0x2},
 			},
 		},
 	},
@@ -138,6 +149,11 @@ var testClientKeyExchangeDecoded = &TLS{
 				Version:     0x0301,
 				Length:      70,
 			},
+			ClientKeyChange: TLSHandshakeRecordClientKeyChange{
+				HandshakeType: 0x10,
+				Length:        0x42,
+				ExchangeKeys:  testClientKeyExchange[11:75],
+			},
 		},
 		{
 			TLSRecordHeader: TLSRecordHeader{
@@ -406,3 +422,237 @@ func TestSerializeTLSAlertEncrypted(t *testing.T) {
 		t.Error("No TLS layer type found in reconstructed packet")
 	}
 }
+
+func TestParseTLSServerHelloCertificate(t *testing.T) {
+	p := gopacket.NewPacket(testServerHello, LayerTypeTLS, testTLSDecodeOptions)
+	if p.ErrorLayer() != nil {
+		t.Fatal("Failed to decode packet:", p.ErrorLayer().Error())
+	}
+	tls, ok := p.Layer(LayerTypeTLS).(*TLS)
+	if !ok {
+		t.Fatal("No TLS layer type found in packet")
+	}
+	if len(tls.Handshake) != 3 {
+		t.Fatalf("expected 3 handshake records, got %d", len(tls.Handshake))
+	}
+
+	sh := tls.Handshake[0].ServerHello
+	if sh.HandshakeType != TLSHandshakeServerHello || sh.CipherSuite != 0x002f || sh.CompressionMethod != 1 {
+		t.Errorf("bad ServerHello: %+v", sh)
+	}
+	if sh.NegotiatedVersion() != 0x0301 || sh.HelloRetryRequest {
+		t.Errorf("bad ServerHello version %v", sh.NegotiatedVersion())
+	}
+	wantExts := []TLSExtension{TLSExtRenegotiationInfo, TLSExtSessionTicket, TLSExtHeartbeat}
+	if len(sh.ExtensionList) != len(wantExts) {
+		t.Fatalf("expected %d extensions, got %d", len(wantExts), len(sh.ExtensionList))
+	}
+	for i, ext := range sh.ExtensionList {
+		if ext.Type != wantExts[i] {
+			t.Errorf("extension %d: got %v, want %v", i, ext.Type, wantExts[i])
+		}
+	}
+
+	cert := tls.Handshake[1].Certificate
+	if cert.HandshakeType != TLSHandshakeCertificate || len(cert.Certificates) != 1 {
+		t.Fatalf("bad Certificate: %+v", cert)
+	}
+	x, err := x509.ParseCertificate(cert.Certificates[0])
+	if err != nil {
+		t.Fatal("certificate is not usable with crypto/x509:", err)
+	}
+	if x.Subject.CommonName != "SSLeay demo server" {
+		t.Errorf("unexpected certificate subject %q", x.Subject.CommonName)
+	}
+}
+
+func TestParseTLSNewSessionTicket(t *testing.T) {
+	p := gopacket.NewPacket(testNewSessionTicket, LayerTypeTLS, testTLSDecodeOptions)
+	if p.ErrorLayer() != nil {
+		t.Fatal("Failed to decode packet:", p.ErrorLayer().Error())
+	}
+	tls := p.Layer(LayerTypeTLS).(*TLS)
+	nst := tls.Handshake[0].NewSessionTicket
+	if nst.HandshakeType != TLSHandshakeNewSessionTicket || nst.LifetimeHint != 7200 {
+		t.Errorf("bad NewSessionTicket: %+v", nst)
+	}
+	if !bytes.Equal(nst.Ticket, testNewSessionTicket[15:15+160]) {
+		t.Errorf("bad ticket %x", nst.Ticket)
+	}
+}
+
+// tlsVec prefixes data with its length on n bytes.
+func tlsVec(n int, data ...[]byte) []byte {
+	body := bytes.Join(data, nil)
+	out := make([]byte, n, n+len(body))
+	for i := 0; i < n; i++ {
+		out[i] = byte(len(body) >> (8 * (n - 1 - i)))
+	}
+	return append(out, body...)
+}
+
+// tlsRecord wraps handshake messages into a TLS 1.2 handshake record.
+func tlsRecord(msgs ...[]byte) []byte {
+	return append([]byte{byte(TLSHandshake), 0x03, 0x03}, tlsVec(2, msgs...)...)
+}
+
+func tlsExt(typ TLSExtension, data ...[]byte) []byte {
+	return append([]byte{byte(typ >> 8), byte(typ)}, tlsVec(2, data...)...)
+}
+
+func TestParseTLS13Hellos(t *testing.T) {
+	random := bytes.Repeat([]byte{0x42}, 32)
+	key := bytes.Repeat([]byte{0x11}, 32)
+	clientHello := append([]byte{TLSHandshakeClientHello}, tlsVec(3,
+		[]byte{0x03, 0x03}, random, tlsVec(1),
+		tlsVec(2, []byte{0x0a, 0x0a, 0x13, 0x01, 0x13, 0x02}),
+		tlsVec(1, []byte{0}),
+		tlsVec(2,
+			tlsExt(0x1a1a),
+			tlsExt(TLSExtServerName, tlsVec(2, []byte{0}, tlsVec(2, []byte("example.com")))),
+			tlsExt(TLSExtALPN, tlsVec(2, tlsVec(1, []byte("h2")), tlsVec(1, []byte("http/1.1")))),
+			tlsExt(TLSExtSupportedGroups, tlsVec(2, []byte{0x00, 0x1d, 0x00, 0x17})),
+			tlsExt(TLSExtSignatureAlgs, tlsVec(2, []byte{0x04, 0x03, 0x08, 0x04})),
+			tlsExt(TLSExtSupportedVersions, tlsVec(1, []byte{0x03, 0x04, 0x03, 0x03})),
+			tlsExt(TLSExtKeyShare, tlsVec(2, []byte{0x00, 0x1d}, tlsVec(2, key))),
+			tlsExt(TLSExtPSKKeyExchangeModes, tlsVec(1, []byte{1})),
+		),
+	)...)
+	serverHello := append([]byte{TLSHandshakeServerHello}, tlsVec(3,
+		[]byte{0x03, 0x03}, random, tlsVec(1), []byte{0x13, 0x01, 0x00},
+		tlsVec(2,
+			tlsExt(TLSExtSupportedVersions, []byte{0x03, 0x04}),
+			tlsExt(TLSExtKeyShare, []byte{0x00, 0x1d}, tlsVec(2, key)),
+		),
+	)...)
+
+	var tls TLS
+	if err := tls.DecodeFromBytes(append(tlsRecord(clientHello), tlsRecord(serverHello)...), gopacket.NilDecodeFeedback); err != nil {
+		t.Fatal(err)
+	}
+	ch := tls.Handshake[0].ClientHello
+	if string(ch.SNI) != "example.com" {
+		t.Errorf("bad SNI %q", ch.SNI)
+	}
+	if !reflect.DeepEqual(ch.ALPN, []string{"h2", "http/1.1"}) {
+		t.Errorf("bad ALPN %v", ch.ALPN)
+	}
+	if !reflect.DeepEqual(ch.CipherSuiteList(), []uint16{0x0a0a, 0x1301, 0x1302}) {
+		t.Errorf("bad cipher suites %v", ch.CipherSuiteList())
+	}
+	if !reflect.DeepEqual(ch.SupportedGroups, []uint16{0x1d, 0x17}) {
+		t.Errorf("bad supported groups %v", ch.SupportedGroups)
+	}
+	if !reflect.DeepEqual(ch.SignatureAlgorithms, []uint16{0x0403, 0x0804}) {
+		t.Errorf("bad signature algorithms %v", ch.SignatureAlgorithms)
+	}
+	if !reflect.DeepEqual(ch.SupportedVersions, []TLSVersion{0x0304, 0x0303}) {
+		t.Errorf("bad supported versions %v", ch.SupportedVersions)
+	}
+	if len(ch.KeyShares) != 1 || ch.KeyShares[0].Group != 0x1d || !bytes.Equal(ch.KeyShares[0].KeyExchange, key) {
+		t.Errorf("bad key shares %v", ch.KeyShares)
+	}
+	if !bytes.Equal(ch.PSKKeyExchangeModes, []byte{1}) {
+		t.Errorf("bad psk modes %v", ch.PSKKeyExchangeModes)
+	}
+	if len(ch.ExtensionList) != 8 || ch.ExtensionList[0].Type.String() != "GREASE" {
+		t.Errorf("bad extension list %v", ch.ExtensionList)
+	}
+
+	sh := tls.Handshake[1].ServerHello
+	if sh.NegotiatedVersion() != 0x0304 || sh.CipherSuite != 0x1301 {
+		t.Errorf("bad ServerHello version %v suite %x", sh.NegotiatedVersion(), sh.CipherSuite)
+	}
+	if sh.KeyShare.Group != 0x1d || !bytes.Equal(sh.KeyShare.KeyExchange, key) {
+		t.Errorf("bad server key share %v", sh.KeyShare)
+	}
+}
+
+func TestParseTLSServerKeyExchangeECDHE(t *testing.T) {
+	point := bytes.Repeat([]byte{0x04}, 65)
+	sig := bytes.Repeat([]byte{0x99}, 71)
+	ske := append([]byte{TLSHandshakeServerKeyExchange}, tlsVec(3,
+		[]byte{3, 0x00, 0x17}, tlsVec(1, point), []byte{0x04, 0x03}, tlsVec(2, sig),
+	)...)
+	var tls TLS
+	if err := tls.DecodeFromBytes(tlsRecord(ske), gopacket.NilDecodeFeedback); err != nil {
+		t.Fatal(err)
+	}
+	got := tls.Handshake[0].ServerKeyExchange
+	if got.CurveType != 3 || got.NamedGroup != 0x17 || !bytes.Equal(got.PublicKey, point) {
+		t.Errorf("bad ECDHE parameters %+v", got)
+	}
+	if got.SignatureAlgorithm != 0x0403 || !bytes.Equal(got.Signature, sig) {
+		t.Errorf("bad signature %x %x", got.SignatureAlgorithm, got.Signature)
+	}
+}
+
+func TestParseTLSClientHelloTruncated(t *testing.T) {
+	// the handshake length claims more than the ClientHello carries
+	data := tlsRecord([]byte{TLSHandshakeClientHello, 0, 0, 16, 0x03, 0x03, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14})
+	var tls TLS
+	if err := tls.DecodeFromBytes(data, gopacket.NilDecodeFeedback); err == nil {
+		t.Error("No Decoding Error when parsing a truncated ClientHello")
+	}
+}
+
+func TestParseTLSServerHelloSplitCertificate(t *testing.T) {
+	serverHello := append([]byte{TLSHandshakeServerHello}, tlsVec(3,
+		[]byte{0x03, 0x03}, bytes.Repeat([]byte{0x42}, 32), tlsVec(1), []byte{0xc0, 0x2f, 0x00},
+		tlsVec(2, tlsExt(TLSExtRenegotiationInfo, []byte{0})),
+	)...)
+	cert := bytes.Repeat([]byte{0xaa}, 300)
+	certificate := append([]byte{TLSHandshakeCertificate}, tlsVec(3, tlsVec(3, tlsVec(3, cert)))...)
+	// the Certificate continues in the second record
+	split := 100
+
+	var tls TLS
+	data := append(tlsRecord(serverHello, certificate[:split]), tlsRecord(certificate[split:])...)
+	if err := tls.DecodeFromBytes(data, gopacket.NilDecodeFeedback); err != nil {
+		t.Fatal(err)
+	}
+	if len(tls.Handshake) != 2 {
+		t.Fatalf("expected 2 handshake records, got %d", len(tls.Handshake))
+	}
+	sh := tls.Handshake[0].ServerHello
+	if sh.HandshakeType != TLSHandshakeServerHello || sh.CipherSuite != 0xc02f {
+		t.Errorf("bad ServerHello: %+v", sh)
+	}
+	if sh.JA3SString() != "771,49199,65281" {
+		t.Errorf("bad JA3S string %q", sh.JA3SString())
+	}
+	if !bytes.Equal(tls.Handshake[0].Fragment, certificate[:split]) {
+		t.Errorf("bad fragment %x", tls.Handshake[0].Fragment)
+	}
+	if tls.Handshake[1].Certificate.HandshakeType != 0 || tls.Handshake[1].Fragment != nil {
+		t.Errorf("continuation record decoded: %+v", tls.Handshake[1])
+	}
+
+	// the fragment and the next record make up the whole message
+	var whole TLS
+	if err := whole.DecodeFromBytes(tlsRecord(append(tls.Handshake[0].Fragment, certificate[split:]...)), gopacket.NilDecodeFeedback); err != nil {
+		t.Fatal(err)
+	}
+	if certs := whole.Handshake[0].Certificate.Certificates; len(certs) != 1 || !bytes.Equal(certs[0], cert) {
+		t.Errorf("bad reassembled certificates %x", certs)
+	}
+}
+
+func TestParseTLSServerKeyExchangeDHE(t *testing.T) {
+	// a 800-byte prime has a length starting with 3, like named_curve
+	prime := bytes.Repeat([]byte{0xff}, 800)
+	sig := bytes.Repeat([]byte{0x99}, 256)
+	params := bytes.Join([][]byte{tlsVec(2, prime), tlsVec(2, []byte{2}), tlsVec(2, prime), {0x08, 0x04}, tlsVec(2, sig)}, nil)
+	ske := append([]byte{TLSHandshakeServerKeyExchange}, tlsVec(3, params)...)
+	var tls TLS
+	if err := tls.DecodeFromBytes(tlsRecord(ske), gopacket.NilDecodeFeedback); err != nil {
+		t.Fatal(err)
+	}
+	got := tls.Handshake[0].ServerKeyExchange
+	if got.CurveType != 0 || got.NamedGroup != 0 || got.PublicKey != nil || got.Signature != nil {
+		t.Errorf("DHE parameters decoded as ECDHE: %+v", got)
+	}
+	if !bytes.Equal(got.Params, params) {
+		t.Error("bad raw parameters")
+	}
+}
@@ -9,6 +9,7 @@ package layers
 
 import (
 	"encoding/binary"
+	"fmt"
 
 	"github.com/gopacket/gopacket"
 )
@@ -20,6 +21,7 @@ type UDPLite struct {
 	ChecksumCoverage uint16
 	Checksum         uint16
 	sPort, dPort     []byte
+	tcpipchecksum
 }
 
 // LayerType returns gopacket.LayerTypeUDPLite
@@ -43,3 +45,46 @@ func decodeUDPLite(data []byte, p gopacket.PacketBuilder) error {
 func (u *UDPLite) TransportFlow() gopacket.Flow {
 	return gopacket.NewFlow(EndpointUDPLitePort, u.sPort, u.dPort)
 }
+
+// SerializeTo writes the serialized form of this layer into the
+// SerializationBuffer, implementing gopacket.SerializableLayer.
+// See the docs for gopacket.SerializableLayer for more info.
+func (u *UDPLite) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOptions) error {
+	bytes, err := b.PrependBytes(8)
+	if err != nil {
+		return err
+	}
+	binary.BigEndian.PutUint16(bytes, uint16(u.SrcPort))
+	binary.BigEndian.PutUint16(bytes[2:], uint16(u.DstPort))
+	binary.BigEndian.PutUint16(bytes[4:], u.ChecksumCoverage)
+	if opts.ComputeChecksums {
+		// zero out checksum bytes
+		bytes[6] = 0
+		bytes[7] = 0
+
+		// RFC3828: the checksum covers the first ChecksumCoverage bytes,
+		// or the whole datagram when it is zero, but the pseudo-header
+		// always carries the length of the whole datagram.
+		data := b.Bytes()
+		coverage := len(data)
+		if u.ChecksumCoverage != 0 {
+			coverage = int(u.ChecksumCoverage)
+			if coverage < 8 || coverage > len(data) {
+				return fmt.Errorf("invalid UDPLite checksum coverage %d for %d bytes", coverage, len(data))
+			}
+		}
+		csum, err := u.pseudoheaderSum(uint32(len(data)), IPProtocolUDPLite)
+		if err != nil {
+			return err
+		}
+		csum = gopacket.ComputeChecksum(data[:coverage], csum)
+		csumFolded := gopacket.FoldChecksum(csum)
+		// RFC3828: a computed checksum of zero is transmitted as all ones.
+		if csumFolded == 0 {
+			csumFolded = 0xffff
+		}
+		u.Checksum = csumFolded
+	}
+	binary.BigEndian.PutUint16(bytes[6:], u.Checksum)
+	return nil
+}
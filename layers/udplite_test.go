@@ -0,0 +1,59 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package layers
+
+import (
+	"net"
+	"testing"
+
+	"github.com/gopacket/gopacket"
+)
+
+func serializeUDPLite(t *testing.T, coverage uint16, payload []byte) []byte {
+	ip := &IPv4{
+		Version:  4,
+		TTL:      64,
+		Protocol: IPProtocolUDPLite,
+		SrcIP:    net.IP{192, 168, 0, 1},
+		DstIP:    net.IP{192, 168, 0, 2},
+	}
+	udp := &UDPLite{SrcPort: 1234, DstPort: 5678, ChecksumCoverage: coverage}
+	udp.SetNetworkLayerForChecksum(ip)
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	if err := gopacket.SerializeLayers(buf, opts, ip, udp, gopacket.Payload(payload)); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestUDPLiteSerializeChecksum(t *testing.T) {
+	for _, coverage := range []uint16{0, 8, 13} {
+		data := serializeUDPLite(t, coverage, []byte("partially covered payload"))
+		p := gopacket.NewPacket(data, LayerTypeIPv4, gopacket.Default)
+		checkLayers(p, []gopacket.LayerType{LayerTypeIPv4, LayerTypeUDPLite, gopacket.LayerTypePayload}, t)
+
+		datagram := data[20:]
+		covered := datagram
+		if coverage != 0 {
+			covered = datagram[:coverage]
+		}
+		// the pseudo-header carries the length of the whole datagram
+		pseudo := []byte{192, 168, 0, 1, 192, 168, 0, 2, 0, byte(IPProtocolUDPLite), 0, byte(len(datagram))}
+		csum := gopacket.ComputeChecksum(covered, gopacket.ComputeChecksum(pseudo, 0))
+		if folded := gopacket.FoldChecksum(csum); folded != 0 {
+			t.Errorf("coverage %d: checksum %x does not verify", coverage, datagram[6:8])
+		}
+	}
+
+	// bytes beyond the coverage do not change the checksum
+	a := serializeUDPLite(t, 8, []byte("payload"))
+	b := serializeUDPLite(t, 8, []byte("PAYLOAD"))
+	if a[26] != b[26] || a[27] != b[27] {
+		t.Errorf("checksum changed with uncovered data, %x != %x", a[26:28], b[26:28])
+	}
+}
@@ -179,28 +179,104 @@ func (m *USB) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
 	m.Contents = data[:40]
 	m.Payload = data[40:]
 
-	if m.Setup {
+	if len(data) >= 64 {
+		// the 64 byte header of LinkTypeLinuxUSB captures, whose last
+		// 24 bytes are the setup bytes and the header extension
+		m.UrbInterval = binary.LittleEndian.Uint32(data[48:52])
+		m.UrbStartFrame = binary.LittleEndian.Uint32(data[52:56])
+		m.UrbCopyOfTransferFlags = binary.LittleEndian.Uint32(data[56:60])
+		m.IsoNumDesc = binary.LittleEndian.Uint32(data[60:64])
+		m.Contents = data[:64]
+		m.Payload = data[64:]
+		if m.Setup {
+			// the setup bytes are decoded by USBRequestBlockSetup,
+			// which is followed by the data
+			m.Payload = data[40:48]
+			if len(data) > 64 {
+				m.Payload = append(append([]byte(nil), data[40:48]...), data[64:]...)
+			}
+		} else if m.Data && uint32(len(data)-64) >= m.UrbDataLength {
+			m.Payload = data[uint32(len(data))-m.UrbDataLength:]
+		}
+	} else if m.Setup {
 		m.Payload = data[40:]
 	} else if m.Data {
 		m.Payload = data[uint32(len(data))-m.UrbDataLength:]
 	}
 
-	// if 64 bit, dissect_linux_usb_pseudo_header_ext
-	if false {
-		m.UrbInterval = binary.LittleEndian.Uint32(data[40:44])
-		m.UrbStartFrame = binary.LittleEndian.Uint32(data[44:48])
-		m.UrbDataLength = binary.LittleEndian.Uint32(data[48:52])
-		m.IsoNumDesc = binary.LittleEndian.Uint32(data[52:56])
-		m.Contents = data[:56]
-		m.Payload = data[56:]
-	}
-
 	// crc5 or crc16
 	// eop (end of packet)
 
 	return nil
 }
 
+// SerializeTo writes the serialized form of this layer into the
+// SerializationBuffer, implementing gopacket.SerializableLayer.
+// See the docs for gopacket.SerializableLayer for more info.
+//
+// The 64 byte header of LinkTypeLinuxUSB is written. When Setup is set,
+// the setup bytes are expected at the start of the payload, as written
+// by USBRequestBlockSetup, and are moved into the header. With
+// FixLengths, UrbDataLength is set to the length of the data following
+// the header when Data is set. The error count and descriptors of
+// isochronous transfers are not decoded, and are not written back.
+func (m *USB) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOptions) error {
+	data := len(b.Bytes())
+	if m.Setup {
+		if data < 8 {
+			return errors.New("USB setup bytes missing from the payload")
+		}
+		data -= 8
+	}
+	if opts.FixLengths && m.Data {
+		m.UrbDataLength = uint32(data)
+	}
+	length := 64
+	if m.Setup {
+		length = 56
+	}
+	if _, err := b.PrependBytes(length); err != nil {
+		return err
+	}
+	bytes := b.Bytes()[:64]
+	if m.Setup {
+		copy(bytes[40:48], bytes[56:64])
+	} else {
+		copy(bytes[40:48], lotsOfZeros[:8])
+	}
+	binary.LittleEndian.PutUint64(bytes[0:8], m.ID)
+	bytes[8] = uint8(m.EventType)
+	bytes[9] = uint8(m.TransferType)
+	bytes[10] = m.EndpointNumber & 0x7f
+	if m.Direction == USBDirectionTypeIn {
+		bytes[10] |= uint8(USBTransportTypeTransferIn)
+	}
+	bytes[11] = m.DeviceAddress
+	binary.LittleEndian.PutUint16(bytes[12:14], m.BusID)
+	bytes[14] = '-'
+	if m.Setup {
+		bytes[14] = 0
+	}
+	switch {
+	case m.Data:
+		bytes[15] = 0
+	case m.Direction == USBDirectionTypeIn:
+		bytes[15] = '<'
+	default:
+		bytes[15] = '>'
+	}
+	binary.LittleEndian.PutUint64(bytes[16:24], uint64(m.TimestampSec))
+	binary.LittleEndian.PutUint32(bytes[24:28], uint32(m.TimestampUsec))
+	binary.LittleEndian.PutUint32(bytes[28:32], uint32(m.Status))
+	binary.LittleEndian.PutUint32(bytes[32:36], m.UrbLength)
+	binary.LittleEndian.PutUint32(bytes[36:40], m.UrbDataLength)
+	binary.LittleEndian.PutUint32(bytes[48:52], m.UrbInterval)
+	binary.LittleEndian.PutUint32(bytes[52:56], m.UrbStartFrame)
+	binary.LittleEndian.PutUint32(bytes[56:60], m.UrbCopyOfTransferFlags)
+	binary.LittleEndian.PutUint32(bytes[60:64], m.IsoNumDesc)
+	return nil
+}
+
 type USBRequestBlockSetup struct {
 	BaseLayer
 	RequestType uint8
@@ -227,6 +303,22 @@ func (m *USBRequestBlockSetup) DecodeFromBytes(data []byte, df gopacket.DecodeFe
 	return nil
 }
 
+// SerializeTo writes the serialized form of this layer into the
+// SerializationBuffer, implementing gopacket.SerializableLayer.
+// See the docs for gopacket.SerializableLayer for more info.
+func (m *USBRequestBlockSetup) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOptions) error {
+	bytes, err := b.PrependBytes(8)
+	if err != nil {
+		return err
+	}
+	bytes[0] = m.RequestType
+	bytes[1] = uint8(m.Request)
+	binary.LittleEndian.PutUint16(bytes[2:4], m.Value)
+	binary.LittleEndian.PutUint16(bytes[4:6], m.Index)
+	binary.LittleEndian.PutUint16(bytes[6:8], m.Length)
+	return nil
+}
+
 func decodeUSBRequestBlockSetup(data []byte, p gopacket.PacketBuilder) error {
 	d := &USBRequestBlockSetup{}
 	return decodingLayerDecoder(d, data, p)
@@ -247,6 +339,18 @@ func (m *USBControl) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) er
 	return nil
 }
 
+// SerializeTo writes the serialized form of this layer into the
+// SerializationBuffer, implementing gopacket.SerializableLayer.
+// See the docs for gopacket.SerializableLayer for more info.
+func (m *USBControl) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOptions) error {
+	bytes, err := b.PrependBytes(len(m.Contents))
+	if err != nil {
+		return err
+	}
+	copy(bytes, m.Contents)
+	return nil
+}
+
 func decodeUSBControl(data []byte, p gopacket.PacketBuilder) error {
 	d := &USBControl{}
 	return decodingLayerDecoder(d, data, p)
@@ -267,6 +371,18 @@ func (m *USBInterrupt) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback)
 	return nil
 }
 
+// SerializeTo writes the serialized form of this layer into the
+// SerializationBuffer, implementing gopacket.SerializableLayer.
+// See the docs for gopacket.SerializableLayer for more info.
+func (m *USBInterrupt) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOptions) error {
+	bytes, err := b.PrependBytes(len(m.Contents))
+	if err != nil {
+		return err
+	}
+	copy(bytes, m.Contents)
+	return nil
+}
+
 func decodeUSBInterrupt(data []byte, p gopacket.PacketBuilder) error {
 	d := &USBInterrupt{}
 	return decodingLayerDecoder(d, data, p)
@@ -287,6 +403,18 @@ func (m *USBBulk) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error
 	return nil
 }
 
+// SerializeTo writes the serialized form of this layer into the
+// SerializationBuffer, implementing gopacket.SerializableLayer.
+// See the docs for gopacket.SerializableLayer for more info.
+func (m *USBBulk) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOptions) error {
+	bytes, err := b.PrependBytes(len(m.Contents))
+	if err != nil {
+		return err
+	}
+	copy(bytes, m.Contents)
+	return nil
+}
+
 func decodeUSBBulk(data []byte, p gopacket.PacketBuilder) error {
 	d := &USBBulk{}
 	return decodingLayerDecoder(d, data, p)
@@ -43,7 +43,7 @@ func TestPacketUSB0(t *testing.T) {
 	if got, ok := p.Layer(LayerTypeUSB).(*USB); ok {
 		want := &USB{
 			BaseLayer: BaseLayer{
-				Contents: []uint8{0x0, 0x38, 0x4a, 0x3b, 0x0, 0x88, 0xff, 0xff, 0x43, 0x1, 0x81, 0x1, 0x2, 0x0, 0x2d, 0x0, 0xc0, 0xd3, 0x5b, 0x50, 0x0, 0x0, 0x0, 0x0, 0x8a, 0x85, 0xa, 0x0, 0x0, 0x0, 0x0, 0x0, 0x1, 0x0, 0x0, 0x0, 0x1, 0x0, 0x0, 0x0},
+				Contents: testPacketUSB0[:64],
 				Payload:  []uint8{0x4},
 			},
 			ID:             0xffff88003b4a3800,
@@ -60,16 +60,51 @@ func TestPacketUSB0(t *testing.T) {
 			Status:         0,
 			UrbLength:      0x1,
 			UrbDataLength:  0x1,
+
+			UrbInterval:            0x80,
+			UrbCopyOfTransferFlags: 0x200,
 		}
 
 		if !reflect.DeepEqual(got, want) {
 			t.Errorf("USB packet processing failed:\ngot  :\n%#v\n\nwant :\n%#v\n\n", got, want)
 		}
 	}
-
+	testSerialization(t, p, testPacketUSB0)
 }
+
 func BenchmarkDecodePacketUSB0(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		gopacket.NewPacket(testPacketUSB0, LinkTypeLinuxUSB, gopacket.NoCopy)
 	}
 }
+
+// testPacketUSBControlSetup is a SET_CONFIGURATION control transfer
+// submitted with two bytes of data, captured with the 64 byte header.
+var testPacketUSBControlSetup = []byte{
+	0x00, 0x9e, 0x11, 0x36, 0x00, 0x88, 0xff, 0xff, 0x53, 0x02, 0x00, 0x03, 0x01, 0x00, 0x00, 0x00,
+	0xc0, 0xd3, 0x5b, 0x50, 0x00, 0x00, 0x00, 0x00, 0x10, 0x27, 0x00, 0x00, 0x8d, 0xff, 0xff, 0xff,
+	0x02, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00, 0x09, 0x01, 0x00, 0x00, 0x00, 0x02, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xaa, 0xbb,
+}
+
+func TestPacketUSBControlSetup(t *testing.T) {
+	p := gopacket.NewPacket(testPacketUSBControlSetup, LinkTypeLinuxUSB, gopacket.Default)
+	if p.ErrorLayer() != nil {
+		t.Error("Failed to decode packet:", p.ErrorLayer().Error())
+	}
+	checkLayers(p, []gopacket.LayerType{LayerTypeUSB, LayerTypeUSBRequestBlockSetup, gopacket.LayerTypePayload}, t)
+
+	usb := p.Layer(LayerTypeUSB).(*USB)
+	if !usb.Setup || !usb.Data || usb.UrbCopyOfTransferFlags != 0x200 || len(usb.Contents) != 64 {
+		t.Errorf("bad USB header %+v", usb)
+	}
+	setup := p.Layer(LayerTypeUSBRequestBlockSetup).(*USBRequestBlockSetup)
+	if setup.Request != USBRequestBlockSetupRequestSetConfiguration || setup.Value != 1 || setup.Length != 2 {
+		t.Errorf("bad setup %+v", setup)
+	}
+	if payload := p.ApplicationLayer().Payload(); !reflect.DeepEqual(payload, []byte{0xaa, 0xbb}) {
+		t.Errorf("bad data %x", payload)
+	}
+	testSerialization(t, p, testPacketUSBControlSetup)
+}
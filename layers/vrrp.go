@@ -9,6 +9,7 @@ package layers
 import (
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"net"
 
 	"github.com/gopacket/gopacket"
@@ -154,3 +155,40 @@ func decodeVRRP(data []byte, p gopacket.PacketBuilder) error {
 	v := &VRRPv2{}
 	return decodingLayerDecoder(v, data, p)
 }
+
+// SerializeTo writes the serialized form of this layer into the
+// SerializationBuffer, implementing gopacket.SerializableLayer.
+// See the docs for gopacket.SerializableLayer for more info.
+func (v *VRRPv2) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOptions) error {
+	if opts.FixLengths {
+		v.CountIPAddr = uint8(len(v.IPAddress))
+	}
+	// the two words of authentication data are kept for compatibility
+	// with RFC 2338, and set to zero
+	bytes, err := b.PrependBytes(8 + 4*len(v.IPAddress) + 8)
+	if err != nil {
+		return err
+	}
+	bytes[0] = v.Version<<4 | uint8(v.Type)&0x0F
+	bytes[1] = v.VirtualRtrID
+	bytes[2] = v.Priority
+	bytes[3] = v.CountIPAddr
+	bytes[4] = uint8(v.AuthType)
+	bytes[5] = v.AdverInt
+	offset := 8
+	for _, ip := range v.IPAddress {
+		ip4 := ip.To4()
+		if ip4 == nil {
+			return fmt.Errorf("invalid VRRPv2 IPv4 address %v", ip)
+		}
+		copy(bytes[offset:], ip4)
+		offset += 4
+	}
+	copy(bytes[offset:], lotsOfZeros[:8])
+	if opts.ComputeChecksums {
+		bytes[6], bytes[7] = 0, 0
+		v.Checksum = gopacket.FoldChecksum(gopacket.ComputeChecksum(bytes, 0))
+	}
+	binary.BigEndian.PutUint16(bytes[6:8], v.Checksum)
+	return nil
+}
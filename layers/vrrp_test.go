@@ -49,6 +49,8 @@ func TestVRRPPacketPacket0(t *testing.T) {
 	if vrrp.Checksum != 47698 {
 		t.Fatalf("Unable to decode VRRPv2 checksum. Received %d, expected %d", vrrp.Checksum, 47698)
 	}
+
+	testSerialization(t, p, vrrpPacketPriority100)
 }
 func BenchmarkDecodeVRRPPacket0(b *testing.B) {
 	for i := 0; i < b.N; i++ {
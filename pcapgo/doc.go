@@ -13,8 +13,26 @@ This package contains implementations for native PCAP support. Currently support
 
   - pcap-files read/write: Reader, Writer
   - pcapng-files read/write: NgReader, NgWriter
+  - snoop-files read/write: SnoopReader, SnoopWriter
+  - any of the above, optionally gzip compressed, or zstandard compressed for reading: OpenFile, CreateFile
+  - random access to pcap and pcapng files: IndexedReader
   - raw socket capture (linux only): EthernetHandle
 
+# Basic Usage capture files
+
+OpenFile detects the format of a capture file from its magic number and transparently uncompresses
+gzip and zstandard compressed files. CreateFile writes pcap, pcapng or snoop
+files, compressing them on the fly if requested, or if the name ends in ".gz".
+
+	r, err := OpenFile("somefile.pcapng.gz")
+	if err != nil {
+		...
+	}
+	defer r.Close()
+
+	source := gopacket.NewPacketSource(r, r.LinkType())
+	...
+
 # Basic Usage pcapng
 
 Pcapng files can be read and written. Reading supports both big and little endian files, packet blocks,
@@ -0,0 +1,380 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package pcapgo
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+	"github.com/klauspost/compress/zstd"
+)
+
+// FileFormat is the format of a capture file.
+type FileFormat uint8
+
+const (
+	FileFormatUnknown FileFormat = iota
+	FileFormatPcap
+	FileFormatPcapNg
+	FileFormatSnoop
+)
+
+func (f FileFormat) String() string {
+	switch f {
+	case FileFormatPcap:
+		return "pcap"
+	case FileFormatPcapNg:
+		return "pcapng"
+	case FileFormatSnoop:
+		return "snoop"
+	}
+	return "unknown"
+}
+
+// magicZstd is the magic number of a zstandard frame, in little endian.
+const magicZstd = 0xFD2FB528
+
+// ErrUnknownFileFormat is returned by OpenFile and NewFileReader if the
+// data doesn't start with a known magic number.
+var ErrUnknownFileFormat = errors.New("unknown capture file format")
+
+// packetSource is implemented by Reader, NgReader and SnoopReader.
+type packetSource interface {
+	gopacket.PacketDataSource
+	gopacket.ZeroCopyPacketDataSource
+}
+
+// FileReader reads packets from a capture file in any of the formats
+// supported by this package, optionally gzip or zstandard compressed. It implements
+// gopacket.PacketDataSource and gopacket.ZeroCopyPacketDataSource.
+type FileReader struct {
+	src        packetSource
+	format     FileFormat
+	compressed bool
+	linkType   layers.LinkType
+	resolution gopacket.TimestampResolution
+	closers    []io.Closer
+}
+
+// OpenFile opens the named capture file for reading. The format (pcap
+// in either byte order and with micro- or nanosecond timestamps, pcapng
+// or snoop) is detected from the magic number, and gzip or zstandard
+// compressed files are transparently uncompressed.
+//
+// pcapng files are read with DefaultNgReaderOptions. Close must be
+// called once done with the file.
+//
+//	r, err := pcapgo.OpenFile("/tmp/file.pcap.gz")
+//	if err != nil {
+//		...
+//	}
+//	defer r.Close()
+//	source := gopacket.NewPacketSource(r, r.LinkType())
+func OpenFile(path string) (*FileReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := NewFileReader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	r.closers = append(r.closers, f)
+	return r, nil
+}
+
+// NewFileReader is like OpenFile, reading the capture from r. Closing
+// the returned FileReader doesn't close r.
+func NewFileReader(r io.Reader) (*FileReader, error) {
+	ret := &FileReader{}
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(4)
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	switch {
+	case magic[0] == magicGzip1 && magic[1] == magicGzip2:
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		ret.compressed = true
+		ret.closers = append(ret.closers, gz)
+		br = bufio.NewReader(gz)
+	case binary.LittleEndian.Uint32(magic) == magicZstd:
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		zrc := zr.IOReadCloser()
+		ret.compressed = true
+		ret.closers = append(ret.closers, zrc)
+		br = bufio.NewReader(zrc)
+	}
+
+	if err := ret.open(br); err != nil {
+		ret.Close()
+		return nil, err
+	}
+	return ret, nil
+}
+
+func (r *FileReader) open(br *bufio.Reader) error {
+	magic, err := br.Peek(8)
+	if err != nil && len(magic) < 4 {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	switch m := binary.LittleEndian.Uint32(magic); {
+	case m == magicMicroseconds || m == magicNanoseconds ||
+		m == magicMicrosecondsBigendian || m == magicNanosecondsBigendian:
+		pr, err := NewReader(br)
+		if err != nil {
+			return err
+		}
+		r.src, r.format = pr, FileFormatPcap
+		r.linkType, r.resolution = pr.LinkType(), pr.Resolution()
+	case ngBlockType(m) == ngBlockTypeSectionHeader:
+		nr, err := NewNgReader(br, DefaultNgReaderOptions)
+		if err != nil {
+			return err
+		}
+		r.src, r.format = nr, FileFormatPcapNg
+		r.linkType, r.resolution = nr.LinkType(), nr.Resolution()
+	case len(magic) == 8 && binary.BigEndian.Uint64(magic) == snoopMagic:
+		sr, err := NewSnoopReader(br)
+		if err != nil {
+			return err
+		}
+		lt, err := sr.LinkType()
+		if err != nil {
+			return err
+		}
+		r.src, r.format = sr, FileFormatSnoop
+		r.linkType, r.resolution = *lt, gopacket.TimestampResolutionMicrosecond
+	default:
+		return ErrUnknownFileFormat
+	}
+	return nil
+}
+
+// ReadPacketData reads the next packet.
+func (r *FileReader) ReadPacketData() (data []byte, ci gopacket.CaptureInfo, err error) {
+	return r.src.ReadPacketData()
+}
+
+// ZeroCopyReadPacketData reads the next packet. The data buffer is owned
+// by the FileReader, and each call invalidates the data returned by the
+// previous one.
+func (r *FileReader) ZeroCopyReadPacketData() (data []byte, ci gopacket.CaptureInfo, err error) {
+	return r.src.ZeroCopyReadPacketData()
+}
+
+// LinkType returns the link type of the capture. For pcapng files this
+// is the link type of the first interface.
+func (r *FileReader) LinkType() layers.LinkType {
+	return r.linkType
+}
+
+// Resolution returns the timestamp resolution of the capture.
+func (r *FileReader) Resolution() gopacket.TimestampResolution {
+	return r.resolution
+}
+
+// Format returns the detected file format.
+func (r *FileReader) Format() FileFormat {
+	return r.format
+}
+
+// Compressed reports whether the capture is gzip or zstandard compressed.
+func (r *FileReader) Compressed() bool {
+	return r.compressed
+}
+
+// Close closes the decompressor and, if the FileReader was returned by
+// OpenFile, the file.
+func (r *FileReader) Close() error {
+	var err error
+	for _, c := range r.closers {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	r.closers = nil
+	return err
+}
+
+// FileWriterOptions holds the options for CreateFile and NewFileWriter.
+type FileWriterOptions struct {
+	// Format is FileFormatPcap, FileFormatPcapNg or FileFormatSnoop.
+	// FileFormatUnknown selects pcap.
+	Format FileFormat
+	// Nanoseconds writes pcap files with nanosecond timestamps. pcapng
+	// files always use nanosecond timestamps, snoop files microsecond
+	// timestamps.
+	Nanoseconds bool
+	// Snaplen is the snapshot length written to the pcap file header, or
+	// to the pcapng interface. Zero means 65536 for pcap and unlimited for
+	// pcapng. It is ignored for snoop.
+	Snaplen uint32
+	// Gzip compresses the output on the fly.
+	Gzip bool
+}
+
+// FileWriter writes packets to a pcap, pcapng or snoop file, optionally gzip
+// compressed. Close must be called to flush the buffered data and
+// finish the compressed stream.
+type FileWriter struct {
+	pcap   *Writer
+	ng     *NgWriter
+	snoop  *SnoopWriter
+	buf    *bufio.Writer
+	gz     *gzip.Writer
+	file   *os.File
+	closed bool
+}
+
+// CreateFile creates or truncates the named file and writes the file
+// header for the given link type. If options.Gzip is false, a path
+// ending in ".gz" still selects gzip compression.
+//
+//	w, err := pcapgo.CreateFile("/tmp/file.pcapng.gz", layers.LinkTypeEthernet,
+//		pcapgo.FileWriterOptions{Format: pcapgo.FileFormatPcapNg})
+//	if err != nil {
+//		...
+//	}
+//	defer w.Close()
+//	err = w.WritePacket(ci, data)
+func CreateFile(path string, linkType layers.LinkType, options FileWriterOptions) (*FileWriter, error) {
+	if strings.HasSuffix(path, ".gz") {
+		options.Gzip = true
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w, err := NewFileWriter(f, linkType, options)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	w.file = f
+	return w, nil
+}
+
+// NewFileWriter is like CreateFile, writing the capture to w. Closing the
+// returned FileWriter doesn't close w.
+func NewFileWriter(w io.Writer, linkType layers.LinkType, options FileWriterOptions) (*FileWriter, error) {
+	ret := &FileWriter{}
+	if options.Gzip {
+		ret.gz = gzip.NewWriter(w)
+		w = ret.gz
+	}
+	switch options.Format {
+	case FileFormatUnknown, FileFormatPcap:
+		ret.buf = bufio.NewWriter(w)
+		if options.Nanoseconds {
+			ret.pcap = NewWriterNanos(ret.buf)
+		} else {
+			ret.pcap = NewWriter(ret.buf)
+		}
+		snaplen := options.Snaplen
+		if snaplen == 0 {
+			snaplen = 65536
+		}
+		if err := ret.pcap.WriteFileHeader(snaplen, linkType); err != nil {
+			return nil, err
+		}
+	case FileFormatPcapNg:
+		intf := DefaultNgInterface
+		intf.LinkType = linkType
+		intf.SnapLength = options.Snaplen
+		ng, err := NewNgWriterInterface(w, intf, DefaultNgWriterOptions)
+		if err != nil {
+			return nil, err
+		}
+		ret.ng = ng
+	case FileFormatSnoop:
+		ret.buf = bufio.NewWriter(w)
+		ret.snoop = NewSnoopWriter(ret.buf)
+		if err := ret.snoop.WriteFileHeader(linkType); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("writing %s files is not supported", options.Format)
+	}
+	return ret, nil
+}
+
+// WritePacket writes the given packet. The interface index of ci is
+// ignored, pcapng files have a single interface.
+func (w *FileWriter) WritePacket(ci gopacket.CaptureInfo, data []byte) error {
+	if w.ng != nil {
+		ci.InterfaceIndex = 0
+		return w.ng.WritePacket(ci, data)
+	}
+	if w.snoop != nil {
+		return w.snoop.WritePacket(ci, data)
+	}
+	return w.pcap.WritePacket(ci, data)
+}
+
+// Flush writes the buffered data to the underlying writer. With gzip
+// compression, the data written so far can be decompressed afterwards,
+// at the cost of a worse compression ratio.
+func (w *FileWriter) Flush() error {
+	var err error
+	if w.ng != nil {
+		err = w.ng.Flush()
+	} else {
+		err = w.buf.Flush()
+	}
+	if err == nil && w.gz != nil {
+		err = w.gz.Flush()
+	}
+	return err
+}
+
+// Close flushes the buffered data, finishes the gzip stream and, if the
+// FileWriter was returned by CreateFile, closes the file.
+func (w *FileWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	var err error
+	if w.ng != nil {
+		err = w.ng.Flush()
+	} else {
+		err = w.buf.Flush()
+	}
+	if w.gz != nil {
+		if gerr := w.gz.Close(); err == nil {
+			err = gerr
+		}
+	}
+	if w.file != nil {
+		if ferr := w.file.Close(); err == nil {
+			err = ferr
+		}
+	}
+	return err
+}
@@ -0,0 +1,203 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package pcapgo
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestFileRoundTrip(t *testing.T) {
+	ci := gopacket.CaptureInfo{
+		Timestamp:     time.Unix(0x01020304, 123456789).UTC(),
+		CaptureLength: 5,
+		Length:        10,
+	}
+	data := []byte{1, 2, 3, 4, 5}
+
+	for _, test := range []struct {
+		name       string
+		options    FileWriterOptions
+		format     FileFormat
+		compressed bool
+		resolution gopacket.TimestampResolution
+	}{
+		{"a.pcap", FileWriterOptions{}, FileFormatPcap, false, gopacket.TimestampResolutionMicrosecond},
+		{"b.pcap", FileWriterOptions{Nanoseconds: true, Gzip: true}, FileFormatPcap, true, gopacket.TimestampResolutionNanosecond},
+		{"c.pcap.gz", FileWriterOptions{}, FileFormatPcap, true, gopacket.TimestampResolutionMicrosecond},
+		{"d.pcapng", FileWriterOptions{Format: FileFormatPcapNg}, FileFormatPcapNg, false, gopacket.TimestampResolutionNanosecond},
+		{"e.pcapng.gz", FileWriterOptions{Format: FileFormatPcapNg}, FileFormatPcapNg, true, gopacket.TimestampResolutionNanosecond},
+	} {
+		path := filepath.Join(t.TempDir(), test.name)
+		w, err := CreateFile(path, layers.LinkTypeRaw, test.options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 3; i++ {
+			if err := w.WritePacket(ci, data); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		r, err := OpenFile(path)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if r.Format() != test.format || r.Compressed() != test.compressed {
+			t.Errorf("%s: got format %s compressed %v, want %s %v", test.name, r.Format(), r.Compressed(), test.format, test.compressed)
+		}
+		if r.LinkType() != layers.LinkTypeRaw {
+			t.Errorf("%s: got link type %s", test.name, r.LinkType())
+		}
+		if r.Resolution() != test.resolution {
+			t.Errorf("%s: got resolution %s, want %s", test.name, r.Resolution(), test.resolution)
+		}
+		want := ci
+		want.Timestamp = ci.Timestamp.Truncate(test.resolution.ToDuration())
+		for i := 0; i < 3; i++ {
+			got, gotCI, err := r.ReadPacketData()
+			if err != nil {
+				t.Fatalf("%s: packet %d: %v", test.name, i, err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("%s: packet %d: got data %v", test.name, i, got)
+			}
+			if !gotCI.Timestamp.Equal(want.Timestamp) || gotCI.CaptureLength != want.CaptureLength || gotCI.Length != want.Length {
+				t.Errorf("%s: packet %d: got %+v, want %+v", test.name, i, gotCI, want)
+			}
+		}
+		if _, _, err := r.ReadPacketData(); err != io.EOF {
+			t.Errorf("%s: got %v at end of file, want EOF", test.name, err)
+		}
+		if err := r.Close(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestFileReaderFormats(t *testing.T) {
+	gzipped := func(b []byte) []byte {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		gz.Write(b)
+		gz.Close()
+		return buf.Bytes()
+	}
+	zstded := func(b []byte) []byte {
+		zw, err := zstd.NewWriter(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer zw.Close()
+		return zw.EncodeAll(b, nil)
+	}
+	snoop := append(append([]byte{}, spHeader...), pack...)
+	// big endian pcap with microsecond timestamps
+	pcapBE := []byte{
+		0xa1, 0xb2, 0xc3, 0xd4, 0x00, 0x02, 0x00, 0x04,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0xff, 0xff, 0x00, 0x00, 0x00, 0x01,
+	}
+
+	for _, test := range []struct {
+		name       string
+		data       []byte
+		format     FileFormat
+		linkType   layers.LinkType
+		resolution gopacket.TimestampResolution
+		compressed bool
+		packets    int
+	}{
+		{"snoop", snoop, FileFormatSnoop, layers.LinkTypeEthernet, gopacket.TimestampResolutionMicrosecond, false, 1},
+		{"snoop.gz", gzipped(snoop), FileFormatSnoop, layers.LinkTypeEthernet, gopacket.TimestampResolutionMicrosecond, true, 1},
+		{"snoop.zst", zstded(snoop), FileFormatSnoop, layers.LinkTypeEthernet, gopacket.TimestampResolutionMicrosecond, true, 1},
+		{"pcap big endian", pcapBE, FileFormatPcap, layers.LinkTypeEthernet, gopacket.TimestampResolutionMicrosecond, false, 0},
+		{"pcap big endian.zst", zstded(pcapBE), FileFormatPcap, layers.LinkTypeEthernet, gopacket.TimestampResolutionMicrosecond, true, 0},
+	} {
+		r, err := NewFileReader(bytes.NewReader(test.data))
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if r.Format() != test.format || r.LinkType() != test.linkType || r.Resolution() != test.resolution || r.Compressed() != test.compressed {
+			t.Errorf("%s: got %s %s %s compressed %v", test.name, r.Format(), r.LinkType(), r.Resolution(), r.Compressed())
+		}
+		n := 0
+		for ; ; n++ {
+			if _, _, err := r.ZeroCopyReadPacketData(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s: %v", test.name, err)
+			}
+		}
+		if n != test.packets {
+			t.Errorf("%s: got %d packets, want %d", test.name, n, test.packets)
+		}
+		if err := r.Close(); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		}
+	}
+
+	for _, data := range [][]byte{
+		{},
+		// a zstandard frame with a reserved bit set
+		{0x28, 0xb5, 0x2f, 0xfd, 0x08, 0, 0, 0},
+		[]byte("not a capture file"),
+	} {
+		if _, err := NewFileReader(bytes.NewReader(data)); err == nil {
+			t.Errorf("%x: expected an error", data)
+		}
+	}
+}
+
+func TestOpenFileTestdata(t *testing.T) {
+	r, err := OpenFile("tests/be/test001.pcapng")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if r.Format() != FileFormatPcapNg {
+		t.Errorf("got format %s", r.Format())
+	}
+}
+
+func TestFileSnoop(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewFileWriter(&buf, layers.LinkTypeEthernet, FileWriterOptions{Format: FileFormatSnoop, Gzip: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ci := gopacket.CaptureInfo{Timestamp: time.Unix(1700000000, 0).UTC(), CaptureLength: 3, Length: 3}
+	if err := w.WritePacket(ci, []byte{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewFileReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Format() != FileFormatSnoop || !r.Compressed() {
+		t.Errorf("got format %s compressed %v", r.Format(), r.Compressed())
+	}
+	data, gotCI, err := r.ReadPacketData()
+	if err != nil || !bytes.Equal(data, []byte{1, 2, 3}) || !gotCI.Timestamp.Equal(ci.Timestamp) {
+		t.Errorf("got %v %+v %v", data, gotCI, err)
+	}
+}
@@ -0,0 +1,642 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package pcapgo
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+)
+
+// ErrIndexMismatch is returned by NewIndexedReader if the given index
+// was not built for the given capture file.
+var ErrIndexMismatch = errors.New("packet index doesn't match the capture file")
+
+const (
+	indexMagic   = "GPIX"
+	indexVersion = 1
+)
+
+// PacketIndex holds the position and timestamp of every packet of a pcap
+// or pcapng file, allowing random access with an IndexedReader. An index
+// can be saved with WriteTo and loaded with ReadPacketIndex, so that a
+// capture file needs to be scanned only once.
+type PacketIndex struct {
+	format   FileFormat
+	size     int64
+	packets  []indexEntry
+	sections []indexSection
+	// sorted is set if the packet timestamps never decrease. Otherwise
+	// maxIdx holds, for every packet, the packet with the latest
+	// timestamp up to it.
+	sorted bool
+	maxIdx []int
+}
+
+type indexEntry struct {
+	offset int64
+	sec    int64
+	nsec   int32
+}
+
+func (e indexEntry) before(o indexEntry) bool {
+	return e.sec < o.sec || e.sec == o.sec && e.nsec < o.nsec
+}
+
+// indexSection is a pcapng section.
+type indexSection struct {
+	offset int64
+	// first is the number of the first packet in the section.
+	first int
+	// interfaces holds the offsets of the interface description blocks.
+	interfaces []int64
+}
+
+// Len returns the number of packets in the index.
+func (ix *PacketIndex) Len() int {
+	return len(ix.packets)
+}
+
+// Offset returns the offset in the file of the record or block holding
+// packet i.
+func (ix *PacketIndex) Offset(i int) int64 {
+	return ix.packets[i].offset
+}
+
+// Timestamp returns the timestamp of packet i.
+func (ix *PacketIndex) Timestamp(i int) time.Time {
+	return time.Unix(ix.packets[i].sec, int64(ix.packets[i].nsec)).UTC()
+}
+
+func (ix *PacketIndex) add(offset int64, ts time.Time) {
+	e := indexEntry{offset: offset, sec: ts.Unix(), nsec: int32(ts.Nanosecond())}
+	if n := len(ix.packets); n > 0 && e.before(ix.packets[n-1]) {
+		ix.sorted = false
+	}
+	ix.packets = append(ix.packets, e)
+}
+
+// search returns the first packet with a timestamp not before t.
+func (ix *PacketIndex) search(t time.Time) int {
+	e := indexEntry{sec: t.Unix(), nsec: int32(t.Nanosecond())}
+	if ix.sorted {
+		return sort.Search(len(ix.packets), func(i int) bool { return !ix.packets[i].before(e) })
+	}
+	if ix.maxIdx == nil {
+		ix.maxIdx = make([]int, len(ix.packets))
+		max := 0
+		for i := range ix.packets {
+			if ix.packets[max].before(ix.packets[i]) {
+				max = i
+			}
+			ix.maxIdx[i] = max
+		}
+	}
+	// The latest timestamp up to a packet never decreases, and the first
+	// packet where it isn't before t is the first packet not before t.
+	return sort.Search(len(ix.packets), func(i int) bool { return !ix.packets[ix.maxIdx[i]].before(e) })
+}
+
+// section returns the pcapng section holding packet i.
+func (ix *PacketIndex) section(i int) int {
+	return sort.Search(len(ix.sections), func(s int) bool { return ix.sections[s].first > i }) - 1
+}
+
+// WriteTo writes the index to w, in a compact binary format that can be
+// read back with ReadPacketIndex. It implements io.WriterTo.
+func (ix *PacketIndex) WriteTo(w io.Writer) (n int64, err error) {
+	bw := bufio.NewWriter(w)
+	buf := make([]byte, 0, 64)
+	flush := func() {
+		if err == nil {
+			var nn int
+			nn, err = bw.Write(buf)
+			n += int64(nn)
+		}
+		buf = buf[:0]
+	}
+
+	buf = append(buf, indexMagic...)
+	buf = append(buf, indexVersion, byte(ix.format))
+	buf = binary.AppendUvarint(buf, uint64(ix.size))
+	buf = binary.AppendUvarint(buf, uint64(len(ix.sections)))
+	for _, s := range ix.sections {
+		buf = binary.AppendUvarint(buf, uint64(s.offset))
+		buf = binary.AppendUvarint(buf, uint64(s.first))
+		buf = binary.AppendUvarint(buf, uint64(len(s.interfaces)))
+		for _, o := range s.interfaces {
+			buf = binary.AppendUvarint(buf, uint64(o))
+		}
+		flush()
+	}
+	buf = binary.AppendUvarint(buf, uint64(len(ix.packets)))
+	var last indexEntry
+	for _, e := range ix.packets {
+		// offsets always increase, timestamps usually do
+		buf = binary.AppendUvarint(buf, uint64(e.offset-last.offset))
+		buf = binary.AppendVarint(buf, e.sec-last.sec)
+		buf = binary.AppendUvarint(buf, uint64(e.nsec))
+		last = e
+		if len(buf) > 32 {
+			flush()
+		}
+	}
+	flush()
+	if err == nil {
+		err = bw.Flush()
+	}
+	return n, err
+}
+
+// ReadPacketIndex reads an index written by PacketIndex.WriteTo.
+func ReadPacketIndex(r io.Reader) (*PacketIndex, error) {
+	br := bufio.NewReader(r)
+	var hdr [6]byte
+	if _, err := io.ReadFull(br, hdr[:]); err != nil {
+		return nil, err
+	}
+	if string(hdr[:4]) != indexMagic {
+		return nil, errors.New("not a packet index")
+	}
+	if hdr[4] != indexVersion {
+		return nil, fmt.Errorf("unknown packet index version %d", hdr[4])
+	}
+	ix := &PacketIndex{format: FileFormat(hdr[5]), sorted: true}
+
+	var err error
+	read := func() uint64 {
+		var v uint64
+		if err == nil {
+			v, err = binary.ReadUvarint(br)
+		}
+		return v
+	}
+	// capacity hints are capped, the counts aren't trusted
+	capped := func(n uint64) int {
+		if n > 1<<16 {
+			return 1 << 16
+		}
+		return int(n)
+	}
+
+	ix.size = int64(read())
+	nsections := read()
+	ix.sections = make([]indexSection, 0, capped(nsections))
+	for i := uint64(0); i < nsections && err == nil; i++ {
+		s := indexSection{offset: int64(read()), first: int(read())}
+		nintf := read()
+		s.interfaces = make([]int64, 0, capped(nintf))
+		for j := uint64(0); j < nintf && err == nil; j++ {
+			s.interfaces = append(s.interfaces, int64(read()))
+		}
+		ix.sections = append(ix.sections, s)
+	}
+	npackets := read()
+	ix.packets = make([]indexEntry, 0, capped(npackets))
+	var last indexEntry
+	for i := uint64(0); i < npackets && err == nil; i++ {
+		var e indexEntry
+		e.offset = last.offset + int64(read())
+		if err == nil {
+			var d int64
+			d, err = binary.ReadVarint(br)
+			e.sec = last.sec + d
+		}
+		e.nsec = int32(read())
+		if err == nil && i > 0 && e.before(last) {
+			ix.sorted = false
+		}
+		ix.packets = append(ix.packets, e)
+		last = e
+	}
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return ix, nil
+}
+
+// IndexedReader reads packets from a pcap or pcapng file in any order. It
+// indexes the position of every packet, after which packets can be read
+// forwards or backwards from any packet number or timestamp.
+//
+// Like a cursor, the reader is positioned between packets: ReadPacketData
+// returns the packet after the position and ReadPreviousPacketData the
+// packet before it.
+//
+// All the packets of pcapng files are read, whatever their link type. As
+// with NgReaderOptions.WantMixedLinkType, ci.AncillaryData[0] contains the
+// link type of pcapng packets. Compressed files are not seekable, and so
+// can't be read by an IndexedReader.
+type IndexedReader struct {
+	rs    io.ReadSeeker
+	br    *bufio.Reader
+	index *PacketIndex
+	pcap  *Reader
+	ng    *NgReader
+	// section is the pcapng section whose interfaces are loaded in ng,
+	// or -1.
+	section int
+	// next is the position, at is the packet br is positioned at or -1.
+	next       int
+	at         int
+	linkType   layers.LinkType
+	resolution gopacket.TimestampResolution
+}
+
+// NewIndexedReader returns a reader for the pcap or pcapng file rs. If
+// index is nil, the whole file is scanned to build it. Otherwise the index
+// must have been built for that file, or ErrIndexMismatch is returned.
+//
+// A truncated last packet, as found in captures still being written, is
+// left out of the index.
+//
+//	f, _ := os.Open("/tmp/file.pcapng")
+//	defer f.Close()
+//	r, err := NewIndexedReader(f, nil)
+//	r.SeekTime(t)
+//	data, ci, err := r.ReadPacketData()
+func NewIndexedReader(rs io.ReadSeeker, index *PacketIndex) (*IndexedReader, error) {
+	size, err := rs.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	ret := &IndexedReader{
+		rs:         rs,
+		br:         bufio.NewReader(rs),
+		section:    -1,
+		at:         -1,
+		resolution: gopacket.TimestampResolutionNanosecond,
+	}
+	magic, err := ret.br.Peek(4)
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	var format FileFormat
+	switch m := binary.LittleEndian.Uint32(magic); {
+	case magic[0] == magicGzip1 && magic[1] == magicGzip2, m == magicZstd:
+		return nil, errors.New("compressed capture files can't be indexed")
+	case m == magicMicroseconds || m == magicNanoseconds ||
+		m == magicMicrosecondsBigendian || m == magicNanosecondsBigendian:
+		if ret.pcap, err = NewReader(ret.br); err != nil {
+			return nil, err
+		}
+		ret.pcap.r = ret.br
+		format = FileFormatPcap
+		ret.linkType, ret.resolution = ret.pcap.LinkType(), ret.pcap.Resolution()
+	case ngBlockType(m) == ngBlockTypeSectionHeader:
+		if ret.ng, err = NewNgReader(ret.br, NgReaderOptions{WantMixedLinkType: true}); err != nil {
+			return nil, err
+		}
+		ret.ng.r = ret.br
+		format = FileFormatPcapNg
+	default:
+		return nil, ErrUnknownFileFormat
+	}
+
+	if index == nil {
+		index = &PacketIndex{format: format, size: size, sorted: true}
+		if format == FileFormatPcap {
+			err = ret.indexPcap(index)
+		} else {
+			err = ret.indexNg(index)
+		}
+		if err != nil {
+			return nil, err
+		}
+	} else if index.format != format || index.size != size {
+		return nil, ErrIndexMismatch
+	}
+	ret.index = index
+
+	if ret.ng != nil && len(index.sections) > 0 && len(index.sections[0].interfaces) > 0 {
+		if err := ret.loadInterfaces(0, 1); err != nil {
+			return nil, err
+		}
+		ret.linkType = ret.ng.ifaces[0].LinkType
+		ret.resolution = ret.ng.ifaces[0].Resolution()
+	}
+	return ret, nil
+}
+
+// isEOF reports whether err is caused by the end of the file.
+func isEOF(err error) bool {
+	return err == io.EOF || err == io.ErrUnexpectedEOF
+}
+
+// indexPcap scans the packet records following the pcap file header.
+func (r *IndexedReader) indexPcap(ix *PacketIndex) error {
+	offset := int64(24)
+	for {
+		ci, err := r.pcap.readPacketHeader()
+		if isEOF(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if _, err := r.br.Discard(ci.CaptureLength); isEOF(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		ix.add(offset, ci.Timestamp)
+		offset += 16 + int64(ci.CaptureLength)
+	}
+}
+
+// indexNg scans the blocks of a pcapng file, recording the sections,
+// interfaces and packets. NewNgReader already read the first section
+// header.
+func (r *IndexedReader) indexNg(ix *PacketIndex) error {
+	if _, err := r.rs.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	r.br.Reset(r.rs)
+	ng := r.ng
+	var offset int64
+	for {
+		if err := ng.readBlock(); isEOF(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		length := int64(ng.currentBlock.length) + 8
+		switch ng.currentBlock.typ {
+		case ngBlockTypeSectionHeader:
+			length += 4
+			if err := ng.readSectionHeader(); isEOF(err) {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			ix.sections = append(ix.sections, indexSection{offset: offset, first: len(ix.packets)})
+			offset += length
+			continue
+		case ngBlockTypeInterfaceDescriptor:
+			if err := ng.readInterfaceDescriptor(); isEOF(err) {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			s := &ix.sections[len(ix.sections)-1]
+			s.interfaces = append(s.interfaces, offset)
+			offset += length
+			continue
+		case ngBlockTypeEnhancedPacket, ngBlockTypePacket:
+			if err := ng.readBytes(ng.buf[:20]); isEOF(err) {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			ng.currentBlock.length -= 20
+			intf := int(ng.getUint32(ng.buf[:4]))
+			if ng.currentBlock.typ == ngBlockTypePacket {
+				intf = int(ng.getUint16(ng.buf[:2]))
+			}
+			if intf >= len(ng.ifaces) {
+				return fmt.Errorf("Interface id %d not present in section (have only %d interfaces)", intf, len(ng.ifaces))
+			}
+			ts := time.Unix(ng.convertTime(intf, uint64(ng.getUint32(ng.buf[4:8]))<<32|uint64(ng.getUint32(ng.buf[8:12]))))
+			if _, err := ng.r.Discard(int(ng.currentBlock.length)); isEOF(err) {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			ix.add(offset, ts)
+		case ngBlockTypeSimplePacket:
+			if len(ng.ifaces) == 0 {
+				return errors.New("At least one Interface is needed for a packet")
+			}
+			if _, err := ng.r.Discard(int(ng.currentBlock.length)); isEOF(err) {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			ix.add(offset, time.Time{})
+		default:
+			if _, err := ng.r.Discard(int(ng.currentBlock.length)); isEOF(err) {
+				return nil
+			} else if err != nil {
+				return err
+			}
+		}
+		offset += length
+	}
+}
+
+func (r *IndexedReader) seek(offset int64) error {
+	if _, err := r.rs.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	r.br.Reset(r.rs)
+	return nil
+}
+
+// loadInterfaces loads section s into the NgReader, with its first n
+// interfaces.
+func (r *IndexedReader) loadInterfaces(s, n int) error {
+	ng := r.ng
+	section := r.index.sections[s]
+	if r.section != s {
+		r.section = -1
+		if err := r.seek(section.offset); err != nil {
+			return err
+		}
+		if err := ng.readBlock(); err != nil {
+			return err
+		}
+		if ng.currentBlock.typ != ngBlockTypeSectionHeader {
+			return ErrIndexMismatch
+		}
+		if err := ng.readSectionHeader(); err != nil {
+			return err
+		}
+		r.section = s
+	}
+	// Interfaces may follow packets, and are appended again while reading
+	// on. The ones before a packet are always the same.
+	if len(ng.ifaces) > n {
+		ng.ifaces = ng.ifaces[:n]
+	}
+	for i := len(ng.ifaces); i < n; i++ {
+		if err := r.seek(section.interfaces[i]); err != nil {
+			return err
+		}
+		if err := ng.readBlock(); err != nil {
+			return err
+		}
+		if ng.currentBlock.typ != ngBlockTypeInterfaceDescriptor {
+			return ErrIndexMismatch
+		}
+		if err := ng.readInterfaceDescriptor(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// seekPacket positions the underlying reader at packet i.
+func (r *IndexedReader) seekPacket(i int) error {
+	offset := r.index.packets[i].offset
+	if r.ng != nil {
+		s := r.index.section(i)
+		if s < 0 {
+			return ErrIndexMismatch
+		}
+		intfs := r.index.sections[s].interfaces
+		n := sort.Search(len(intfs), func(j int) bool { return intfs[j] > offset })
+		if err := r.loadInterfaces(s, n); err != nil {
+			return err
+		}
+	}
+	if err := r.seek(offset); err != nil {
+		return err
+	}
+	r.at = i
+	return nil
+}
+
+func (r *IndexedReader) readPacket(i int, zeroCopy bool) (data []byte, ci gopacket.CaptureInfo, err error) {
+	if r.at != i {
+		if err = r.seekPacket(i); err != nil {
+			r.at = -1
+			return
+		}
+	}
+	switch {
+	case r.pcap != nil && zeroCopy:
+		data, ci, err = r.pcap.ZeroCopyReadPacketData()
+	case r.pcap != nil:
+		data, ci, err = r.pcap.ReadPacketData()
+	case zeroCopy:
+		data, ci, err = r.ng.ZeroCopyReadPacketData()
+	default:
+		data, ci, err = r.ng.ReadPacketData()
+	}
+	if err != nil {
+		r.at = -1
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return
+	}
+	r.at = i + 1
+	if r.ng != nil {
+		// sections were crossed by the NgReader
+		for r.section+1 < len(r.index.sections) && r.index.sections[r.section+1].first <= i {
+			r.section++
+		}
+	}
+	return
+}
+
+// ReadPacketData reads the packet after the current position, and moves
+// the position past it. io.EOF is returned after the last packet.
+func (r *IndexedReader) ReadPacketData() (data []byte, ci gopacket.CaptureInfo, err error) {
+	if r.next >= r.index.Len() {
+		return nil, ci, io.EOF
+	}
+	if data, ci, err = r.readPacket(r.next, false); err == nil {
+		r.next++
+	}
+	return
+}
+
+// ZeroCopyReadPacketData is like ReadPacketData, but the data buffer is
+// owned by the IndexedReader and each call invalidates the data returned
+// by the previous one.
+func (r *IndexedReader) ZeroCopyReadPacketData() (data []byte, ci gopacket.CaptureInfo, err error) {
+	if r.next >= r.index.Len() {
+		return nil, ci, io.EOF
+	}
+	if data, ci, err = r.readPacket(r.next, true); err == nil {
+		r.next++
+	}
+	return
+}
+
+// ReadPreviousPacketData reads the packet before the current position,
+// and moves the position before it. Calling it repeatedly reads the file
+// in reverse. io.EOF is returned after the first packet.
+func (r *IndexedReader) ReadPreviousPacketData() (data []byte, ci gopacket.CaptureInfo, err error) {
+	if r.next <= 0 {
+		return nil, ci, io.EOF
+	}
+	if data, ci, err = r.readPacket(r.next-1, false); err == nil {
+		r.next--
+	}
+	return
+}
+
+// SeekPacket sets the position before packet n, numbered from 0. Seeking
+// to Len() positions the reader at the end of the file.
+func (r *IndexedReader) SeekPacket(n int) error {
+	if n < 0 || n > r.index.Len() {
+		return fmt.Errorf("packet %d out of range [0, %d]", n, r.index.Len())
+	}
+	r.next = n
+	return nil
+}
+
+// SeekTime sets the position before the first packet with a timestamp
+// not before t, and returns its number. If there is none, the reader is
+// positioned at the end of the file and Len() is returned.
+func (r *IndexedReader) SeekTime(t time.Time) int {
+	r.next = r.index.search(t)
+	return r.next
+}
+
+// Position returns the number of the packet read by the next call to
+// ReadPacketData.
+func (r *IndexedReader) Position() int {
+	return r.next
+}
+
+// Len returns the number of packets in the file.
+func (r *IndexedReader) Len() int {
+	return r.index.Len()
+}
+
+// Index returns the packet index of the file, e.g. to save it with
+// PacketIndex.WriteTo.
+func (r *IndexedReader) Index() *PacketIndex {
+	return r.index
+}
+
+// Format returns the format of the file, FileFormatPcap or
+// FileFormatPcapNg.
+func (r *IndexedReader) Format() FileFormat {
+	return r.index.format
+}
+
+// LinkType returns the link type of the file. For pcapng files this is
+// the link type of the first interface.
+func (r *IndexedReader) LinkType() layers.LinkType {
+	return r.linkType
+}
+
+// Resolution returns the timestamp resolution of the file. For pcapng
+// files this is the resolution of the first interface.
+func (r *IndexedReader) Resolution() gopacket.TimestampResolution {
+	return r.resolution
+}
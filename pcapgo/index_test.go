@@ -0,0 +1,265 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package pcapgo
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+)
+
+type indexTestPacket struct {
+	data []byte
+	ci   gopacket.CaptureInfo
+}
+
+func checkIndexedPacket(t *testing.T, name string, i int, want indexTestPacket, data []byte, ci gopacket.CaptureInfo, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("%s: packet %d: %v", name, i, err)
+	}
+	if !bytes.Equal(data, want.data) || !reflect.DeepEqual(ci, want.ci) {
+		t.Errorf("%s: packet %d:\ngot  %v %+v\nwant %v %+v", name, i, data, ci, want.data, want.ci)
+	}
+}
+
+// testIndexedReader checks random access against the sequential reading
+// of the file.
+func testIndexedReader(t *testing.T, name string, r *IndexedReader, want []indexTestPacket) {
+	t.Helper()
+	if r.Len() != len(want) {
+		t.Fatalf("%s: got %d packets, want %d", name, r.Len(), len(want))
+	}
+	for i := range want {
+		data, ci, err := r.ReadPacketData()
+		checkIndexedPacket(t, name, i, want[i], data, ci, err)
+	}
+	if _, _, err := r.ReadPacketData(); err != io.EOF {
+		t.Errorf("%s: got %v after the last packet, want EOF", name, err)
+	}
+	for i := len(want) - 1; i >= 0; i-- {
+		data, ci, err := r.ReadPreviousPacketData()
+		checkIndexedPacket(t, name, i, want[i], data, ci, err)
+	}
+	if _, _, err := r.ReadPreviousPacketData(); err != io.EOF {
+		t.Errorf("%s: got %v before the first packet, want EOF", name, err)
+	}
+	for _, i := range []int{len(want) / 2, len(want) - 1, 0, len(want) / 3} {
+		if i < 0 {
+			continue
+		}
+		if err := r.SeekPacket(i); err != nil {
+			t.Fatal(err)
+		}
+		data, ci, err := r.ZeroCopyReadPacketData()
+		checkIndexedPacket(t, name, i, want[i], data, ci, err)
+		if r.Position() != i+1 {
+			t.Errorf("%s: got position %d after reading packet %d", name, r.Position(), i)
+		}
+	}
+}
+
+func TestIndexedReaderPcap(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriterNanos(&buf)
+	w.WriteFileHeader(65536, layers.LinkTypeEthernet)
+	base := time.Unix(1700000000, 0).UTC()
+	var want []indexTestPacket
+	for i := 0; i < 20; i++ {
+		p := indexTestPacket{
+			data: bytes.Repeat([]byte{byte(i)}, i+1),
+			ci: gopacket.CaptureInfo{
+				Timestamp:     base.Add(time.Duration(i) * time.Second),
+				CaptureLength: i + 1,
+				Length:        i + 1,
+			},
+		}
+		// one packet out of order
+		if i == 10 {
+			p.ci.Timestamp = base.Add(-time.Second)
+		}
+		if err := w.WritePacket(p.ci, p.data); err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, p)
+	}
+	file := buf.Bytes()
+
+	r, err := NewIndexedReader(bytes.NewReader(file), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Format() != FileFormatPcap || r.LinkType() != layers.LinkTypeEthernet || r.Resolution() != gopacket.TimestampResolutionNanosecond {
+		t.Errorf("got %s %s %s", r.Format(), r.LinkType(), r.Resolution())
+	}
+	testIndexedReader(t, "pcap", r, want)
+
+	for _, test := range []struct {
+		t    time.Time
+		want int
+	}{
+		{base.Add(-time.Hour), 0},
+		{base, 0},
+		{base.Add(1500 * time.Millisecond), 2},
+		{base.Add(11 * time.Second), 11},
+		{base.Add(time.Hour), 20},
+	} {
+		if got := r.SeekTime(test.t); got != test.want {
+			t.Errorf("SeekTime(%s): got %d, want %d", test.t, got, test.want)
+		}
+	}
+
+	// save and restore the index
+	var saved bytes.Buffer
+	if _, err := r.Index().WriteTo(&saved); err != nil {
+		t.Fatal(err)
+	}
+	index, err := ReadPacketIndex(&saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(index.packets, r.Index().packets) || index.sorted {
+		t.Errorf("restored index differs")
+	}
+	r, err = NewIndexedReader(bytes.NewReader(file), index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testIndexedReader(t, "pcap restored", r, want)
+
+	// a truncated last packet isn't indexed, and a truncated file doesn't
+	// match the index anymore
+	truncated := file[:len(file)-5]
+	if _, err := NewIndexedReader(bytes.NewReader(truncated), index); err != ErrIndexMismatch {
+		t.Errorf("got %v for a mismatching index", err)
+	}
+	r, err = NewIndexedReader(bytes.NewReader(truncated), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testIndexedReader(t, "pcap truncated", r, want[:19])
+}
+
+func TestIndexedReaderPcapNg(t *testing.T) {
+	files, err := filepath.Glob("tests/*/*.pcapng")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tested := 0
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ng, err := NewNgReader(bytes.NewReader(content), NgReaderOptions{WantMixedLinkType: true})
+		if err != nil {
+			continue
+		}
+		var want []indexTestPacket
+		for {
+			data, ci, err := ng.ReadPacketData()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				want = nil
+				break
+			}
+			want = append(want, indexTestPacket{data, ci})
+		}
+		if want == nil {
+			// not readable by NgReader, or no packets
+			continue
+		}
+		tested++
+
+		r, err := NewIndexedReader(bytes.NewReader(content), nil)
+		if err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		testIndexedReader(t, file, r, want)
+
+		var saved bytes.Buffer
+		if _, err := r.Index().WriteTo(&saved); err != nil {
+			t.Fatal(err)
+		}
+		index, err := ReadPacketIndex(&saved)
+		if err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		r, err = NewIndexedReader(bytes.NewReader(content), index)
+		if err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		testIndexedReader(t, file+" restored", r, want)
+	}
+	if tested == 0 {
+		t.Fatal("no pcapng files tested")
+	}
+}
+
+func TestIndexedReaderPcapNgInterfaces(t *testing.T) {
+	// interfaces with different link types, one added after packets
+	var buf bytes.Buffer
+	w, err := NewNgWriter(&buf, layers.LinkTypeEthernet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := time.Unix(1700000000, 123456789).UTC()
+	var want []indexTestPacket
+	write := func(intf int) {
+		ci := gopacket.CaptureInfo{Timestamp: ts, CaptureLength: 3, Length: 3, InterfaceIndex: intf}
+		data := []byte{byte(len(want)), byte(intf), 0xff}
+		if err := w.WritePacket(ci, data); err != nil {
+			t.Fatal(err)
+		}
+		ci.AncillaryData = []interface{}{layers.LinkTypeEthernet}
+		if intf == 1 {
+			ci.AncillaryData[0] = layers.LinkTypeRaw
+		}
+		want = append(want, indexTestPacket{data, ci})
+		ts = ts.Add(time.Millisecond)
+	}
+	write(0)
+	write(0)
+	if _, err := w.AddInterface(NgInterface{LinkType: layers.LinkTypeRaw}); err != nil {
+		t.Fatal(err)
+	}
+	write(1)
+	write(0)
+	write(1)
+	w.Flush()
+
+	r, err := NewIndexedReader(bytes.NewReader(buf.Bytes()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testIndexedReader(t, "interfaces", r, want)
+	if got := r.SeekTime(ts.Add(-2 * time.Millisecond)); got != 3 {
+		t.Errorf("SeekTime: got %d, want 3", got)
+	}
+}
+
+func TestIndexedReaderErrors(t *testing.T) {
+	if _, err := NewIndexedReader(bytes.NewReader([]byte("not a capture file")), nil); err != ErrUnknownFileFormat {
+		t.Errorf("got %v for an unknown format", err)
+	}
+	for _, magic := range [][]byte{{0x1f, 0x8b, 8, 0}, {0x28, 0xb5, 0x2f, 0xfd}} {
+		if _, err := NewIndexedReader(bytes.NewReader(magic), nil); err == nil || err == ErrUnknownFileFormat {
+			t.Errorf("%x: got %v, expected an error for a compressed file", magic, err)
+		}
+	}
+	if _, err := ReadPacketIndex(bytes.NewReader([]byte("GPIX\x01\x01\x80"))); err != io.ErrUnexpectedEOF {
+		t.Errorf("got %v for a truncated index", err)
+	}
+}
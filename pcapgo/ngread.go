@@ -58,6 +58,7 @@ type NgReader struct {
 	bigEndian         bool
 	decryptionSecrets []decryptionSecret
 	nameRecords       []NgNameRecord
+	customBlocks      []NgCustomBlock
 }
 
 // NewNgReader initializes a new reader, reads the first section header, and if necessary according to the options the first interface.
@@ -222,10 +223,11 @@ func (r *NgReader) readSectionHeader() error {
 		}
 		r.options.SectionEndCallback(interfaces, r.sectionInfo)
 	}
-	// clear interfaces, decryption secrets and name records
+	// clear interfaces, decryption secrets, name records and custom blocks
 	r.ifaces = r.ifaces[:0]
 	r.decryptionSecrets = r.decryptionSecrets[:0]
 	r.nameRecords = r.nameRecords[:0]
+	r.customBlocks = r.customBlocks[:0]
 	r.activeSection = false
 
 RESTART:
@@ -344,6 +346,10 @@ func (r *NgReader) firstInterface() error {
 			if err := r.readNameResolutionBlock(); err != nil {
 				return err
 			}
+		case ngBlockTypeCustom, ngBlockTypeCustomNoCopy:
+			if err := r.readCustomBlock(); err != nil {
+				return err
+			}
 		}
 		if _, err := r.r.Discard(int(r.currentBlock.length)); err != nil {
 			return err
@@ -536,6 +542,10 @@ FIND_PACKET:
 			if err := r.readNameResolutionBlock(); err != nil {
 				return err
 			}
+		case ngBlockTypeCustom, ngBlockTypeCustomNoCopy:
+			if err := r.readCustomBlock(); err != nil {
+				return err
+			}
 		default:
 			if _, err := r.r.Discard(int(r.currentBlock.length)); err != nil {
 				return err
@@ -740,3 +750,16 @@ func (r *NgReader) Name(i int) (NgNameRecord, error) {
 func (r *NgReader) NNames() int {
 	return len(r.nameRecords)
 }
+
+// CustomBlock returns the custom block with the given index, among the ones read so far in the current section.
+func (r *NgReader) CustomBlock(i int) (NgCustomBlock, error) {
+	if i >= len(r.customBlocks) || i < 0 {
+		return NgCustomBlock{}, fmt.Errorf("Custom block %d invalid. There are only %d custom blocks", i, len(r.customBlocks))
+	}
+	return r.customBlocks[i], nil
+}
+
+// NCustomBlocks returns the number of custom blocks read so far in the current section.
+func (r *NgReader) NCustomBlocks() int {
+	return len(r.customBlocks)
+}
@@ -0,0 +1,33 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package pcapgo
+
+import "fmt"
+
+// readCustomBlock parses a custom block, of either type, keeping its data
+// up to the block trailer.
+func (r *NgReader) readCustomBlock() error {
+	if r.currentBlock.length < 8 {
+		return fmt.Errorf("custom block too short: %d bytes", r.currentBlock.length)
+	}
+	if err := r.readBytes(r.buf[:4]); err != nil {
+		return fmt.Errorf("could not read custom block PEN: %v", err)
+	}
+	r.currentBlock.length -= 4
+
+	block := NgCustomBlock{
+		PEN:    r.getUint32(r.buf[:4]),
+		NoCopy: r.currentBlock.typ == ngBlockTypeCustomNoCopy,
+		Data:   make([]byte, r.currentBlock.length-4), // without trailer
+	}
+	if err := r.readBytes(block.Data); err != nil {
+		return fmt.Errorf("could not read %d bytes of custom block data: %v", len(block.Data), err)
+	}
+	r.currentBlock.length -= uint32(len(block.Data))
+	r.customBlocks = append(r.customBlocks, block)
+	return r.discard(int(r.currentBlock.length))
+}
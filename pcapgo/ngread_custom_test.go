@@ -0,0 +1,52 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package pcapgo
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestNgReaderCustomBlock(t *testing.T) {
+	for _, name := range []string{"tests/le/test017.pcapng", "tests/be/test017.pcapng"} {
+		f, err := os.Open(name)
+		if err != nil {
+			t.Fatal("Couldn't open file:", err)
+		}
+		defer f.Close()
+		// test017 has no interface
+		r, err := NewNgReader(f, NgReaderOptions{WantMixedLinkType: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := r.ReadPacketData(); err != io.EOF {
+			t.Fatalf("%s: expected EOF, got %v", name, err)
+		}
+
+		want := []struct {
+			pen    uint32
+			noCopy bool
+			data   string
+		}{
+			{32473, false, "an example Custom Block\x00"},
+			{32473, true, "an example Custom Block not to be copied"},
+			{36724, false, "my Custom Block\x00"},
+			{36724, true, "all your block are belong to us\x00"},
+		}
+		if r.NCustomBlocks() != len(want) {
+			t.Fatalf("%s: read %d custom blocks, want %d", name, r.NCustomBlocks(), len(want))
+		}
+		for i, w := range want {
+			b, _ := r.CustomBlock(i)
+			if b.PEN != w.pen || b.NoCopy != w.noCopy || !bytes.HasPrefix(b.Data, []byte(w.data)) {
+				t.Errorf("%s: custom block %d: got %d %v %q", name, i, b.PEN, b.NoCopy, b.Data)
+			}
+		}
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package pcapgo
+
+import "encoding/binary"
+
+// WriteCustomBlock writes the given Custom Block to the file. Its data is padded to 32 bits.
+func (w *NgWriter) WriteCustomBlock(block NgCustomBlock) error {
+	typ := ngBlockTypeCustom
+	if block.NoCopy {
+		typ = ngBlockTypeCustomNoCopy
+	}
+	padding := paddingBytes32b(len(block.Data))
+	length := uint32(12 + // header and PEN
+		len(block.Data) + padding +
+		4) // trailer
+
+	binary.LittleEndian.PutUint32(w.buf[:4], uint32(typ))
+	binary.LittleEndian.PutUint32(w.buf[4:8], length)
+	binary.LittleEndian.PutUint32(w.buf[8:12], block.PEN)
+	if _, err := w.w.Write(w.buf[:12]); err != nil {
+		return err
+	}
+
+	if _, err := w.w.Write(block.Data); err != nil {
+		return err
+	}
+
+	binary.LittleEndian.PutUint32(w.buf[:4], 0)
+	binary.LittleEndian.PutUint32(w.buf[4:8], length)
+	_, err := w.w.Write(w.buf[4-padding : 8]) // padding + length
+	return err
+}
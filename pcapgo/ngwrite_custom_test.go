@@ -0,0 +1,72 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package pcapgo
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+)
+
+func TestNgCustomBlock(t *testing.T) {
+	blocks := []NgCustomBlock{
+		{PEN: 32473, Data: []byte("metadata")},
+		{PEN: 32473, NoCopy: true, Data: []byte("odd")},
+		{PEN: 1},
+	}
+
+	var buf bytes.Buffer
+	w, err := NewNgWriter(&buf, layers.LinkTypeEthernet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range blocks {
+		if err := w.WriteCustomBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data := []byte{1, 2, 3, 4, 5}
+	ci := gopacket.CaptureInfo{Timestamp: time.Unix(1, 0).UTC(), CaptureLength: len(data), Length: len(data)}
+	if err := w.WritePacket(ci, data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewNgReader(&buf, DefaultNgReaderOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _, err := r.ReadPacketData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("packet data %x, want %x", got, data)
+	}
+	if _, _, err := r.ReadPacketData(); err != io.EOF {
+		t.Errorf("expected EOF, got %v", err)
+	}
+	if r.NCustomBlocks() != len(blocks) {
+		t.Fatalf("read %d custom blocks, want %d", r.NCustomBlocks(), len(blocks))
+	}
+	for i, want := range blocks {
+		b, _ := r.CustomBlock(i)
+		if b.PEN != want.PEN || b.NoCopy != want.NoCopy {
+			t.Errorf("custom block %d: got PEN %d NoCopy %v, want PEN %d NoCopy %v", i, b.PEN, b.NoCopy, want.PEN, want.NoCopy)
+		}
+		// read data includes the padding
+		if !bytes.Equal(bytes.TrimRight(b.Data, "\x00"), want.Data) || len(b.Data)%4 != 0 {
+			t.Errorf("custom block %d: got data %q, want %q", i, b.Data, want.Data)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package pcapgo
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// nameRecordType returns the record type and address bytes of the given
+// address.
+func nameRecordType(addr NgAddress) (uint16, []byte, error) {
+	switch a := addr.(type) {
+	case *NgIPAddress:
+		switch {
+		case a.Addr.Is4():
+			b := a.Addr.As4()
+			return ngNameRecordIPv4, b[:], nil
+		case a.Addr.Is6():
+			b := a.Addr.As16()
+			return ngNameRecordIPv6, b[:], nil
+		}
+		return 0, nil, fmt.Errorf("invalid IP address %v", a.Addr)
+	case *NgEUIAddress:
+		switch len(a.Addr) {
+		case 6:
+			return ngNameRecordEUI48, a.Addr, nil
+		case 8:
+			return ngNameRecordEUI64, a.Addr, nil
+		}
+		return 0, nil, fmt.Errorf("invalid EUI address %v", a.Addr)
+	}
+	return 0, nil, fmt.Errorf("unsupported address type %T", addr)
+}
+
+// WriteNameResolution writes a Name Resolution Block holding the given records to the file. Every record needs an IPv4, IPv6, EUI-48 or EUI-64 address and at least one name.
+func (w *NgWriter) WriteNameResolution(records []NgNameRecord) error {
+	type record struct {
+		typ  uint16
+		addr []byte
+		len  int
+	}
+	prepared := make([]record, len(records))
+	length := 12 + // header and trailer
+		4 // end of records
+	for i, nr := range records {
+		typ, addr, err := nameRecordType(nr.Addr)
+		if err != nil {
+			return err
+		}
+		if len(nr.Names) == 0 {
+			return errors.New("name record without names")
+		}
+		recordLen := len(addr)
+		for _, name := range nr.Names {
+			if strings.IndexByte(name, 0) >= 0 {
+				return fmt.Errorf("name %q contains a NUL byte", name)
+			}
+			recordLen += len(name) + 1
+		}
+		if recordLen > math.MaxUint16 {
+			return fmt.Errorf("name record too long: %d bytes", recordLen)
+		}
+		prepared[i] = record{typ, addr, recordLen}
+		length += 4 + recordLen + paddingBytes32b(recordLen)
+	}
+
+	binary.LittleEndian.PutUint32(w.buf[:4], uint32(ngBlockTypeNameResolution))
+	binary.LittleEndian.PutUint32(w.buf[4:8], uint32(length))
+	if _, err := w.w.Write(w.buf[:8]); err != nil {
+		return err
+	}
+
+	var zero [4]byte
+	for i, nr := range records {
+		binary.LittleEndian.PutUint16(w.buf[0:2], prepared[i].typ)
+		binary.LittleEndian.PutUint16(w.buf[2:4], uint16(prepared[i].len))
+		if _, err := w.w.Write(w.buf[:4]); err != nil {
+			return err
+		}
+		if _, err := w.w.Write(prepared[i].addr); err != nil {
+			return err
+		}
+		for _, name := range nr.Names {
+			if _, err := w.w.WriteString(name); err != nil {
+				return err
+			}
+			if err := w.w.WriteByte(0); err != nil {
+				return err
+			}
+		}
+		if _, err := w.w.Write(zero[:paddingBytes32b(prepared[i].len)]); err != nil {
+			return err
+		}
+	}
+
+	// end of records, followed by the trailer
+	binary.LittleEndian.PutUint32(w.buf[0:4], uint32(ngNameRecordEnd))
+	binary.LittleEndian.PutUint32(w.buf[4:8], uint32(length))
+	_, err := w.w.Write(w.buf[:8])
+	return err
+}
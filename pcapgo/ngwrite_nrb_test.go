@@ -0,0 +1,79 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package pcapgo
+
+import (
+	"bytes"
+	"net"
+	"net/netip"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+)
+
+func TestNgWriteNameResolution(t *testing.T) {
+	records := []NgNameRecord{
+		{Addr: &NgIPAddress{Addr: netip.MustParseAddr("192.0.2.1")}, Names: []string{"example.com", "www.example.com"}},
+		{Addr: &NgIPAddress{Addr: netip.MustParseAddr("2001:db8::1")}, Names: []string{"example.net"}},
+		{Addr: &NgEUIAddress{Addr: net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}}, Names: []string{"router"}},
+		{Addr: &NgEUIAddress{Addr: net.HardwareAddr{0x00, 0x11, 0x22, 0xff, 0xfe, 0x33, 0x44, 0x55}}, Names: []string{"sensor"}},
+	}
+
+	var buf bytes.Buffer
+	w, err := NewNgWriter(&buf, layers.LinkTypeEthernet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteNameResolution(records); err != nil {
+		t.Fatal(err)
+	}
+	data := []byte{1, 2, 3}
+	ci := gopacket.CaptureInfo{Timestamp: time.Unix(0, 0).UTC(), CaptureLength: len(data), Length: len(data)}
+	if err := w.WritePacket(ci, data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewNgReader(&buf, DefaultNgReaderOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := r.ReadPacketData(); err != nil {
+		t.Fatal(err)
+	}
+	if r.NNames() != len(records) {
+		t.Fatalf("read %d name records, want %d", r.NNames(), len(records))
+	}
+	for i, want := range records {
+		got, _ := r.Name(i)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("name record %d: got %v %v, want %v %v", i, got.Addr, got.Names, want.Addr, want.Names)
+		}
+	}
+}
+
+func TestNgWriteNameResolutionInvalid(t *testing.T) {
+	for _, nr := range []NgNameRecord{
+		{Addr: &NgIPAddress{}, Names: []string{"invalid"}},
+		{Addr: &NgEUIAddress{Addr: net.HardwareAddr{1, 2, 3}}, Names: []string{"short"}},
+		{Addr: &NgIPAddress{Addr: netip.MustParseAddr("192.0.2.1")}},
+		{Addr: &NgIPAddress{Addr: netip.MustParseAddr("192.0.2.1")}, Names: []string{"nul\x00"}},
+	} {
+		w, err := NewNgWriter(&bytes.Buffer{}, layers.LinkTypeEthernet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.WriteNameResolution([]NgNameRecord{nr}); err == nil {
+			t.Errorf("name record %v %q accepted", nr.Addr, nr.Names)
+		}
+	}
+}
@@ -41,6 +41,8 @@ const (
 	ngBlockTypeInterfaceStatistics ngBlockType = 5          // Interface statistics block
 	ngBlockTypeEnhancedPacket      ngBlockType = 6          // Enhanced packet block
 	ngBlockTypeDecryptionSecrets   ngBlockType = 0x0000000A // Decryption secrets block
+	ngBlockTypeCustom              ngBlockType = 0x00000BAD // Custom block that may be copied
+	ngBlockTypeCustomNoCopy        ngBlockType = 0x40000BAD // Custom block that should not be copied
 	ngBlockTypeSectionHeader       ngBlockType = 0x0A0D0D0A // Section header block (same in both endians)
 )
 
@@ -442,3 +444,18 @@ type NgNameRecord struct {
 	Addr  NgAddress
 	Names []string
 }
+
+// NgCustomBlock is a pcapng Custom Block, holding data in a format defined
+// by the organization identified by PEN.
+type NgCustomBlock struct {
+	// PEN is the IANA Private Enterprise Number of the organization that
+	// defined the data format.
+	PEN uint32
+	// NoCopy marks a block that should not be copied to a new file by
+	// tools modifying the capture, as its data may refer to other blocks.
+	NoCopy bool
+	// Data is the custom data. When read, it also holds the padding and
+	// the options that may follow, since only the owner of the format
+	// knows where the data ends.
+	Data []byte
+}
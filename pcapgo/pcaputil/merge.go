@@ -0,0 +1,214 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+// Package pcaputil provides the equivalents of the mergecap and editcap
+// tools for capture files: merging captures in timestamp order, and
+// splitting a capture into files of limited size, duration or packet
+// count.
+package pcaputil
+
+import (
+	"container/heap"
+	"errors"
+	"io"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/pcapgo"
+)
+
+// Merger merges packet sources into a pcapng file in timestamp order.
+// Packets with equal timestamps are written in the order their sources
+// were added. The sources must be sorted by timestamp themselves.
+type Merger struct {
+	w       *pcapgo.NgWriter
+	sources mergeHeap
+	primed  bool
+	n       int
+}
+
+type mergeSource struct {
+	src gopacket.PacketDataSource
+	// order is the position the source was added at.
+	order int
+	// intf is the interface the packets are written on. If ng is set,
+	// ids maps the interfaces of ng to the interfaces written instead.
+	intf int
+	ng   *pcapgo.NgReader
+	ids  map[int]int
+	// next packet
+	data []byte
+	ci   gopacket.CaptureInfo
+}
+
+type mergeHeap []*mergeSource
+
+func (h mergeHeap) Len() int { return len(h) }
+func (h mergeHeap) Less(i, j int) bool {
+	if h[i].ci.Timestamp.Equal(h[j].ci.Timestamp) {
+		return h[i].order < h[j].order
+	}
+	return h[i].ci.Timestamp.Before(h[j].ci.Timestamp)
+}
+func (h mergeHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *mergeHeap) Push(x interface{}) {
+	*h = append(*h, x.(*mergeSource))
+}
+func (h *mergeHeap) Pop() interface{} {
+	old := *h
+	s := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return s
+}
+
+// NewMerger returns a Merger writing to w.
+func NewMerger(w *pcapgo.NgWriter) *Merger {
+	return &Merger{w: w}
+}
+
+// AddSource adds a source whose packets are written on interface intf of
+// the writer. intf must be 0, the interface the writer was created with,
+// or an id returned by NgWriter.AddInterface.
+func (m *Merger) AddSource(src gopacket.PacketDataSource, intf int) {
+	m.sources = append(m.sources, &mergeSource{src: src, order: len(m.sources), intf: intf})
+}
+
+// AddNgReader adds a pcapng source, keeping its interfaces apart: each
+// interface of r is added to the writer with AddInterface when its first
+// packet is merged. r should be created with
+// NgReaderOptions.WantMixedLinkType, so that it returns the packets of all
+// its interfaces.
+func (m *Merger) AddNgReader(r *pcapgo.NgReader) {
+	m.sources = append(m.sources, &mergeSource{src: r, order: len(m.sources), ng: r, ids: make(map[int]int)})
+}
+
+// next reads the next packet of s.
+func (s *mergeSource) next() error {
+	var err error
+	s.data, s.ci, err = s.src.ReadPacketData()
+	return err
+}
+
+// prime reads the first packet of every source.
+func (m *Merger) prime() error {
+	if m.primed {
+		return nil
+	}
+	m.primed = true
+	sources := m.sources[:0]
+	for _, s := range m.sources {
+		if err := s.next(); err == io.EOF {
+			continue
+		} else if err != nil {
+			return err
+		}
+		sources = append(sources, s)
+	}
+	m.sources = sources
+	heap.Init(&m.sources)
+	return nil
+}
+
+// Merge reads all the packets of the sources and writes them out, and
+// returns the number of packets written. Once all the sources are
+// exhausted, the writer is flushed.
+func (m *Merger) Merge() (int, error) {
+	if err := m.prime(); err != nil {
+		return m.n, err
+	}
+	for len(m.sources) > 0 {
+		s := m.sources[0]
+		if err := m.write(s); err != nil {
+			return m.n, err
+		}
+		if err := s.next(); err == io.EOF {
+			heap.Pop(&m.sources)
+		} else if err != nil {
+			return m.n, err
+		} else {
+			heap.Fix(&m.sources, 0)
+		}
+	}
+	return m.n, m.w.Flush()
+}
+
+func (m *Merger) write(s *mergeSource) error {
+	ci := s.ci
+	ci.AncillaryData = nil
+	if s.ng == nil {
+		ci.InterfaceIndex = s.intf
+	} else {
+		id, ok := s.ids[ci.InterfaceIndex]
+		if !ok {
+			intf, err := s.ng.Interface(ci.InterfaceIndex)
+			if err != nil {
+				return err
+			}
+			if id, err = m.w.AddInterface(intf); err != nil {
+				return err
+			}
+			s.ids[ci.InterfaceIndex] = id
+		}
+		ci.InterfaceIndex = id
+	}
+	if err := m.w.WritePacket(ci, s.data); err != nil {
+		return err
+	}
+	m.n++
+	return nil
+}
+
+// Source is a packet source to merge with Merge.
+type Source struct {
+	gopacket.PacketDataSource
+	// Interface describes the interface the packets were captured on. It
+	// is ignored if the source is a *pcapgo.NgReader, whose interfaces
+	// are used instead.
+	Interface pcapgo.NgInterface
+}
+
+// Merge merges the sources into a pcapng file written to w, in timestamp
+// order, and returns the number of packets written. Every source, and
+// every interface of pcapng sources, gets its own interface in the
+// output.
+func Merge(w io.Writer, sources ...Source) (int, error) {
+	if len(sources) == 0 {
+		return 0, errors.New("no sources to merge")
+	}
+	// The writer is created once the first packets are read, as only then
+	// the first interface of a pcapng source is known.
+	m := &Merger{}
+	for _, s := range sources {
+		if r, ok := s.PacketDataSource.(*pcapgo.NgReader); ok {
+			m.AddNgReader(r)
+		} else {
+			m.AddSource(s.PacketDataSource, 0)
+		}
+	}
+	all := append([]*mergeSource(nil), m.sources...)
+	if err := m.prime(); err != nil {
+		return 0, err
+	}
+
+	first := sources[0].Interface
+	if all[0].ng != nil {
+		if intf, err := all[0].ng.Interface(0); err == nil {
+			first = intf
+			all[0].ids[0] = 0
+		}
+	}
+	var err error
+	if m.w, err = pcapgo.NewNgWriterInterface(w, first, pcapgo.DefaultNgWriterOptions); err != nil {
+		return 0, err
+	}
+	for i, s := range all {
+		if i > 0 && s.ng == nil {
+			if s.intf, err = m.w.AddInterface(sources[i].Interface); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return m.Merge()
+}
@@ -0,0 +1,212 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package pcaputil
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+	"github.com/gopacket/gopacket/pcapgo"
+)
+
+var testBase = time.Unix(1700000000, 0).UTC()
+
+type testPacket struct {
+	data []byte
+	ci   gopacket.CaptureInfo
+}
+
+// sliceSource is a PacketDataSource returning the given packets.
+type sliceSource []testPacket
+
+func (s *sliceSource) ReadPacketData() ([]byte, gopacket.CaptureInfo, error) {
+	if len(*s) == 0 {
+		return nil, gopacket.CaptureInfo{}, io.EOF
+	}
+	p := (*s)[0]
+	*s = (*s)[1:]
+	return p.data, p.ci, nil
+}
+
+// packets returns packets whose data is id followed by the packet
+// number, at the given offsets in milliseconds.
+func packets(id byte, ms ...int) *sliceSource {
+	var s sliceSource
+	for i, m := range ms {
+		s = append(s, testPacket{
+			data: []byte{id, byte(i)},
+			ci: gopacket.CaptureInfo{
+				Timestamp:     testBase.Add(time.Duration(m) * time.Millisecond),
+				CaptureLength: 2,
+				Length:        2,
+			},
+		})
+	}
+	return &s
+}
+
+func readMerged(t *testing.T, b []byte) []testPacket {
+	t.Helper()
+	r, err := pcapgo.NewNgReader(bytes.NewReader(b), pcapgo.NgReaderOptions{WantMixedLinkType: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []testPacket
+	for {
+		data, ci, err := r.ReadPacketData()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, testPacket{data, ci})
+	}
+	return got
+}
+
+func TestMerge(t *testing.T) {
+	var buf bytes.Buffer
+	n, err := Merge(&buf,
+		Source{packets(1, 0, 10, 20, 30), pcapgo.NgInterface{Name: "a", LinkType: layers.LinkTypeEthernet}},
+		Source{packets(2, 5, 10, 40), pcapgo.NgInterface{Name: "b", LinkType: layers.LinkTypeRaw}},
+		Source{packets(3), pcapgo.NgInterface{Name: "empty", LinkType: layers.LinkTypeEthernet}},
+		Source{packets(4, 1), pcapgo.NgInterface{Name: "c", LinkType: layers.LinkTypeLinuxSLL}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 8 {
+		t.Errorf("got %d packets merged, want 8", n)
+	}
+
+	want := []struct {
+		data     []byte
+		ms       int
+		intf     int
+		linkType layers.LinkType
+	}{
+		{[]byte{1, 0}, 0, 0, layers.LinkTypeEthernet},
+		{[]byte{4, 0}, 1, 3, layers.LinkTypeLinuxSLL},
+		{[]byte{2, 0}, 5, 1, layers.LinkTypeRaw},
+		{[]byte{1, 1}, 10, 0, layers.LinkTypeEthernet},
+		{[]byte{2, 1}, 10, 1, layers.LinkTypeRaw},
+		{[]byte{1, 2}, 20, 0, layers.LinkTypeEthernet},
+		{[]byte{1, 3}, 30, 0, layers.LinkTypeEthernet},
+		{[]byte{2, 2}, 40, 1, layers.LinkTypeRaw},
+	}
+	got := readMerged(t, buf.Bytes())
+	if len(got) != len(want) {
+		t.Fatalf("got %d packets, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		g := got[i]
+		if !bytes.Equal(g.data, w.data) || !g.ci.Timestamp.Equal(testBase.Add(time.Duration(w.ms)*time.Millisecond)) ||
+			g.ci.InterfaceIndex != w.intf || g.ci.AncillaryData[0] != w.linkType {
+			t.Errorf("packet %d: got %v %+v, want %v at %dms on %d", i, g.data, g.ci, w.data, w.ms, w.intf)
+		}
+	}
+}
+
+func TestMergeNgInterfaces(t *testing.T) {
+	// a pcapng source with two interfaces
+	var ng bytes.Buffer
+	w, err := pcapgo.NewNgWriterInterface(&ng, pcapgo.NgInterface{Name: "eth0", LinkType: layers.LinkTypeEthernet}, pcapgo.DefaultNgWriterOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.AddInterface(pcapgo.NgInterface{Name: "tun0", LinkType: layers.LinkTypeRaw}); err != nil {
+		t.Fatal(err)
+	}
+	for i, p := range []struct{ ms, intf int }{{0, 1}, {10, 0}, {20, 1}} {
+		ci := gopacket.CaptureInfo{Timestamp: testBase.Add(time.Duration(p.ms) * time.Millisecond), CaptureLength: 2, Length: 2, InterfaceIndex: p.intf}
+		if err := w.WritePacket(ci, []byte{9, byte(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.Flush()
+
+	for _, ngFirst := range []bool{true, false} {
+		r, err := pcapgo.NewNgReader(bytes.NewReader(ng.Bytes()), pcapgo.NgReaderOptions{WantMixedLinkType: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sources := []Source{
+			{packets(1, 5, 15), pcapgo.NgInterface{Name: "other", LinkType: layers.LinkTypeLinuxSLL}},
+			{PacketDataSource: r},
+		}
+		if ngFirst {
+			sources[0], sources[1] = sources[1], sources[0]
+		}
+		var buf bytes.Buffer
+		if _, err := Merge(&buf, sources...); err != nil {
+			t.Fatal(err)
+		}
+
+		names := map[string]layers.LinkType{}
+		out, err := pcapgo.NewNgReader(bytes.NewReader(buf.Bytes()), pcapgo.NgReaderOptions{WantMixedLinkType: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var order []byte
+		for {
+			data, ci, err := out.ReadPacketData()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			intf, err := out.Interface(ci.InterfaceIndex)
+			if err != nil {
+				t.Fatal(err)
+			}
+			names[intf.Name] = intf.LinkType
+			order = append(order, data[0])
+		}
+		if out.NInterfaces() != 3 {
+			t.Errorf("ngFirst %v: got %d interfaces, want 3", ngFirst, out.NInterfaces())
+		}
+		wantNames := map[string]layers.LinkType{"eth0": layers.LinkTypeEthernet, "tun0": layers.LinkTypeRaw, "other": layers.LinkTypeLinuxSLL}
+		if len(names) != 3 {
+			t.Errorf("ngFirst %v: got interfaces %v, want %v", ngFirst, names, wantNames)
+		}
+		for name, lt := range wantNames {
+			if names[name] != lt {
+				t.Errorf("ngFirst %v: interface %s: got %s, want %s", ngFirst, name, names[name], lt)
+			}
+		}
+		if !bytes.Equal(order, []byte{9, 1, 9, 1, 9}) {
+			t.Errorf("ngFirst %v: got order %v", ngFirst, order)
+		}
+	}
+}
+
+func TestMerger(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := pcapgo.NewNgWriter(&buf, layers.LinkTypeEthernet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewMerger(w)
+	// both sources on the same interface
+	m.AddSource(packets(1, 0, 20), 0)
+	m.AddSource(packets(2, 10), 0)
+	if n, err := m.Merge(); err != nil || n != 3 {
+		t.Fatalf("got %d, %v", n, err)
+	}
+	got := readMerged(t, buf.Bytes())
+	var order []byte
+	for _, p := range got {
+		order = append(order, p.data[0])
+	}
+	if !bytes.Equal(order, []byte{1, 2, 1}) {
+		t.Errorf("got order %v", order)
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package pcaputil
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+	"github.com/gopacket/gopacket/pcapgo"
+)
+
+// SplitOptions holds the limits of the files written by a Splitter. A new
+// file is started before a packet that would exceed any of the non-zero
+// limits. A file always holds at least one packet.
+type SplitOptions struct {
+	// MaxPackets is the maximum number of packets per file.
+	MaxPackets int
+	// MaxBytes is the maximum size of the packet records in a file,
+	// uncompressed and excluding the file header.
+	MaxBytes int64
+	// MaxDuration is the length of the time windows covered by the files.
+	// The windows start at the timestamp of the first packet, and empty
+	// windows don't get a file. Packets older than the window of the
+	// current file are kept in the current file.
+	MaxDuration time.Duration
+	// File holds the format and compression of the files.
+	File pcapgo.FileWriterOptions
+}
+
+// CreateFunc creates the n-th output file of a Splitter, numbered from 0.
+// ci is the capture info of the first packet going to the file.
+type CreateFunc func(n int, ci gopacket.CaptureInfo) (io.WriteCloser, error)
+
+// Splitter writes packets to a sequence of capture files, starting a new
+// file whenever one of the limits is reached, like editcap -c and -i.
+type Splitter struct {
+	linkType layers.LinkType
+	options  SplitOptions
+	create   CreateFunc
+
+	files  int
+	out    io.WriteCloser
+	w      *pcapgo.FileWriter
+	count  int
+	bytes  int64
+	window time.Time
+	start  time.Time
+}
+
+// NewSplitter returns a Splitter writing packets of the given link type
+// to files created by create.
+func NewSplitter(linkType layers.LinkType, options SplitOptions, create CreateFunc) *Splitter {
+	return &Splitter{linkType: linkType, options: options, create: create}
+}
+
+// recordSize returns the number of bytes the packet takes in the file.
+func (s *Splitter) recordSize(ci gopacket.CaptureInfo) int64 {
+	n := int64(ci.CaptureLength)
+	pad := (4 - n&3) & 3
+	switch s.options.File.Format {
+	case pcapgo.FileFormatPcapNg:
+		return 32 + n + pad
+	case pcapgo.FileFormatSnoop:
+		return 24 + n + pad
+	}
+	return 16 + n
+}
+
+// rotate reports whether the packet must go to a new file.
+func (s *Splitter) rotate(ci gopacket.CaptureInfo, size int64) bool {
+	if s.w == nil {
+		return true
+	}
+	if s.count == 0 {
+		return false
+	}
+	o := s.options
+	return o.MaxPackets > 0 && s.count >= o.MaxPackets ||
+		o.MaxBytes > 0 && s.bytes+size > o.MaxBytes ||
+		o.MaxDuration > 0 && !ci.Timestamp.Before(s.window.Add(o.MaxDuration))
+}
+
+// WritePacket writes the packet to the current file, or to a new one if
+// a limit is reached.
+func (s *Splitter) WritePacket(ci gopacket.CaptureInfo, data []byte) error {
+	size := s.recordSize(ci)
+	if s.rotate(ci, size) {
+		if err := s.closeFile(); err != nil {
+			return err
+		}
+		out, err := s.create(s.files, ci)
+		if err != nil {
+			return err
+		}
+		s.files++
+		w, err := pcapgo.NewFileWriter(out, s.linkType, s.options.File)
+		if err != nil {
+			out.Close()
+			return err
+		}
+		s.out, s.w = out, w
+		if s.start.IsZero() {
+			s.start = ci.Timestamp
+		}
+		s.window = s.start
+		if d := s.options.MaxDuration; d > 0 && ci.Timestamp.After(s.start) {
+			s.window = s.start.Add(ci.Timestamp.Sub(s.start) / d * d)
+		}
+	}
+	if err := s.w.WritePacket(ci, data); err != nil {
+		return err
+	}
+	s.count++
+	s.bytes += size
+	return nil
+}
+
+func (s *Splitter) closeFile() error {
+	if s.w == nil {
+		return nil
+	}
+	err := s.w.Close()
+	if cerr := s.out.Close(); err == nil {
+		err = cerr
+	}
+	s.w, s.out = nil, nil
+	s.count, s.bytes = 0, 0
+	return err
+}
+
+// Close closes the current file.
+func (s *Splitter) Close() error {
+	return s.closeFile()
+}
+
+// Files returns the number of files created so far.
+func (s *Splitter) Files() int {
+	return s.files
+}
+
+// Split writes all the packets of src to files created by create, and
+// returns the number of files written.
+func Split(src gopacket.PacketDataSource, linkType layers.LinkType, options SplitOptions, create CreateFunc) (int, error) {
+	s := NewSplitter(linkType, options, create)
+	for {
+		data, ci, err := src.ReadPacketData()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			s.Close()
+			return s.Files(), err
+		}
+		if err := s.WritePacket(ci, data); err != nil {
+			s.Close()
+			return s.Files(), err
+		}
+	}
+	return s.Files(), s.Close()
+}
+
+// NumberedFiles returns a CreateFunc naming the files like editcap does:
+// the file number and the timestamp of the first packet are inserted
+// before the extension of path, e.g. out_00001_20240102150405.pcap for
+// out.pcap. A ".gz" suffix is kept after the extension.
+func NumberedFiles(path string) CreateFunc {
+	gz := ""
+	if strings.HasSuffix(path, ".gz") {
+		path, gz = path[:len(path)-3], ".gz"
+	}
+	stem, ext := path, ""
+	if i := strings.LastIndexByte(path, '.'); i > strings.LastIndexAny(path, `/\`) {
+		stem, ext = path[:i], path[i:]
+	}
+	return func(n int, ci gopacket.CaptureInfo) (io.WriteCloser, error) {
+		name := fmt.Sprintf("%s_%05d_%s%s%s", stem, n, ci.Timestamp.Format("20060102150405"), ext, gz)
+		return os.Create(name)
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package pcaputil
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+	"github.com/gopacket/gopacket/pcapgo"
+)
+
+type bufferCloser struct {
+	bytes.Buffer
+}
+
+func (b *bufferCloser) Close() error { return nil }
+
+// splitPackets splits the packets and returns the packet numbers in every
+// file.
+func splitPackets(t *testing.T, src *sliceSource, options SplitOptions) [][]byte {
+	t.Helper()
+	var files []*bufferCloser
+	create := func(n int, ci gopacket.CaptureInfo) (io.WriteCloser, error) {
+		if n != len(files) {
+			t.Errorf("got file number %d, want %d", n, len(files))
+		}
+		b := &bufferCloser{}
+		files = append(files, b)
+		return b, nil
+	}
+	n, err := Split(src, layers.LinkTypeEthernet, options, create)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(files) {
+		t.Errorf("got %d files, created %d", n, len(files))
+	}
+	var got [][]byte
+	for _, f := range files {
+		r, err := pcapgo.NewFileReader(&f.Buffer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var numbers []byte
+		for {
+			data, _, err := r.ReadPacketData()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			numbers = append(numbers, data[1])
+		}
+		got = append(got, numbers)
+	}
+	return got
+}
+
+func TestSplit(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		ms      []int
+		options SplitOptions
+		want    [][]byte
+	}{
+		{
+			name:    "packets",
+			ms:      []int{0, 1, 2, 3, 4, 5, 6},
+			options: SplitOptions{MaxPackets: 3},
+			want:    [][]byte{{0, 1, 2}, {3, 4, 5}, {6}},
+		},
+		{
+			// pcap records of 2 bytes take 18 bytes
+			name:    "bytes",
+			ms:      []int{0, 1, 2, 3, 4},
+			options: SplitOptions{MaxBytes: 40},
+			want:    [][]byte{{0, 1}, {2, 3}, {4}},
+		},
+		{
+			// pcapng records of 2 bytes take 36 bytes, a file holds at
+			// least one packet
+			name:    "bytes pcapng",
+			ms:      []int{0, 1, 2},
+			options: SplitOptions{MaxBytes: 10, File: pcapgo.FileWriterOptions{Format: pcapgo.FileFormatPcapNg}},
+			want:    [][]byte{{0}, {1}, {2}},
+		},
+		{
+			// windows of 100ms from the first packet, the empty window
+			// [200, 300) gets no file, packet 4 is late
+			name:    "duration",
+			ms:      []int{50, 120, 149, 150, 140, 360, 449},
+			options: SplitOptions{MaxDuration: 100 * time.Millisecond},
+			want:    [][]byte{{0, 1, 2}, {3, 4}, {5, 6}},
+		},
+		{
+			name:    "combined gzip",
+			ms:      []int{0, 1, 2, 500, 501},
+			options: SplitOptions{MaxPackets: 2, MaxDuration: time.Second, File: pcapgo.FileWriterOptions{Gzip: true}},
+			want:    [][]byte{{0, 1}, {2, 3}, {4}},
+		},
+		{
+			name:    "no limits",
+			ms:      []int{0, 1, 2},
+			options: SplitOptions{File: pcapgo.FileWriterOptions{Format: pcapgo.FileFormatSnoop}},
+			want:    [][]byte{{0, 1, 2}},
+		},
+	} {
+		got := splitPackets(t, packets(1, test.ms...), test.options)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestNumberedFiles(t *testing.T) {
+	dir := t.TempDir()
+	ci := gopacket.CaptureInfo{Timestamp: time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)}
+	for _, test := range []struct{ path, want string }{
+		{"out.pcap", "out_00003_20240102150405.pcap"},
+		{"out.pcapng.gz", "out_00003_20240102150405.pcapng.gz"},
+		{"out", "out_00003_20240102150405"},
+	} {
+		f, err := NumberedFiles(filepath.Join(dir, test.path))(3, ci)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+		if _, err := os.Stat(filepath.Join(dir, test.want)); err != nil {
+			t.Errorf("%s: %v", test.path, err)
+		}
+	}
+}
@@ -27,6 +27,7 @@ const unknownVersion = "Unknown Snoop Format Version"
 const unkownLinkType = "Unknown Link Type"
 const originalLenExceeded = "Capture length exceeds original packet length"
 const captureLenExceeded = "Capture length exceeds max capture length"
+const recordLenTooShort = "Packet record length shorter than capture length"
 
 type snoopHeader struct {
 	Version  uint32
@@ -43,6 +44,7 @@ type SnoopReader struct {
 	header snoopHeader
 	//reuseable
 	pad       int
+	drops     uint32
 	packetBuf []byte
 	buf       [24]byte
 }
@@ -127,7 +129,8 @@ func (r *SnoopReader) readPacketHeader() (ci gopacket.CaptureInfo, err error) {
 	ci.Timestamp = time.Unix(int64(binary.BigEndian.Uint32(r.buf[16:20])), int64(binary.BigEndian.Uint32(r.buf[20:24])*1000)).UTC()
 	ci.Length = int(binary.BigEndian.Uint32(r.buf[0:4]))
 	ci.CaptureLength = int(binary.BigEndian.Uint32(r.buf[4:8]))
-	r.pad = int(binary.BigEndian.Uint32(r.buf[8:12])) - (24 + ci.Length)
+	r.pad = int(binary.BigEndian.Uint32(r.buf[8:12])) - (24 + ci.CaptureLength)
+	r.drops = binary.BigEndian.Uint32(r.buf[12:16])
 
 	if ci.CaptureLength > ci.Length {
 		err = errors.New(originalLenExceeded)
@@ -136,11 +139,22 @@ func (r *SnoopReader) readPacketHeader() (ci gopacket.CaptureInfo, err error) {
 
 	if ci.CaptureLength > maxCaptureLen {
 		err = errors.New(captureLenExceeded)
+		return
+	}
+
+	if r.pad < 0 {
+		err = errors.New(recordLenTooShort)
 	}
 
 	return
 }
 
+// CumulativeDrops returns the number of packets dropped since the start
+// of the capture, as recorded with the last packet read.
+func (r *SnoopReader) CumulativeDrops() uint32 {
+	return r.drops
+}
+
 // ReadPacketData reads next packet data.
 func (r *SnoopReader) ReadPacketData() (data []byte, ci gopacket.CaptureInfo, err error) {
 	if ci, err = r.readPacketHeader(); err != nil {
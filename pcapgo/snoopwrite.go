@@ -0,0 +1,113 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package pcapgo
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+)
+
+// snoopLinkTypes maps link types to snoop datalink types, the reverse of
+// layerTypes.
+var snoopLinkTypes = map[layers.LinkType]uint32{
+	layers.LinkTypeEthernet:  4,
+	layers.LinkTypeTokenRing: 2,
+	layers.LinkTypeC_HDLC:    5,
+	layers.LinkTypeFDDI:      8,
+}
+
+// SnoopWriter wraps an underlying io.Writer to write packet data in SNOOP
+// format. See https://tools.ietf.org/html/rfc1761 for information on the
+// file format.
+//
+// We write v2 files. Timestamps are truncated to microseconds and packet
+// records are padded to a multiple of 4 bytes.
+type SnoopWriter struct {
+	w     io.Writer
+	drops uint32
+	// reusable buffer
+	buf [24 + 3]byte
+}
+
+// NewSnoopWriter returns a new writer object, for writing packet data out
+// to the given writer. WriteFileHeader must be called before WritePacket.
+//
+//	f, _ := os.Create("/tmp/file.snoop")
+//	w := pcapgo.NewSnoopWriter(f)
+//	w.WriteFileHeader(layers.LinkTypeEthernet)
+//	w.WritePacket(gopacket.CaptureInfo{...}, data)
+//	f.Close()
+func NewSnoopWriter(w io.Writer) *SnoopWriter {
+	return &SnoopWriter{w: w}
+}
+
+// WriteFileHeader writes the file header out to the writer. Only the link
+// types Ethernet, TokenRing, C_HDLC and FDDI can be written. This must be
+// called exactly once per output.
+func (w *SnoopWriter) WriteFileHeader(linkType layers.LinkType) error {
+	code, ok := snoopLinkTypes[linkType]
+	if !ok {
+		return fmt.Errorf("%s: %s", unkownLinkType, linkType)
+	}
+	var buf [16]byte
+	binary.BigEndian.PutUint64(buf[0:8], snoopMagic)
+	binary.BigEndian.PutUint32(buf[8:12], snoopVersion)
+	binary.BigEndian.PutUint32(buf[12:16], code)
+	_, err := w.w.Write(buf[:])
+	return err
+}
+
+// SetCumulativeDrops sets the number of packets dropped since the start of
+// the capture, which is recorded with every following packet.
+func (w *SnoopWriter) SetCumulativeDrops(drops uint32) {
+	w.drops = drops
+}
+
+// WritePacket writes the given packet data out to the file.
+func (w *SnoopWriter) WritePacket(ci gopacket.CaptureInfo, data []byte) error {
+	if ci.CaptureLength != len(data) {
+		return fmt.Errorf("capture length %d does not match data length %d", ci.CaptureLength, len(data))
+	}
+	if ci.CaptureLength > ci.Length {
+		return fmt.Errorf("invalid capture info %+v:  capture length > length", ci)
+	}
+	t := ci.Timestamp
+	if t.IsZero() {
+		t = time.Now()
+	}
+	pad := (4 - ci.CaptureLength&3) & 3
+
+	// 	OriginalLength        uint32	4
+	// 	IncludedLength        uint32	8
+	// 	PacketRecordLength    uint32	12
+	// 	CumulativeDrops       uint32	16
+	// 	TimestampSeconds      uint32	20
+	// 	TimestampMicroseconds uint32	24
+	binary.BigEndian.PutUint32(w.buf[0:4], uint32(ci.Length))
+	binary.BigEndian.PutUint32(w.buf[4:8], uint32(ci.CaptureLength))
+	binary.BigEndian.PutUint32(w.buf[8:12], uint32(24+ci.CaptureLength+pad))
+	binary.BigEndian.PutUint32(w.buf[12:16], w.drops)
+	binary.BigEndian.PutUint32(w.buf[16:20], uint32(t.Unix()))
+	binary.BigEndian.PutUint32(w.buf[20:24], uint32(t.Nanosecond()/1000))
+	if _, err := w.w.Write(w.buf[:24]); err != nil {
+		return fmt.Errorf("error writing packet header: %v", err)
+	}
+	if _, err := w.w.Write(data); err != nil {
+		return err
+	}
+	if pad > 0 {
+		// buf[24:] is never written, and stays zero
+		_, err := w.w.Write(w.buf[24 : 24+pad])
+		return err
+	}
+	return nil
+}
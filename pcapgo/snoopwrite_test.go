@@ -0,0 +1,108 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package pcapgo
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+)
+
+func TestSnoopWriteHeaderAndPacket(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewSnoopWriter(&buf)
+	if err := w.WriteFileHeader(layers.LinkTypeEthernet); err != nil {
+		t.Fatal(err)
+	}
+	ci := gopacket.CaptureInfo{
+		Timestamp:     time.Date(2019, 04, 23, 07, 01, 32, 831815*1000+999, time.UTC),
+		CaptureLength: 42,
+		Length:        42,
+	}
+	if err := w.WritePacket(ci, pack[24:66]); err != nil {
+		t.Fatal(err)
+	}
+	want := append(append([]byte{}, spHeader...), pack...)
+	if got := buf.Bytes(); !bytes.Equal(got, want) {
+		t.Errorf("buf mismatch:\nwant: %x\ngot:  %x", want, got)
+	}
+}
+
+func TestSnoopWriteRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewSnoopWriter(&buf)
+	if err := w.WriteFileHeader(layers.LinkTypeFDDI); err != nil {
+		t.Fatal(err)
+	}
+	type packet struct {
+		data  []byte
+		ci    gopacket.CaptureInfo
+		drops uint32
+	}
+	var packets []packet
+	ts := time.Unix(1700000000, 123456000).UTC()
+	for i := 0; i < 8; i++ {
+		p := packet{
+			data: bytes.Repeat([]byte{byte(i + 1)}, 60+i),
+			ci: gopacket.CaptureInfo{
+				Timestamp:     ts.Add(time.Duration(i) * time.Millisecond),
+				CaptureLength: 60 + i,
+				// every other packet is truncated
+				Length: 60 + i + i%2*100,
+			},
+			drops: uint32(i / 3),
+		}
+		w.SetCumulativeDrops(p.drops)
+		if err := w.WritePacket(p.ci, p.data); err != nil {
+			t.Fatal(err)
+		}
+		packets = append(packets, p)
+	}
+
+	r, err := NewSnoopReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lt, err := r.LinkType(); err != nil || *lt != layers.LinkTypeFDDI {
+		t.Errorf("got link type %v, %v", lt, err)
+	}
+	for i, p := range packets {
+		data, ci, err := r.ReadPacketData()
+		if err != nil {
+			t.Fatalf("packet %d: %v", i, err)
+		}
+		if !bytes.Equal(data, p.data) {
+			t.Errorf("packet %d: got data %x, want %x", i, data, p.data)
+		}
+		if !ci.Timestamp.Equal(p.ci.Timestamp) || ci.CaptureLength != p.ci.CaptureLength || ci.Length != p.ci.Length {
+			t.Errorf("packet %d: got %+v, want %+v", i, ci, p.ci)
+		}
+		if r.CumulativeDrops() != p.drops {
+			t.Errorf("packet %d: got %d drops, want %d", i, r.CumulativeDrops(), p.drops)
+		}
+	}
+	if _, _, err := r.ReadPacketData(); err != io.EOF {
+		t.Errorf("got %v after the last packet, want EOF", err)
+	}
+}
+
+func TestSnoopWriteErrors(t *testing.T) {
+	w := NewSnoopWriter(io.Discard)
+	if err := w.WriteFileHeader(layers.LinkTypeRaw); err == nil {
+		t.Error("expected an error for an unsupported link type")
+	}
+	if err := w.WritePacket(gopacket.CaptureInfo{CaptureLength: 2, Length: 2}, []byte{1}); err == nil {
+		t.Error("expected an error for a wrong capture length")
+	}
+	if err := w.WritePacket(gopacket.CaptureInfo{CaptureLength: 2, Length: 1}, []byte{1, 2}); err == nil {
+		t.Error("expected an error for a capture length exceeding the length")
+	}
+}
@@ -0,0 +1,285 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package reassembly
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+)
+
+// OverlapPolicy selects the data kept when a segment overlaps data buffered
+// by the Assembler, the way the target host's TCP stack would.  The policies
+// follow the target-based reassembly of Novak and Sturges, also used by
+// Snort's stream5.
+//
+// Data already given to the Stream is never replaced: the overlapping part
+// of a retransmission is always discarded, whatever the policy.  It is
+// compared with the original data if AssemblerOptions.OverlapHistoryBytes
+// is set.
+//
+// Policies are applied to the buffered pages, which hold at most
+// pageBytes (1900) bytes of a single segment, so larger segments are
+// compared page by page.
+type OverlapPolicy int
+
+const (
+	// OverlapLast keeps the new data (Cisco IOS).  This is the default.
+	OverlapLast OverlapPolicy = iota
+	// OverlapFirst keeps the original data (Mac OS, HP-UX 11).
+	OverlapFirst
+	// OverlapBSD keeps the original data, unless the new segment begins
+	// before it (BSD, AIX).
+	OverlapBSD
+	// OverlapLinux keeps the original data, unless the new segment begins
+	// before it, or begins at the same sequence number and ends after it.
+	OverlapLinux
+	// OverlapWindows keeps the original data, unless the new segment
+	// begins before it and covers it whole (Windows, following Snort's
+	// stream5).
+	OverlapWindows
+)
+
+func (p OverlapPolicy) String() string {
+	switch p {
+	case OverlapLast:
+		return "last"
+	case OverlapFirst:
+		return "first"
+	case OverlapBSD:
+		return "bsd"
+	case OverlapLinux:
+		return "linux"
+	case OverlapWindows:
+		return "windows"
+	}
+	return fmt.Sprintf("OverlapPolicy(%d)", int(p))
+}
+
+// newWins reports whether the new segment [start, end) replaces the
+// overlapped part of the original one [origStart, origEnd).
+func (p OverlapPolicy) newWins(start, end, origStart, origEnd Sequence) bool {
+	switch p {
+	case OverlapFirst:
+		return false
+	case OverlapBSD:
+		return start.Difference(origStart) > 0
+	case OverlapWindows:
+		return start.Difference(origStart) > 0 && origEnd.Difference(end) >= 0
+	case OverlapLinux:
+		diff := start.Difference(origStart)
+		return diff > 0 || diff == 0 && origEnd.Difference(end) > 0
+	}
+	return true
+}
+
+// TCPAnomalyKind is the kind of a TCPAnomaly.
+type TCPAnomalyKind int
+
+const (
+	// TCPAnomalyOverlapConflict is reported when the data of a segment
+	// differs from the data it overlaps.
+	TCPAnomalyOverlapConflict TCPAnomalyKind = iota
+	// TCPAnomalyBeyondWindow is reported for data beyond the window last
+	// advertised by the receiver.  It is only checked once both SYNs were
+	// seen, when the window scale is known.
+	TCPAnomalyBeyondWindow
+	// TCPAnomalyDataAfterFin is reported for data beyond the FIN of the
+	// sender.
+	TCPAnomalyDataAfterFin
+	// TCPAnomalyDataAfterReset is reported for data sent after a RST in
+	// either direction.
+	TCPAnomalyDataAfterReset
+	// TCPAnomalyUrgentPointer is reported for segments with the URG flag.
+	TCPAnomalyUrgentPointer
+)
+
+func (k TCPAnomalyKind) String() string {
+	switch k {
+	case TCPAnomalyOverlapConflict:
+		return "OverlapConflict"
+	case TCPAnomalyBeyondWindow:
+		return "BeyondWindow"
+	case TCPAnomalyDataAfterFin:
+		return "DataAfterFin"
+	case TCPAnomalyDataAfterReset:
+		return "DataAfterReset"
+	case TCPAnomalyUrgentPointer:
+		return "UrgentPointer"
+	}
+	return fmt.Sprintf("TCPAnomalyKind(%d)", int(k))
+}
+
+// TCPAnomaly describes a suspicious segment, which may be an attempt to
+// evade an IDS.
+type TCPAnomaly struct {
+	Kind      TCPAnomalyKind
+	Direction TCPFlowDirection
+	// Seq and Length are the sequence number and length of the data
+	// concerned: the overlapping part for TCPAnomalyOverlapConflict, the
+	// payload of the segment otherwise.
+	Seq    Sequence
+	Length int
+	// Original and Data are the original and the new overlapping data for
+	// TCPAnomalyOverlapConflict.  They are only valid until Anomaly returns.
+	Original, Data []byte
+	// OriginalKept is set for TCPAnomalyOverlapConflict if the original
+	// data was kept, following the OverlapPolicy.
+	OriginalKept bool
+	// Urgent is the urgent pointer of a TCPAnomalyUrgentPointer segment.
+	Urgent uint16
+	// CaptureInfo is the capture info of the segment.
+	CaptureInfo gopacket.CaptureInfo
+}
+
+// AnomalyStream is implemented by Streams that want to be told about
+// TCPAnomalies.  Anomalies of the wire, i.e. all but
+// TCPAnomalyOverlapConflict, are reported before Accept is called for the
+// segment, so even for segments the Stream then rejects.
+type AnomalyStream interface {
+	Stream
+	Anomaly(anomaly *TCPAnomaly, ac AssemblerContext)
+}
+
+// anomaly reports an anomaly to the Stream of half, if it is an
+// AnomalyStream.
+func (a *Assembler) anomaly(half *halfconnection, anomaly TCPAnomaly, ac AssemblerContext) {
+	if s, ok := half.stream.(AnomalyStream); ok {
+		anomaly.Direction = half.dir
+		anomaly.CaptureInfo = ac.GetCaptureInfo()
+		s.Anomaly(&anomaly, ac)
+	}
+}
+
+// checkAnomalies checks a segment sent by half for the anomalies of the
+// wire, and tracks the FIN, RST and windows it needs.  Nothing is tracked
+// for other Streams.
+func (a *Assembler) checkAnomalies(conn *connection, half, rev *halfconnection, t *layers.TCP, ac AssemblerContext) {
+	if _, ok := half.stream.(AnomalyStream); ok {
+		seq, length := Sequence(t.Seq), len(t.Payload)
+		if t.SYN {
+			seq = seq.Add(1)
+		}
+		end := seq.Add(length)
+		if length > 0 {
+			if rev.window >= 0 && rev.ackSeq != invalidSequence {
+				limit := rev.ackSeq.Add(rev.window)
+				// a 1 byte probe of a zero window is fine
+				if limit.Difference(end) > 0 && !(rev.window == 0 && length == 1) {
+					a.anomaly(half, TCPAnomaly{Kind: TCPAnomalyBeyondWindow, Seq: seq, Length: length}, ac)
+				}
+			}
+			if half.finSeq != invalidSequence && half.finSeq.Difference(end) > 0 {
+				a.anomaly(half, TCPAnomaly{Kind: TCPAnomalyDataAfterFin, Seq: seq, Length: length}, ac)
+			}
+			if conn.rstSeen {
+				a.anomaly(half, TCPAnomaly{Kind: TCPAnomalyDataAfterReset, Seq: seq, Length: length}, ac)
+			}
+		}
+		if t.URG {
+			a.anomaly(half, TCPAnomaly{Kind: TCPAnomalyUrgentPointer, Seq: seq, Length: length, Urgent: t.Urgent}, ac)
+		}
+		if t.FIN && half.finSeq == invalidSequence {
+			half.finSeq = end
+		}
+		if t.RST {
+			conn.rstSeen = true
+		}
+		half.updateWindow(rev, t)
+	}
+}
+
+// updateWindow records the window advertised by a segment sent by half.
+func (half *halfconnection) updateWindow(rev *halfconnection, t *layers.TCP) {
+	if t.SYN {
+		half.synSeen = true
+		half.wscale = -1
+		for _, o := range t.Options {
+			if o.OptionType == layers.TCPOptionKindWindowScale && len(o.OptionData) == 1 {
+				half.wscale = min(int(o.OptionData[0]), 14)
+			}
+		}
+		// the window of SYN segments is never scaled
+		half.window = int(t.Window)
+		return
+	}
+	if !half.synSeen || !rev.synSeen {
+		half.window = -1
+		return
+	}
+	scale := 0
+	if half.wscale >= 0 && rev.wscale >= 0 {
+		scale = half.wscale
+	}
+	half.window = int(t.Window) << scale
+}
+
+// checkOverlapConflict reports an anomaly if the new data [start, end)
+// differs from the original data overlapping it.
+func (a *Assembler) checkOverlapConflict(half *halfconnection, start Sequence, data []byte, origStart Sequence, orig []byte, originalKept bool, ac AssemblerContext) {
+	if _, ok := half.stream.(AnomalyStream); !ok {
+		return
+	}
+	lo, hi := start, start.Add(len(data))
+	if lo.Difference(origStart) > 0 {
+		lo = origStart
+	}
+	if origEnd := origStart.Add(len(orig)); hi.Difference(origEnd) < 0 {
+		hi = origEnd
+	}
+	if lo.Difference(hi) <= 0 {
+		return
+	}
+	o := orig[origStart.Difference(lo):origStart.Difference(hi)]
+	d := data[start.Difference(lo):start.Difference(hi)]
+	if !bytes.Equal(o, d) {
+		a.anomaly(half, TCPAnomaly{
+			Kind:         TCPAnomalyOverlapConflict,
+			Seq:          lo,
+			Length:       len(d),
+			Original:     o,
+			Data:         d,
+			OriginalKept: originalKept,
+		}, ac)
+	}
+}
+
+// copyOverlap copies the part of src, starting at srcSeq, overlapping dst,
+// starting at dstSeq.
+func copyOverlap(dst []byte, dstSeq Sequence, src []byte, srcSeq Sequence) {
+	if diff := dstSeq.Difference(srcSeq); diff >= 0 {
+		if diff < len(dst) {
+			copy(dst[diff:], src)
+		}
+	} else if -diff < len(src) {
+		copy(dst, src[-diff:])
+	}
+}
+
+// recordHistory keeps the last OverlapHistoryBytes bytes given to the Stream,
+// ending at nextSeq, to compare them with retransmissions.
+func (a *Assembler) recordHistory(half *halfconnection, nextSeq Sequence) {
+	if a.cacheSG.Skip != 0 {
+		half.history = half.history[:0]
+	}
+	skip := a.cacheSG.saved
+	for _, r := range a.cacheSG.all {
+		b := r.getBytes()
+		if skip >= len(b) {
+			skip -= len(b)
+			continue
+		}
+		half.history = append(half.history, b[skip:]...)
+		skip = 0
+	}
+	if n := len(half.history) - a.OverlapHistoryBytes; n > 0 {
+		half.history = half.history[:copy(half.history, half.history[n:])]
+	}
+	half.historyEnd = nextSeq
+}
@@ -0,0 +1,273 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package reassembly
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+)
+
+/* For anomaly tests: records the client data and the anomalies */
+type testAnomalyStream struct {
+	data      bytes.Buffer
+	anomalies []string
+}
+
+func (s *testAnomalyStream) New(a, b gopacket.Flow, tcp *layers.TCP, ac AssemblerContext) Stream {
+	return s
+}
+
+func (s *testAnomalyStream) Accept(tcp *layers.TCP, ci gopacket.CaptureInfo, dir TCPFlowDirection, seq Sequence, start *bool, ac AssemblerContext) bool {
+	return true
+}
+
+func (s *testAnomalyStream) ReassembledSG(sg ScatterGather, ac AssemblerContext) {
+	l, _ := sg.Lengths()
+	if dir, _, _, _ := sg.Info(); dir == TCPDirClientToServer {
+		s.data.Write(sg.Fetch(l))
+	}
+}
+
+func (s *testAnomalyStream) ReassemblyComplete(ac AssemblerContext) bool {
+	return true
+}
+
+func (s *testAnomalyStream) Anomaly(anomaly *TCPAnomaly, ac AssemblerContext) {
+	str := fmt.Sprintf("%s %s %d+%d", anomaly.Kind, anomaly.Direction, anomaly.Seq, anomaly.Length)
+	switch anomaly.Kind {
+	case TCPAnomalyOverlapConflict:
+		str += fmt.Sprintf(" %q/%q kept:%v", anomaly.Original, anomaly.Data, anomaly.OriginalKept)
+	case TCPAnomalyUrgentPointer:
+		str += fmt.Sprintf(" urgent:%d", anomaly.Urgent)
+	}
+	s.anomalies = append(s.anomalies, str)
+}
+
+// anomalySegment returns a segment with a payload, or URG if payload is
+// "!".
+func anomalySegment(client bool, flags string, seq, ack uint32, payload string) *layers.TCP {
+	tcp := checkpointSegment(client, flags, seq, ack, payload)
+	if payload == "!" {
+		tcp.Payload = nil
+		tcp.URG = true
+		tcp.Urgent = 5
+	}
+	return tcp
+}
+
+func assembleAnomalies(options AssemblerOptions, segments []*layers.TCP) *testAnomalyStream {
+	s := &testAnomalyStream{}
+	a := NewAssembler(NewStreamPool(s))
+	a.AssemblerOptions = options
+	ctx := assemblerSimpleContext(gopacket.CaptureInfo{Timestamp: time.Unix(1432538521, 0)})
+	for _, tcp := range segments {
+		flow := netFlow
+		if tcp.SrcPort != testClientPort {
+			flow = flow.Reverse()
+		}
+		a.AssembleWithContext(flow, tcp, &ctx)
+	}
+	return s
+}
+
+func TestOverlapPolicies(t *testing.T) {
+	tests := []struct {
+		name     string
+		segments []*layers.TCP
+		want     map[OverlapPolicy]string
+	}{
+		{
+			name: "new begins before",
+			segments: []*layers.TCP{
+				anomalySegment(true, "A", 111, 0, "AAAAAAAAAA"),
+				anomalySegment(true, "A", 106, 0, "bbbbbbbbbb"),
+			},
+			want: map[OverlapPolicy]string{
+				OverlapLast:  "cccccbbbbbbbbbbAAAAA",
+				OverlapFirst: "cccccbbbbbAAAAAAAAAA",
+				OverlapBSD:   "cccccbbbbbbbbbbAAAAA",
+				OverlapLinux: "cccccbbbbbbbbbbAAAAA",
+			},
+		},
+		{
+			name: "same start, ends after",
+			segments: []*layers.TCP{
+				anomalySegment(true, "A", 111, 0, "AAAAA"),
+				anomalySegment(true, "A", 111, 0, "bbbbbbbbbb"),
+			},
+			want: map[OverlapPolicy]string{
+				OverlapLast:  "cccccccccc" + "bbbbbbbbbb",
+				OverlapFirst: "cccccccccc" + "AAAAAbbbbb",
+				OverlapBSD:   "cccccccccc" + "AAAAAbbbbb",
+				OverlapLinux: "cccccccccc" + "bbbbbbbbbb",
+			},
+		},
+		{
+			name: "inside",
+			segments: []*layers.TCP{
+				anomalySegment(true, "A", 111, 0, "AAAAAAAAAA"),
+				anomalySegment(true, "A", 113, 0, "bb"),
+			},
+			want: map[OverlapPolicy]string{
+				OverlapLast:  "cccccccccc" + "AAbbAAAAAA",
+				OverlapFirst: "cccccccccc" + "AAAAAAAAAA",
+				OverlapBSD:   "cccccccccc" + "AAAAAAAAAA",
+				OverlapLinux: "cccccccccc" + "AAAAAAAAAA",
+			},
+		},
+	}
+	for _, test := range tests {
+		for policy, want := range test.want {
+			s := assembleOverlap(policy, test.segments)
+			if got := s.data.String(); got != want {
+				t.Errorf("%s, %s: got %q, want %q", test.name, policy, got, want)
+			}
+			if len(s.anomalies) != 1 {
+				t.Errorf("%s, %s: got anomalies %q", test.name, policy, s.anomalies)
+			}
+		}
+	}
+}
+
+// assembleOverlap assembles two overlapping segments following the SYN,
+// then the data filling the hole before them.
+func assembleOverlap(policy OverlapPolicy, overlapping []*layers.TCP) *testAnomalyStream {
+	segments := append([]*layers.TCP{anomalySegment(true, "S", 100, 0, "")}, overlapping...)
+	fill := 10
+	if overlapping[1].Seq < 111 {
+		fill = 5
+	}
+	segments = append(segments, anomalySegment(true, "A", 101, 0, string(bytes.Repeat([]byte{'c'}, fill))))
+	return assembleAnomalies(AssemblerOptions{OverlapPolicy: policy}, segments)
+}
+
+func TestOverlapWindows(t *testing.T) {
+	type result struct {
+		data         string
+		originalKept bool
+	}
+	for _, test := range []struct {
+		name         string
+		seq          uint32
+		payload      string
+		bsd, windows result
+	}{
+		// Windows only gives the new segment the overlap when it begins
+		// before the original one and covers it whole
+		{"begins before, ends inside", 106, "bbbbbbbbbb",
+			result{"cccccbbbbbbbbbbAAAAA", false},
+			result{"cccccbbbbbAAAAAAAAAA", true}},
+		{"begins before, ends at its end", 106, "bbbbbbbbbbbbbbb",
+			result{"cccccbbbbbbbbbbbbbbb", false},
+			result{"cccccbbbbbbbbbbbbbbb", false}},
+		{"begins before, ends after", 106, "bbbbbbbbbbbbbbbbbbbb",
+			result{"cccccbbbbbbbbbbbbbbbbbbbb", false},
+			result{"cccccbbbbbbbbbbbbbbbbbbbb", false}},
+		// the original data is kept by both otherwise, even when the new
+		// segment ends after it
+		{"same start, ends after", 111, "bbbbbbbbbbbbbbb",
+			result{"cccccccccc" + "AAAAAAAAAAbbbbb", true},
+			result{"cccccccccc" + "AAAAAAAAAAbbbbb", true}},
+		{"begins inside, ends after", 115, "bbbbbbbbbb",
+			result{"cccccccccc" + "AAAAAAAAAAbbbb", true},
+			result{"cccccccccc" + "AAAAAAAAAAbbbb", true}},
+	} {
+		for _, policy := range []OverlapPolicy{OverlapBSD, OverlapWindows} {
+			want := test.bsd
+			if policy == OverlapWindows {
+				want = test.windows
+			}
+			s := assembleOverlap(policy, []*layers.TCP{
+				anomalySegment(true, "A", 111, 0, "AAAAAAAAAA"),
+				anomalySegment(true, "A", test.seq, 0, test.payload),
+			})
+			if got := s.data.String(); got != want.data {
+				t.Errorf("%s, %s: got %q, want %q", policy, test.name, got, want.data)
+			}
+			if kept := fmt.Sprintf("kept:%v", want.originalKept); len(s.anomalies) != 1 || !strings.HasSuffix(s.anomalies[0], kept) {
+				t.Errorf("%s, %s: got anomalies %q, want %s", policy, test.name, s.anomalies, kept)
+			}
+		}
+	}
+}
+
+func TestOverlapConflicts(t *testing.T) {
+	segments := []*layers.TCP{
+		anomalySegment(true, "S", 100, 0, ""),
+		anomalySegment(true, "A", 101, 0, "hello"),
+		// same data: no conflict
+		anomalySegment(true, "A", 101, 0, "hello"),
+		anomalySegment(true, "A", 104, 0, "p me"),
+		// queued
+		anomalySegment(true, "A", 120, 0, "queued"),
+		anomalySegment(true, "A", 120, 0, "QUEUED"),
+	}
+	queued := `OverlapConflict client->server 120+6 "queued"/"QUEUED" kept:true`
+	// without history, only the overlaps of queued data are checked
+	s := assembleAnomalies(AssemblerOptions{OverlapPolicy: OverlapFirst}, segments)
+	if want := []string{queued}; !reflect.DeepEqual(s.anomalies, want) {
+		t.Errorf("without history: got %q, want %q", s.anomalies, want)
+	}
+	s = assembleAnomalies(AssemblerOptions{OverlapPolicy: OverlapFirst, OverlapHistoryBytes: 4}, segments)
+	want := []string{
+		`OverlapConflict client->server 104+2 "lo"/"p " kept:true`,
+		queued,
+	}
+	if !reflect.DeepEqual(s.anomalies, want) {
+		t.Errorf("got %q, want %q", s.anomalies, want)
+	}
+	if got := s.data.String(); got != "hellome" {
+		t.Errorf("got data %q", got)
+	}
+}
+
+func TestWireAnomalies(t *testing.T) {
+	handshake := func() []*layers.TCP {
+		syn := withOption(testSegment(true, "S", 100, 0, 0), layers.TCPOptionKindWindowScale, []byte{2})
+		synack := withOption(testSegment(false, "SA", 500, 101, 0), layers.TCPOptionKindWindowScale, []byte{1})
+		syn.SetInternalPortsForTesting()
+		synack.SetInternalPortsForTesting()
+		// the window of the server is 200
+		ack := anomalySegment(false, "A", 501, 101, "")
+		ack.Window = 100
+		return []*layers.TCP{&syn, &synack, anomalySegment(true, "A", 101, 501, ""), ack}
+	}
+	ack := anomalySegment(false, "A", 501, 351, "")
+	ack.Window = 100
+	s := assembleAnomalies(DefaultAssemblerOptions, append(handshake(),
+		anomalySegment(true, "A", 101, 501, string(make([]byte, 150))),
+		anomalySegment(true, "A", 251, 501, string(make([]byte, 100))),
+		ack,
+		anomalySegment(true, "A", 351, 501, "!"),
+		anomalySegment(true, "FA", 351, 501, ""),
+		anomalySegment(true, "A", 352, 501, "after fin"),
+	))
+	want := []string{
+		"BeyondWindow client->server 251+100",
+		"UrgentPointer client->server 351+0 urgent:5",
+		"DataAfterFin client->server 352+9",
+	}
+	if !reflect.DeepEqual(s.anomalies, want) {
+		t.Errorf("got %q, want %q", s.anomalies, want)
+	}
+
+	s = assembleAnomalies(DefaultAssemblerOptions, append(handshake(),
+		anomalySegment(false, "R", 501, 0, ""),
+		anomalySegment(true, "A", 101, 501, "after rst"),
+	))
+	want = []string{"DataAfterReset client->server 101+9"}
+	if !reflect.DeepEqual(s.anomalies, want) {
+		t.Errorf("got %q, want %q", s.anomalies, want)
+	}
+}
@@ -0,0 +1,322 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package reassembly
+
+import (
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+)
+
+// CheckpointStream is implemented by Streams with state to save in a
+// checkpoint.  Checkpoint is called by StreamPool.Checkpoint, and the state
+// it returns is given back to RestoreStreamFactory.Restore.
+type CheckpointStream interface {
+	Stream
+	Checkpoint() ([]byte, error)
+}
+
+// RestoreStreamFactory is implemented by StreamFactories able to restore the
+// Streams of a checkpoint.  state is the value returned by the Stream's
+// Checkpoint method, or nil if it did not implement CheckpointStream.
+//
+// Assembler.Restore calls New instead for factories not implementing
+// RestoreStreamFactory, with a TCP layer only holding the ports and the
+// next sequence number of the connection.
+type RestoreStreamFactory interface {
+	StreamFactory
+	Restore(netFlow, tcpFlow gopacket.Flow, state []byte, ac AssemblerContext) (Stream, error)
+}
+
+// checkpointVersion is the version of the checkpoint format.
+const checkpointVersion = 1
+
+var errCheckpointVersion = errors.New("reassembly: unsupported checkpoint version")
+
+type checkpointHeader struct {
+	Version     int
+	Connections int
+}
+
+type checkpointConnection struct {
+	NetType, TransportType         gopacket.EndpointType
+	NetSrc, NetDst                 []byte
+	TransportSrc, TransportDst     []byte
+	ClientToServer, ServerToClient checkpointHalf
+	RSTSeen                        bool
+	State                          []byte
+}
+
+type checkpointHalf struct {
+	NextSeq, AckSeq   Sequence
+	Created, LastSeen time.Time
+	Closed            bool
+	QueuedBytes       int
+	QueuedPackets     int
+	OverlapBytes      int
+	OverlapPackets    int
+	Saved, Pages      []checkpointPage
+	// for anomalies
+	SynSeen     bool
+	WindowScale int
+	Window      int
+	FinSeq      Sequence
+	History     []byte
+	HistoryEnd  Sequence
+}
+
+type checkpointPage struct {
+	Seq        Sequence
+	Bytes      []byte
+	Seen       time.Time
+	Start, End bool
+	// Packet is set for the first page of a packet, which has a CaptureInfo
+	Packet      bool
+	CaptureInfo gopacket.CaptureInfo
+}
+
+// Checkpoint writes the connections of the pool to w: their flows, sequence
+// numbers, buffered pages and, for CheckpointStreams, their state.  The
+// Assemblers using the pool must not run during the checkpoint.  The
+// connections are left untouched, so the pool can go on after a checkpoint.
+//
+// The AncillaryData of the buffered packets' CaptureInfo is not saved.
+func (p *StreamPool) Checkpoint(w io.Writer) error {
+	conns := p.connections()
+	enc := gob.NewEncoder(w)
+	if err := enc.Encode(checkpointHeader{Version: checkpointVersion, Connections: len(conns)}); err != nil {
+		return err
+	}
+	for _, conn := range conns {
+		conn.mu.Lock()
+		c, err := conn.checkpoint()
+		conn.mu.Unlock()
+		if err != nil {
+			return fmt.Errorf("reassembly: checkpoint of %s: %v", &conn.key, err)
+		}
+		if err := enc.Encode(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *connection) checkpoint() (*checkpointConnection, error) {
+	net, transport := c.key[0], c.key[1]
+	src, dst := net.Endpoints()
+	tsrc, tdst := transport.Endpoints()
+	cc := &checkpointConnection{
+		NetType:        net.EndpointType(),
+		TransportType:  transport.EndpointType(),
+		NetSrc:         src.Raw(),
+		NetDst:         dst.Raw(),
+		TransportSrc:   tsrc.Raw(),
+		TransportDst:   tdst.Raw(),
+		ClientToServer: c.c2s.checkpoint(),
+		ServerToClient: c.s2c.checkpoint(),
+		RSTSeen:        c.rstSeen,
+	}
+	if s, ok := c.c2s.stream.(CheckpointStream); ok {
+		state, err := s.Checkpoint()
+		if err != nil {
+			return nil, err
+		}
+		cc.State = state
+	}
+	return cc, nil
+}
+
+func (half *halfconnection) checkpoint() checkpointHalf {
+	return checkpointHalf{
+		NextSeq:        half.nextSeq,
+		AckSeq:         half.ackSeq,
+		Created:        half.created,
+		LastSeen:       half.lastSeen,
+		Closed:         half.closed,
+		QueuedBytes:    half.queuedBytes,
+		QueuedPackets:  half.queuedPackets,
+		OverlapBytes:   half.overlapBytes,
+		OverlapPackets: half.overlapPackets,
+		Saved:          checkpointPages(half.saved),
+		Pages:          checkpointPages(half.first),
+		SynSeen:        half.synSeen,
+		WindowScale:    half.wscale,
+		Window:         half.window,
+		FinSeq:         half.finSeq,
+		History:        half.history,
+		HistoryEnd:     half.historyEnd,
+	}
+}
+
+func checkpointPages(first *page) []checkpointPage {
+	var pages []checkpointPage
+	for p := first; p != nil; p = p.next {
+		cp := checkpointPage{
+			Seq:   p.seq,
+			Bytes: p.bytes,
+			Seen:  p.seen,
+			Start: p.start,
+			End:   p.end,
+		}
+		if p.isPacket() {
+			cp.Packet = true
+			cp.CaptureInfo = p.captureInfo()
+			cp.CaptureInfo.AncillaryData = nil
+		}
+		pages = append(pages, cp)
+	}
+	return pages
+}
+
+// Restore reads a checkpoint written by StreamPool.Checkpoint into the
+// Assembler's pool, which should be new, and returns the number of
+// connections restored.  The Streams are created by the pool's factory,
+// through its Restore method if it is a RestoreStreamFactory.  Connections
+// already in the pool are kept, and take precedence over the restored ones.
+//
+// The buffered pages are taken from the Assembler's page cache.  Restore
+// doesn't check MaxBufferedPagesTotal, but the restored pages count
+// towards it once restored: over the limit, the Assembler flushes the
+// connections it gets packets for rather than buffering more pages.
+func (a *Assembler) Restore(r io.Reader) (restored int, err error) {
+	dec := gob.NewDecoder(r)
+	var h checkpointHeader
+	if err := dec.Decode(&h); err != nil {
+		return 0, err
+	}
+	if h.Version != checkpointVersion {
+		return 0, errCheckpointVersion
+	}
+	for i := 0; i < h.Connections; i++ {
+		var cc checkpointConnection
+		if err := dec.Decode(&cc); err != nil {
+			return restored, err
+		}
+		ok, err := a.restoreConnection(&cc)
+		if err != nil {
+			return restored, err
+		}
+		if ok {
+			restored++
+		}
+	}
+	return restored, nil
+}
+
+func (a *Assembler) restoreConnection(cc *checkpointConnection) (bool, error) {
+	p := a.connPool
+	k := key{
+		gopacket.NewFlow(cc.NetType, cc.NetSrc, cc.NetDst),
+		gopacket.NewFlow(cc.TransportType, cc.TransportSrc, cc.TransportDst),
+	}
+	for _, ch := range []*checkpointHalf{&cc.ClientToServer, &cc.ServerToClient} {
+		for _, cps := range [][]checkpointPage{ch.Saved, ch.Pages} {
+			for i := range cps {
+				if n := len(cps[i].Bytes); n > pageBytes {
+					return false, fmt.Errorf("reassembly: restore of %s: page of %d bytes, more than %d", &k, n, pageBytes)
+				}
+			}
+		}
+	}
+	lastSeen := cc.ClientToServer.LastSeen
+	if lastSeen.Before(cc.ServerToClient.LastSeen) {
+		lastSeen = cc.ServerToClient.LastSeen
+	}
+	ac := packetContext(gopacket.CaptureInfo{Timestamp: lastSeen})
+	var s Stream
+	if f, ok := p.factory.(RestoreStreamFactory); ok {
+		var err error
+		if s, err = f.Restore(k[0], k[1], cc.State, &ac); err != nil {
+			return false, fmt.Errorf("reassembly: restore of %s: %v", &k, err)
+		}
+	} else {
+		s = p.factory.New(k[0], k[1], restoredTCP(k[1], cc.ClientToServer.NextSeq), &ac)
+	}
+	if s == nil {
+		return false, nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if conn, _, _ := p.getHalf(k); conn != nil {
+		return false, nil
+	}
+	conn, c2s, s2c := p.newConnection(k, s, cc.ClientToServer.Created)
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	a.restoreHalf(conn, c2s, &cc.ClientToServer)
+	a.restoreHalf(conn, s2c, &cc.ServerToClient)
+	conn.rstSeen = cc.RSTSeen
+	p.conns[k] = conn
+	return true, nil
+}
+
+func (a *Assembler) restoreHalf(conn *connection, half *halfconnection, ch *checkpointHalf) {
+	half.nextSeq = ch.NextSeq
+	half.ackSeq = ch.AckSeq
+	half.created = ch.Created
+	half.lastSeen = ch.LastSeen
+	half.closed = ch.Closed
+	half.queuedBytes = ch.QueuedBytes
+	half.queuedPackets = ch.QueuedPackets
+	half.overlapBytes = ch.OverlapBytes
+	half.overlapPackets = ch.OverlapPackets
+	half.saved, _ = a.restorePages(ch.Saved)
+	half.first, half.last = a.restorePages(ch.Pages)
+	half.pages = len(ch.Saved) + len(ch.Pages)
+	half.synSeen = ch.SynSeen
+	half.wscale = ch.WindowScale
+	half.window = ch.Window
+	half.finSeq = ch.FinSeq
+	half.history = ch.History
+	half.historyEnd = ch.HistoryEnd
+	a.connPool.trackBuffered(conn, half, a.pc)
+}
+
+// restorePages returns a list of pages taken from the Assembler's pageCache.
+func (a *Assembler) restorePages(cps []checkpointPage) (first, last *page) {
+	for i := range cps {
+		cp := &cps[i]
+		pg := a.pc.next(cp.Seen)
+		pg.seq, pg.start, pg.end = cp.Seq, cp.Start, cp.End
+		// the size of cp.Bytes was checked by restoreConnection
+		pg.bytes = pg.buf[:copy(pg.buf[:], cp.Bytes)]
+		if cp.Packet {
+			ac := packetContext(cp.CaptureInfo)
+			pg.ac = &ac
+		}
+		if first == nil {
+			first = pg
+		} else {
+			last.next = pg
+			pg.prev = last
+		}
+		last = pg
+	}
+	return first, last
+}
+
+// restoredTCP returns the TCP layer given to StreamFactory.New for restored
+// connections.
+func restoredTCP(tcpFlow gopacket.Flow, seq Sequence) *layers.TCP {
+	src, dst := tcpFlow.Endpoints()
+	header := make([]byte, 20)
+	copy(header[0:2], src.Raw())
+	copy(header[2:4], dst.Raw())
+	if seq != invalidSequence {
+		binary.BigEndian.PutUint32(header[4:8], uint32(seq))
+	}
+	header[12] = 5 << 4
+	tcp := &layers.TCP{}
+	tcp.DecodeFromBytes(header, gopacket.NilDecodeFeedback)
+	return tcp
+}
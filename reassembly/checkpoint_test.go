@@ -0,0 +1,224 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package reassembly
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+)
+
+/* For checkpoint tests: saves its state machine and the data received */
+type testCheckpointFactory struct {
+	streams  []*testCheckpointStream
+	restored int
+}
+
+type testCheckpointStream struct {
+	fsm      *TCPSimpleFSM
+	data     bytes.Buffer
+	rejected int
+}
+
+func (f *testCheckpointFactory) New(a, b gopacket.Flow, tcp *layers.TCP, ac AssemblerContext) Stream {
+	s := &testCheckpointStream{fsm: NewTCPSimpleFSM(TCPSimpleFSMOptions{})}
+	f.streams = append(f.streams, s)
+	return s
+}
+
+func (f *testCheckpointFactory) Restore(a, b gopacket.Flow, state []byte, ac AssemblerContext) (Stream, error) {
+	s := f.New(a, b, nil, ac).(*testCheckpointStream)
+	if err := s.fsm.UnmarshalBinary(state[:2]); err != nil {
+		return nil, err
+	}
+	s.data.Write(state[2:])
+	f.restored++
+	return s, nil
+}
+
+func (s *testCheckpointStream) Checkpoint() ([]byte, error) {
+	state, err := s.fsm.MarshalBinary()
+	return append(state, s.data.Bytes()...), err
+}
+
+func (s *testCheckpointStream) Accept(tcp *layers.TCP, ci gopacket.CaptureInfo, dir TCPFlowDirection, seq Sequence, start *bool, ac AssemblerContext) bool {
+	if !s.fsm.CheckState(tcp, dir) {
+		s.rejected++
+		return false
+	}
+	return true
+}
+
+func (s *testCheckpointStream) ReassembledSG(sg ScatterGather, ac AssemblerContext) {
+	l, _ := sg.Lengths()
+	s.data.Write(sg.Fetch(l))
+}
+
+func (s *testCheckpointStream) ReassemblyComplete(ac AssemblerContext) bool {
+	return true
+}
+
+func checkpointSegment(client bool, flags string, seq, ack uint32, payload string) *layers.TCP {
+	tcp := testSegment(client, flags, seq, ack, 0)
+	tcp.Payload = []byte(payload)
+	tcp.SetInternalPortsForTesting()
+	return &tcp
+}
+
+func TestCheckpointRestore(t *testing.T) {
+	start := time.Unix(1432538521, 0)
+	ctx := assemblerSimpleContext(gopacket.CaptureInfo{Timestamp: start})
+	fact := &testCheckpointFactory{}
+	pool := NewStreamPool(fact)
+	a := NewAssembler(pool)
+	for _, tcp := range []*layers.TCP{
+		checkpointSegment(true, "S", 100, 0, ""),
+		checkpointSegment(false, "SA", 500, 101, ""),
+		checkpointSegment(true, "A", 101, 501, "hello "),
+		// out of order, buffered
+		checkpointSegment(true, "A", 113, 501, "world"),
+		checkpointSegment(false, "A", 501, 107, "ok"),
+	} {
+		flow := netFlow
+		if tcp.SrcPort != testClientPort {
+			flow = flow.Reverse()
+		}
+		a.AssembleWithContext(flow, tcp, &ctx)
+	}
+	var buf bytes.Buffer
+	if err := pool.Checkpoint(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	fact2 := &testCheckpointFactory{}
+	pool2 := NewStreamPool(fact2)
+	a2 := NewAssembler(pool2)
+	if n, err := a2.Restore(bytes.NewReader(buf.Bytes())); err != nil || n != 1 {
+		t.Fatalf("restored %d connections: %v", n, err)
+	}
+	if fact2.restored != 1 {
+		t.Fatalf("got %d streams restored", fact2.restored)
+	}
+	if got := pool2.MemoryStats().PagesInUse; got != 1 || a2.pc.used != 1 {
+		t.Errorf("got %d pages in use, %d in the page cache, want 1", got, a2.pc.used)
+	}
+	if pool2.buffered.Len() != 1 {
+		t.Errorf("got %d buffering halves, want 1", pool2.buffered.Len())
+	}
+	s := fact2.streams[0]
+	if s.fsm.String() != "Established" {
+		t.Errorf("got state %s", s.fsm)
+	}
+	// the connection goes on: no new stream for the missing SYN
+	a2.AssembleWithContext(netFlow, checkpointSegment(true, "A", 107, 503, "brave "), &ctx)
+	if got, want := s.data.String(), "hello okbrave world"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if len(fact2.streams) != 1 || s.rejected != 0 {
+		t.Errorf("got %d streams, %d packets rejected", len(fact2.streams), s.rejected)
+	}
+	if closed := a2.FlushAll(); closed != 1 {
+		t.Errorf("got %d connections closed", closed)
+	}
+	if got := pool2.MemoryStats().PagesInUse; got != 0 || a2.pc.used != 0 {
+		t.Errorf("got %d pages in use, %d in the page cache after flush", got, a2.pc.used)
+	}
+}
+
+func TestCheckpointNew(t *testing.T) {
+	ctx := assemblerSimpleContext(gopacket.CaptureInfo{Timestamp: time.Unix(1432538521, 0)})
+	pool := NewStreamPool(&testFactory{})
+	a := NewAssembler(pool)
+	a.AssembleWithContext(netFlow, checkpointSegment(true, "S", 100, 0, ""), &ctx)
+	a.AssembleWithContext(netFlow, checkpointSegment(true, "A", 110, 0, "later"), &ctx)
+	var buf bytes.Buffer
+	if err := pool.Checkpoint(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	// without Restore, New is called with the ports and the next sequence
+	var got *layers.TCP
+	fact := &testFactory{}
+	pool2 := NewStreamPool(testNewFunc(func(a, b gopacket.Flow, tcp *layers.TCP, ac AssemblerContext) Stream {
+		got = tcp
+		return fact
+	}))
+	a2 := NewAssembler(pool2)
+	if n, err := a2.Restore(&buf); err != nil || n != 1 {
+		t.Fatalf("restored %d connections: %v", n, err)
+	}
+	if got == nil || got.SrcPort != testClientPort || got.DstPort != testServerPort || got.Seq != 101 {
+		t.Fatalf("got TCP layer %+v", got)
+	}
+	a2.AssembleWithContext(netFlow, checkpointSegment(true, "A", 101, 0, "123456789"), &ctx)
+	if len(fact.reassembly) != 1 || string(fact.reassembly[0].Bytes) != "123456789later" {
+		t.Errorf("got %+v", fact.reassembly)
+	}
+}
+
+type testNewFunc func(a, b gopacket.Flow, tcp *layers.TCP, ac AssemblerContext) Stream
+
+func (f testNewFunc) New(a, b gopacket.Flow, tcp *layers.TCP, ac AssemblerContext) Stream {
+	return f(a, b, tcp, ac)
+}
+
+func TestCheckpointVersion(t *testing.T) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(checkpointHeader{Version: 42}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewAssembler(NewStreamPool(&testFactory{})).Restore(&buf); err != errCheckpointVersion {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func TestCheckpointPageTooLarge(t *testing.T) {
+	ctx := assemblerSimpleContext(gopacket.CaptureInfo{Timestamp: time.Unix(1432538521, 0)})
+	pool := NewStreamPool(&testFactory{})
+	a := NewAssembler(pool)
+	a.AssembleWithContext(netFlow, checkpointSegment(true, "S", 100, 0, ""), &ctx)
+	a.AssembleWithContext(netFlow, checkpointSegment(true, "A", 110, 0, "later"), &ctx)
+	var buf bytes.Buffer
+	if err := pool.Checkpoint(&buf); err != nil {
+		t.Fatal(err)
+	}
+	dec := gob.NewDecoder(&buf)
+	var h checkpointHeader
+	var cc checkpointConnection
+	if err := dec.Decode(&h); err != nil {
+		t.Fatal(err)
+	}
+	if err := dec.Decode(&cc); err != nil {
+		t.Fatal(err)
+	}
+	if len(cc.ClientToServer.Pages) != 1 {
+		t.Fatalf("got %d pages", len(cc.ClientToServer.Pages))
+	}
+	cc.ClientToServer.Pages[0].Bytes = make([]byte, pageBytes+1)
+	buf.Reset()
+	enc := gob.NewEncoder(&buf)
+	if err := enc.Encode(h); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Encode(cc); err != nil {
+		t.Fatal(err)
+	}
+
+	fact := &testCheckpointFactory{}
+	pool2 := NewStreamPool(fact)
+	a2 := NewAssembler(pool2)
+	if n, err := a2.Restore(&buf); err == nil || n != 0 {
+		t.Fatalf("restored %d connections: %v", n, err)
+	}
+	if len(fact.streams) != 0 || a2.pc.used != 0 || pool2.buffered.Len() != 0 {
+		t.Errorf("got %d streams, %d pages used, %d buffering halves", len(fact.streams), a2.pc.used, pool2.buffered.Len())
+	}
+}
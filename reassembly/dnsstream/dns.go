@@ -0,0 +1,210 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+/*
+Package dnsstream decodes DNS messages carried by TCP streams reassembled
+by the reassembly package, such as zone transfers and queries retried
+over TCP after a truncated UDP response.
+
+Over TCP, every message is preceded by its two byte length (RFC 1035,
+section 4.2.2). Each direction of a connection is split into messages as
+data is handed to ReassembledSG, whether a segment carries several
+messages or a message spans several segments, and every message is
+delivered to a Handler:
+
+	handler := dnsstream.HandlerFunc(func(m *dnsstream.Message) {
+		for _, q := range m.DNS.Questions {
+			fmt.Println(m.DNS.ID, m.DNS.QR, string(q.Name), q.Type)
+		}
+	})
+	factory := &dnsstream.StreamFactory{Handler: handler}
+	pool := reassembly.NewStreamPool(factory)
+	assembler := reassembly.NewAssembler(pool)
+	for packet := range packets {
+		tcp := packet.TransportLayer().(*layers.TCP)
+		assembler.AssembleWithContext(packet.NetworkLayer().NetworkFlow(), tcp, ctx)
+	}
+	assembler.FlushAll()
+
+When bytes are lost in the capture the message boundaries can no longer
+be found, and the rest of the direction is ignored.
+*/
+package dnsstream
+
+import (
+	"encoding/binary"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+	"github.com/gopacket/gopacket/reassembly"
+)
+
+// Message is a DNS message seen on the wire.
+type Message struct {
+	// NetFlow and TransportFlow are oriented from the sender of the
+	// message to its receiver.
+	NetFlow, TransportFlow gopacket.Flow
+	// DNS is the decoded message. Its TCP field is set, and its Contents
+	// hold the length prefix and the message.
+	DNS *layers.DNS
+	// Err is the error decoding the message, if any, in which case DNS is
+	// only partially decoded.
+	Err error
+	// Seen is the capture info of the packet carrying the first byte of
+	// the message, Complete the one carrying its last byte.
+	Seen, Complete gopacket.CaptureInfo
+}
+
+// Handler receives the messages decoded from the streams.
+type Handler interface {
+	HandleMessage(m *Message)
+}
+
+// HandlerFunc adapts a function to the Handler interface.
+type HandlerFunc func(m *Message)
+
+// HandleMessage calls f(m).
+func (f HandlerFunc) HandleMessage(m *Message) { f(m) }
+
+// StreamFactory implements reassembly.StreamFactory, creating a Stream
+// decoding DNS for every new connection.
+type StreamFactory struct {
+	// Handler is called for every message, from the goroutine calling
+	// the Assembler.
+	Handler Handler
+	// AllowMissingInit accepts connections whose SYN was not captured.
+	// Their first bytes must still be the start of a message.
+	AllowMissingInit bool
+}
+
+// New implements reassembly.StreamFactory.
+func (f *StreamFactory) New(netFlow, tcpFlow gopacket.Flow, tcp *layers.TCP, ac reassembly.AssemblerContext) reassembly.Stream {
+	s := &Stream{factory: f}
+	s.halves[0] = halfStream{stream: s, netFlow: netFlow, transportFlow: tcpFlow}
+	s.halves[1] = halfStream{stream: s, netFlow: netFlow.Reverse(), transportFlow: tcpFlow.Reverse()}
+	return s
+}
+
+// Stream decodes the DNS messages of one TCP connection. It implements
+// reassembly.Stream.
+type Stream struct {
+	factory *StreamFactory
+	halves  [2]halfStream
+}
+
+func (s *Stream) half(dir reassembly.TCPFlowDirection) *halfStream {
+	if dir == reassembly.TCPDirClientToServer {
+		return &s.halves[0]
+	}
+	return &s.halves[1]
+}
+
+// Accept implements reassembly.Stream.
+func (s *Stream) Accept(tcp *layers.TCP, ci gopacket.CaptureInfo, dir reassembly.TCPFlowDirection, nextSeq reassembly.Sequence, start *bool, ac reassembly.AssemblerContext) bool {
+	if s.factory.AllowMissingInit {
+		*start = true
+	}
+	return true
+}
+
+// ReassembledSG implements reassembly.Stream.
+func (s *Stream) ReassembledSG(sg reassembly.ScatterGather, ac reassembly.AssemblerContext) {
+	dir, _, end, skip := sg.Info()
+	h := s.half(dir)
+	length, _ := sg.Lengths()
+	if skip > 0 {
+		h.stop()
+	}
+	if length > 0 {
+		h.feed(sg.Fetch(length), sg)
+	}
+	if end {
+		h.stop()
+	}
+}
+
+// ReassemblyComplete implements reassembly.Stream. Incomplete messages
+// are dropped.
+func (s *Stream) ReassemblyComplete(ac reassembly.AssemblerContext) bool {
+	s.halves[0].stop()
+	s.halves[1].stop()
+	return true
+}
+
+// halfStream splits one direction of a connection into messages.
+type halfStream struct {
+	stream                 *Stream
+	netFlow, transportFlow gopacket.Flow
+	done                   bool
+	// buf holds the bytes of the messages not complete yet.
+	buf []byte
+	// sg is the ScatterGather being fed, and base the offset in buf of
+	// its first byte.
+	sg   reassembly.ScatterGather
+	base int
+	// first is the capture info of the packet carrying buf[0], when it
+	// came with a previous ScatterGather.
+	first gopacket.CaptureInfo
+}
+
+// ci returns the capture info of the packet carrying buf[offset].
+func (h *halfStream) ci(offset int) gopacket.CaptureInfo {
+	if offset -= h.base; offset >= 0 {
+		return h.sg.CaptureInfo(offset)
+	}
+	return h.first
+}
+
+func (h *halfStream) feed(data []byte, sg reassembly.ScatterGather) {
+	if h.done {
+		return
+	}
+	h.buf = append(h.buf, data...)
+	h.base = len(h.buf) - len(data)
+	h.sg = sg
+	for len(h.buf) >= 2 {
+		n := 2 + int(binary.BigEndian.Uint16(h.buf))
+		if len(h.buf) < n {
+			break
+		}
+		h.emit(n)
+		h.buf = h.buf[n:]
+		h.base -= n
+	}
+	if len(h.buf) > 0 {
+		h.first = h.ci(0)
+		// keep the incomplete message only, not the whole segment
+		h.buf = append([]byte(nil), h.buf...)
+	} else {
+		h.buf = nil
+	}
+	h.sg = nil
+}
+
+// emit decodes and delivers the message of n bytes, length included,
+// at the start of buf.
+func (h *halfStream) emit(n int) {
+	if h.stream.factory.Handler == nil {
+		return
+	}
+	m := &Message{
+		NetFlow:       h.netFlow,
+		TransportFlow: h.transportFlow,
+		DNS:           &layers.DNS{TCP: true},
+		Seen:          h.ci(0),
+		Complete:      h.ci(n - 1),
+	}
+	// the layer points into the data it decodes
+	data := append([]byte(nil), h.buf[:n]...)
+	m.Err = m.DNS.DecodeFromBytes(data, gopacket.NilDecodeFeedback)
+	h.stream.factory.Handler.HandleMessage(m)
+}
+
+// stop ignores the rest of the direction.
+func (h *halfStream) stop() {
+	h.done = true
+	h.buf = nil
+}
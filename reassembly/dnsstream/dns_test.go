@@ -0,0 +1,143 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package dnsstream
+
+import (
+	"net"
+	"testing"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+	"github.com/gopacket/gopacket/reassembly/internal/streamtest"
+)
+
+// testConn feeds the packets of a synthetic TCP connection to an
+// assembler and records the messages.
+type testConn struct {
+	*streamtest.Conn
+	msgs []*Message
+}
+
+func newTestConn(t *testing.T, f *StreamFactory) *testConn {
+	c := &testConn{}
+	f.Handler = HandlerFunc(func(m *Message) { c.msgs = append(c.msgs, m) })
+	c.Conn = streamtest.NewConn(t, f, 53)
+	return c
+}
+
+// send sends data in segments of at most size bytes.
+func (c *testConn) send(server bool, data []byte, size int) {
+	c.Send(server, string(data), size)
+}
+
+// message returns a length-prefixed message.
+func message(t *testing.T, d *layers.DNS) []byte {
+	t.Helper()
+	d.TCP = true
+	buf := gopacket.NewSerializeBuffer()
+	if err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{FixLengths: true}, d); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func query(t *testing.T, id uint16, name string) []byte {
+	return message(t, &layers.DNS{ID: id, Questions: []layers.DNSQuestion{{Name: []byte(name), Type: layers.DNSTypeA, Class: layers.DNSClassIN}}})
+}
+
+func answer(t *testing.T, id uint16, name string, ip net.IP) []byte {
+	return message(t, &layers.DNS{ID: id, QR: true, AA: true, Answers: []layers.DNSResourceRecord{
+		{Name: []byte(name), Type: layers.DNSTypeA, Class: layers.DNSClassIN, TTL: 60, IP: ip},
+	}})
+}
+
+func TestMessages(t *testing.T) {
+	c := newTestConn(t, &StreamFactory{})
+	c.Handshake()
+	// two queries in one segment
+	c.send(false, append(query(t, 1, "example.com"), query(t, 2, "example.org")...), 1000)
+	// a zone transfer answered by three messages, in segments that do not
+	// follow the message boundaries, the first one splitting a length
+	var answers []byte
+	for i, name := range []string{"a.example.com", "b.example.com", "c.example.com"} {
+		answers = append(answers, answer(t, 1, name, net.IP{192, 0, 2, byte(i + 1)})...)
+	}
+	c.send(true, answers, 44)
+	c.Assembler.FlushAll()
+
+	if len(c.msgs) != 5 {
+		t.Fatalf("got %d messages, want 5", len(c.msgs))
+	}
+	for i, m := range c.msgs[:2] {
+		if m.Err != nil || m.DNS.QR || m.DNS.ID != uint16(i+1) || len(m.DNS.Questions) != 1 {
+			t.Errorf("query %d: got %+v, error %v", i, m.DNS, m.Err)
+		}
+		if m.NetFlow != streamtest.ClientFlow || m.Seen.Timestamp != streamtest.PacketTime(3) || m.Complete.Timestamp != streamtest.PacketTime(3) {
+			t.Errorf("query %d: got flow %v, seen %v, complete %v", i, m.NetFlow, m.Seen.Timestamp, m.Complete.Timestamp)
+		}
+	}
+	if name := string(c.msgs[1].DNS.Questions[0].Name); name != "example.org" {
+		t.Errorf("got second query for %q", name)
+	}
+	// the answers are 43 bytes long, at offsets 0, 43 and 86, in the
+	// segments of packets 4 to 6
+	seen := []int{4, 4, 5}
+	complete := []int{4, 5, 6}
+	for i, m := range c.msgs[2:] {
+		if m.Err != nil || !m.DNS.QR || len(m.DNS.Answers) != 1 || !m.DNS.Answers[0].IP.Equal(net.IP{192, 0, 2, byte(i + 1)}) {
+			t.Errorf("answer %d: got %+v, error %v", i, m.DNS, m.Err)
+		}
+		if m.NetFlow != streamtest.ClientFlow.Reverse() || len(m.DNS.Contents) != 43 {
+			t.Errorf("answer %d: got flow %v, %d bytes", i, m.NetFlow, len(m.DNS.Contents))
+		}
+		if m.Seen.Timestamp != streamtest.PacketTime(seen[i]) || m.Complete.Timestamp != streamtest.PacketTime(complete[i]) {
+			t.Errorf("answer %d: got seen %v, complete %v", i, m.Seen.Timestamp, m.Complete.Timestamp)
+		}
+	}
+}
+
+func TestSpanningMessage(t *testing.T) {
+	c := newTestConn(t, &StreamFactory{})
+	c.Handshake()
+	c.send(false, query(t, 7, "example.com"), 1)
+	c.Assembler.FlushAll()
+	if len(c.msgs) != 1 {
+		t.Fatalf("got %d messages, want 1", len(c.msgs))
+	}
+	m := c.msgs[0]
+	if m.Err != nil || m.DNS.ID != 7 || string(m.DNS.Questions[0].Name) != "example.com" {
+		t.Errorf("got %+v, error %v", m.DNS, m.Err)
+	}
+	if m.Seen.Timestamp != streamtest.PacketTime(3) || m.Complete.Timestamp != streamtest.PacketTime(2+len(m.DNS.Contents)) {
+		t.Errorf("got seen %v, complete %v", m.Seen.Timestamp, m.Complete.Timestamp)
+	}
+}
+
+func TestLostBytes(t *testing.T) {
+	c := newTestConn(t, &StreamFactory{})
+	c.Handshake()
+	c.send(false, query(t, 1, "example.com"), 1000)
+	c.Skip(false, 10)
+	c.send(false, query(t, 2, "example.com"), 1000)
+	c.send(true, answer(t, 1, "example.com", net.IP{192, 0, 2, 1}), 1000)
+	c.Assembler.FlushAll()
+	if len(c.msgs) != 2 || c.msgs[0].DNS.ID != 1 || c.msgs[0].DNS.QR || !c.msgs[1].DNS.QR {
+		t.Fatalf("got %d messages", len(c.msgs))
+	}
+}
+
+func TestMalformedMessage(t *testing.T) {
+	c := newTestConn(t, &StreamFactory{})
+	c.Handshake()
+	// a length covering a message too short for the DNS header, then a
+	// valid message
+	c.send(false, append([]byte{0, 3, 1, 2, 3}, query(t, 1, "example.com")...), 1000)
+	c.Assembler.FlushAll()
+	if len(c.msgs) != 2 || c.msgs[0].Err == nil || c.msgs[1].Err != nil || c.msgs[1].DNS.ID != 1 {
+		t.Fatalf("got %d messages", len(c.msgs))
+	}
+}
@@ -0,0 +1,412 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+/*
+Package flowwriter writes the TCP streams reassembled by the reassembly
+package to files, one per direction of every connection, named after their
+endpoints like tcpflow does:
+
+	010.000.000.001.54321-010.000.000.002.00080
+	010.000.000.002.00080-010.000.000.001.54321
+
+The original packets of every connection can also be written to their own
+pcap file, and a JSON record describing every connection written to an
+index once it is complete:
+
+	index, _ := os.Create("flows/index.json")
+	factory := &flowwriter.StreamFactory{Dir: "flows", WritePcap: true, Index: index}
+	pool := reassembly.NewStreamPool(factory)
+	assembler := reassembly.NewAssembler(pool)
+	for packet := range packets {
+		tcp := packet.TransportLayer().(*layers.TCP)
+		assembler.AssembleWithContext(packet.NetworkLayer().NetworkFlow(), tcp, flowwriter.NewContext(packet))
+	}
+	assembler.FlushAll()
+
+The pcap files need the data of the packets, which is only available if the
+AssemblerContext given to the Assembler is a PacketContext, such as the
+Context returned by NewContext.
+
+Bytes missing from the capture are left as holes in the files, so that
+the offset of every byte in its file is its offset in the stream.  Files
+are kept open until their connection is complete, so the number of open
+files grows with the number of open connections.
+*/
+package flowwriter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+	"github.com/gopacket/gopacket/pcapgo"
+	"github.com/gopacket/gopacket/reassembly"
+)
+
+// PacketContext is implemented by AssemblerContexts carrying the data of
+// their packet, needed to write pcap files.
+type PacketContext interface {
+	reassembly.AssemblerContext
+	PacketData() []byte
+}
+
+// Context is a PacketContext.
+type Context struct {
+	CaptureInfo gopacket.CaptureInfo
+	Data        []byte
+}
+
+// NewContext returns the Context of a decoded packet.
+func NewContext(packet gopacket.Packet) *Context {
+	return &Context{CaptureInfo: packet.Metadata().CaptureInfo, Data: packet.Data()}
+}
+
+// GetCaptureInfo implements reassembly.AssemblerContext.
+func (c *Context) GetCaptureInfo() gopacket.CaptureInfo {
+	return c.CaptureInfo
+}
+
+// PacketData implements PacketContext.
+func (c *Context) PacketData() []byte {
+	return c.Data
+}
+
+// FileRecord describes a file written for a connection.
+type FileRecord struct {
+	// Name is the name of the file, relative to StreamFactory.Dir.  It is
+	// empty if no data was written.
+	Name string `json:"name,omitempty"`
+	// Bytes is the size of the file.
+	Bytes int64 `json:"bytes"`
+	// Missing is the number of bytes missing from the capture, left as
+	// holes in the file.
+	Missing int64 `json:"missing,omitempty"`
+	// Truncated is set if data was discarded once the file reached
+	// StreamFactory.MaxFileSize.
+	Truncated bool `json:"truncated,omitempty"`
+	// Error is the error that stopped the writing of the file, if any.
+	Error string `json:"error,omitempty"`
+}
+
+// ConnectionRecord describes a connection, in the index.
+type ConnectionRecord struct {
+	// Client and Server are the endpoints of the connection, as
+	// "host:port".  The client is the sender of the first packet seen.
+	Client string    `json:"client"`
+	Server string    `json:"server"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	// Packets is the number of packets seen.
+	Packets        int        `json:"packets"`
+	ClientToServer FileRecord `json:"client_to_server"`
+	ServerToClient FileRecord `json:"server_to_client"`
+	// Pcap is the pcap file of the connection, if StreamFactory.WritePcap
+	// is set.
+	Pcap *FileRecord `json:"pcap,omitempty"`
+}
+
+// StreamFactory implements reassembly.StreamFactory, creating a Stream
+// writing the data of every new connection to files.  It is safe for
+// concurrency, so it can be shared by Assemblers running concurrently.
+type StreamFactory struct {
+	// Dir is the directory of the files, created if needed.  The current
+	// directory is used if it is empty.
+	Dir string
+	// MaxFileSize limits the size of every file, pcap files included: the
+	// data beyond it is discarded.  If <= 0, the size is not limited.
+	MaxFileSize int64
+	// WritePcap writes the packets of every connection to a pcap file
+	// named after its client and server, with a ".pcap" suffix.
+	WritePcap bool
+	// LinkType is the link type of the pcap files.  If it is zero, i.e.
+	// LinkTypeNull, LinkTypeEthernet is used.
+	LinkType layers.LinkType
+	// Index, if set, receives a ConnectionRecord for every connection once
+	// it is complete, as a line of JSON.
+	Index io.Writer
+	// AllowMissingInit accepts connections whose SYN was not captured.
+	AllowMissingInit bool
+
+	mu    sync.Mutex
+	names map[string]int
+	index *json.Encoder
+	// err is the first error writing the index or creating Dir
+	err error
+}
+
+// Err returns the first error writing the index or creating Dir.  The
+// errors of the stream files are in the ConnectionRecords.
+func (f *StreamFactory) Err() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.err
+}
+
+// New implements reassembly.StreamFactory.
+func (f *StreamFactory) New(netFlow, tcpFlow gopacket.Flow, tcp *layers.TCP, ac reassembly.AssemblerContext) reassembly.Stream {
+	client := endpointName(netFlow.Src(), tcpFlow.Src())
+	server := endpointName(netFlow.Dst(), tcpFlow.Dst())
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.names == nil {
+		f.names = make(map[string]int)
+		if f.Dir != "" {
+			if err := os.MkdirAll(f.Dir, 0755); err != nil && f.err == nil {
+				f.err = err
+			}
+		}
+	}
+	// the same endpoints can be reused by later connections: their files
+	// get a "c1", "c2"... suffix
+	base := client + "-" + server
+	suffix := ""
+	if n := f.names[base]; n > 0 {
+		suffix = fmt.Sprintf("c%d", n)
+	}
+	f.names[base]++
+	s := &Stream{
+		factory: f,
+		record: ConnectionRecord{
+			Client: net.JoinHostPort(netFlow.Src().String(), tcpFlow.Src().String()),
+			Server: net.JoinHostPort(netFlow.Dst().String(), tcpFlow.Dst().String()),
+			Start:  ac.GetCaptureInfo().Timestamp,
+		},
+	}
+	s.halves[0].name = base + suffix
+	s.halves[1].name = server + "-" + client + suffix
+	if f.WritePcap {
+		s.pcap = &pcapFile{file: file{name: base + suffix + ".pcap"}}
+	}
+	return s
+}
+
+func (f *StreamFactory) path(name string) string {
+	return filepath.Join(f.Dir, name)
+}
+
+func (f *StreamFactory) writeRecord(r *ConnectionRecord) {
+	if f.Index == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.index == nil {
+		f.index = json.NewEncoder(f.Index)
+	}
+	if err := f.index.Encode(r); err != nil && f.err == nil {
+		f.err = err
+	}
+}
+
+// endpointName returns the tcpflow name of an endpoint.  IPv4 addresses
+// and ports are zero-padded, the colons of IPv6 addresses replaced by
+// dots.
+func endpointName(addr, port gopacket.Endpoint) string {
+	var p uint16
+	if raw := port.Raw(); len(raw) == 2 {
+		p = uint16(raw[0])<<8 | uint16(raw[1])
+	}
+	if raw := addr.Raw(); addr.EndpointType() == layers.EndpointIPv4 && len(raw) == 4 {
+		return fmt.Sprintf("%03d.%03d.%03d.%03d.%05d", raw[0], raw[1], raw[2], raw[3], p)
+	}
+	name := []byte(addr.String())
+	for i, c := range name {
+		if c == ':' {
+			name[i] = '.'
+		}
+	}
+	return fmt.Sprintf("%s.%05d", name, p)
+}
+
+// Stream writes the data of one TCP connection.  It implements
+// reassembly.Stream.
+type Stream struct {
+	factory *StreamFactory
+	record  ConnectionRecord
+	halves  [2]file
+	pcap    *pcapFile
+}
+
+// file is a file written lazily, once there is data.
+type file struct {
+	name   string
+	f      *os.File
+	record FileRecord
+	done   bool
+}
+
+// reserve returns the number of the n bytes to write that fit in the file,
+// opening it if needed.
+func (w *file) reserve(factory *StreamFactory, n int64) int64 {
+	if w.done {
+		return 0
+	}
+	if w.f == nil {
+		w.record.Name = w.name
+		f, err := os.Create(factory.path(w.name))
+		if err != nil {
+			w.fail(err)
+			return 0
+		}
+		w.f = f
+	}
+	if max := factory.MaxFileSize; max > 0 && w.record.Bytes+n > max {
+		n = max - w.record.Bytes
+		w.record.Truncated = true
+		w.done = n <= 0
+	}
+	return n
+}
+
+func (w *file) write(factory *StreamFactory, data []byte) {
+	n := w.reserve(factory, int64(len(data)))
+	if n <= 0 {
+		return
+	}
+	written, err := w.f.Write(data[:n])
+	w.record.Bytes += int64(written)
+	if err != nil {
+		w.fail(err)
+	}
+}
+
+// skip leaves a hole of n missing bytes.
+func (w *file) skip(factory *StreamFactory, n int) {
+	w.record.Missing += int64(n)
+	skip := w.reserve(factory, int64(n))
+	if skip <= 0 {
+		return
+	}
+	if _, err := w.f.Seek(skip, io.SeekCurrent); err != nil {
+		w.fail(err)
+		return
+	}
+	w.record.Bytes += skip
+}
+
+func (w *file) fail(err error) {
+	w.record.Error = err.Error()
+	w.done = true
+}
+
+func (w *file) close() {
+	if w.f == nil {
+		return
+	}
+	// the hole at the end of a file must be allocated
+	if err := w.f.Truncate(w.record.Bytes); err != nil && w.record.Error == "" {
+		w.record.Error = err.Error()
+	}
+	if err := w.f.Close(); err != nil && w.record.Error == "" {
+		w.record.Error = err.Error()
+	}
+	w.f = nil
+	w.done = true
+}
+
+// pcapFile is the pcap file of a connection.
+type pcapFile struct {
+	file
+	w *pcapgo.Writer
+}
+
+const (
+	pcapFileHeaderLen   = 24
+	pcapPacketHeaderLen = 16
+)
+
+func (p *pcapFile) writePacket(factory *StreamFactory, ci gopacket.CaptureInfo, data []byte) {
+	if p.w == nil {
+		if p.reserve(factory, pcapFileHeaderLen) < pcapFileHeaderLen {
+			return
+		}
+		p.w = pcapgo.NewWriter(p.f)
+		linkType := factory.LinkType
+		if linkType == layers.LinkTypeNull {
+			linkType = layers.LinkTypeEthernet
+		}
+		if err := p.w.WriteFileHeader(65536, linkType); err != nil {
+			p.fail(err)
+			return
+		}
+		p.record.Bytes += pcapFileHeaderLen
+	}
+	// packets are not split
+	n := int64(pcapPacketHeaderLen + len(data))
+	if p.reserve(factory, n) < n {
+		p.record.Truncated = true
+		p.done = true
+		return
+	}
+	ci.CaptureLength = len(data)
+	if ci.Length < len(data) {
+		ci.Length = len(data)
+	}
+	if err := p.w.WritePacket(ci, data); err != nil {
+		p.fail(err)
+		return
+	}
+	p.record.Bytes += n
+}
+
+func (s *Stream) half(dir reassembly.TCPFlowDirection) *file {
+	if dir == reassembly.TCPDirClientToServer {
+		return &s.halves[0]
+	}
+	return &s.halves[1]
+}
+
+// Accept implements reassembly.Stream.  It writes the packet to the pcap
+// file.
+func (s *Stream) Accept(tcp *layers.TCP, ci gopacket.CaptureInfo, dir reassembly.TCPFlowDirection, nextSeq reassembly.Sequence, start *bool, ac reassembly.AssemblerContext) bool {
+	if s.factory.AllowMissingInit {
+		*start = true
+	}
+	s.record.Packets++
+	s.record.End = ci.Timestamp
+	if s.pcap != nil {
+		if pc, ok := ac.(PacketContext); ok {
+			s.pcap.writePacket(s.factory, ci, pc.PacketData())
+		}
+	}
+	return true
+}
+
+// ReassembledSG implements reassembly.Stream.
+func (s *Stream) ReassembledSG(sg reassembly.ScatterGather, ac reassembly.AssemblerContext) {
+	dir, _, _, skip := sg.Info()
+	h := s.half(dir)
+	length, _ := sg.Lengths()
+	if skip > 0 {
+		h.skip(s.factory, skip)
+	}
+	if length > 0 {
+		h.write(s.factory, sg.Fetch(length))
+	}
+}
+
+// ReassemblyComplete implements reassembly.Stream.  It closes the files and
+// writes the record of the connection to the index.
+func (s *Stream) ReassemblyComplete(ac reassembly.AssemblerContext) bool {
+	for i := range s.halves {
+		s.halves[i].close()
+	}
+	s.record.ClientToServer = s.halves[0].record
+	s.record.ServerToClient = s.halves[1].record
+	if s.pcap != nil {
+		s.pcap.close()
+		s.record.Pcap = &s.pcap.record
+	}
+	s.factory.writeRecord(&s.record)
+	return true
+}
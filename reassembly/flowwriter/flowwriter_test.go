@@ -0,0 +1,192 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package flowwriter
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+	"github.com/gopacket/gopacket/pcapgo"
+	"github.com/gopacket/gopacket/reassembly"
+	"github.com/gopacket/gopacket/reassembly/internal/streamtest"
+)
+
+const (
+	clientName = "010.000.000.001.54321"
+	serverName = "010.000.000.002.00080"
+)
+
+// testConn feeds the packets of a synthetic TCP connection to an
+// assembler.
+type testConn struct {
+	*streamtest.Conn
+}
+
+func newTestConn(t *testing.T, f *StreamFactory) *testConn {
+	c := &testConn{streamtest.NewConn(t, f, 80)}
+	c.NewContext = func(p gopacket.Packet) reassembly.AssemblerContext { return NewContext(p) }
+	return c
+}
+
+func (c *testConn) handshake() {
+	c.Handshake()
+	c.Packet(false, &layers.TCP{ACK: true}, nil)
+}
+
+func readFile(t *testing.T, dir, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func readIndex(t *testing.T, index *bytes.Buffer) []ConnectionRecord {
+	t.Helper()
+	var records []ConnectionRecord
+	dec := json.NewDecoder(index)
+	for dec.More() {
+		var r ConnectionRecord
+		if err := dec.Decode(&r); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+func TestStreamFiles(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "flows")
+	index := &bytes.Buffer{}
+	f := &StreamFactory{Dir: dir, WritePcap: true, Index: index}
+	c := newTestConn(t, f)
+	c.handshake()
+	c.Packet(false, &layers.TCP{ACK: true, PSH: true}, []byte("GET / HTTP/1.0\r\n\r\n"))
+	c.Packet(true, &layers.TCP{ACK: true, PSH: true}, []byte("HTTP/1.0 200 OK\r\n\r\n"))
+	c.Close()
+	if err := f.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := readFile(t, dir, clientName+"-"+serverName); got != "GET / HTTP/1.0\r\n\r\n" {
+		t.Errorf("client data: got %q", got)
+	}
+	if got := readFile(t, dir, serverName+"-"+clientName); got != "HTTP/1.0 200 OK\r\n\r\n" {
+		t.Errorf("server data: got %q", got)
+	}
+	pcap, err := os.Open(filepath.Join(dir, clientName+"-"+serverName+".pcap"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pcap.Close()
+	r, err := pcapgo.NewReader(pcap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.LinkType() != layers.LinkTypeEthernet {
+		t.Errorf("got link type %v", r.LinkType())
+	}
+	packets := 0
+	for {
+		data, ci, err := r.ReadPacketData()
+		if err != nil {
+			break
+		}
+		if want := streamtest.PacketTime(packets + 1); !ci.Timestamp.Equal(want) || ci.CaptureLength != len(data) {
+			t.Errorf("packet %d: got %+v", packets, ci)
+		}
+		packets++
+	}
+	if packets != c.Packets {
+		t.Errorf("got %d packets in the pcap, want %d", packets, c.Packets)
+	}
+
+	records := readIndex(t, index)
+	if len(records) != 1 {
+		t.Fatalf("got %d records", len(records))
+	}
+	rec := records[0]
+	if rec.Client != "10.0.0.1:54321" || rec.Server != "10.0.0.2:80" || rec.Packets != 7 {
+		t.Errorf("got record %+v", rec)
+	}
+	if !rec.Start.Equal(streamtest.PacketTime(1)) || !rec.End.Equal(streamtest.PacketTime(7)) {
+		t.Errorf("got start %v, end %v", rec.Start, rec.End)
+	}
+	if rec.ClientToServer.Bytes != 18 || rec.ServerToClient.Bytes != 19 {
+		t.Errorf("got %+v and %+v", rec.ClientToServer, rec.ServerToClient)
+	}
+	if st, err := pcap.Stat(); err != nil || rec.Pcap == nil || rec.Pcap.Bytes != st.Size() {
+		t.Errorf("got pcap record %+v, file %v", rec.Pcap, st)
+	}
+}
+
+func TestStreamHolesAndLimits(t *testing.T) {
+	dir := t.TempDir()
+	index := &bytes.Buffer{}
+	f := &StreamFactory{Dir: dir, MaxFileSize: 10, Index: index}
+	c := newTestConn(t, f)
+	c.handshake()
+	c.Packet(false, &layers.TCP{ACK: true}, []byte("abc"))
+	// lost
+	c.Skip(false, 3)
+	c.Packet(false, &layers.TCP{ACK: true}, []byte("ghi"))
+	c.Packet(true, &layers.TCP{ACK: true}, []byte("0123456789abcdef"))
+	c.Assembler.FlushAll()
+
+	// a second connection between the same endpoints
+	c2 := newTestConn(t, f)
+	c2.handshake()
+	c2.Packet(false, &layers.TCP{ACK: true}, []byte("again"))
+	c2.Close()
+
+	if got := readFile(t, dir, clientName+"-"+serverName); got != "abc\x00\x00\x00ghi" {
+		t.Errorf("client data: got %q", got)
+	}
+	if got := readFile(t, dir, serverName+"-"+clientName); got != "0123456789" {
+		t.Errorf("server data: got %q", got)
+	}
+	if got := readFile(t, dir, clientName+"-"+serverName+"c1"); got != "again" {
+		t.Errorf("second connection: got %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, serverName+"-"+clientName+"c1")); !os.IsNotExist(err) {
+		t.Errorf("file created without data: %v", err)
+	}
+	records := readIndex(t, index)
+	if len(records) != 2 {
+		t.Fatalf("got %d records", len(records))
+	}
+	if got, want := records[0].ClientToServer, (FileRecord{Name: clientName + "-" + serverName, Bytes: 9, Missing: 3}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got, want := records[0].ServerToClient, (FileRecord{Name: serverName + "-" + clientName, Bytes: 10, Truncated: true}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if records[0].Pcap != nil || records[1].ServerToClient.Name != "" {
+		t.Errorf("got records %+v", records)
+	}
+}
+
+func TestEndpointName(t *testing.T) {
+	for _, test := range []struct {
+		addr gopacket.Endpoint
+		want string
+	}{
+		{layers.NewIPEndpoint(net.IP{192, 168, 1, 10}), "192.168.001.010.00443"},
+		{layers.NewIPEndpoint(net.ParseIP("2001:db8::1")), "2001.db8..1.00443"},
+	} {
+		if got := endpointName(test.addr, layers.NewTCPPortEndpoint(443)); got != test.want {
+			t.Errorf("got %q, want %q", got, test.want)
+		}
+	}
+}
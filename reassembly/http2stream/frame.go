@@ -0,0 +1,294 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package http2stream
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// FrameHeaderLen is the length of the header of every frame.
+const FrameHeaderLen = 9
+
+// FrameType is the type of a frame (RFC 9113, section 6).
+type FrameType uint8
+
+// FrameType known values.
+const (
+	FrameData         FrameType = 0x0
+	FrameHeaders      FrameType = 0x1
+	FramePriority     FrameType = 0x2
+	FrameRSTStream    FrameType = 0x3
+	FrameSettings     FrameType = 0x4
+	FramePushPromise  FrameType = 0x5
+	FramePing         FrameType = 0x6
+	FrameGoAway       FrameType = 0x7
+	FrameWindowUpdate FrameType = 0x8
+	FrameContinuation FrameType = 0x9
+)
+
+func (t FrameType) String() string {
+	switch t {
+	case FrameData:
+		return "DATA"
+	case FrameHeaders:
+		return "HEADERS"
+	case FramePriority:
+		return "PRIORITY"
+	case FrameRSTStream:
+		return "RST_STREAM"
+	case FrameSettings:
+		return "SETTINGS"
+	case FramePushPromise:
+		return "PUSH_PROMISE"
+	case FramePing:
+		return "PING"
+	case FrameGoAway:
+		return "GOAWAY"
+	case FrameWindowUpdate:
+		return "WINDOW_UPDATE"
+	case FrameContinuation:
+		return "CONTINUATION"
+	}
+	return fmt.Sprintf("UNKNOWN_FRAME_TYPE_%d", uint8(t))
+}
+
+// Flags are the flags of a frame.  Their meaning depends on the frame
+// type.
+type Flags uint8
+
+// Flags known values.
+const (
+	// FlagEndStream is set on DATA and HEADERS frames.
+	FlagEndStream Flags = 0x1
+	// FlagAck is set on SETTINGS and PING frames.
+	FlagAck Flags = 0x1
+	// FlagEndHeaders is set on HEADERS, PUSH_PROMISE and CONTINUATION
+	// frames.
+	FlagEndHeaders Flags = 0x4
+	// FlagPadded is set on DATA, HEADERS and PUSH_PROMISE frames.
+	FlagPadded Flags = 0x8
+	// FlagPriority is set on HEADERS frames.
+	FlagPriority Flags = 0x20
+)
+
+// Has reports whether f has all the flags of v.
+func (f Flags) Has(v Flags) bool { return f&v == v }
+
+// ErrCode is the error code of RST_STREAM and GOAWAY frames (RFC 9113,
+// section 7).
+type ErrCode uint32
+
+// ErrCode known values.
+const (
+	ErrCodeNo                 ErrCode = 0x0
+	ErrCodeProtocol           ErrCode = 0x1
+	ErrCodeInternal           ErrCode = 0x2
+	ErrCodeFlowControl        ErrCode = 0x3
+	ErrCodeSettingsTimeout    ErrCode = 0x4
+	ErrCodeStreamClosed       ErrCode = 0x5
+	ErrCodeFrameSize          ErrCode = 0x6
+	ErrCodeRefusedStream      ErrCode = 0x7
+	ErrCodeCancel             ErrCode = 0x8
+	ErrCodeCompression        ErrCode = 0x9
+	ErrCodeConnect            ErrCode = 0xa
+	ErrCodeEnhanceYourCalm    ErrCode = 0xb
+	ErrCodeInadequateSecurity ErrCode = 0xc
+	ErrCodeHTTP11Required     ErrCode = 0xd
+)
+
+var errCodeNames = map[ErrCode]string{
+	ErrCodeNo:                 "NO_ERROR",
+	ErrCodeProtocol:           "PROTOCOL_ERROR",
+	ErrCodeInternal:           "INTERNAL_ERROR",
+	ErrCodeFlowControl:        "FLOW_CONTROL_ERROR",
+	ErrCodeSettingsTimeout:    "SETTINGS_TIMEOUT",
+	ErrCodeStreamClosed:       "STREAM_CLOSED",
+	ErrCodeFrameSize:          "FRAME_SIZE_ERROR",
+	ErrCodeRefusedStream:      "REFUSED_STREAM",
+	ErrCodeCancel:             "CANCEL",
+	ErrCodeCompression:        "COMPRESSION_ERROR",
+	ErrCodeConnect:            "CONNECT_ERROR",
+	ErrCodeEnhanceYourCalm:    "ENHANCE_YOUR_CALM",
+	ErrCodeInadequateSecurity: "INADEQUATE_SECURITY",
+	ErrCodeHTTP11Required:     "HTTP_1_1_REQUIRED",
+}
+
+func (e ErrCode) String() string {
+	if name, ok := errCodeNames[e]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown error code 0x%x", uint32(e))
+}
+
+// SettingID is the identifier of a setting (RFC 9113, section 6.5.2).
+type SettingID uint16
+
+// SettingID known values.
+const (
+	SettingHeaderTableSize      SettingID = 0x1
+	SettingEnablePush           SettingID = 0x2
+	SettingMaxConcurrentStreams SettingID = 0x3
+	SettingInitialWindowSize    SettingID = 0x4
+	SettingMaxFrameSize         SettingID = 0x5
+	SettingMaxHeaderListSize    SettingID = 0x6
+)
+
+func (s SettingID) String() string {
+	switch s {
+	case SettingHeaderTableSize:
+		return "HEADER_TABLE_SIZE"
+	case SettingEnablePush:
+		return "ENABLE_PUSH"
+	case SettingMaxConcurrentStreams:
+		return "MAX_CONCURRENT_STREAMS"
+	case SettingInitialWindowSize:
+		return "INITIAL_WINDOW_SIZE"
+	case SettingMaxFrameSize:
+		return "MAX_FRAME_SIZE"
+	case SettingMaxHeaderListSize:
+		return "MAX_HEADER_LIST_SIZE"
+	}
+	return fmt.Sprintf("UNKNOWN_SETTING_%d", uint16(s))
+}
+
+// Setting is a parameter of a SETTINGS frame.
+type Setting struct {
+	ID    SettingID
+	Value uint32
+}
+
+// Frame is an HTTP/2 frame.  Only the fields of its Type are set.
+type Frame struct {
+	Type     FrameType
+	Flags    Flags
+	StreamID uint32
+	// Length is the length of the frame payload.
+	Length uint32
+	// Data is the payload of a DATA frame, the header block fragment of a
+	// HEADERS, PUSH_PROMISE or CONTINUATION frame, the opaque data of a
+	// PING frame, and the debug data of a GOAWAY frame.  It is the raw
+	// payload of frames of unknown types.  Padding is removed.
+	Data []byte
+	// PadLength is the length of the padding of a padded frame.
+	PadLength uint8
+	// Exclusive, StreamDependency and Weight are the priority of a
+	// PRIORITY frame, or of a HEADERS frame with FlagPriority.
+	Exclusive        bool
+	StreamDependency uint32
+	Weight           uint8
+	// PromisedStreamID is the stream reserved by a PUSH_PROMISE frame.
+	PromisedStreamID uint32
+	// ErrCode is the error of a RST_STREAM or GOAWAY frame.
+	ErrCode ErrCode
+	// LastStreamID is the last stream processed by the sender of a
+	// GOAWAY frame.
+	LastStreamID uint32
+	// Settings are the parameters of a SETTINGS frame.
+	Settings []Setting
+	// WindowIncrement is the increment of a WINDOW_UPDATE frame.
+	WindowIncrement uint32
+}
+
+var errFrameTruncated = errors.New("HTTP/2 frame truncated")
+
+// frameLength returns the length of the frame starting data, header
+// included, or 0 if the header is incomplete.
+func frameLength(data []byte) int {
+	if len(data) < FrameHeaderLen {
+		return 0
+	}
+	return FrameHeaderLen + (int(data[0])<<16 | int(data[1])<<8 | int(data[2]))
+}
+
+// DecodeFromBytes decodes the frame starting data, which must hold the
+// whole frame.  Data and the other slices point into data.
+func (f *Frame) DecodeFromBytes(data []byte) error {
+	*f = Frame{Settings: f.Settings[:0]}
+	n := frameLength(data)
+	if n == 0 || len(data) < n {
+		return errFrameTruncated
+	}
+	f.Length = uint32(n - FrameHeaderLen)
+	f.Type = FrameType(data[3])
+	f.Flags = Flags(data[4])
+	f.StreamID = binary.BigEndian.Uint32(data[5:9]) & 0x7fffffff
+	p := data[FrameHeaderLen:n]
+
+	switch f.Type {
+	case FrameData, FrameHeaders, FramePushPromise:
+		if f.Flags.Has(FlagPadded) {
+			if len(p) < 1 || int(p[0]) >= len(p) {
+				return fmt.Errorf("HTTP/2 %v frame with invalid padding", f.Type)
+			}
+			f.PadLength = p[0]
+			p = p[1 : len(p)-int(f.PadLength)]
+		}
+	}
+	switch f.Type {
+	case FrameData, FrameContinuation, FramePing:
+		f.Data = p
+	case FrameHeaders:
+		if f.Flags.Has(FlagPriority) {
+			if len(p) < 5 {
+				return errFrameTruncated
+			}
+			f.decodePriority(p)
+			p = p[5:]
+		}
+		f.Data = p
+	case FramePriority:
+		if len(p) != 5 {
+			return errors.New("HTTP/2 PRIORITY frame with invalid length")
+		}
+		f.decodePriority(p)
+	case FrameRSTStream:
+		if len(p) != 4 {
+			return errors.New("HTTP/2 RST_STREAM frame with invalid length")
+		}
+		f.ErrCode = ErrCode(binary.BigEndian.Uint32(p))
+	case FrameSettings:
+		if len(p)%6 != 0 {
+			return errors.New("HTTP/2 SETTINGS frame with invalid length")
+		}
+		for ; len(p) > 0; p = p[6:] {
+			f.Settings = append(f.Settings, Setting{
+				ID:    SettingID(binary.BigEndian.Uint16(p)),
+				Value: binary.BigEndian.Uint32(p[2:]),
+			})
+		}
+	case FramePushPromise:
+		if len(p) < 4 {
+			return errFrameTruncated
+		}
+		f.PromisedStreamID = binary.BigEndian.Uint32(p) & 0x7fffffff
+		f.Data = p[4:]
+	case FrameGoAway:
+		if len(p) < 8 {
+			return errFrameTruncated
+		}
+		f.LastStreamID = binary.BigEndian.Uint32(p) & 0x7fffffff
+		f.ErrCode = ErrCode(binary.BigEndian.Uint32(p[4:]))
+		f.Data = p[8:]
+	case FrameWindowUpdate:
+		if len(p) != 4 {
+			return errors.New("HTTP/2 WINDOW_UPDATE frame with invalid length")
+		}
+		f.WindowIncrement = binary.BigEndian.Uint32(p) & 0x7fffffff
+	default:
+		f.Data = p
+	}
+	return nil
+}
+
+func (f *Frame) decodePriority(p []byte) {
+	dep := binary.BigEndian.Uint32(p)
+	f.Exclusive = dep&0x80000000 != 0
+	f.StreamDependency = dep & 0x7fffffff
+	f.Weight = p[4]
+}
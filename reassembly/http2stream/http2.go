@@ -0,0 +1,593 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+/*
+Package http2stream decodes HTTP/2 traffic carried by TCP streams
+reassembled by the reassembly package.
+
+Connections may start with the client connection preface (prior knowledge,
+or TLS decrypted before reassembly), or be upgraded from HTTP/1.1 to h2c.
+Each direction is split into frames as data is handed to ReassembledSG,
+and header blocks are decompressed by an HPACK decoder per direction,
+whose dynamic table lives as long as the connection. The header sets of
+every HTTP/2 stream are delivered to a Handler as an Exchange once the
+stream is closed:
+
+	type printer struct{}
+
+	func (printer) HandleExchange(e *http2stream.Exchange) {
+		if e.Request != nil && e.Response != nil {
+			fmt.Println(e.StreamID, e.Request.Get(":path"), e.Response.Get(":status"))
+		}
+	}
+
+	factory := &http2stream.StreamFactory{Handler: printer{}}
+	pool := reassembly.NewStreamPool(factory)
+	assembler := reassembly.NewAssembler(pool)
+	for packet := range packets {
+		tcp := packet.TransportLayer().(*layers.TCP)
+		assembler.AssembleWithContext(packet.NetworkLayer().NetworkFlow(), tcp, ctx)
+	}
+	assembler.FlushAll()
+
+Every frame is also given to the FrameHandler, if set.
+
+Once bytes are lost in a direction, its frame boundaries and HPACK state
+are lost too: the rest of the direction is ignored, and the exchanges
+still open are delivered as truncated when the connection ends.
+*/
+package http2stream
+
+import (
+	"bufio"
+	"bytes"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+	"github.com/gopacket/gopacket/reassembly"
+	"golang.org/x/net/http2/hpack"
+)
+
+// ClientPreface is the connection preface sent by clients before their
+// first frame.
+const ClientPreface = "PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n"
+
+const (
+	// initialHeaderTableSize is the size of the HPACK dynamic tables until
+	// changed by SETTINGS_HEADER_TABLE_SIZE.
+	initialHeaderTableSize = 4096
+	// maxHeaderBlockSize bounds a header block, whose fragments are kept
+	// until its end.
+	maxHeaderBlockSize = 1 << 20
+	// maxHTTP1HeadSize bounds the HTTP/1.1 messages of an h2c upgrade.
+	maxHTTP1HeadSize = 64 << 10
+)
+
+// HeaderSet is a decoded header block.
+type HeaderSet struct {
+	// Fields are the fields of the block, pseudo-header fields first, in
+	// the order of the block.
+	Fields []hpack.HeaderField
+	// Seen is the capture info of the packet carrying the first byte of
+	// the HEADERS or PUSH_PROMISE frame.
+	Seen gopacket.CaptureInfo
+}
+
+// Get returns the value of the first field named name, or "".
+func (h *HeaderSet) Get(name string) string {
+	for _, f := range h.Fields {
+		if f.Name == name {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+// Header returns the regular fields of the set, without the pseudo-header
+// fields.
+func (h *HeaderSet) Header() http.Header {
+	header := make(http.Header)
+	for _, f := range h.Fields {
+		if !f.IsPseudo() {
+			header.Add(f.Name, f.Value)
+		}
+	}
+	return header
+}
+
+// Exchange is the request and response of an HTTP/2 stream. Request is
+// nil if its headers were not captured, and Response is nil if the stream
+// was closed before the response.
+type Exchange struct {
+	// NetFlow and TransportFlow are oriented from the client to the
+	// server.
+	NetFlow, TransportFlow gopacket.Flow
+	StreamID               uint32
+	// PushedBy is the stream of the request a pushed stream was promised
+	// on, 0 for streams opened by the client.  The Request of a pushed
+	// stream is the one of the PUSH_PROMISE frame.
+	PushedBy uint32
+	Request  *HeaderSet
+	// Informational are the 1xx responses preceding Response.
+	Informational                     []*HeaderSet
+	Response                          *HeaderSet
+	RequestTrailers, ResponseTrailers *HeaderSet
+	// RequestBytes and ResponseBytes count the bytes of the DATA frames,
+	// without padding.
+	RequestBytes, ResponseBytes int64
+	// Reset is set when the stream was reset by a RST_STREAM frame with
+	// ErrCode.
+	Reset   bool
+	ErrCode ErrCode
+	// Truncated is set when the connection ended, or became impossible to
+	// follow, before the stream was closed.
+	Truncated bool
+
+	// ended records the END_STREAM of the client and of the server.
+	ended [2]bool
+}
+
+// Handler receives the exchanges decoded from the streams.
+type Handler interface {
+	HandleExchange(e *Exchange)
+}
+
+// HandlerFunc adapts a function to the Handler interface.
+type HandlerFunc func(e *Exchange)
+
+// HandleExchange calls f(e).
+func (f HandlerFunc) HandleExchange(e *Exchange) { f(e) }
+
+// FrameHandler receives every frame decoded from the streams.  client is
+// set for the frames sent by the client.  The frame is only valid until
+// HandleFrame returns.
+type FrameHandler interface {
+	HandleFrame(f *Frame, client bool, ci gopacket.CaptureInfo)
+}
+
+// StreamFactory implements reassembly.StreamFactory, creating a Stream
+// decoding HTTP/2 for every new connection.
+type StreamFactory struct {
+	// Handler is called for every exchange, and FrameHandler for every
+	// frame, from the goroutine calling the Assembler.
+	Handler      Handler
+	FrameHandler FrameHandler
+	// AllowMissingInit accepts connections whose SYN was not captured.
+	// Their header blocks can only be decoded if the connection preface
+	// was captured, since HPACK needs the whole connection.
+	AllowMissingInit bool
+}
+
+// New implements reassembly.StreamFactory.
+func (f *StreamFactory) New(netFlow, tcpFlow gopacket.Flow, tcp *layers.TCP, ac reassembly.AssemblerContext) reassembly.Stream {
+	s := &Stream{
+		factory:       f,
+		netFlow:       netFlow,
+		transportFlow: tcpFlow,
+		exchanges:     make(map[uint32]*Exchange),
+	}
+	for i := range s.halves {
+		h := &s.halves[i]
+		h.stream = s
+		h.decoder = hpack.NewDecoder(initialHeaderTableSize, nil)
+		h.decoder.SetMaxStringLength(maxHeaderBlockSize)
+	}
+	s.halves[1].dir = reassembly.TCPDirServerToClient
+	return s
+}
+
+// Stream decodes the HTTP/2 frames of one TCP connection. It implements
+// reassembly.Stream.
+type Stream struct {
+	factory                *StreamFactory
+	netFlow, transportFlow gopacket.Flow
+	halves                 [2]halfStream
+	// exchanges are the streams not closed yet.
+	exchanges map[uint32]*Exchange
+	// reversed is set when the client is the server as seen by the
+	// assembler, i.e. the connection preface came from the server side.
+	reversed bool
+}
+
+func (s *Stream) half(dir reassembly.TCPFlowDirection) *halfStream {
+	if dir == reassembly.TCPDirClientToServer {
+		return &s.halves[0]
+	}
+	return &s.halves[1]
+}
+
+// Accept implements reassembly.Stream.
+func (s *Stream) Accept(tcp *layers.TCP, ci gopacket.CaptureInfo, dir reassembly.TCPFlowDirection, nextSeq reassembly.Sequence, start *bool, ac reassembly.AssemblerContext) bool {
+	if s.factory.AllowMissingInit {
+		*start = true
+	}
+	return true
+}
+
+// ReassembledSG implements reassembly.Stream.
+func (s *Stream) ReassembledSG(sg reassembly.ScatterGather, ac reassembly.AssemblerContext) {
+	dir, _, end, skip := sg.Info()
+	h := s.half(dir)
+	length, _ := sg.Lengths()
+	if skip > 0 {
+		h.fail()
+	}
+	if length > 0 {
+		h.feed(sg.Fetch(length), sg)
+	}
+	if end {
+		h.fail()
+	}
+}
+
+// ReassemblyComplete implements reassembly.Stream. The exchanges still
+// open are delivered as truncated.
+func (s *Stream) ReassemblyComplete(ac reassembly.AssemblerContext) bool {
+	s.halves[0].fail()
+	s.halves[1].fail()
+	ids := make([]uint32, 0, len(s.exchanges))
+	for id := range s.exchanges {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		e := s.exchanges[id]
+		e.Truncated = true
+		s.emit(e)
+	}
+	return true
+}
+
+// exchange returns the exchange of a stream, opening it if needed.
+func (s *Stream) exchange(id uint32) *Exchange {
+	e := s.exchanges[id]
+	if e == nil {
+		e = &Exchange{NetFlow: s.netFlow, TransportFlow: s.transportFlow, StreamID: id}
+		if s.reversed {
+			e.NetFlow, e.TransportFlow = e.NetFlow.Reverse(), e.TransportFlow.Reverse()
+		}
+		s.exchanges[id] = e
+	}
+	return e
+}
+
+// endStream records the END_STREAM of one side of a stream, and delivers
+// the exchange once both sides ended.
+func (s *Stream) endStream(e *Exchange, client bool) {
+	if client {
+		e.ended[0] = true
+	} else {
+		e.ended[1] = true
+	}
+	if e.ended[0] && e.ended[1] {
+		s.emit(e)
+	}
+}
+
+func (s *Stream) emit(e *Exchange) {
+	delete(s.exchanges, e.StreamID)
+	if s.factory.Handler != nil {
+		s.factory.Handler.HandleExchange(e)
+	}
+}
+
+type parseState int
+
+const (
+	stateStart   parseState = iota // waiting for the preface, the first frame or an HTTP/1.1 message
+	stateHTTP1                     // reading the HTTP/1.1 message of an h2c upgrade
+	statePreface                   // looking for the preface after an h2c upgrade request
+	stateFrames                    // reading frames
+	stateDone                      // ignoring the rest of the direction
+)
+
+// headerBlock is a header block being received, made of a HEADERS or
+// PUSH_PROMISE frame followed by CONTINUATION frames.
+type headerBlock struct {
+	streamID  uint32
+	promised  uint32
+	push      bool
+	endStream bool
+	seen      gopacket.CaptureInfo
+	data      []byte
+}
+
+// halfStream parses one direction of a connection.
+type halfStream struct {
+	stream *Stream
+	dir    reassembly.TCPFlowDirection
+	state  parseState
+	// buf holds the bytes not parsed yet.
+	buf []byte
+	// sg is the ScatterGather being fed, and base the offset in buf of
+	// its first byte.
+	sg   reassembly.ScatterGather
+	base int
+	// first is the capture info of the packet carrying buf[0], when it
+	// came with a previous ScatterGather.
+	first   gopacket.CaptureInfo
+	frame   Frame
+	decoder *hpack.Decoder
+	// block is the header block being received, if any.
+	block *headerBlock
+}
+
+// ci returns the capture info of the packet carrying buf[0].
+func (h *halfStream) ci() gopacket.CaptureInfo {
+	if h.sg != nil && h.base <= 0 {
+		return h.sg.CaptureInfo(-h.base)
+	}
+	return h.first
+}
+
+func (h *halfStream) consume(n int) {
+	h.buf = h.buf[n:]
+	h.base -= n
+	if len(h.buf) == 0 {
+		h.buf = nil
+	}
+}
+
+// client reports whether the client sends this direction.
+func (h *halfStream) client() bool {
+	return (h.dir == reassembly.TCPDirClientToServer) != h.stream.reversed
+}
+
+// setClient records that the client sends this direction.
+func (h *halfStream) setClient() {
+	h.stream.reversed = h.dir == reassembly.TCPDirServerToClient
+}
+
+// other returns the opposite direction.
+func (h *halfStream) other() *halfStream {
+	return h.stream.half(!h.dir)
+}
+
+func (h *halfStream) feed(data []byte, sg reassembly.ScatterGather) {
+	if h.state == stateDone {
+		return
+	}
+	h.buf = append(h.buf, data...)
+	h.base = len(h.buf) - len(data)
+	h.sg = sg
+	for h.step() {
+	}
+	if len(h.buf) > 0 {
+		h.first = h.ci()
+	}
+	h.sg = nil
+}
+
+// fail stops parsing the direction.
+func (h *halfStream) fail() {
+	h.state = stateDone
+	h.buf = nil
+	h.block = nil
+}
+
+// step parses the next element of buf, and reports whether to go on.
+func (h *halfStream) step() bool {
+	if len(h.buf) == 0 {
+		return false
+	}
+	switch h.state {
+	case stateStart:
+		return h.start()
+	case stateHTTP1:
+		return h.http1()
+	case statePreface:
+		i := bytes.Index(h.buf, []byte(ClientPreface))
+		if i < 0 {
+			if len(h.buf) > maxHTTP1HeadSize {
+				h.fail()
+			}
+			return false
+		}
+		h.consume(i + len(ClientPreface))
+		h.state = stateFrames
+		return true
+	case stateFrames:
+		n := frameLength(h.buf)
+		if n == 0 || len(h.buf) < n {
+			return false
+		}
+		if err := h.frame.DecodeFromBytes(h.buf[:n]); err != nil {
+			h.fail()
+			return false
+		}
+		h.handleFrame(&h.frame, h.ci())
+		if h.state != stateDone {
+			h.consume(n)
+		}
+		return h.state != stateDone
+	}
+	return false
+}
+
+// start recognizes the beginning of a direction.
+func (h *halfStream) start() bool {
+	n := min(len(h.buf), len(ClientPreface))
+	switch {
+	case string(h.buf[:n]) == ClientPreface[:n]:
+		if n < len(ClientPreface) {
+			return false
+		}
+		h.consume(n)
+		h.setClient()
+		h.state = stateFrames
+	case h.buf[0] >= 'A' && h.buf[0] <= 'Z':
+		// frames start with the high byte of their length, which is
+		// never that large
+		h.state = stateHTTP1
+	default:
+		h.state = stateFrames
+	}
+	return true
+}
+
+// http1 reads the HTTP/1.1 request or response of an h2c upgrade.  The
+// request is the one of stream 1.
+func (h *halfStream) http1() bool {
+	i := bytes.Index(h.buf, []byte("\r\n\r\n"))
+	if i < 0 {
+		if len(h.buf) > maxHTTP1HeadSize {
+			h.fail()
+		}
+		return false
+	}
+	head := h.buf[:i+4]
+	if bytes.HasPrefix(head, []byte("HTTP/")) {
+		resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(head)), nil)
+		if err != nil || resp.StatusCode != http.StatusSwitchingProtocols {
+			h.fail()
+			return false
+		}
+		h.consume(len(head))
+		h.state = stateFrames
+		return true
+	}
+	req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(head)))
+	if err != nil || !strings.EqualFold(req.Header.Get("Upgrade"), "h2c") {
+		h.fail()
+		return false
+	}
+	h.setClient()
+	set := &HeaderSet{Seen: h.ci(), Fields: []hpack.HeaderField{
+		{Name: ":method", Value: req.Method},
+		{Name: ":scheme", Value: "http"},
+		{Name: ":authority", Value: req.Host},
+		{Name: ":path", Value: req.RequestURI},
+	}}
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		switch name {
+		case "Connection", "Upgrade", "Http2-Settings":
+			continue
+		}
+		for _, v := range req.Header[name] {
+			set.Fields = append(set.Fields, hpack.HeaderField{Name: strings.ToLower(name), Value: v})
+		}
+	}
+	e := h.stream.exchange(1)
+	e.Request = set
+	e.ended[0] = true
+	e.RequestBytes = max(req.ContentLength, 0)
+	h.consume(len(head))
+	h.state = statePreface
+	return true
+}
+
+// handleFrame handles a frame of the direction, starting at the packet ci.
+func (h *halfStream) handleFrame(f *Frame, ci gopacket.CaptureInfo) {
+	s := h.stream
+	if s.factory.FrameHandler != nil {
+		s.factory.FrameHandler.HandleFrame(f, h.client(), ci)
+	}
+	if h.block != nil && f.Type != FrameContinuation {
+		// header blocks cannot be interleaved with other frames
+		h.fail()
+		return
+	}
+	switch f.Type {
+	case FrameHeaders, FramePushPromise:
+		h.block = &headerBlock{
+			streamID:  f.StreamID,
+			promised:  f.PromisedStreamID,
+			push:      f.Type == FramePushPromise,
+			endStream: f.Type == FrameHeaders && f.Flags.Has(FlagEndStream),
+			seen:      ci,
+			data:      append([]byte(nil), f.Data...),
+		}
+		if f.Flags.Has(FlagEndHeaders) {
+			h.endBlock()
+		}
+	case FrameContinuation:
+		if h.block == nil || h.block.streamID != f.StreamID || len(h.block.data)+len(f.Data) > maxHeaderBlockSize {
+			h.fail()
+			return
+		}
+		h.block.data = append(h.block.data, f.Data...)
+		if f.Flags.Has(FlagEndHeaders) {
+			h.endBlock()
+		}
+	case FrameData:
+		if f.StreamID == 0 {
+			return
+		}
+		e := s.exchange(f.StreamID)
+		if h.client() {
+			e.RequestBytes += int64(len(f.Data))
+		} else {
+			e.ResponseBytes += int64(len(f.Data))
+		}
+		if f.Flags.Has(FlagEndStream) {
+			s.endStream(e, h.client())
+		}
+	case FrameRSTStream:
+		if e := s.exchanges[f.StreamID]; e != nil {
+			e.Reset = true
+			e.ErrCode = f.ErrCode
+			s.emit(e)
+		}
+	case FrameSettings:
+		if f.Flags.Has(FlagAck) {
+			return
+		}
+		for _, setting := range f.Settings {
+			if setting.ID == SettingHeaderTableSize {
+				// bounds the table of the blocks sent to this side
+				h.other().decoder.SetAllowedMaxDynamicTableSize(setting.Value)
+			}
+		}
+	}
+}
+
+// endBlock decodes a complete header block.
+func (h *halfStream) endBlock() {
+	b := h.block
+	h.block = nil
+	fields, err := h.decoder.DecodeFull(b.data)
+	if err != nil {
+		h.fail()
+		return
+	}
+	s := h.stream
+	set := &HeaderSet{Fields: fields, Seen: b.seen}
+	if b.push {
+		e := s.exchange(b.promised)
+		e.PushedBy = b.streamID
+		e.Request = set
+		// the client never sends on a pushed stream
+		e.ended[0] = true
+		return
+	}
+	e := s.exchange(b.streamID)
+	switch {
+	case h.client() && e.Request == nil:
+		e.Request = set
+	case h.client():
+		e.RequestTrailers = set
+	case e.Response == nil:
+		if code, _ := strconv.Atoi(set.Get(":status")); code >= 100 && code < 200 {
+			e.Informational = append(e.Informational, set)
+		} else {
+			e.Response = set
+		}
+	default:
+		e.ResponseTrailers = set
+	}
+	if b.endStream {
+		s.endStream(e, h.client())
+	}
+}
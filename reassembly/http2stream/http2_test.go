@@ -0,0 +1,276 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package http2stream
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/reassembly/internal/streamtest"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// testConn feeds the packets of a synthetic TCP connection to an
+// assembler, and records the exchanges and frames.
+type testConn struct {
+	*streamtest.Conn
+	exchanges []*Exchange
+	frames    []string
+	// framers write the frames of the client and the server, and
+	// encoders their header blocks.
+	framers  [2]*http2.Framer
+	encoders [2]*hpack.Encoder
+	out      [2]bytes.Buffer
+	blocks   [2]bytes.Buffer
+}
+
+type frameRecorder func(f *Frame, client bool, ci gopacket.CaptureInfo)
+
+func (r frameRecorder) HandleFrame(f *Frame, client bool, ci gopacket.CaptureInfo) { r(f, client, ci) }
+
+func newTestConn(t *testing.T, f *StreamFactory) *testConn {
+	c := &testConn{}
+	f.Handler = HandlerFunc(func(e *Exchange) { c.exchanges = append(c.exchanges, e) })
+	f.FrameHandler = frameRecorder(func(f *Frame, client bool, ci gopacket.CaptureInfo) {
+		side := "server"
+		if client {
+			side = "client"
+		}
+		c.frames = append(c.frames, side+" "+f.Type.String())
+	})
+	c.Conn = streamtest.NewConn(t, f, 80)
+	for i := range c.framers {
+		c.framers[i] = http2.NewFramer(&c.out[i], nil)
+		c.encoders[i] = hpack.NewEncoder(&c.blocks[i])
+	}
+	return c
+}
+
+func (c *testConn) idx(server bool) int {
+	if server {
+		return 1
+	}
+	return 0
+}
+
+// send sends data followed by the frames written so far by a side, in
+// segments of at most size bytes.
+func (c *testConn) send(server bool, data string, size int) {
+	i := c.idx(server)
+	data += c.out[i].String()
+	c.out[i].Reset()
+	c.Send(server, data, size)
+}
+
+// block returns the header block of fields, encoded by a side.
+func (c *testConn) block(server bool, fields ...string) []byte {
+	i := c.idx(server)
+	for j := 0; j < len(fields); j += 2 {
+		if err := c.encoders[i].WriteField(hpack.HeaderField{Name: fields[j], Value: fields[j+1]}); err != nil {
+			c.T.Fatal(err)
+		}
+	}
+	b := append([]byte(nil), c.blocks[i].Bytes()...)
+	c.blocks[i].Reset()
+	return b
+}
+
+func fields(set *HeaderSet) []string {
+	if set == nil {
+		return nil
+	}
+	var out []string
+	for _, f := range set.Fields {
+		out = append(out, f.Name+": "+f.Value)
+	}
+	return out
+}
+
+func checkFields(t *testing.T, what string, set *HeaderSet, want ...string) {
+	t.Helper()
+	if got := fields(set); !reflect.DeepEqual(got, want) {
+		t.Errorf("%s: got %q, want %q", what, got, want)
+	}
+}
+
+func TestExchanges(t *testing.T) {
+	c := newTestConn(t, &StreamFactory{})
+	client, server := c.framers[0], c.framers[1]
+	c.Handshake()
+
+	client.WriteSettings(http2.Setting{ID: http2.SettingHeaderTableSize, Val: 8192})
+	request := c.block(false, ":method", "GET", ":scheme", "https", ":authority", "example.com", ":path", "/", "user-agent", "test")
+	client.WriteHeaders(http2.HeadersFrameParam{StreamID: 1, BlockFragment: request[:5], EndStream: true})
+	client.WriteContinuation(1, true, request[5:])
+	// the dynamic table makes the second request much shorter
+	client.WriteHeaders(http2.HeadersFrameParam{
+		StreamID:      3,
+		BlockFragment: c.block(false, ":method", "POST", ":scheme", "https", ":authority", "example.com", ":path", "/form", "user-agent", "test"),
+		EndHeaders:    true,
+		Priority:      http2.PriorityParam{StreamDep: 1, Weight: 15},
+	})
+	client.WriteDataPadded(3, true, []byte("a=1&b=2"), make([]byte, 10))
+	c.send(false, ClientPreface, 7)
+
+	c.encoders[1].SetMaxDynamicTableSizeLimit(8192)
+	c.encoders[1].SetMaxDynamicTableSize(8192)
+	server.WriteSettings()
+	server.WriteSettingsAck()
+	server.WriteHeaders(http2.HeadersFrameParam{StreamID: 1, BlockFragment: c.block(true, ":status", "103", "link", "</style.css>"), EndHeaders: true})
+	server.WritePushPromise(http2.PushPromiseParam{
+		StreamID:      1,
+		PromiseID:     2,
+		BlockFragment: c.block(true, ":method", "GET", ":scheme", "https", ":authority", "example.com", ":path", "/style.css"),
+		EndHeaders:    true,
+	})
+	server.WriteHeaders(http2.HeadersFrameParam{StreamID: 1, BlockFragment: c.block(true, ":status", "200", "content-type", "text/html"), EndHeaders: true})
+	server.WriteData(1, false, []byte("<html>"))
+	server.WriteHeaders(http2.HeadersFrameParam{StreamID: 1, BlockFragment: c.block(true, "grpc-status", "0"), EndHeaders: true, EndStream: true})
+	server.WriteHeaders(http2.HeadersFrameParam{StreamID: 2, BlockFragment: c.block(true, ":status", "200", "content-type", "text/css"), EndHeaders: true})
+	server.WriteData(2, true, []byte("p{}"))
+	server.WriteRSTStream(3, http2.ErrCodeRefusedStream)
+	server.WriteGoAway(3, http2.ErrCodeNo, nil)
+	c.send(true, "", 11)
+	c.Close()
+
+	wantFrames := []string{
+		"client SETTINGS", "client HEADERS", "client CONTINUATION", "client HEADERS", "client DATA",
+		"server SETTINGS", "server SETTINGS", "server HEADERS", "server PUSH_PROMISE", "server HEADERS",
+		"server DATA", "server HEADERS", "server HEADERS", "server DATA", "server RST_STREAM", "server GOAWAY",
+	}
+	if !reflect.DeepEqual(c.frames, wantFrames) {
+		t.Errorf("got frames %q", c.frames)
+	}
+	if len(c.exchanges) != 3 {
+		t.Fatalf("got %d exchanges", len(c.exchanges))
+	}
+
+	e := c.exchanges[0]
+	if e.StreamID != 1 || e.PushedBy != 0 || e.Truncated || e.Reset || e.ResponseBytes != 6 || e.NetFlow != streamtest.ClientFlow {
+		t.Errorf("got exchange %+v", e)
+	}
+	checkFields(t, "request", e.Request, ":method: GET", ":scheme: https", ":authority: example.com", ":path: /", "user-agent: test")
+	if len(e.Informational) != 1 || e.Informational[0].Get("link") != "</style.css>" {
+		t.Errorf("got informational responses %v", e.Informational)
+	}
+	checkFields(t, "response", e.Response, ":status: 200", "content-type: text/html")
+	checkFields(t, "trailers", e.ResponseTrailers, "grpc-status: 0")
+	if got := e.Response.Header().Get("Content-Type"); got != "text/html" {
+		t.Errorf("got content type %q", got)
+	}
+	// the HEADERS frame starts after 39 bytes, in the 6th segment
+	if !e.Request.Seen.Timestamp.Equal(streamtest.PacketTime(8)) {
+		t.Errorf("request seen at %v", e.Request.Seen.Timestamp)
+	}
+
+	e = c.exchanges[1]
+	if e.StreamID != 2 || e.PushedBy != 1 || e.ResponseBytes != 3 {
+		t.Errorf("got pushed exchange %+v", e)
+	}
+	checkFields(t, "promised request", e.Request, ":method: GET", ":scheme: https", ":authority: example.com", ":path: /style.css")
+	checkFields(t, "pushed response", e.Response, ":status: 200", "content-type: text/css")
+
+	e = c.exchanges[2]
+	if e.StreamID != 3 || !e.Reset || e.ErrCode != ErrCodeRefusedStream || e.RequestBytes != 7 || e.Response != nil {
+		t.Errorf("got reset exchange %+v", e)
+	}
+	checkFields(t, "second request", e.Request, ":method: POST", ":scheme: https", ":authority: example.com", ":path: /form", "user-agent: test")
+}
+
+func TestUpgrade(t *testing.T) {
+	c := newTestConn(t, &StreamFactory{})
+	c.Handshake()
+	c.send(false, "GET /index.html HTTP/1.1\r\nHost: example.com\r\nAccept: */*\r\n"+
+		"Connection: Upgrade, HTTP2-Settings\r\nUpgrade: h2c\r\nHTTP2-Settings: AAMAAABkAAQAAP__\r\n\r\n", 1000)
+	c.framers[1].WriteSettings()
+	c.framers[1].WriteHeaders(http2.HeadersFrameParam{StreamID: 1, BlockFragment: c.block(true, ":status", "200"), EndHeaders: true, EndStream: true})
+	c.send(true, "HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: h2c\r\n\r\n", 1000)
+	c.framers[0].WriteSettings()
+	c.send(false, ClientPreface, 1000)
+	c.Close()
+
+	if len(c.exchanges) != 1 {
+		t.Fatalf("got %d exchanges", len(c.exchanges))
+	}
+	e := c.exchanges[0]
+	checkFields(t, "upgrade request", e.Request, ":method: GET", ":scheme: http", ":authority: example.com", ":path: /index.html", "accept: */*")
+	checkFields(t, "response", e.Response, ":status: 200")
+	if e.Truncated {
+		t.Error("exchange truncated")
+	}
+}
+
+func TestLostBytes(t *testing.T) {
+	c := newTestConn(t, &StreamFactory{})
+	c.Handshake()
+	c.framers[0].WriteHeaders(http2.HeadersFrameParam{StreamID: 1, BlockFragment: c.block(false, ":method", "GET", ":path", "/"), EndHeaders: true, EndStream: true})
+	c.send(false, ClientPreface, 1000)
+	c.framers[1].WriteSettings()
+	c.send(true, "", 1000)
+	// the server's response is lost
+	c.Skip(true, 100)
+	c.framers[1].WriteData(1, true, []byte("x"))
+	c.send(true, "", 1000)
+	c.Assembler.FlushAll()
+
+	if len(c.exchanges) != 1 {
+		t.Fatalf("got %d exchanges", len(c.exchanges))
+	}
+	if e := c.exchanges[0]; !e.Truncated || e.Request == nil || e.Response != nil || e.ResponseBytes != 0 {
+		t.Errorf("got exchange %+v", e)
+	}
+}
+
+func TestFrameDecodeFromBytes(t *testing.T) {
+	var buf bytes.Buffer
+	fr := http2.NewFramer(&buf, nil)
+	fr.WriteHeaders(http2.HeadersFrameParam{StreamID: 5, BlockFragment: []byte("block"), EndHeaders: true, PadLength: 3,
+		Priority: http2.PriorityParam{StreamDep: 3, Exclusive: true, Weight: 42}})
+	fr.WriteSettings(http2.Setting{ID: http2.SettingMaxFrameSize, Val: 1 << 20})
+	fr.WriteWindowUpdate(0, 1000)
+	fr.WriteGoAway(7, http2.ErrCodeEnhanceYourCalm, []byte("slow down"))
+	fr.WritePing(true, [8]byte{1, 2, 3, 4, 5, 6, 7, 8})
+	data := buf.Bytes()
+
+	var frames []Frame
+	for len(data) > 0 {
+		n := frameLength(data)
+		var f Frame
+		if err := f.DecodeFromBytes(data); err != nil {
+			t.Fatal(err)
+		}
+		frames = append(frames, f)
+		data = data[n:]
+	}
+	want := []Frame{
+		{Type: FrameHeaders, Flags: FlagEndHeaders | FlagPadded | FlagPriority, StreamID: 5, Length: 14, Data: []byte("block"),
+			PadLength: 3, Exclusive: true, StreamDependency: 3, Weight: 42},
+		{Type: FrameSettings, Length: 6, Settings: []Setting{{SettingMaxFrameSize, 1 << 20}}},
+		{Type: FrameWindowUpdate, Length: 4, WindowIncrement: 1000},
+		{Type: FrameGoAway, Length: 17, LastStreamID: 7, ErrCode: ErrCodeEnhanceYourCalm, Data: []byte("slow down")},
+		{Type: FramePing, Flags: FlagAck, Length: 8, Data: []byte{1, 2, 3, 4, 5, 6, 7, 8}},
+	}
+	for i := range want {
+		if i >= len(frames) || !reflect.DeepEqual(frames[i], want[i]) {
+			t.Errorf("frame %d: got %+v, want %+v", i, frames[i], want[i])
+		}
+	}
+
+	for _, bad := range [][]byte{
+		{0, 0, 5, byte(FrameSettings), 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+		{0, 0, 2, byte(FrameData), byte(FlagPadded), 0, 0, 0, 1, 2, 0},
+		{0, 0, 4, byte(FrameData), 0, 0, 0, 0, 1, 0},
+	} {
+		var f Frame
+		if err := f.DecodeFromBytes(bad); err == nil {
+			t.Errorf("no error for %x", bad)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+/*
+Package httpstream decodes HTTP/1.x traffic carried by TCP streams
+reassembled by the reassembly package.
+
+Unlike the tcpreader package, no goroutine is started per stream: each
+direction of a connection is parsed incrementally as data is handed to
+ReassembledSG. Requests and responses are paired in order, so pipelined
+requests are supported, and every completed exchange is delivered to a
+Handler as a Transaction:
+
+	type printer struct{}
+
+	func (printer) HandleTransaction(t *httpstream.Transaction) {
+		if t.Request != nil && t.Response != nil {
+			fmt.Println(t.Request.Method, t.Request.URI, t.Response.StatusCode)
+		}
+	}
+
+	factory := &httpstream.StreamFactory{Handler: printer{}}
+	pool := reassembly.NewStreamPool(factory)
+	assembler := reassembly.NewAssembler(pool)
+	for packet := range packets {
+		tcp := packet.TransportLayer().(*layers.TCP)
+		assembler.AssembleWithContext(packet.NetworkLayer().NetworkFlow(), tcp, ctx)
+	}
+	assembler.FlushAll()
+
+Message bodies are de-chunked and, unless DisableDecompression is set,
+gzip and deflate content codings are removed. Bodies larger than
+MaxBodySize are truncated and flagged as such, while the parser keeps
+following the message boundaries. When bytes are lost in the capture the
+current message is delivered as truncated and parsing resumes at the next
+data that looks like the start of a message.
+
+Once a connection is upgraded (101 Switching Protocols) or tunneled by a
+successful CONNECT, the rest of the stream is ignored.
+*/
+package httpstream
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+	"github.com/gopacket/gopacket/reassembly"
+)
+
+// DefaultMaxBodySize is the body size limit used when
+// StreamFactory.MaxBodySize is zero.
+const DefaultMaxBodySize = 10 << 20
+
+// Request is an HTTP request seen on the wire.
+type Request struct {
+	Method string
+	URI    string
+	Proto  string
+	Host   string
+	// Header holds the message headers, followed by the trailers of a
+	// chunked body.
+	Header http.Header
+	// Body is the message body, after removal of the transfer coding.
+	Body []byte
+	// Uncompressed is set when a gzip or deflate content coding was
+	// removed from Body.
+	Uncompressed bool
+	// Truncated is set when the body is incomplete, because it exceeded
+	// the size limit, bytes were missing from the capture or the stream
+	// ended early.
+	Truncated bool
+	// Seen is the capture info of the packet carrying the first byte of
+	// the request, Complete the one carrying its last byte.
+	Seen, Complete gopacket.CaptureInfo
+}
+
+// Response is an HTTP response seen on the wire.
+type Response struct {
+	Proto string
+	// StatusCode is the numeric status code, e.g. 200, and Status the
+	// whole status, e.g. "200 OK".
+	StatusCode   int
+	Status       string
+	Header       http.Header
+	Body         []byte
+	Uncompressed bool
+	Truncated    bool
+	Seen         gopacket.CaptureInfo
+	Complete     gopacket.CaptureInfo
+}
+
+// Transaction is a request paired with its response. Either may be nil:
+// Response is nil for a request that was never answered, and Request is
+// nil for a response whose request was not captured.
+type Transaction struct {
+	// NetFlow and TransportFlow are oriented from the client to the
+	// server.
+	NetFlow, TransportFlow gopacket.Flow
+	Request                *Request
+	Response               *Response
+}
+
+// Latency returns the time between the end of the request and the start
+// of the response, or zero if either is missing.
+func (t *Transaction) Latency() time.Duration {
+	if t.Request == nil || t.Response == nil {
+		return 0
+	}
+	return t.Response.Seen.Timestamp.Sub(t.Request.Complete.Timestamp)
+}
+
+// Handler receives the transactions decoded from the streams.
+type Handler interface {
+	HandleTransaction(t *Transaction)
+}
+
+// HandlerFunc adapts a function to the Handler interface.
+type HandlerFunc func(t *Transaction)
+
+// HandleTransaction calls f(t).
+func (f HandlerFunc) HandleTransaction(t *Transaction) { f(t) }
+
+// StreamFactory implements reassembly.StreamFactory, creating a Stream
+// decoding HTTP for every new connection.
+type StreamFactory struct {
+	// Handler is called for every transaction, from the goroutine
+	// calling the Assembler.
+	Handler Handler
+	// MaxBodySize limits the number of body bytes kept per message.
+	// Zero means DefaultMaxBodySize, a negative value keeps no body.
+	MaxBodySize int
+	// AllowMissingInit accepts connections whose SYN was not captured.
+	AllowMissingInit bool
+	// DisableDecompression keeps bodies in their content coding.
+	DisableDecompression bool
+}
+
+// New implements reassembly.StreamFactory.
+func (f *StreamFactory) New(netFlow, tcpFlow gopacket.Flow, tcp *layers.TCP, ac reassembly.AssemblerContext) reassembly.Stream {
+	s := &Stream{
+		factory:       f,
+		netFlow:       netFlow,
+		transportFlow: tcpFlow,
+	}
+	s.halves[0].stream = s
+	s.halves[1].stream = s
+	s.halves[1].dir = reassembly.TCPDirServerToClient
+	return s
+}
+
+// Stream decodes the HTTP messages of one TCP connection. It implements
+// reassembly.Stream.
+type Stream struct {
+	factory                *StreamFactory
+	netFlow, transportFlow gopacket.Flow
+	halves                 [2]halfStream
+	// requests are the completed requests still waiting for a response,
+	// in order.
+	requests []*Request
+	// reversed is set when the requests flow from the server to the
+	// client, as seen by the assembler, i.e. the SYN was not captured
+	// and the first packet came from the server.
+	reversed bool
+}
+
+func (s *Stream) half(dir reassembly.TCPFlowDirection) *halfStream {
+	if dir == reassembly.TCPDirClientToServer {
+		return &s.halves[0]
+	}
+	return &s.halves[1]
+}
+
+func (s *Stream) maxBodySize() int {
+	switch {
+	case s.factory.MaxBodySize == 0:
+		return DefaultMaxBodySize
+	case s.factory.MaxBodySize < 0:
+		return 0
+	}
+	return s.factory.MaxBodySize
+}
+
+// Accept implements reassembly.Stream.
+func (s *Stream) Accept(tcp *layers.TCP, ci gopacket.CaptureInfo, dir reassembly.TCPFlowDirection, nextSeq reassembly.Sequence, start *bool, ac reassembly.AssemblerContext) bool {
+	if s.factory.AllowMissingInit {
+		*start = true
+	}
+	s.half(dir).last = ci
+	return true
+}
+
+// ReassembledSG implements reassembly.Stream.
+func (s *Stream) ReassembledSG(sg reassembly.ScatterGather, ac reassembly.AssemblerContext) {
+	dir, _, end, skip := sg.Info()
+	h := s.half(dir)
+	length, _ := sg.Lengths()
+	if skip > 0 {
+		h.gap(skip)
+	}
+	if length > 0 {
+		h.feed(sg.Fetch(length), sg)
+	}
+	if end {
+		h.close()
+	}
+}
+
+// ReassemblyComplete implements reassembly.Stream. Messages still in
+// progress are delivered as truncated, and requests never answered are
+// delivered without a response.
+func (s *Stream) ReassemblyComplete(ac reassembly.AssemblerContext) bool {
+	s.halves[0].close()
+	s.halves[1].close()
+	for _, req := range s.requests {
+		s.emit(req, nil)
+	}
+	s.requests = nil
+	return true
+}
+
+// pendingRequest returns the oldest request waiting for a response.
+func (s *Stream) pendingRequest() *Request {
+	if len(s.requests) == 0 {
+		return nil
+	}
+	return s.requests[0]
+}
+
+func (s *Stream) requestDone(req *Request) {
+	s.requests = append(s.requests, req)
+}
+
+func (s *Stream) responseDone(resp *Response) {
+	if resp.StatusCode >= 100 && resp.StatusCode < 200 && resp.StatusCode != http.StatusSwitchingProtocols {
+		// interim response, the final one is still to come
+		return
+	}
+	var req *Request
+	if len(s.requests) > 0 {
+		req = s.requests[0]
+		s.requests = s.requests[1:]
+	}
+	s.emit(req, resp)
+}
+
+// tunnel stops parsing both directions, once the connection no longer
+// carries HTTP.
+func (s *Stream) tunnel() {
+	s.halves[0].state = stateDone
+	s.halves[1].state = stateDone
+}
+
+func (s *Stream) emit(req *Request, resp *Response) {
+	if s.factory.Handler == nil {
+		return
+	}
+	t := &Transaction{
+		NetFlow:       s.netFlow,
+		TransportFlow: s.transportFlow,
+		Request:       req,
+		Response:      resp,
+	}
+	if s.reversed {
+		t.NetFlow, t.TransportFlow = t.NetFlow.Reverse(), t.TransportFlow.Reverse()
+	}
+	s.factory.Handler.HandleTransaction(t)
+}
@@ -0,0 +1,241 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package httpstream
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gopacket/gopacket/reassembly/internal/streamtest"
+)
+
+// testConn feeds the packets of a synthetic TCP connection to an
+// assembler and records the transactions.
+type testConn struct {
+	*streamtest.Conn
+	trans []*Transaction
+}
+
+func newTestConn(t *testing.T, f *StreamFactory) *testConn {
+	c := &testConn{}
+	f.Handler = HandlerFunc(func(tr *Transaction) { c.trans = append(c.trans, tr) })
+	c.Conn = streamtest.NewConn(t, f, 80)
+	return c
+}
+
+func (c *testConn) checkCount(n int) {
+	c.T.Helper()
+	if len(c.trans) != n {
+		for _, tr := range c.trans {
+			c.T.Logf("%+v %+v", tr.Request, tr.Response)
+		}
+		c.T.Fatalf("got %d transactions, want %d", len(c.trans), n)
+	}
+}
+
+func checkRequest(t *testing.T, req *Request, method, uri, body string) {
+	t.Helper()
+	if req == nil {
+		t.Fatalf("missing request %s %s", method, uri)
+	}
+	if req.Method != method || req.URI != uri || string(req.Body) != body {
+		t.Errorf("got request %s %s %q, want %s %s %q", req.Method, req.URI, req.Body, method, uri, body)
+	}
+}
+
+func checkResponse(t *testing.T, resp *Response, code int, body string) {
+	t.Helper()
+	if resp == nil {
+		t.Fatalf("missing response %d", code)
+	}
+	if resp.StatusCode != code || string(resp.Body) != body {
+		t.Errorf("got response %d %q, want %d %q", resp.StatusCode, resp.Body, code, body)
+	}
+}
+
+func TestPipelined(t *testing.T) {
+	c := newTestConn(t, &StreamFactory{})
+	c.Handshake()
+	c.Send(false, "GET /a HTTP/1.1\r\nHost: example.com\r\n\r\n"+
+		"HEAD /b HTTP/1.1\r\nHost: example.com\r\n\r\n"+
+		"POST /c HTTP/1.1\r\nHost: example.com\r\nTransfer-Encoding: chunked\r\n\r\n"+
+		"3\r\nabc\r\n4;ext=1\r\ndefg\r\n0\r\n\r\n", 1400)
+	c.checkCount(0)
+	c.Send(true, "HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\nhello"+
+		"HTTP/1.1 200 OK\r\nContent-Length: 1000\r\n\r\n"+
+		"HTTP/1.1 100 Continue\r\n\r\n"+
+		"HTTP/1.1 201 Created\r\nContent-Length: 2\r\n\r\nok", 9)
+	c.checkCount(3)
+
+	checkRequest(t, c.trans[0].Request, "GET", "/a", "")
+	checkResponse(t, c.trans[0].Response, 200, "hello")
+	checkRequest(t, c.trans[1].Request, "HEAD", "/b", "")
+	checkResponse(t, c.trans[1].Response, 200, "")
+	checkRequest(t, c.trans[2].Request, "POST", "/c", "abcdefg")
+	checkResponse(t, c.trans[2].Response, 201, "ok")
+
+	tr := c.trans[0]
+	if tr.Request.Host != "example.com" || tr.Response.Status != "200 OK" || tr.Request.Proto != "HTTP/1.1" {
+		t.Errorf("bad transaction %+v %+v", tr.Request, tr.Response)
+	}
+	if tr.NetFlow != streamtest.ClientFlow || tr.TransportFlow.Dst().String() != "80" {
+		t.Errorf("bad flows %v %v", tr.NetFlow, tr.TransportFlow)
+	}
+	// the requests all came in packet 3, the first response in packets
+	// 4 to 10
+	if !tr.Request.Seen.Timestamp.Equal(streamtest.PacketTime(3)) || !tr.Request.Complete.Timestamp.Equal(streamtest.PacketTime(3)) {
+		t.Errorf("bad request capture info %v %v", tr.Request.Seen.Timestamp, tr.Request.Complete.Timestamp)
+	}
+	if !tr.Response.Seen.Timestamp.Equal(streamtest.PacketTime(4)) || !tr.Response.Complete.Timestamp.Equal(streamtest.PacketTime(8)) {
+		t.Errorf("bad response capture info %v %v", tr.Response.Seen.Timestamp, tr.Response.Complete.Timestamp)
+	}
+	if tr.Latency() != time.Millisecond {
+		t.Errorf("bad latency %v", tr.Latency())
+	}
+}
+
+func gzipped(s string) string {
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	w.Write([]byte(s))
+	w.Close()
+	return b.String()
+}
+
+func TestChunkedGzip(t *testing.T) {
+	const text = "The quick brown fox jumps over the lazy dog. "
+	z := gzipped(text + text + text)
+	resp := "HTTP/1.1 200 OK\r\nContent-Encoding: gzip\r\nTransfer-Encoding: chunked\r\n\r\n" +
+		fmt.Sprintf("%x\r\n%s\r\n%x\r\n%s\r\n", 10, z[:10], len(z)-10, z[10:]) +
+		"0\r\nX-Checksum: 42\r\n\r\n"
+
+	for _, disable := range []bool{false, true} {
+		c := newTestConn(t, &StreamFactory{DisableDecompression: disable})
+		c.Handshake()
+		c.Send(false, "GET / HTTP/1.1\r\nAccept-Encoding: gzip\r\n\r\n", 1400)
+		c.Send(true, resp, 7)
+		c.checkCount(1)
+
+		r := c.trans[0].Response
+		want := text + text + text
+		if disable {
+			want = z
+		}
+		checkResponse(t, r, 200, want)
+		if r.Uncompressed == disable || r.Truncated {
+			t.Errorf("bad flags %+v", r)
+		}
+		if r.Header.Get("X-Checksum") != "42" {
+			t.Errorf("trailer not merged: %v", r.Header)
+		}
+	}
+}
+
+func TestCloseDelimited(t *testing.T) {
+	c := newTestConn(t, &StreamFactory{})
+	c.Handshake()
+	c.Send(false, "GET / HTTP/1.0\r\n\r\n", 1400)
+	c.Send(true, "HTTP/1.0 200 OK\r\nContent-Type: text/plain\r\n\r\nfirst ", 1400)
+	c.Send(true, "second", 1400)
+	c.checkCount(0)
+	c.Fin(true)
+	c.checkCount(1)
+	checkResponse(t, c.trans[0].Response, 200, "first second")
+	if c.trans[0].Response.Truncated {
+		t.Error("close delimited body flagged truncated")
+	}
+}
+
+func TestConnectionClose(t *testing.T) {
+	c := newTestConn(t, &StreamFactory{})
+	c.Handshake()
+	c.Send(false, "GET / HTTP/1.1\r\nConnection: close\r\n\r\n", 1400)
+	c.Send(true, "HTTP/1.1 204 No Content\r\n\r\ngarbage", 1400)
+	c.checkCount(1)
+	checkResponse(t, c.trans[0].Response, 204, "")
+	c.Assembler.FlushAll()
+	c.checkCount(1)
+}
+
+func TestBodyLimit(t *testing.T) {
+	c := newTestConn(t, &StreamFactory{MaxBodySize: 4})
+	c.Handshake()
+	c.Send(false, "POST / HTTP/1.1\r\nContent-Length: 10\r\n\r\n0123456789", 6)
+	c.Send(false, "GET /next HTTP/1.1\r\n\r\n", 1400)
+	c.Send(true, "HTTP/1.1 200 OK\r\nContent-Length: 0\r\n\r\n", 1400)
+	c.Send(true, "HTTP/1.1 200 OK\r\nContent-Length: 0\r\n\r\n", 1400)
+	c.checkCount(2)
+	checkRequest(t, c.trans[0].Request, "POST", "/", "0123")
+	if !c.trans[0].Request.Truncated {
+		t.Error("body over the limit not flagged truncated")
+	}
+	checkRequest(t, c.trans[1].Request, "GET", "/next", "")
+}
+
+func TestMissingData(t *testing.T) {
+	c := newTestConn(t, &StreamFactory{})
+	c.Handshake()
+	c.Send(false, "GET /a HTTP/1.1\r\n\r\n", 1400)
+	c.Send(false, "GET /b HTTP/1.1\r\n\r\n", 1400)
+	c.Send(true, "HTTP/1.1 200 OK\r\nContent-Length: 10\r\n\r\n01", 1400)
+	c.Skip(true, 4)
+	c.Send(true, "6789", 1400)
+	c.Send(true, "HTTP/1.1 404 Not Found\r\nContent-Length: 3\r\n\r\nnope", 1400)
+	c.Assembler.FlushAll()
+	c.checkCount(2)
+
+	checkRequest(t, c.trans[0].Request, "GET", "/a", "")
+	checkResponse(t, c.trans[0].Response, 200, "016789")
+	if !c.trans[0].Response.Truncated {
+		t.Error("response with missing bytes not flagged truncated")
+	}
+	checkRequest(t, c.trans[1].Request, "GET", "/b", "")
+	checkResponse(t, c.trans[1].Response, 404, "nop")
+}
+
+func TestUnanswered(t *testing.T) {
+	c := newTestConn(t, &StreamFactory{})
+	c.Handshake()
+	c.Send(false, "GET / HTTP/1.1\r\n\r\n", 1400)
+	c.checkCount(0)
+	c.Assembler.FlushAll()
+	c.checkCount(1)
+	checkRequest(t, c.trans[0].Request, "GET", "/", "")
+	if c.trans[0].Response != nil {
+		t.Errorf("unexpected response %+v", c.trans[0].Response)
+	}
+}
+
+func TestMissingInit(t *testing.T) {
+	// the capture starts with the server's response: the request of
+	// the next exchange flows the other way
+	c := newTestConn(t, &StreamFactory{AllowMissingInit: true})
+	c.Send(true, "ent-Length: 3\r\n\r\nabcHTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nhi", 1400)
+	c.Send(false, "GET /x HTTP/1.1\r\n\r\n", 1400)
+	c.Send(true, "HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nyo", 1400)
+	c.checkCount(1)
+	checkRequest(t, c.trans[0].Request, "GET", "/x", "")
+	checkResponse(t, c.trans[0].Response, 200, "yo")
+	if c.trans[0].NetFlow != streamtest.ClientFlow {
+		t.Errorf("flows not oriented from the client: %v", c.trans[0].NetFlow)
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	c := newTestConn(t, &StreamFactory{})
+	c.Handshake()
+	c.Send(false, "GET /ws HTTP/1.1\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n", 1400)
+	c.Send(true, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\n\r\n\x81\x02hi", 1400)
+	c.Send(false, "GET /not-http HTTP/1.1\r\n\r\n", 1400)
+	c.Send(true, "HTTP/1.1 200 OK\r\n\r\n", 1400)
+	c.Assembler.FlushAll()
+	c.checkCount(1)
+	checkResponse(t, c.trans[0].Response, 101, "")
+}
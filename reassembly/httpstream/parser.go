@@ -0,0 +1,532 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package httpstream
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/reassembly"
+	"golang.org/x/net/http/httpguts"
+)
+
+const (
+	// maxHeadSize bounds the start line and headers of a message; a
+	// direction going past it without ending the headers is not HTTP.
+	maxHeadSize = 64 << 10
+	// maxLineSize bounds chunk size and trailer lines.
+	maxLineSize = 4 << 10
+	// maxMethodLen bounds the method token looked for at message start.
+	maxMethodLen = 20
+)
+
+type parseState int
+
+const (
+	stateIdle       parseState = iota // waiting for a start line
+	stateHead                         // reading the start line and headers
+	stateBody                         // reading a Content-Length body
+	stateChunkSize                    // reading a chunk size line
+	stateChunkData                    // reading chunk data
+	stateChunkEnd                     // reading the CRLF after chunk data
+	stateTrailer                      // reading the trailers of a chunked body
+	stateUntilClose                   // reading a body delimited by the end of the stream
+	stateDone                         // ignoring the rest of the direction
+)
+
+type role int
+
+const (
+	roleUnknown role = iota
+	roleRequest
+	roleResponse
+)
+
+// message is the message being parsed by a halfStream.
+type message struct {
+	req       *Request
+	resp      *Response
+	header    http.Header
+	body      []byte
+	truncated bool
+	seen      gopacket.CaptureInfo
+	// request is the request answered by resp, if known.
+	request *Request
+}
+
+// halfStream parses one direction of a connection.
+type halfStream struct {
+	stream *Stream
+	dir    reassembly.TCPFlowDirection
+	role   role
+	state  parseState
+	// buf holds the bytes not parsed yet.
+	buf []byte
+	// sg is the ScatterGather being fed, and base the offset in buf of
+	// its first byte.
+	sg   reassembly.ScatterGather
+	base int
+	// last is the capture info of the last packet seen in this
+	// direction.
+	last gopacket.CaptureInfo
+	msg  *message
+	// remaining is the number of bytes left in the current body or
+	// chunk.
+	remaining int64
+}
+
+// ci returns the capture info of the packet carrying buf[pos].
+func (h *halfStream) ci(pos int) gopacket.CaptureInfo {
+	if h.sg != nil && pos >= h.base {
+		return h.sg.CaptureInfo(pos - h.base)
+	}
+	return h.last
+}
+
+func (h *halfStream) consume(n int) {
+	h.buf = h.buf[n:]
+	h.base -= n
+	if len(h.buf) == 0 {
+		h.buf = nil
+	}
+}
+
+func (h *halfStream) feed(data []byte, sg reassembly.ScatterGather) {
+	if h.state == stateDone {
+		return
+	}
+	h.buf = append(h.buf, data...)
+	h.base = len(h.buf) - len(data)
+	h.sg = sg
+	for h.step() {
+	}
+	h.sg = nil
+}
+
+// gap handles skip bytes missing from the capture before the next data.
+func (h *halfStream) gap(skip int) {
+	switch h.state {
+	case stateDone:
+		return
+	case stateIdle:
+		h.msg = nil
+		h.buf = nil
+		return
+	case stateUntilClose:
+		h.msg.truncated = true
+		return
+	case stateBody:
+		if int64(skip) < h.remaining {
+			h.remaining -= int64(skip)
+			h.msg.truncated = true
+			return
+		}
+	}
+	h.lost()
+}
+
+// lost abandons the message being parsed, delivering it as truncated if
+// its headers were parsed, and waits for the start of a new message.
+func (h *halfStream) lost() {
+	if h.msg != nil && h.msg.header != nil {
+		h.msg.truncated = true
+		h.complete(h.last)
+	}
+	h.msg = nil
+	h.buf = nil
+	if h.state != stateDone {
+		h.state = stateIdle
+	}
+}
+
+// close handles the end of the direction.
+func (h *halfStream) close() {
+	switch h.state {
+	case stateUntilClose:
+		h.complete(h.last)
+	case stateBody, stateChunkSize, stateChunkData, stateChunkEnd, stateTrailer:
+		h.msg.truncated = true
+		h.complete(h.last)
+	}
+	h.msg = nil
+	h.buf = nil
+	h.state = stateDone
+}
+
+// step parses what it can from buf, and returns false once more data is
+// needed.
+func (h *halfStream) step() bool {
+	switch h.state {
+	case stateIdle:
+		return h.parseStart()
+	case stateHead:
+		return h.parseHead()
+	case stateBody, stateChunkData:
+		n := len(h.buf)
+		if int64(n) > h.remaining {
+			n = int(h.remaining)
+		}
+		if n == 0 {
+			return false
+		}
+		h.addBody(h.buf[:n])
+		h.remaining -= int64(n)
+		ci := h.ci(n - 1)
+		h.consume(n)
+		if h.remaining > 0 {
+			return false
+		}
+		if h.state == stateChunkData {
+			h.state = stateChunkEnd
+		} else {
+			h.complete(ci)
+		}
+		return true
+	case stateChunkSize:
+		line, ok := h.line()
+		if !ok {
+			return false
+		}
+		if i := strings.IndexByte(line, ';'); i >= 0 {
+			line = line[:i]
+		}
+		size, err := strconv.ParseInt(strings.TrimSpace(line), 16, 64)
+		if err != nil || size < 0 {
+			h.lost()
+			return false
+		}
+		if size == 0 {
+			h.state = stateTrailer
+		} else {
+			h.remaining = size
+			h.state = stateChunkData
+		}
+		return true
+	case stateChunkEnd:
+		switch {
+		case bytes.HasPrefix(h.buf, []byte("\r\n")):
+			h.consume(2)
+		case bytes.HasPrefix(h.buf, []byte("\n")):
+			h.consume(1)
+		case bytes.Equal(h.buf, []byte("\r")) || len(h.buf) == 0:
+			return false
+		default:
+			h.lost()
+			return false
+		}
+		h.state = stateChunkSize
+		return true
+	case stateTrailer:
+		ci := h.ci(bytes.IndexByte(h.buf, '\n'))
+		line, ok := h.line()
+		if !ok {
+			return false
+		}
+		if line == "" {
+			h.complete(ci)
+			return true
+		}
+		if i := strings.IndexByte(line, ':'); i > 0 {
+			h.msg.header.Add(strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:]))
+		}
+		return true
+	case stateUntilClose:
+		h.addBody(h.buf)
+		h.consume(len(h.buf))
+		return false
+	}
+	h.buf = nil
+	return false
+}
+
+// line returns the next line of buf without its line ending, consuming
+// it.
+func (h *halfStream) line() (string, bool) {
+	i := bytes.IndexByte(h.buf, '\n')
+	if i < 0 {
+		if len(h.buf) > maxLineSize {
+			h.lost()
+		}
+		return "", false
+	}
+	line := strings.TrimSuffix(string(h.buf[:i]), "\r")
+	h.consume(i + 1)
+	return line, true
+}
+
+// startRole tells whether data looks like the start of a request or a
+// response. more is set when data is too short to tell.
+func startRole(data []byte) (r role, more bool) {
+	const prefix = "HTTP/"
+	n := len(data)
+	if n > len(prefix) {
+		n = len(prefix)
+	}
+	if string(data[:n]) == prefix[:n] {
+		if n < len(prefix) {
+			return roleUnknown, true
+		}
+		return roleResponse, false
+	}
+	for i, c := range data {
+		switch {
+		case c >= 'A' && c <= 'Z', c == '-', c == '_':
+			if i >= maxMethodLen {
+				return roleUnknown, false
+			}
+		case c == ' ' && i > 0:
+			return roleRequest, false
+		default:
+			return roleUnknown, false
+		}
+	}
+	return roleUnknown, true
+}
+
+func (h *halfStream) parseStart() bool {
+	i := 0
+	for i < len(h.buf) && (h.buf[i] == '\r' || h.buf[i] == '\n') {
+		i++
+	}
+	h.consume(i)
+	if len(h.buf) == 0 {
+		return false
+	}
+	if h.msg == nil {
+		h.msg = &message{seen: h.ci(0)}
+	}
+	r, more := startRole(h.buf)
+	if more {
+		return false
+	}
+	if r == roleUnknown || (h.role != roleUnknown && r != h.role) {
+		// not the start of a message: wait for the next data
+		h.msg = nil
+		h.buf = nil
+		return false
+	}
+	if h.role == roleUnknown {
+		h.role = r
+		h.stream.reversed = (r == roleRequest) != (h.dir == reassembly.TCPDirClientToServer)
+	}
+	h.state = stateHead
+	return true
+}
+
+// headEnd returns the length of the head at the start of data, up to
+// and including the empty line ending it, or -1.
+func headEnd(data []byte) int {
+	end := -1
+	if i := bytes.Index(data, []byte("\n\r\n")); i >= 0 {
+		end = i + 3
+	}
+	if i := bytes.Index(data, []byte("\n\n")); i >= 0 && (end < 0 || i+2 < end) {
+		end = i + 2
+	}
+	return end
+}
+
+func (h *halfStream) parseHead() bool {
+	end := headEnd(h.buf)
+	if end < 0 {
+		if len(h.buf) > maxHeadSize {
+			h.state = stateDone
+			h.buf = nil
+		}
+		return false
+	}
+	ci := h.ci(end - 1)
+	tp := textproto.NewReader(bufio.NewReader(bytes.NewReader(h.buf[:end])))
+	h.consume(end)
+	first, err := tp.ReadLine()
+	if err != nil {
+		h.lost()
+		return false
+	}
+	header, err := tp.ReadMIMEHeader()
+	if err != nil {
+		h.lost()
+		return false
+	}
+	h.msg.header = http.Header(header)
+	if h.role == roleRequest {
+		err = h.startRequest(first, ci)
+	} else {
+		err = h.startResponse(first, ci)
+	}
+	if err != nil {
+		h.msg.header = nil
+		h.lost()
+	}
+	return true
+}
+
+type parseError string
+
+func (e parseError) Error() string { return "httpstream: " + string(e) }
+
+func (h *halfStream) startRequest(line string, ci gopacket.CaptureInfo) error {
+	parts := strings.SplitN(line, " ", 3)
+	if len(parts) != 3 || !strings.HasPrefix(parts[2], "HTTP/") {
+		return parseError("malformed request line")
+	}
+	h.msg.req = &Request{
+		Method: parts[0],
+		URI:    parts[1],
+		Proto:  parts[2],
+		Host:   h.msg.header.Get("Host"),
+		Header: h.msg.header,
+		Seen:   h.msg.seen,
+	}
+	return h.startBody(false, ci)
+}
+
+func (h *halfStream) startResponse(line string, ci gopacket.CaptureInfo) error {
+	proto, status, _ := strings.Cut(line, " ")
+	status = strings.TrimSpace(status)
+	if len(status) < 3 {
+		return parseError("malformed status line")
+	}
+	code, err := strconv.Atoi(status[:3])
+	if err != nil || code < 100 {
+		return parseError("malformed status code")
+	}
+	h.msg.resp = &Response{
+		Proto:      proto,
+		StatusCode: code,
+		Status:     status,
+		Header:     h.msg.header,
+		Seen:       h.msg.seen,
+	}
+	h.msg.request = h.stream.pendingRequest()
+	noBody := code < 200 || code == http.StatusNoContent || code == http.StatusNotModified
+	if req := h.msg.request; req != nil {
+		noBody = noBody || req.Method == http.MethodHead ||
+			req.Method == http.MethodConnect && code < 300
+	}
+	return h.startBody(noBody, ci)
+}
+
+// startBody sets up the parsing of the body from the headers of the
+// message, completing it right away when it has none.
+func (h *halfStream) startBody(noBody bool, ci gopacket.CaptureInfo) error {
+	header := h.msg.header
+	switch {
+	case noBody:
+	case len(header["Transfer-Encoding"]) > 0:
+		if !httpguts.HeaderValuesContainsToken(header["Transfer-Encoding"], "chunked") {
+			if h.role == roleRequest {
+				return parseError("unsupported request transfer coding")
+			}
+			h.state = stateUntilClose
+			return nil
+		}
+		h.state = stateChunkSize
+		return nil
+	case len(header["Content-Length"]) > 0:
+		n, err := strconv.ParseInt(strings.TrimSpace(header.Get("Content-Length")), 10, 64)
+		if err != nil || n < 0 {
+			return parseError("malformed Content-Length")
+		}
+		if n > 0 {
+			h.remaining = n
+			h.state = stateBody
+			return nil
+		}
+	case h.role == roleResponse:
+		h.state = stateUntilClose
+		return nil
+	}
+	h.complete(ci)
+	return nil
+}
+
+func (h *halfStream) addBody(data []byte) {
+	max := h.stream.maxBodySize()
+	if room := max - len(h.msg.body); len(data) > room {
+		data = data[:room]
+		h.msg.truncated = true
+	}
+	h.msg.body = append(h.msg.body, data...)
+}
+
+// decodeBody removes the gzip or deflate content coding of a complete
+// body. It returns the body unchanged if it cannot be decoded.
+func decodeBody(body []byte, coding string, max int) (decoded []byte, ok bool, truncated bool) {
+	var r io.Reader
+	var err error
+	switch strings.ToLower(strings.TrimSpace(coding)) {
+	case "gzip", "x-gzip":
+		r, err = gzip.NewReader(bytes.NewReader(body))
+	case "deflate":
+		// deflate is meant to be zlib wrapped, but raw deflate is
+		// common in the wild
+		r, err = zlib.NewReader(bytes.NewReader(body))
+		if err != nil {
+			r, err = flate.NewReader(bytes.NewReader(body)), nil
+		}
+	default:
+		return body, false, false
+	}
+	if err != nil {
+		return body, false, false
+	}
+	decoded, err = io.ReadAll(io.LimitReader(r, int64(max)+1))
+	if err != nil {
+		return body, false, false
+	}
+	if len(decoded) > max {
+		return decoded[:max], true, true
+	}
+	return decoded, true, false
+}
+
+// wantsClose tells whether the connection is closed after the message.
+func wantsClose(proto string, header http.Header) bool {
+	if httpguts.HeaderValuesContainsToken(header["Connection"], "close") {
+		return true
+	}
+	return proto == "HTTP/1.0" && !httpguts.HeaderValuesContainsToken(header["Connection"], "keep-alive")
+}
+
+// complete delivers the current message, last seen in ci.
+func (h *halfStream) complete(ci gopacket.CaptureInfo) {
+	m := h.msg
+	h.msg = nil
+	h.state = stateIdle
+	body, uncompressed := m.body, false
+	if len(body) > 0 && !m.truncated && !h.stream.factory.DisableDecompression {
+		body, uncompressed, m.truncated = decodeBody(body, m.header.Get("Content-Encoding"), h.stream.maxBodySize())
+	}
+	if req := m.req; req != nil {
+		req.Body, req.Uncompressed, req.Truncated, req.Complete = body, uncompressed, m.truncated, ci
+		h.stream.requestDone(req)
+		return
+	}
+	resp := m.resp
+	resp.Body, resp.Uncompressed, resp.Truncated, resp.Complete = body, uncompressed, m.truncated, ci
+	h.stream.responseDone(resp)
+	switch req := m.request; {
+	case resp.StatusCode == http.StatusSwitchingProtocols,
+		req != nil && req.Method == http.MethodConnect && resp.StatusCode >= 200 && resp.StatusCode < 300:
+		h.stream.tunnel()
+	case wantsClose(resp.Proto, resp.Header),
+		req != nil && resp.StatusCode >= 200 && wantsClose(req.Proto, req.Header):
+		// nothing may follow on this direction
+		h.state = stateDone
+		h.buf = nil
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+// Package streamtest feeds the packets of synthetic TCP connections to a
+// reassembly.Assembler, for the tests of the reassembly stream packages.
+package streamtest
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+	"github.com/gopacket/gopacket/reassembly"
+)
+
+// The addresses of the connections.
+var (
+	ClientIP = net.IP{10, 0, 0, 1}
+	ServerIP = net.IP{10, 0, 0, 2}
+	// ClientFlow is the network flow of the packets sent by the client.
+	ClientFlow, _ = gopacket.FlowFromEndpoints(layers.NewIPEndpoint(ClientIP), layers.NewIPEndpoint(ServerIP))
+)
+
+// ClientPort is the port of the client of the connections.
+const ClientPort layers.TCPPort = 54321
+
+// Start is the timestamp of the packets before the first one.
+var Start = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// PacketTime returns the timestamp of the n-th packet of a connection,
+// counting from 1.
+func PacketTime(n int) time.Time {
+	return Start.Add(time.Duration(n) * time.Millisecond)
+}
+
+// Context is the AssemblerContext given to the Assembler by default.
+type Context gopacket.CaptureInfo
+
+// GetCaptureInfo returns the CaptureInfo of the packet.
+func (c *Context) GetCaptureInfo() gopacket.CaptureInfo {
+	return gopacket.CaptureInfo(*c)
+}
+
+// Conn feeds the packets of a TCP connection between ClientIP and ServerIP
+// to an Assembler.
+type Conn struct {
+	T          testing.TB
+	Assembler  *reassembly.Assembler
+	ServerPort layers.TCPPort
+	// Seq holds the next sequence numbers of the client and the server.
+	Seq [2]uint32
+	// Packets is the number of packets sent so far.
+	Packets int
+	// NewContext, if set, returns the AssemblerContext of a packet.
+	// Otherwise a Context holding its CaptureInfo is used.
+	NewContext func(p gopacket.Packet) reassembly.AssemblerContext
+}
+
+// NewConn returns a connection to serverPort, whose Streams are created
+// by f.
+func NewConn(t testing.TB, f reassembly.StreamFactory, serverPort layers.TCPPort) *Conn {
+	return &Conn{
+		T:          t,
+		Assembler:  reassembly.NewAssembler(reassembly.NewStreamPool(f)),
+		ServerPort: serverPort,
+		Seq:        [2]uint32{1000, 5000},
+	}
+}
+
+func (c *Conn) idx(server bool) int {
+	if server {
+		return 1
+	}
+	return 0
+}
+
+// Packet sends a segment carrying payload from a side, with the next
+// sequence number of the side.  The packet is serialized and decoded
+// again, so it looks like one coming from a capture.
+func (c *Conn) Packet(server bool, tcp *layers.TCP, payload []byte) {
+	c.T.Helper()
+	i := c.idx(server)
+	ip := &layers.IPv4{Version: 4, TTL: 64, Protocol: layers.IPProtocolTCP, SrcIP: ClientIP, DstIP: ServerIP}
+	tcp.SrcPort, tcp.DstPort = ClientPort, c.ServerPort
+	if server {
+		ip.SrcIP, ip.DstIP = ip.DstIP, ip.SrcIP
+		tcp.SrcPort, tcp.DstPort = tcp.DstPort, tcp.SrcPort
+	}
+	tcp.Seq = c.Seq[i]
+	tcp.Window = 65535
+	tcp.SetNetworkLayerForChecksum(ip)
+	eth := &layers.Ethernet{SrcMAC: net.HardwareAddr{0, 0, 0, 0, 0, 1}, DstMAC: net.HardwareAddr{0, 0, 0, 0, 0, 2}, EthernetType: layers.EthernetTypeIPv4}
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	if err := gopacket.SerializeLayers(buf, opts, eth, ip, tcp, gopacket.Payload(payload)); err != nil {
+		c.T.Fatal(err)
+	}
+	p := gopacket.NewPacket(buf.Bytes(), layers.LayerTypeEthernet, gopacket.Default)
+	c.Packets++
+	md := p.Metadata()
+	md.Timestamp = PacketTime(c.Packets)
+	md.CaptureLength = len(buf.Bytes())
+	md.Length = len(buf.Bytes())
+	c.Seq[i] += uint32(len(payload))
+	if tcp.SYN || tcp.FIN {
+		c.Seq[i]++
+	}
+	var ac reassembly.AssemblerContext
+	if c.NewContext != nil {
+		ac = c.NewContext(p)
+	} else {
+		ctx := Context(md.CaptureInfo)
+		ac = &ctx
+	}
+	c.Assembler.AssembleWithContext(p.NetworkLayer().NetworkFlow(), p.TransportLayer().(*layers.TCP), ac)
+}
+
+// Handshake sends the SYN of the client and the SYN+ACK of the server.
+func (c *Conn) Handshake() {
+	c.T.Helper()
+	c.Packet(false, &layers.TCP{SYN: true}, nil)
+	c.Packet(true, &layers.TCP{SYN: true, ACK: true, Ack: c.Seq[0]}, nil)
+}
+
+// Send sends data from a side, in segments of at most size bytes.
+func (c *Conn) Send(server bool, data string, size int) {
+	c.T.Helper()
+	for len(data) > 0 {
+		n := min(size, len(data))
+		c.Packet(server, &layers.TCP{ACK: true, PSH: true}, []byte(data[:n]))
+		data = data[n:]
+	}
+}
+
+// Skip loses the next n bytes of a side.
+func (c *Conn) Skip(server bool, n int) {
+	c.Seq[c.idx(server)] += uint32(n)
+}
+
+// Fin sends a FIN from a side.
+func (c *Conn) Fin(server bool) {
+	c.T.Helper()
+	c.Packet(server, &layers.TCP{FIN: true, ACK: true}, nil)
+}
+
+// Close sends the FINs of the client and the server.
+func (c *Conn) Close() {
+	c.T.Helper()
+	c.Fin(false)
+	c.Fin(true)
+}
@@ -7,9 +7,11 @@
 package reassembly
 
 import (
+	"container/list"
 	"flag"
 	"log"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gopacket/gopacket/layers"
@@ -22,17 +24,28 @@ var memLog = flag.Bool("assembly_memuse_log", defaultDebug, "If true, the github
  */
 // pageCache is a concurrency-unsafe store of page objects we use to avoid
 // memory allocation as much as we can.
+//
+// Released pages are kept in a free list, which is shrunk to the peak usage
+// seen since the previous shrink: pages beyond it go back to a sync.Pool,
+// where the garbage collector can reclaim them after a traffic spike.
 type pageCache struct {
 	pagePool     *sync.Pool
+	free         []*page
 	used         int
 	pageRequests int64
+	// peak is the highest number of pages used since the last shrink
+	peak       int
+	lastShrink time.Time
+	stats      *memoryStats
 }
 
-func newPageCache() *pageCache {
+func newPageCache(stats *memoryStats) *pageCache {
 	pc := &pageCache{
 		pagePool: &sync.Pool{
 			New: func() interface{} { return new(page) },
-		}}
+		},
+		stats: stats,
+	}
 	return pc
 }
 
@@ -41,13 +54,24 @@ func (c *pageCache) next(ts time.Time) (p *page) {
 	if *memLog {
 		c.pageRequests++
 		if c.pageRequests&0xFFFF == 0 {
-			log.Println("PageCache:", c.pageRequests, "requested,", c.used, "used,")
+			log.Println("PageCache:", c.pageRequests, "requested,", c.used, "used,", len(c.free), "free")
 		}
 	}
-	p = c.pagePool.Get().(*page)
+	if n := len(c.free) - 1; n >= 0 {
+		p = c.free[n]
+		c.free[n] = nil
+		c.free = c.free[:n]
+		c.stats.pagesFree.Add(-1)
+	} else {
+		p = c.pagePool.Get().(*page)
+	}
 	p.seen = ts
 	p.bytes = p.buf[:0]
 	c.used++
+	if c.used > c.peak {
+		c.peak = c.used
+	}
+	c.stats.pagesUsed.Add(1)
 	if *memLog {
 		log.Printf("allocator returns %s\n", p)
 	}
@@ -58,12 +82,88 @@ func (c *pageCache) next(ts time.Time) (p *page) {
 // replace replaces a page into the pageCache.
 func (c *pageCache) replace(p *page) {
 	c.used--
+	c.stats.pagesUsed.Add(-1)
 	if *memLog {
 		log.Printf("replacing %s\n", p)
 	}
 	p.prev = nil
 	p.next = nil
-	c.pagePool.Put(p)
+	p.ac = nil
+	c.free = append(c.free, p)
+	c.stats.pagesFree.Add(1)
+}
+
+// shrink releases the free pages that were not needed to serve the peak
+// usage since the previous shrink.
+func (c *pageCache) shrink() {
+	keep := c.peak - c.used
+	if keep < 0 {
+		keep = 0
+	}
+	if n := len(c.free) - keep; n > 0 {
+		for i, p := range c.free[keep:] {
+			c.pagePool.Put(p)
+			c.free[keep+i] = nil
+		}
+		c.free = c.free[:keep]
+		c.stats.pagesFree.Add(-int64(n))
+		if *memLog {
+			log.Println("PageCache: released", n, "pages,", c.used, "used,", keep, "free")
+		}
+	}
+	c.peak = c.used
+}
+
+// maybeShrink shrinks the cache if interval elapsed since the previous
+// shrink.
+func (c *pageCache) maybeShrink(ts time.Time, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	if c.lastShrink.IsZero() || ts.Before(c.lastShrink) {
+		c.lastShrink = ts
+		return
+	}
+	if ts.Sub(c.lastShrink) >= interval {
+		c.shrink()
+		c.lastShrink = ts
+	}
+}
+
+/*
+ * Memory accounting
+ */
+
+// memoryStats holds the gauges shared by the page caches of the Assemblers
+// of a StreamPool.
+type memoryStats struct {
+	pagesUsed atomic.Int64
+	pagesFree atomic.Int64
+	evictions atomic.Int64
+	// maxBytes is the memory budget, see StreamPool.SetMaxBufferedBytes
+	maxBytes atomic.Int64
+}
+
+// bufferedHalf is an element of StreamPool.buffered.
+type bufferedHalf struct {
+	conn *connection
+	half *halfconnection
+	// pc is the page cache of the Assembler that buffered the pages
+	pc *pageCache
+}
+
+// MemoryStats holds gauges of the memory used to buffer out-of-order data by
+// the Assemblers of a StreamPool.
+type MemoryStats struct {
+	// PagesInUse is the number of pages holding buffered data, and
+	// BytesInUse their size in memory.
+	PagesInUse int64
+	BytesInUse int64
+	// PagesFree is the number of pages kept by the page caches for reuse.
+	PagesFree int64
+	// Evictions is the number of times buffered data was flushed to stay
+	// within the memory budget.
+	Evictions int64
 }
 
 /*
@@ -94,6 +194,13 @@ type StreamPool struct {
 	all                [][]connection
 	nextAlloc          int
 	newConnectionCount int64
+	mem                memoryStats
+	// buffered holds the halfconnections with buffered pages, in the order
+	// they started buffering, as *bufferedHalf.  The list and the
+	// halfconnection.buffered elements are only modified with both the
+	// connection lock and bufferedMu held.
+	buffered   list.List
+	bufferedMu sync.Mutex
 }
 
 const initialAllocSize = 1024
@@ -140,6 +247,71 @@ func NewStreamPool(factory StreamFactory) *StreamPool {
 	}
 }
 
+// SetMaxBufferedBytes sets a memory budget for the out-of-order data
+// buffered by all the Assemblers using the pool. When the pages in use
+// exceed it, each Assembler flushes the buffered data of the connections it
+// buffered, as FlushWithOptions would, starting with the connections that
+// have been buffering the longest, until the pool is back under 7/8 of the
+// budget. The budget can be exceeded by the pages of one packet, and by the
+// data of an Assembler until it gets its next packet. If n <= 0, which is
+// the default, the memory is not limited.
+func (p *StreamPool) SetMaxBufferedBytes(n int64) {
+	p.mem.maxBytes.Store(n)
+}
+
+// MemoryStats returns the current memory gauges of the pool.
+func (p *StreamPool) MemoryStats() MemoryStats {
+	used := p.mem.pagesUsed.Load()
+	return MemoryStats{
+		PagesInUse: used,
+		BytesInUse: used * pageBytes,
+		PagesFree:  p.mem.pagesFree.Load(),
+		Evictions:  p.mem.evictions.Load(),
+	}
+}
+
+// overBudget reports whether the pages in use exceed the fraction num/8 of
+// the memory budget.
+func (p *StreamPool) overBudget(num int64) bool {
+	max := p.mem.maxBytes.Load()
+	return max > 0 && p.mem.pagesUsed.Load()*pageBytes > max/8*num
+}
+
+// trackBuffered adds half to or removes it from the buffered list, after its
+// pages changed.  pc, if not nil, is the page cache of the Assembler that
+// buffered them.  The connection must be locked.
+func (p *StreamPool) trackBuffered(conn *connection, half *halfconnection, pc *pageCache) {
+	buffered := !half.closed && half.first != nil
+	switch {
+	case buffered && half.buffered == nil:
+		p.bufferedMu.Lock()
+		half.buffered = p.buffered.PushBack(&bufferedHalf{conn, half, pc})
+		p.bufferedMu.Unlock()
+	case buffered && pc != nil && half.buffered.Value.(*bufferedHalf).pc != pc:
+		p.bufferedMu.Lock()
+		half.buffered.Value.(*bufferedHalf).pc = pc
+		p.bufferedMu.Unlock()
+	case !buffered && half.buffered != nil:
+		p.bufferedMu.Lock()
+		p.buffered.Remove(half.buffered)
+		p.bufferedMu.Unlock()
+		half.buffered = nil
+	}
+}
+
+// oldestBuffered returns the connection and half that has been buffering
+// pages of pc the longest, if any.
+func (p *StreamPool) oldestBuffered(pc *pageCache) (*connection, *halfconnection) {
+	p.bufferedMu.Lock()
+	defer p.bufferedMu.Unlock()
+	for e := p.buffered.Front(); e != nil; e = e.Next() {
+		if b := e.Value.(*bufferedHalf); b.pc == pc {
+			return b.conn, b.half
+		}
+	}
+	return nil, nil
+}
+
 func (p *StreamPool) connections() []*connection {
 	p.mu.RLock()
 	conns := make([]*connection, 0, len(p.conns))
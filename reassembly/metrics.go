@@ -0,0 +1,405 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package reassembly
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+)
+
+// TCPDirectionMetrics holds the metrics of the packets sent in one
+// direction of a connection. RTTs are measured from the capture point: the
+// time between a segment and its acknowledgment.
+type TCPDirectionMetrics struct {
+	Packets int
+	// Bytes is the number of payload bytes, including retransmissions.
+	Bytes int
+	// RTTSamples is the number of RTT samples taken for the data sent in
+	// this direction. SmoothedRTT is computed as in RFC 6298.
+	RTTSamples      int
+	MinRTT, MaxRTT  time.Duration
+	SmoothedRTT     time.Duration
+	Retransmissions int
+	// SpuriousRetransmissions counts the retransmissions that were not
+	// needed: their data was acknowledged before they were seen, or the
+	// timestamps option showed that the original segment was received
+	// (RFC 3522). They are included in Retransmissions.
+	SpuriousRetransmissions int
+	// OutOfOrder counts the segments filling a gap in the sequence space
+	// less than an RTT after the gap was seen.
+	OutOfOrder int
+	// ZeroWindows counts the segments advertising a zero window.
+	ZeroWindows int
+	// DupAcks counts the duplicate acknowledgments sent.
+	DupAcks int
+	// Window is the last receive window advertised, after scaling.
+	Window int
+	// BytesInFlight is the number of bytes sent but not acknowledged yet,
+	// and MaxBytesInFlight its highest value.
+	BytesInFlight    int
+	MaxBytesInFlight int
+}
+
+// ConnectionMetrics holds the performance metrics of a TCP connection.
+type ConnectionMetrics struct {
+	// HandshakeRTT is the time between the SYN and the ACK of the
+	// SYN-ACK, or zero if the handshake wasn't seen.
+	HandshakeRTT   time.Duration
+	ClientToServer TCPDirectionMetrics
+	ServerToClient TCPDirectionMetrics
+}
+
+// Direction returns the metrics of the given direction.
+func (c *ConnectionMetrics) Direction(dir TCPFlowDirection) *TCPDirectionMetrics {
+	if dir == TCPDirClientToServer {
+		return &c.ClientToServer
+	}
+	return &c.ServerToClient
+}
+
+// maxMetricsTracked bounds the number of unacknowledged segments, gaps and
+// timestamps remembered per direction.
+const maxMetricsTracked = 1024
+
+// defaultReorderWindow is the time during which a gap is assumed to be
+// filled by reordered segments when no RTT is known.
+const defaultReorderWindow = 3 * time.Millisecond
+
+type sentSegment struct {
+	end           Sequence
+	seen          time.Time
+	retransmitted bool
+}
+
+type sequenceGap struct {
+	start, end Sequence
+	seen       time.Time
+}
+
+type sentTimestamp struct {
+	val  uint32
+	seen time.Time
+}
+
+type retransmission struct {
+	end   Sequence
+	tsval uint32
+}
+
+// tcpMetricsDirection holds the state of one direction.
+type tcpMetricsDirection struct {
+	metrics *TCPDirectionMetrics
+	started bool
+	// nextSeq follows the highest sequence number sent.
+	nextSeq Sequence
+	// ack is the highest acknowledgment sent, window the last window
+	// advertised without scaling.
+	ack      Sequence
+	window   uint16
+	scale    int
+	segments []sentSegment
+	gaps     []sequenceGap
+	tsvals   []sentTimestamp
+	retrans  []retransmission
+}
+
+// TCPMetricsTracker computes the performance metrics of a TCP connection.
+// It sees the packets as they are captured, and is typically fed from
+// Stream.Accept, its metrics being read in ReassemblyComplete:
+//
+//	func (s *myStream) Accept(tcp *layers.TCP, ci gopacket.CaptureInfo, dir reassembly.TCPFlowDirection, nextSeq reassembly.Sequence, start *bool, ac reassembly.AssemblerContext) bool {
+//		s.metrics.Track(tcp, ci, dir)
+//		return true
+//	}
+//
+//	func (s *myStream) ReassemblyComplete(ac reassembly.AssemblerContext) bool {
+//		m := s.metrics.Metrics()
+//		log.Printf("%s: RTT %v, %d retransmissions", s.ident, m.HandshakeRTT, m.ClientToServer.Retransmissions)
+//		return true
+//	}
+type TCPMetricsTracker struct {
+	metrics ConnectionMetrics
+	dirs    [2]tcpMetricsDirection
+	// the handshake
+	synDir  TCPFlowDirection
+	synSeen time.Time
+	synAck  Sequence
+}
+
+// NewTCPMetricsTracker creates a new TCPMetricsTracker.
+func NewTCPMetricsTracker() *TCPMetricsTracker {
+	m := &TCPMetricsTracker{synAck: invalidSequence}
+	m.dirs[0] = tcpMetricsDirection{metrics: &m.metrics.ClientToServer, ack: invalidSequence, scale: -1}
+	m.dirs[1] = tcpMetricsDirection{metrics: &m.metrics.ServerToClient, ack: invalidSequence, scale: -1}
+	return m
+}
+
+// Metrics returns the metrics computed so far.
+func (m *TCPMetricsTracker) Metrics() ConnectionMetrics {
+	return m.metrics
+}
+
+func (m *TCPMetricsTracker) direction(dir TCPFlowDirection) *tcpMetricsDirection {
+	if dir == TCPDirClientToServer {
+		return &m.dirs[0]
+	}
+	return &m.dirs[1]
+}
+
+// tcpTimestamps returns the values of the timestamps option.
+func tcpTimestamps(tcp *layers.TCP) (tsval, tsecr uint32, ok bool) {
+	for _, o := range tcp.Options {
+		if o.OptionType == layers.TCPOptionKindTimestamps && len(o.OptionData) == 8 {
+			return binary.BigEndian.Uint32(o.OptionData), binary.BigEndian.Uint32(o.OptionData[4:]), true
+		}
+	}
+	return 0, 0, false
+}
+
+// Track updates the metrics with a packet sent in direction dir.
+func (m *TCPMetricsTracker) Track(tcp *layers.TCP, ci gopacket.CaptureInfo, dir TCPFlowDirection) {
+	d, r := m.direction(dir), m.direction(dir.Reverse())
+	now := ci.Timestamp
+	tsval, tsecr, hasTS := tcpTimestamps(tcp)
+	d.metrics.Packets++
+	d.metrics.Bytes += len(tcp.Payload)
+
+	m.handshake(tcp, now, dir)
+	if tcp.SYN {
+		for _, o := range tcp.Options {
+			if o.OptionType == layers.TCPOptionKindWindowScale && len(o.OptionData) == 1 {
+				d.scale = int(o.OptionData[0])
+			}
+		}
+	}
+	// RFC 7323: the window of SYN segments is never scaled
+	d.metrics.Window = int(tcp.Window)
+	if !tcp.SYN && d.scale >= 0 && r.scale >= 0 {
+		d.metrics.Window <<= uint(d.scale)
+	}
+	if tcp.Window == 0 && !tcp.SYN && !tcp.FIN && !tcp.RST {
+		d.metrics.ZeroWindows++
+	}
+
+	length := len(tcp.Payload)
+	if tcp.SYN {
+		length++
+	}
+	if tcp.FIN {
+		length++
+	}
+	keepAlive := len(tcp.Payload) <= 1 && !tcp.SYN && !tcp.FIN && !tcp.RST &&
+		d.started && Sequence(tcp.Seq).Add(1) == d.nextSeq
+	if length > 0 && !keepAlive {
+		m.sent(d, r, Sequence(tcp.Seq), length, now, tsval, hasTS)
+	}
+	if tcp.ACK && !tcp.RST {
+		m.acked(tcp, d, r, now, tsecr, hasTS)
+	}
+	d.window = tcp.Window
+
+	d.updateInFlight(r)
+	r.updateInFlight(d)
+}
+
+func (m *TCPMetricsTracker) handshake(tcp *layers.TCP, now time.Time, dir TCPFlowDirection) {
+	switch {
+	case tcp.SYN && !tcp.ACK:
+		m.synDir, m.synSeen = dir, now
+	case tcp.SYN && tcp.ACK:
+		if !m.synSeen.IsZero() && dir != m.synDir {
+			m.synAck = Sequence(tcp.Seq).Add(1)
+		}
+	case tcp.ACK && dir == m.synDir && m.synAck != invalidSequence && m.metrics.HandshakeRTT == 0:
+		if Sequence(tcp.Ack) == m.synAck {
+			m.metrics.HandshakeRTT = now.Sub(m.synSeen)
+		}
+	}
+}
+
+// sent analyzes a segment of length bytes sent in direction d.
+func (m *TCPMetricsTracker) sent(d, r *tcpMetricsDirection, seq Sequence, length int, now time.Time, tsval uint32, hasTS bool) {
+	end := seq.Add(length)
+	if hasTS && (len(d.tsvals) == 0 || int32(tsval-d.tsvals[len(d.tsvals)-1].val) > 0) {
+		d.tsvals = appendBounded(d.tsvals, sentTimestamp{tsval, now})
+	}
+	if !d.started {
+		d.started = true
+		d.nextSeq = end
+		d.segments = appendBounded(d.segments, sentSegment{end: end, seen: now})
+		return
+	}
+	if diff := d.nextSeq.Difference(seq); diff >= 0 {
+		if diff > 0 {
+			d.gaps = appendBounded(d.gaps, sequenceGap{d.nextSeq, seq, now})
+		}
+		d.nextSeq = end
+		d.segments = appendBounded(d.segments, sentSegment{end: end, seen: now})
+		return
+	}
+
+	// the segment starts before data already sent
+	reordered := d.fillGaps(seq, end, now, m.reorderWindow(d))
+	if end.Difference(d.nextSeq) < 0 {
+		d.nextSeq = end
+		d.segments = appendBounded(d.segments, sentSegment{end: end, seen: now})
+	}
+	if reordered {
+		d.metrics.OutOfOrder++
+		return
+	}
+	d.metrics.Retransmissions++
+	if r.ack != invalidSequence && end.Difference(r.ack) >= 0 {
+		// already acknowledged
+		d.metrics.SpuriousRetransmissions++
+		return
+	}
+	for i := range d.segments {
+		if seq.Difference(d.segments[i].end) > 0 {
+			d.segments[i].retransmitted = true
+		}
+	}
+	if hasTS {
+		d.retrans = appendBounded(d.retrans, retransmission{end, tsval})
+	}
+}
+
+// reorderWindow returns the time after which a segment filling a gap is
+// considered as a retransmission.
+func (m *TCPMetricsTracker) reorderWindow(d *tcpMetricsDirection) time.Duration {
+	if d.metrics.SmoothedRTT > 0 {
+		return d.metrics.SmoothedRTT
+	}
+	if m.metrics.HandshakeRTT > 0 {
+		return m.metrics.HandshakeRTT
+	}
+	return defaultReorderWindow
+}
+
+// fillGaps removes [start, end) from the gaps, and reports whether it
+// filled a gap seen less than window ago.
+func (d *tcpMetricsDirection) fillGaps(start, end Sequence, now time.Time, window time.Duration) bool {
+	reordered := false
+	gaps := d.gaps[:0]
+	for _, g := range d.gaps {
+		if start.Difference(g.end) <= 0 || g.start.Difference(end) <= 0 {
+			gaps = append(gaps, g)
+			continue
+		}
+		if now.Sub(g.seen) < window {
+			reordered = true
+		}
+		if g.start.Difference(start) > 0 {
+			gaps = append(gaps, sequenceGap{g.start, start, g.seen})
+		}
+		if end.Difference(g.end) > 0 {
+			gaps = append(gaps, sequenceGap{end, g.end, g.seen})
+		}
+	}
+	d.gaps = gaps
+	return reordered
+}
+
+// acked analyzes the acknowledgment sent in direction d, of the data sent
+// in direction r.
+func (m *TCPMetricsTracker) acked(tcp *layers.TCP, d, r *tcpMetricsDirection, now time.Time, tsecr uint32, hasTS bool) {
+	ack := Sequence(tcp.Ack)
+	if d.ack != invalidSequence && d.ack.Difference(ack) <= 0 {
+		if ack == d.ack && len(tcp.Payload) == 0 && !tcp.SYN && !tcp.FIN &&
+			tcp.Window == d.window && r.nextSeq != ack {
+			d.metrics.DupAcks++
+		}
+		return
+	}
+	d.ack = ack
+
+	// RTT sample, using the timestamps option if both directions use it
+	// (RFC 7323), or Karn's algorithm otherwise
+	n := 0
+	var last *sentSegment
+	for i := range r.segments {
+		if r.segments[i].end.Difference(ack) < 0 {
+			break
+		}
+		last = &r.segments[i]
+		n++
+	}
+	if hasTS && len(r.tsvals) > 0 {
+		n := 0
+		for _, ts := range r.tsvals {
+			if int32(ts.val-tsecr) > 0 {
+				break
+			}
+			if ts.val == tsecr {
+				r.addRTT(now.Sub(ts.seen))
+			}
+			n++
+		}
+		r.tsvals = r.tsvals[n:]
+	} else if last != nil && !last.retransmitted {
+		r.addRTT(now.Sub(last.seen))
+	}
+	r.segments = r.segments[n:]
+
+	// RFC 3522: the acknowledgment of a retransmission echoing an older
+	// timestamp means the original segment was received
+	n = 0
+	for _, rt := range r.retrans {
+		if rt.end.Difference(ack) < 0 {
+			break
+		}
+		if hasTS && int32(tsecr-rt.tsval) < 0 {
+			r.metrics.SpuriousRetransmissions++
+		}
+		n++
+	}
+	r.retrans = r.retrans[n:]
+}
+
+func (d *tcpMetricsDirection) addRTT(rtt time.Duration) {
+	if rtt < 0 {
+		return
+	}
+	dm := d.metrics
+	if dm.RTTSamples == 0 {
+		dm.MinRTT, dm.MaxRTT, dm.SmoothedRTT = rtt, rtt, rtt
+	} else {
+		if rtt < dm.MinRTT {
+			dm.MinRTT = rtt
+		}
+		if rtt > dm.MaxRTT {
+			dm.MaxRTT = rtt
+		}
+		dm.SmoothedRTT = dm.SmoothedRTT - dm.SmoothedRTT/8 + rtt/8
+	}
+	dm.RTTSamples++
+}
+
+// updateInFlight computes the bytes in flight of d, acknowledged by r.
+func (d *tcpMetricsDirection) updateInFlight(r *tcpMetricsDirection) {
+	if !d.started || r.ack == invalidSequence {
+		return
+	}
+	n := r.ack.Difference(d.nextSeq)
+	if n < 0 {
+		n = 0
+	}
+	d.metrics.BytesInFlight = n
+	if n > d.metrics.MaxBytesInFlight {
+		d.metrics.MaxBytesInFlight = n
+	}
+}
+
+func appendBounded[T any](s []T, v T) []T {
+	if len(s) >= maxMetricsTracked {
+		s = append(s[:0], s[1:]...)
+	}
+	return append(s, v)
+}
@@ -0,0 +1,157 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package reassembly
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+)
+
+type testMetricsPacket struct {
+	tcp layers.TCP
+	ms  int
+}
+
+func trackPackets(packets []testMetricsPacket) ConnectionMetrics {
+	m := NewTCPMetricsTracker()
+	base := time.Unix(1432538521, 0)
+	for _, p := range packets {
+		dir := TCPDirClientToServer
+		if p.tcp.SrcPort != testClientPort {
+			dir = dir.Reverse()
+		}
+		m.Track(&p.tcp, gopacket.CaptureInfo{Timestamp: base.Add(time.Duration(p.ms) * time.Millisecond)}, dir)
+	}
+	return m.Metrics()
+}
+
+func withOption(tcp layers.TCP, kind layers.TCPOptionKind, data []byte) layers.TCP {
+	tcp.Options = append(tcp.Options, layers.TCPOption{OptionType: kind, OptionLength: uint8(len(data) + 2), OptionData: data})
+	return tcp
+}
+
+func withTimestamps(tcp layers.TCP, tsval, tsecr uint32) layers.TCP {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint32(data, tsval)
+	binary.BigEndian.PutUint32(data[4:], tsecr)
+	return withOption(tcp, layers.TCPOptionKindTimestamps, data)
+}
+
+func TestMetricsRTT(t *testing.T) {
+	m := trackPackets([]testMetricsPacket{
+		{withOption(testSegment(true, "S", 100, 0, 0), layers.TCPOptionKindWindowScale, []byte{3}), 0},
+		{withOption(testSegment(false, "SA", 500, 101, 0), layers.TCPOptionKindWindowScale, []byte{1}), 20},
+		{testSegment(true, "A", 101, 501, 0), 25},
+		{testSegment(true, "A", 101, 501, 100), 30},
+		{testSegment(true, "A", 201, 501, 100), 31},
+		// acknowledges both segments
+		{testSegment(false, "A", 501, 301, 0), 52},
+		{testSegment(true, "A", 301, 501, 50), 60},
+	})
+	if m.HandshakeRTT != 25*time.Millisecond {
+		t.Errorf("got handshake RTT %v", m.HandshakeRTT)
+	}
+	c := m.ClientToServer
+	// SYN, and the last segment of the two
+	if c.RTTSamples != 2 || c.MinRTT != 20*time.Millisecond || c.MaxRTT != 21*time.Millisecond {
+		t.Errorf("got %d RTT samples, min %v, max %v", c.RTTSamples, c.MinRTT, c.MaxRTT)
+	}
+	if c.Packets != 5 || c.Bytes != 250 || c.BytesInFlight != 50 || c.MaxBytesInFlight != 200 {
+		t.Errorf("got %+v", c)
+	}
+	if c.Window != 8000 || m.ServerToClient.Window != 2000 {
+		t.Errorf("got windows %d and %d", c.Window, m.ServerToClient.Window)
+	}
+	if s := m.ServerToClient; s.RTTSamples != 1 || s.SmoothedRTT != 5*time.Millisecond {
+		t.Errorf("got server RTT %d samples %v", s.RTTSamples, s.SmoothedRTT)
+	}
+}
+
+func TestMetricsRetransmissions(t *testing.T) {
+	m := trackPackets(append(timedHandshake(),
+		testMetricsPacket{testSegment(true, "A", 101, 501, 100), 30},
+		// lost, retransmitted
+		testMetricsPacket{testSegment(true, "A", 101, 501, 100), 300},
+		testMetricsPacket{testSegment(false, "A", 501, 201, 0), 320},
+		// Karn: no RTT sample for retransmitted segments
+		testMetricsPacket{testSegment(true, "A", 101, 501, 100), 330},
+		// reordered: 301 seen before 201
+		testMetricsPacket{testSegment(true, "A", 301, 501, 100), 400},
+		testMetricsPacket{testSegment(true, "A", 201, 501, 100), 401},
+		// lost before the capture point: 401 only seen once retransmitted
+		testMetricsPacket{testSegment(true, "A", 501, 501, 100), 402},
+		testMetricsPacket{testSegment(true, "A", 401, 501, 100), 600},
+	))
+	c := m.ClientToServer
+	if c.Retransmissions != 3 || c.SpuriousRetransmissions != 1 || c.OutOfOrder != 1 {
+		t.Errorf("got %d retransmissions, %d spurious, %d out of order", c.Retransmissions, c.SpuriousRetransmissions, c.OutOfOrder)
+	}
+	if c.RTTSamples != 1 {
+		t.Errorf("got %d RTT samples", c.RTTSamples)
+	}
+}
+
+func TestMetricsTimestamps(t *testing.T) {
+	m := trackPackets([]testMetricsPacket{
+		{withTimestamps(testSegment(true, "S", 100, 0, 0), 1000, 0), 0},
+		{withTimestamps(testSegment(false, "SA", 500, 101, 0), 7000, 1000), 20},
+		{withTimestamps(testSegment(true, "A", 101, 501, 0), 1001, 7000), 25},
+		{withTimestamps(testSegment(true, "A", 101, 501, 100), 1002, 7000), 30},
+		// spurious retransmission, the acknowledgment echoes the original
+		{withTimestamps(testSegment(true, "A", 101, 501, 100), 1010, 7000), 100},
+		{withTimestamps(testSegment(false, "A", 501, 201, 0), 7005, 1002), 110},
+		// the retransmission is timed with its own timestamp
+		{withTimestamps(testSegment(true, "A", 201, 501, 100), 1011, 7005), 120},
+		{withTimestamps(testSegment(true, "A", 201, 501, 100), 1020, 7005), 400},
+		{withTimestamps(testSegment(false, "A", 501, 301, 0), 7010, 1020), 415},
+	})
+	c := m.ClientToServer
+	if c.Retransmissions != 2 || c.SpuriousRetransmissions != 1 {
+		t.Errorf("got %d retransmissions, %d spurious", c.Retransmissions, c.SpuriousRetransmissions)
+	}
+	// SYN: 20ms, first segment: 80ms, retransmission: 15ms
+	if c.RTTSamples != 3 || c.MinRTT != 15*time.Millisecond || c.MaxRTT != 80*time.Millisecond {
+		t.Errorf("got %d RTT samples, min %v, max %v", c.RTTSamples, c.MinRTT, c.MaxRTT)
+	}
+}
+
+func TestMetricsWindow(t *testing.T) {
+	zero := testSegment(false, "A", 501, 201, 0)
+	zero.Window = 0
+	m := trackPackets(append(timedHandshake(),
+		testMetricsPacket{testSegment(true, "A", 101, 501, 100), 30},
+		testMetricsPacket{testSegment(true, "A", 201, 501, 100), 31},
+		testMetricsPacket{zero, 50},
+		testMetricsPacket{zero, 60},
+		testMetricsPacket{testSegment(false, "A", 501, 201, 0), 70},
+		testMetricsPacket{testSegment(false, "A", 501, 201, 0), 71},
+		testMetricsPacket{testSegment(false, "A", 501, 201, 0), 72},
+		testMetricsPacket{testSegment(false, "A", 501, 301, 0), 80},
+		// nothing in flight: not a duplicate
+		testMetricsPacket{testSegment(false, "A", 501, 301, 0), 90},
+	))
+	s := m.ServerToClient
+	if s.ZeroWindows != 2 || s.DupAcks != 3 {
+		t.Errorf("got %d zero windows, %d dup acks", s.ZeroWindows, s.DupAcks)
+	}
+	if m.ClientToServer.BytesInFlight != 0 || m.ClientToServer.MaxBytesInFlight != 200 {
+		t.Errorf("got %+v", m.ClientToServer)
+	}
+}
+
+// timedHandshake is a handshake with a 20ms RTT.
+func timedHandshake() []testMetricsPacket {
+	return []testMetricsPacket{
+		{testSegment(true, "S", 100, 0, 0), 0},
+		{testSegment(false, "SA", 500, 101, 0), 10},
+		{testSegment(true, "A", 101, 501, 0), 20},
+	}
+}
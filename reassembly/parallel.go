@@ -0,0 +1,257 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package reassembly
+
+import (
+	"io"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+)
+
+// DefaultParallelAssemblerOptions provides default options for a
+// ParallelAssembler.
+var DefaultParallelAssemblerOptions = ParallelAssemblerOptions{
+	QueueSize:        1024,
+	FlushInterval:    time.Minute,
+	FlushTimeout:     2 * time.Minute,
+	CloseTimeout:     2 * time.Minute,
+	AssemblerOptions: DefaultAssemblerOptions,
+}
+
+// ParallelAssemblerOptions controls the behavior of a ParallelAssembler.
+type ParallelAssemblerOptions struct {
+	// Workers is the number of worker goroutines.  If <= 0,
+	// runtime.GOMAXPROCS(0) workers are started.
+	Workers int
+	// QueueSize is the number of packets queued per worker.
+	QueueSize int
+	// FlushInterval is the interval, in packet time, between flushes done by
+	// Run, with FlushOptions{T: now - FlushTimeout, TC: now - CloseTimeout}.
+	// If <= 0, Run doesn't flush until the end of the source.
+	FlushInterval time.Duration
+	FlushTimeout  time.Duration
+	CloseTimeout  time.Duration
+	// AssemblerOptions are the options of the workers' Assemblers.
+	AssemblerOptions AssemblerOptions
+}
+
+// ParallelAssembler reassembles TCP streams on multiple cores.  Packets are
+// dispatched to worker goroutines by a symmetric hash of their network and
+// transport flows, so both directions of a connection go to the same
+// worker.  Each worker owns an Assembler and a private StreamPool, so the
+// workers never contend on locks.
+//
+// The StreamFactory is shared by all workers: its New method, and the
+// Streams it creates, are called concurrently from multiple goroutines for
+// different connections.
+//
+// Packets are processed asynchronously: the layers passed to Assemble must not
+// be modified afterwards.  Flushes are queued behind the packets already
+// dispatched, and wait for all the workers.
+type ParallelAssembler struct {
+	options ParallelAssemblerOptions
+	workers []*parallelWorker
+	wg      sync.WaitGroup
+	closed  bool
+	// lastFlush is the packet time of the last flush done by Run
+	lastFlush time.Time
+}
+
+type parallelWorker struct {
+	assembler *Assembler
+	pool      *StreamPool
+	queue     chan parallelItem
+}
+
+// parallelItem is either a packet or a flush request.
+type parallelItem struct {
+	netFlow gopacket.Flow
+	tcp     *layers.TCP
+	ac      AssemblerContext
+	flush   *parallelFlush
+}
+
+type parallelFlush struct {
+	options FlushOptions
+	all     bool
+	results chan [2]int
+}
+
+// NewParallelAssembler creates a ParallelAssembler and starts its workers.
+func NewParallelAssembler(factory StreamFactory, options ParallelAssemblerOptions) *ParallelAssembler {
+	n := options.Workers
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	p := &ParallelAssembler{options: options}
+	for i := 0; i < n; i++ {
+		pool := NewStreamPool(factory)
+		w := &parallelWorker{
+			assembler: NewAssembler(pool),
+			pool:      pool,
+			queue:     make(chan parallelItem, options.QueueSize),
+		}
+		w.assembler.AssemblerOptions = options.AssemblerOptions
+		p.workers = append(p.workers, w)
+		p.wg.Add(1)
+		go w.run(&p.wg)
+	}
+	return p
+}
+
+func (w *parallelWorker) run(wg *sync.WaitGroup) {
+	defer wg.Done()
+	for item := range w.queue {
+		if f := item.flush; f != nil {
+			if f.all {
+				f.results <- [2]int{0, w.assembler.FlushAll()}
+			} else {
+				flushed, closed := w.assembler.FlushWithOptions(f.options)
+				f.results <- [2]int{flushed, closed}
+			}
+			continue
+		}
+		w.assembler.AssembleWithContext(item.netFlow, item.tcp, item.ac)
+	}
+}
+
+// Workers returns the number of workers.
+func (p *ParallelAssembler) Workers() int {
+	return len(p.workers)
+}
+
+// worker returns the worker of a connection.
+func (p *ParallelAssembler) worker(netFlow, transportFlow gopacket.Flow) *parallelWorker {
+	// FastHash is symmetric, and so is the combination
+	h := netFlow.FastHash() ^ transportFlow.FastHash()*0x9e3779b97f4a7c15
+	return p.workers[h%uint64(len(p.workers))]
+}
+
+// packetContext is the AssemblerContext of packets given to Assemble.
+type packetContext gopacket.CaptureInfo
+
+func (c *packetContext) GetCaptureInfo() gopacket.CaptureInfo {
+	return gopacket.CaptureInfo(*c)
+}
+
+// Assemble dispatches a decoded packet to its worker, and reports whether it
+// was a TCP packet.
+func (p *ParallelAssembler) Assemble(packet gopacket.Packet) bool {
+	net := packet.NetworkLayer()
+	if net == nil {
+		return false
+	}
+	tcp, ok := packet.TransportLayer().(*layers.TCP)
+	if !ok {
+		return false
+	}
+	ac := packetContext(packet.Metadata().CaptureInfo)
+	p.AssembleWithContext(net.NetworkFlow(), tcp, &ac)
+	return true
+}
+
+// AssembleWithContext dispatches a TCP packet to its worker, which calls
+// Assembler.AssembleWithContext.  It blocks while the worker's queue is
+// full.
+func (p *ParallelAssembler) AssembleWithContext(netFlow gopacket.Flow, t *layers.TCP, ac AssemblerContext) {
+	p.worker(netFlow, t.TransportFlow()).queue <- parallelItem{netFlow: netFlow, tcp: t, ac: ac}
+}
+
+func (p *ParallelAssembler) flush(f parallelFlush) (flushed, closed int) {
+	f.results = make(chan [2]int, len(p.workers))
+	for _, w := range p.workers {
+		w.queue <- parallelItem{flush: &f}
+	}
+	for range p.workers {
+		r := <-f.results
+		flushed += r[0]
+		closed += r[1]
+	}
+	return flushed, closed
+}
+
+// FlushWithOptions calls Assembler.FlushWithOptions in all the workers, once
+// they processed the packets already dispatched, and returns the total
+// number of connections flushed and closed.
+func (p *ParallelAssembler) FlushWithOptions(opt FlushOptions) (flushed, closed int) {
+	return p.flush(parallelFlush{options: opt})
+}
+
+// FlushCloseOlderThan flushes and closes streams older than given time, in
+// all the workers.
+func (p *ParallelAssembler) FlushCloseOlderThan(t time.Time) (flushed, closed int) {
+	return p.FlushWithOptions(FlushOptions{T: t, TC: t})
+}
+
+// FlushAll flushes and closes all the connections of all the workers, and
+// returns the number of connections closed.
+func (p *ParallelAssembler) FlushAll() (closed int) {
+	_, closed = p.flush(parallelFlush{all: true})
+	return closed
+}
+
+// MemoryStats returns the sum of the memory gauges of the workers'
+// StreamPools.
+func (p *ParallelAssembler) MemoryStats() MemoryStats {
+	var s MemoryStats
+	for _, w := range p.workers {
+		ws := w.pool.MemoryStats()
+		s.PagesInUse += ws.PagesInUse
+		s.BytesInUse += ws.BytesInUse
+		s.PagesFree += ws.PagesFree
+		s.Evictions += ws.Evictions
+	}
+	return s
+}
+
+// Run assembles the TCP packets of source until it returns an error, flushing
+// connections every FlushInterval.  It returns nil when the source reaches
+// io.EOF.  The connections are not closed: call FlushAll or Close once done.
+func (p *ParallelAssembler) Run(source *gopacket.PacketSource) error {
+	for {
+		packet, err := source.NextPacket()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		p.Assemble(packet)
+		if p.options.FlushInterval <= 0 {
+			continue
+		}
+		ts := packet.Metadata().Timestamp
+		if p.lastFlush.IsZero() {
+			p.lastFlush = ts
+		} else if ts.Sub(p.lastFlush) >= p.options.FlushInterval {
+			p.FlushWithOptions(FlushOptions{
+				T:  ts.Add(-p.options.FlushTimeout),
+				TC: ts.Add(-p.options.CloseTimeout),
+			})
+			p.lastFlush = ts
+		}
+	}
+}
+
+// Close flushes and closes all the connections, then stops the workers.  It
+// returns the number of connections closed.  The ParallelAssembler must not
+// be used afterwards.
+func (p *ParallelAssembler) Close() (closed int) {
+	if p.closed {
+		return 0
+	}
+	closed = p.FlushAll()
+	for _, w := range p.workers {
+		close(w.queue)
+	}
+	p.wg.Wait()
+	p.closed = true
+	return closed
+}
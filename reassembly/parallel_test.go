@@ -0,0 +1,264 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package reassembly
+
+import (
+	"bytes"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+)
+
+/* For parallel tests: concurrency safe, one stream per connection */
+type testParallelFactory struct {
+	mu      sync.Mutex
+	streams map[string]*testParallelStream
+}
+
+type testParallelStream struct {
+	data     [2]bytes.Buffer
+	complete bool
+}
+
+func (f *testParallelFactory) New(a, b gopacket.Flow, tcp *layers.TCP, ac AssemblerContext) Stream {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s := &testParallelStream{}
+	f.streams[fmt.Sprintf("%v:%v", a, b)] = s
+	return s
+}
+
+func (s *testParallelStream) Accept(tcp *layers.TCP, ci gopacket.CaptureInfo, dir TCPFlowDirection, seq Sequence, start *bool, ac AssemblerContext) bool {
+	return true
+}
+
+func (s *testParallelStream) ReassembledSG(sg ScatterGather, ac AssemblerContext) {
+	l, _ := sg.Lengths()
+	dir, _, _, _ := sg.Info()
+	if dir == TCPDirClientToServer {
+		s.data[0].Write(sg.Fetch(l))
+	} else {
+		s.data[1].Write(sg.Fetch(l))
+	}
+}
+
+func (s *testParallelStream) ReassemblyComplete(ac AssemblerContext) bool {
+	s.complete = true
+	return true
+}
+
+type testPacketData struct {
+	data [][]byte
+	ci   []gopacket.CaptureInfo
+}
+
+func (t *testPacketData) ReadPacketData() ([]byte, gopacket.CaptureInfo, error) {
+	if len(t.data) == 0 {
+		return nil, gopacket.CaptureInfo{}, io.EOF
+	}
+	data, ci := t.data[0], t.ci[0]
+	t.data, t.ci = t.data[1:], t.ci[1:]
+	return data, ci, nil
+}
+
+func (t *testPacketData) add(ts time.Time, src, dst net.IP, tcp *layers.TCP, payload []byte) {
+	ip := &layers.IPv4{Version: 4, TTL: 64, Protocol: layers.IPProtocolTCP, SrcIP: src, DstIP: dst}
+	tcp.SetNetworkLayerForChecksum(ip)
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	eth := &layers.Ethernet{SrcMAC: net.HardwareAddr{0, 0, 0, 0, 0, 1}, DstMAC: net.HardwareAddr{0, 0, 0, 0, 0, 2}, EthernetType: layers.EthernetTypeIPv4}
+	if err := gopacket.SerializeLayers(buf, opts, eth, ip, tcp, gopacket.Payload(payload)); err != nil {
+		panic(err)
+	}
+	t.data = append(t.data, buf.Bytes())
+	t.ci = append(t.ci, gopacket.CaptureInfo{Timestamp: ts, CaptureLength: len(buf.Bytes()), Length: len(buf.Bytes())})
+}
+
+func TestParallelAssembler(t *testing.T) {
+	const conns = 50
+	client, server := net.IP{10, 0, 0, 1}, net.IP{10, 0, 0, 2}
+	src := &testPacketData{}
+	start := time.Unix(1432538521, 0)
+	ts := start
+	for i := 0; i < conns; i++ {
+		port := layers.TCPPort(1000 + i)
+		src.add(ts, client, server, &layers.TCP{SrcPort: port, DstPort: 80, SYN: true, Seq: 100}, nil)
+		src.add(ts, server, client, &layers.TCP{SrcPort: 80, DstPort: port, SYN: true, ACK: true, Seq: 500, Ack: 101}, nil)
+	}
+	for round := 0; round < 4; round++ {
+		for i := 0; i < conns; i++ {
+			ts = ts.Add(time.Millisecond)
+			port := layers.TCPPort(1000 + i)
+			seq := uint32(101 + 10*round)
+			if i%5 == 0 && round%2 == 0 {
+				// out of order: round+1 then round
+				seq += 10
+			} else if i%5 == 0 {
+				seq -= 10
+			}
+			src.add(ts, client, server, &layers.TCP{SrcPort: port, DstPort: 80, ACK: true, Seq: seq, Ack: 501}, bytes.Repeat([]byte{byte(seq)}, 10))
+			src.add(ts, server, client, &layers.TCP{SrcPort: 80, DstPort: port, ACK: true, Seq: uint32(501 + 5*round), Ack: seq + 10}, bytes.Repeat([]byte{byte(i)}, 5))
+		}
+	}
+	// a non TCP packet is ignored
+	buf := gopacket.NewSerializeBuffer()
+	gopacket.SerializeLayers(buf, gopacket.SerializeOptions{},
+		&layers.Ethernet{EthernetType: layers.EthernetTypeIPv4},
+		&layers.IPv4{Version: 4, IHL: 5, Length: 28, Protocol: layers.IPProtocolUDP, SrcIP: client, DstIP: server},
+		&layers.UDP{SrcPort: 53, DstPort: 53, Length: 8})
+	src.data = append(src.data, buf.Bytes())
+	src.ci = append(src.ci, gopacket.CaptureInfo{Timestamp: ts, CaptureLength: len(buf.Bytes()), Length: len(buf.Bytes())})
+
+	fact := &testParallelFactory{streams: map[string]*testParallelStream{}}
+	options := DefaultParallelAssemblerOptions
+	options.Workers = 4
+	options.QueueSize = 8
+	a := NewParallelAssembler(fact, options)
+	if err := a.Run(gopacket.NewPacketSource(src, layers.LayerTypeEthernet)); err != nil {
+		t.Fatal(err)
+	}
+	if closed := a.Close(); closed != conns {
+		t.Errorf("got %d connections closed, want %d", closed, conns)
+	}
+	if len(fact.streams) != conns {
+		t.Fatalf("got %d streams, want %d", len(fact.streams), conns)
+	}
+	var want [2][]byte
+	for round := 0; round < 4; round++ {
+		want[0] = append(want[0], bytes.Repeat([]byte{byte(101 + 10*round)}, 10)...)
+	}
+	for i := 0; i < conns; i++ {
+		key := fmt.Sprintf("%v:%v", gopacket.NewFlow(layers.EndpointIPv4, client, server), gopacket.NewFlow(layers.EndpointTCPPort, []byte{byte((1000 + i) >> 8), byte(1000 + i)}, []byte{0, 80}))
+		s := fact.streams[key]
+		if s == nil {
+			t.Fatalf("no stream for %s", key)
+		}
+		want[1] = bytes.Repeat([]byte{byte(i)}, 20)
+		for dir := range want {
+			if !bytes.Equal(s.data[dir].Bytes(), want[dir]) {
+				t.Errorf("connection %d, direction %d: got %v, want %v", i, dir, s.data[dir].Bytes(), want[dir])
+			}
+		}
+		if !s.complete {
+			t.Errorf("connection %d not complete", i)
+		}
+	}
+}
+
+func TestParallelAssemblerFlush(t *testing.T) {
+	fact := &testParallelFactory{streams: map[string]*testParallelStream{}}
+	a := NewParallelAssembler(fact, ParallelAssemblerOptions{Workers: 3})
+	defer a.Close()
+	start := time.Unix(1432538521, 0)
+	for i := 0; i < 10; i++ {
+		tcp := &layers.TCP{SrcPort: layers.TCPPort(1000 + i), DstPort: 80, SYN: true, Seq: 100}
+		tcp.SetInternalPortsForTesting()
+		ctx := assemblerSimpleContext(gopacket.CaptureInfo{Timestamp: start})
+		a.AssembleWithContext(netFlow, tcp, &ctx)
+		// missing data before this one
+		tcp = &layers.TCP{SrcPort: layers.TCPPort(1000 + i), DstPort: 80, Seq: 200, BaseLayer: layers.BaseLayer{Payload: []byte{1, 2, 3}}}
+		tcp.SetInternalPortsForTesting()
+		a.AssembleWithContext(netFlow, tcp, &ctx)
+	}
+	if flushed, closed := a.FlushCloseOlderThan(start); flushed != 0 || closed != 0 {
+		t.Errorf("got %d flushed, %d closed, want none", flushed, closed)
+	}
+	if got := a.MemoryStats().PagesInUse; got != 10 {
+		t.Errorf("got %d pages in use, want 10", got)
+	}
+	if flushed, closed := a.FlushWithOptions(FlushOptions{T: start.Add(time.Second)}); flushed != 10 || closed != 0 {
+		t.Errorf("got %d flushed, %d closed, want 10 flushed", flushed, closed)
+	}
+	if got := a.MemoryStats().PagesInUse; got != 0 {
+		t.Errorf("got %d pages in use after flush", got)
+	}
+}
+
+/*
+ * Benchmark tests
+ */
+
+/* For benchmarks: checksums the data, to give the workers some work */
+type testChecksumFactory struct{}
+
+type testChecksumStream struct {
+	sum uint32
+}
+
+func (testChecksumFactory) New(a, b gopacket.Flow, tcp *layers.TCP, ac AssemblerContext) Stream {
+	return &testChecksumStream{}
+}
+func (s *testChecksumStream) Accept(tcp *layers.TCP, ci gopacket.CaptureInfo, dir TCPFlowDirection, seq Sequence, start *bool, ac AssemblerContext) bool {
+	return true
+}
+func (s *testChecksumStream) ReassembledSG(sg ScatterGather, ac AssemblerContext) {
+	l, _ := sg.Lengths()
+	s.sum = crc32.Update(s.sum, crc32.IEEETable, sg.Fetch(l))
+}
+func (s *testChecksumStream) ReassemblyComplete(ac AssemblerContext) bool {
+	return true
+}
+
+// benchmarkPackets returns packets of conns connections, round robin, with
+// every eighth packet out of order.
+func benchmarkPackets(conns, n int) []layers.TCP {
+	payload := bytes.Repeat([]byte{0xaa}, 1000)
+	packets := make([]layers.TCP, n)
+	for i := range packets {
+		conn, round := i%conns, i/conns
+		seq := uint32(1000 * round)
+		switch round % 8 {
+		case 6:
+			seq += 1000
+		case 7:
+			seq -= 1000
+		}
+		packets[i] = layers.TCP{
+			SrcPort:   layers.TCPPort(1024 + conn),
+			DstPort:   80,
+			SYN:       round == 0,
+			Seq:       seq,
+			BaseLayer: layers.BaseLayer{Payload: payload},
+		}
+		packets[i].SetInternalPortsForTesting()
+	}
+	return packets
+}
+
+func BenchmarkParallelAssembler(b *testing.B) {
+	packets := benchmarkPackets(1024, 64*1024)
+	ctx := assemblerSimpleContext(gopacket.CaptureInfo{Timestamp: time.Unix(1432538521, 0)})
+	b.Run("single", func(b *testing.B) {
+		b.SetBytes(1000)
+		for i := 0; i < b.N; {
+			a := NewAssembler(NewStreamPool(testChecksumFactory{}))
+			for j := 0; j < len(packets) && i < b.N; j, i = j+1, i+1 {
+				a.AssembleWithContext(netFlow, &packets[j], &ctx)
+			}
+			a.FlushAll()
+		}
+	})
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			b.SetBytes(1000)
+			for i := 0; i < b.N; {
+				a := NewParallelAssembler(testChecksumFactory{}, ParallelAssemblerOptions{Workers: workers, QueueSize: 1024})
+				for j := 0; j < len(packets) && i < b.N; j, i = j+1, i+1 {
+					a.AssembleWithContext(netFlow, &packets[j], &ctx)
+				}
+				a.Close()
+			}
+		})
+	}
+}
@@ -0,0 +1,484 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package reassembly
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+)
+
+// SessionData is a UDP datagram, or a reassembled SCTP user message,
+// delivered to a SessionStream.
+type SessionData struct {
+	// Direction is TCPDirClientToServer for the data sent by the endpoint
+	// that sent the first packet of the session.
+	Direction TCPFlowDirection
+	// CaptureInfo is the capture info of the packet holding the datagram,
+	// or the last chunk of the SCTP message.
+	CaptureInfo gopacket.CaptureInfo
+	// Payload is the datagram or message.  It is only valid until
+	// ReassembledDatagram returns.
+	Payload []byte
+	// The SCTP stream identifier and sequence number, payload protocol
+	// identifier and unordered flag of the message.  Unset for UDP.
+	StreamID        uint16
+	StreamSequence  uint16
+	PayloadProtocol layers.SCTPPayloadProtocol
+	Unordered       bool
+}
+
+// SessionStream receives the data of a UDP or SCTP session.
+type SessionStream interface {
+	// ReassembledDatagram is called for every UDP datagram, and for every
+	// SCTP user message once reassembled.  Ordered SCTP messages are
+	// delivered in stream sequence order for each stream.
+	ReassembledDatagram(data *SessionData, ac AssemblerContext)
+	// SessionComplete is called when the session ends: when it was idle for
+	// longer than the idle timeout, on an SCTP ABORT or SHUTDOWN COMPLETE
+	// chunk, or when it is flushed.
+	SessionComplete()
+}
+
+// SessionFactory creates a SessionStream for every new session.
+type SessionFactory interface {
+	// New returns the stream of a new session, or nil to ignore the
+	// packet.  netFlow and transportFlow are the flows of the first packet,
+	// transport its UDP or SCTP layer.
+	New(netFlow, transportFlow gopacket.Flow, transport gopacket.Layer, ac AssemblerContext) SessionStream
+}
+
+// SessionTrackerOptions controls the behavior of a SessionTracker.
+type SessionTrackerOptions struct {
+	// IdleTimeout is the time, in packet time, after which an idle session
+	// is closed.  A packet arriving later starts a new session.  If <= 0,
+	// sessions are only closed by SCTP or by the Flush methods.
+	IdleTimeout time.Duration
+	// MaxPendingSCTPMessages is an upper limit on the number of ordered SCTP
+	// messages buffered per stream while waiting for a missing message.
+	// Should it be reached, the missing messages are skipped.  If <= 0, this
+	// is ignored.
+	MaxPendingSCTPMessages int
+}
+
+// DefaultSessionTrackerOptions provides default options for a
+// SessionTracker.
+var DefaultSessionTrackerOptions = SessionTrackerOptions{
+	IdleTimeout:            2 * time.Minute,
+	MaxPendingSCTPMessages: 1024,
+}
+
+// SessionTracker groups UDP datagrams and SCTP chunks into bidirectional
+// sessions, keyed on their network and transport flows, the way an
+// Assembler does for TCP.  SCTP DATA chunks are reassembled into user
+// messages, by stream identifier and stream sequence number.
+//
+// A SessionTracker is not safe for concurrency.
+type SessionTracker struct {
+	options   SessionTrackerOptions
+	factory   SessionFactory
+	sessions  map[key]*session
+	lastSweep time.Time
+}
+
+type session struct {
+	key      key
+	stream   SessionStream
+	lastSeen time.Time
+	sctp     [2]*sctpReassembler
+}
+
+// NewSessionTracker creates a SessionTracker using factory to create the
+// streams of new sessions.
+func NewSessionTracker(factory SessionFactory, options SessionTrackerOptions) *SessionTracker {
+	return &SessionTracker{
+		options:  options,
+		factory:  factory,
+		sessions: make(map[key]*session),
+	}
+}
+
+// Sessions returns the number of open sessions.
+func (t *SessionTracker) Sessions() int {
+	return len(t.sessions)
+}
+
+// getSession returns the session of a packet and its direction, creating it
+// if needed.
+func (t *SessionTracker) getSession(netFlow, transportFlow gopacket.Flow, transport gopacket.Layer, ac AssemblerContext) (*session, TCPFlowDirection) {
+	ts := ac.GetCaptureInfo().Timestamp
+	t.sweep(ts)
+	k := key{netFlow, transportFlow}
+	dir := TCPDirClientToServer
+	s := t.sessions[k]
+	if s == nil {
+		if s = t.sessions[k.Reverse()]; s != nil {
+			dir = TCPDirServerToClient
+		}
+	}
+	if s != nil && t.options.IdleTimeout > 0 && ts.Sub(s.lastSeen) > t.options.IdleTimeout {
+		t.closeSession(s)
+		s = nil
+	}
+	if s == nil {
+		stream := t.factory.New(netFlow, transportFlow, transport, ac)
+		if stream == nil {
+			return nil, dir
+		}
+		s = &session{key: k, stream: stream}
+		t.sessions[k] = s
+		dir = TCPDirClientToServer
+	}
+	if s.lastSeen.Before(ts) {
+		s.lastSeen = ts
+	}
+	return s, dir
+}
+
+// sweep closes the idle sessions once per idle timeout.
+func (t *SessionTracker) sweep(ts time.Time) {
+	if t.options.IdleTimeout <= 0 {
+		return
+	}
+	if t.lastSweep.IsZero() {
+		t.lastSweep = ts
+	} else if ts.Sub(t.lastSweep) >= t.options.IdleTimeout {
+		t.FlushOlderThan(ts.Add(-t.options.IdleTimeout))
+		t.lastSweep = ts
+	}
+}
+
+// Assemble tracks a decoded UDP or SCTP packet, and reports whether it was
+// one.
+func (t *SessionTracker) Assemble(packet gopacket.Packet) bool {
+	net := packet.NetworkLayer()
+	if net == nil {
+		return false
+	}
+	ac := packetContext(packet.Metadata().CaptureInfo)
+	switch transport := packet.TransportLayer().(type) {
+	case *layers.UDP:
+		t.AssembleUDP(net.NetworkFlow(), transport, &ac)
+		return true
+	case *layers.SCTP:
+		var chunks []gopacket.Layer
+		found := false
+		for _, l := range packet.Layers() {
+			if found {
+				chunks = append(chunks, l)
+			}
+			found = found || l == transport
+		}
+		t.AssembleSCTP(net.NetworkFlow(), transport, chunks, &ac)
+		return true
+	}
+	return false
+}
+
+// AssembleUDP delivers a UDP datagram to its session.
+func (t *SessionTracker) AssembleUDP(netFlow gopacket.Flow, udp *layers.UDP, ac AssemblerContext) {
+	s, dir := t.getSession(netFlow, udp.TransportFlow(), udp, ac)
+	if s == nil {
+		return
+	}
+	s.stream.ReassembledDatagram(&SessionData{
+		Direction:   dir,
+		CaptureInfo: ac.GetCaptureInfo(),
+		Payload:     udp.Payload,
+	}, ac)
+}
+
+// AssembleSCTP processes the chunks of an SCTP packet, as decoded after the
+// SCTP layer.  DATA chunks are reassembled into user messages, and ABORT and
+// SHUTDOWN COMPLETE chunks end the session.
+func (t *SessionTracker) AssembleSCTP(netFlow gopacket.Flow, sctp *layers.SCTP, chunks []gopacket.Layer, ac AssemblerContext) {
+	s, dir := t.getSession(netFlow, sctp.TransportFlow(), sctp, ac)
+	if s == nil {
+		return
+	}
+	i := 0
+	if dir == TCPDirServerToClient {
+		i = 1
+	}
+	end := false
+	for _, chunk := range chunks {
+		switch chunk.LayerType() {
+		case layers.LayerTypeSCTPData:
+			if s.sctp[i] == nil {
+				s.sctp[i] = newSCTPReassembler(dir, t.options.MaxPendingSCTPMessages)
+			}
+			s.sctp[i].add(chunk.(*layers.SCTPData), ac.GetCaptureInfo(), func(d *SessionData) {
+				s.stream.ReassembledDatagram(d, ac)
+			})
+		case layers.LayerTypeSCTPAbort, layers.LayerTypeSCTPShutdownComplete:
+			end = true
+		}
+	}
+	if end {
+		t.closeSession(s)
+	}
+}
+
+func (t *SessionTracker) closeSession(s *session) {
+	for _, r := range s.sctp {
+		if r == nil {
+			continue
+		}
+		r.flush(func(d *SessionData) {
+			ac := packetContext(d.CaptureInfo)
+			s.stream.ReassembledDatagram(d, &ac)
+		})
+	}
+	s.stream.SessionComplete()
+	delete(t.sessions, s.key)
+}
+
+// FlushOlderThan closes the sessions idle since before t, delivering the
+// SCTP messages still waiting for missing ones, and returns the number of
+// sessions closed.
+func (t *SessionTracker) FlushOlderThan(tm time.Time) (closed int) {
+	for _, s := range t.sessions {
+		if s.lastSeen.Before(tm) {
+			t.closeSession(s)
+			closed++
+		}
+	}
+	return closed
+}
+
+// FlushAll closes all the sessions, and returns the number of sessions
+// closed.
+func (t *SessionTracker) FlushAll() (closed int) {
+	for _, s := range t.sessions {
+		t.closeSession(s)
+		closed++
+	}
+	return closed
+}
+
+/*
+ * SCTP reassembly
+ */
+
+// maxSCTPFragments bounds the TSN distance of buffered fragments from the
+// newest one, so that fragments of lost messages are eventually dropped.
+const maxSCTPFragments = 4096
+
+// maxSCTPRecent is the number of unordered messages remembered to drop
+// retransmissions.
+const maxSCTPRecent = 256
+
+type sctpFragment struct {
+	tsn             uint32
+	begin, end      bool
+	unordered       bool
+	stream, ssn     uint16
+	payloadProtocol layers.SCTPPayloadProtocol
+	payload         []byte
+}
+
+type sctpStream struct {
+	next    uint16
+	pending map[uint16]*SessionData
+}
+
+// sctpReassembler reassembles the DATA chunks of one direction.
+type sctpReassembler struct {
+	dir        TCPFlowDirection
+	maxPending int
+	fragments  map[uint32]*sctpFragment
+	streams    map[uint16]*sctpStream
+	recent     map[uint32]bool
+	recentTSNs []uint32
+}
+
+func newSCTPReassembler(dir TCPFlowDirection, maxPending int) *sctpReassembler {
+	return &sctpReassembler{
+		dir:        dir,
+		maxPending: maxPending,
+		fragments:  make(map[uint32]*sctpFragment),
+		streams:    make(map[uint16]*sctpStream),
+		recent:     make(map[uint32]bool),
+	}
+}
+
+// add adds a DATA chunk, and calls deliver for the messages it completes.
+func (r *sctpReassembler) add(c *layers.SCTPData, ci gopacket.CaptureInfo, deliver func(*SessionData)) {
+	f := &sctpFragment{
+		tsn:             c.TSN,
+		begin:           c.BeginFragment,
+		end:             c.EndFragment,
+		unordered:       c.Unordered,
+		stream:          c.StreamId,
+		ssn:             c.StreamSequence,
+		payloadProtocol: c.PayloadProtocol,
+	}
+	if !f.unordered {
+		// the first chunk seen of a stream, fragment or not, sets the
+		// first sequence number expected
+		r.stream(f.stream, f.ssn)
+	}
+	var payload []byte
+	first := f
+	if f.begin && f.end {
+		payload = c.Payload
+	} else {
+		if _, ok := r.fragments[f.tsn]; ok {
+			// retransmission
+			return
+		}
+		f.payload = append([]byte(nil), c.Payload...)
+		r.fragments[f.tsn] = f
+		r.dropOldFragments(f.tsn)
+		first, payload = r.message(f)
+		if first == nil {
+			return
+		}
+	}
+	d := &SessionData{
+		Direction:       r.dir,
+		CaptureInfo:     ci,
+		Payload:         payload,
+		StreamID:        first.stream,
+		StreamSequence:  first.ssn,
+		PayloadProtocol: first.payloadProtocol,
+		Unordered:       first.unordered,
+	}
+	if first.unordered {
+		if r.recent[first.tsn] {
+			return
+		}
+		r.recent[first.tsn] = true
+		r.recentTSNs = append(r.recentTSNs, first.tsn)
+		if len(r.recentTSNs) > maxSCTPRecent {
+			delete(r.recent, r.recentTSNs[0])
+			r.recentTSNs = r.recentTSNs[1:]
+		}
+		deliver(d)
+		return
+	}
+	r.ordered(d, deliver)
+}
+
+// message returns the first fragment and the payload of the message of f if
+// all its fragments are buffered, and removes them.
+func (r *sctpReassembler) message(f *sctpFragment) (*sctpFragment, []byte) {
+	same := func(g *sctpFragment) bool {
+		return g.stream == f.stream && g.ssn == f.ssn && g.unordered == f.unordered
+	}
+	first := f
+	for !first.begin {
+		g := r.fragments[first.tsn-1]
+		if g == nil || !same(g) {
+			return nil, nil
+		}
+		first = g
+	}
+	last := f
+	for !last.end {
+		g := r.fragments[last.tsn+1]
+		if g == nil || !same(g) {
+			return nil, nil
+		}
+		last = g
+	}
+	var payload []byte
+	for tsn := first.tsn; ; tsn++ {
+		payload = append(payload, r.fragments[tsn].payload...)
+		delete(r.fragments, tsn)
+		if tsn == last.tsn {
+			break
+		}
+	}
+	first.payload = nil
+	return first, payload
+}
+
+func (r *sctpReassembler) dropOldFragments(tsn uint32) {
+	if len(r.fragments) <= maxSCTPFragments {
+		return
+	}
+	for t := range r.fragments {
+		if int32(tsn-t) >= maxSCTPFragments {
+			delete(r.fragments, t)
+		}
+	}
+}
+
+// stream returns a stream, creating it with ssn as the next sequence number
+// if needed.
+func (r *sctpReassembler) stream(id, ssn uint16) *sctpStream {
+	st := r.streams[id]
+	if st == nil {
+		st = &sctpStream{next: ssn, pending: make(map[uint16]*SessionData)}
+		r.streams[id] = st
+	}
+	return st
+}
+
+// ordered delivers an ordered message in stream sequence order.
+func (r *sctpReassembler) ordered(d *SessionData, deliver func(*SessionData)) {
+	st := r.stream(d.StreamID, d.StreamSequence)
+	switch diff := int16(d.StreamSequence - st.next); {
+	case diff < 0:
+		// retransmission
+		return
+	case diff > 0:
+		if _, ok := st.pending[d.StreamSequence]; !ok {
+			d.Payload = append([]byte(nil), d.Payload...)
+			st.pending[d.StreamSequence] = d
+		}
+		if r.maxPending <= 0 || len(st.pending) <= r.maxPending {
+			return
+		}
+		// skip the missing messages
+		st.next = st.lowestPending()
+	default:
+		deliver(d)
+		st.next++
+	}
+	for {
+		p := st.pending[st.next]
+		if p == nil {
+			return
+		}
+		delete(st.pending, st.next)
+		deliver(p)
+		st.next++
+	}
+}
+
+func (st *sctpStream) lowestPending() uint16 {
+	first := true
+	var lowest uint16
+	for ssn := range st.pending {
+		if first || int16(ssn-lowest) < 0 {
+			lowest, first = ssn, false
+		}
+	}
+	return lowest
+}
+
+// flush delivers the pending ordered messages, skipping the missing ones.
+func (r *sctpReassembler) flush(deliver func(*SessionData)) {
+	ids := make([]int, 0, len(r.streams))
+	for id := range r.streams {
+		ids = append(ids, int(id))
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		st := r.streams[uint16(id)]
+		for len(st.pending) > 0 {
+			ssn := st.lowestPending()
+			deliver(st.pending[ssn])
+			delete(st.pending, ssn)
+		}
+	}
+	r.fragments = make(map[uint32]*sctpFragment)
+}
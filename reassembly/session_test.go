@@ -0,0 +1,231 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package reassembly
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+)
+
+/* For session tests: records data as strings */
+type testSessionFactory struct {
+	streams []*testSessionStream
+}
+
+type testSessionStream struct {
+	data     []string
+	complete bool
+}
+
+func (f *testSessionFactory) New(netFlow, transportFlow gopacket.Flow, transport gopacket.Layer, ac AssemblerContext) SessionStream {
+	s := &testSessionStream{}
+	f.streams = append(f.streams, s)
+	return s
+}
+
+func (s *testSessionStream) ReassembledDatagram(d *SessionData, ac AssemblerContext) {
+	prefix := ">"
+	if d.Direction == TCPDirServerToClient {
+		prefix = "<"
+	}
+	if d.StreamID != 0 || d.StreamSequence != 0 || d.Unordered {
+		prefix += fmt.Sprintf("%d/%d", d.StreamID, d.StreamSequence)
+		if d.Unordered {
+			prefix += "u"
+		}
+		prefix += " "
+	}
+	s.data = append(s.data, prefix+string(d.Payload))
+}
+
+func (s *testSessionStream) SessionComplete() {
+	s.complete = true
+}
+
+func testContext(sec int) AssemblerContext {
+	ac := assemblerSimpleContext(gopacket.CaptureInfo{Timestamp: time.Unix(1432538521+int64(sec), 0)})
+	return &ac
+}
+
+func testUDP(src, dst layers.UDPPort, payload string) *layers.UDP {
+	udp := &layers.UDP{SrcPort: src, DstPort: dst}
+	udp.Payload = []byte(payload)
+	udp.SetInternalPortsForTesting()
+	return udp
+}
+
+func TestSessionUDP(t *testing.T) {
+	fact := &testSessionFactory{}
+	tracker := NewSessionTracker(fact, SessionTrackerOptions{IdleTimeout: 10 * time.Second})
+	tracker.AssembleUDP(netFlow, testUDP(5353, 53, "query"), testContext(0))
+	tracker.AssembleUDP(netFlow.Reverse(), testUDP(53, 5353, "response"), testContext(1))
+	tracker.AssembleUDP(netFlow, testUDP(5354, 53, "other"), testContext(2))
+	if tracker.Sessions() != 2 {
+		t.Fatalf("got %d sessions, want 2", tracker.Sessions())
+	}
+	// idle for longer than the timeout: new session
+	tracker.AssembleUDP(netFlow.Reverse(), testUDP(53, 5353, "late"), testContext(20))
+	if len(fact.streams) != 3 {
+		t.Fatalf("got %d streams, want 3", len(fact.streams))
+	}
+	want := [][]string{{">query", "<response"}, {">other"}, {">late"}}
+	for i, s := range fact.streams {
+		if !reflect.DeepEqual(s.data, want[i]) {
+			t.Errorf("stream %d: got %q, want %q", i, s.data, want[i])
+		}
+	}
+	if !fact.streams[0].complete || !fact.streams[1].complete || fact.streams[2].complete {
+		t.Errorf("wrong completion of the sessions")
+	}
+	if closed := tracker.FlushAll(); closed != 1 || tracker.Sessions() != 0 || !fact.streams[2].complete {
+		t.Errorf("FlushAll closed %d sessions", closed)
+	}
+}
+
+func testSCTPData(tsn uint32, stream, ssn uint16, flags string, payload string) gopacket.Layer {
+	d := &layers.SCTPData{
+		SCTPChunk:      layers.SCTPChunk{Type: layers.SCTPChunkTypeData, Length: uint16(16 + len(payload))},
+		TSN:            tsn,
+		StreamId:       stream,
+		StreamSequence: ssn,
+		Payload:        []byte(payload),
+	}
+	for _, f := range flags {
+		switch f {
+		case 'B':
+			d.BeginFragment = true
+		case 'E':
+			d.EndFragment = true
+		case 'U':
+			d.Unordered = true
+		}
+	}
+	return d
+}
+
+func TestSessionSCTP(t *testing.T) {
+	fact := &testSessionFactory{}
+	tracker := NewSessionTracker(fact, DefaultSessionTrackerOptions)
+	sctp := &layers.SCTP{SrcPort: 1000, DstPort: 2000}
+	sctp.SetInternalPortsForTesting()
+	reply := &layers.SCTP{SrcPort: 2000, DstPort: 1000}
+	reply.SetInternalPortsForTesting()
+	for i, chunks := range [][]gopacket.Layer{
+		{testSCTPData(10, 1, 0, "BE", "a"), testSCTPData(11, 2, 0, "BE", "first")},
+		// stream 1 message 1 in two fragments, message 2 before it
+		{testSCTPData(12, 1, 1, "B", "hel"), testSCTPData(14, 1, 2, "BE", "third")},
+		{testSCTPData(13, 1, 1, "E", "lo")},
+		// retransmission
+		{testSCTPData(13, 1, 1, "E", "lo")},
+		// unordered, in fragments out of order, and retransmitted
+		{testSCTPData(16, 3, 0, "UE", "red"), testSCTPData(15, 3, 0, "UB", "unorde")},
+		{testSCTPData(15, 3, 0, "UB", "unorde"), testSCTPData(16, 3, 0, "UE", "red")},
+	} {
+		tracker.AssembleSCTP(netFlow, sctp, chunks, testContext(i))
+	}
+	tracker.AssembleSCTP(netFlow.Reverse(), reply, []gopacket.Layer{testSCTPData(1, 0, 0, "BE", "reply")}, testContext(6))
+	// SHUTDOWN COMPLETE ends the session, delivering the pending messages
+	tracker.AssembleSCTP(netFlow, sctp, []gopacket.Layer{testSCTPData(17, 4, 5, "BE", "gap"), testSCTPData(19, 4, 7, "BE", "after gap")}, testContext(7))
+	tracker.AssembleSCTP(netFlow, sctp, []gopacket.Layer{&layers.SCTPEmptyLayer{SCTPChunk: layers.SCTPChunk{Type: layers.SCTPChunkTypeShutdownComplete}}}, testContext(8))
+	if len(fact.streams) != 1 {
+		t.Fatalf("got %d streams, want 1", len(fact.streams))
+	}
+	s := fact.streams[0]
+	want := []string{
+		">1/0 a", ">2/0 first",
+		">1/1 hello", ">1/2 third",
+		">3/0u unordered",
+		"<reply",
+		">4/5 gap", ">4/7 after gap",
+	}
+	if !reflect.DeepEqual(s.data, want) {
+		t.Errorf("got %q, want %q", s.data, want)
+	}
+	if tracker.Sessions() != 0 || !s.complete {
+		t.Errorf("session not complete")
+	}
+}
+
+func TestSessionSCTPFragments(t *testing.T) {
+	fact := &testSessionFactory{}
+	tracker := NewSessionTracker(fact, DefaultSessionTrackerOptions)
+	sctp := &layers.SCTP{SrcPort: 1000, DstPort: 2000}
+	sctp.SetInternalPortsForTesting()
+	for i, chunks := range [][]gopacket.Layer{
+		{testSCTPData(10, 1, 0, "B", "hel")},
+		{testSCTPData(12, 1, 1, "B", "again")},
+		{testSCTPData(13, 1, 1, "E", "!")},
+		{testSCTPData(11, 1, 0, "E", "lo")},
+		// the retransmission of a delivered message is dropped
+		{testSCTPData(12, 1, 1, "B", "again"), testSCTPData(13, 1, 1, "E", "!")},
+	} {
+		tracker.AssembleSCTP(netFlow, sctp, chunks, testContext(i))
+	}
+	want := []string{">1/0 hello", ">1/1 again!"}
+	if s := fact.streams[0]; !reflect.DeepEqual(s.data, want) {
+		t.Errorf("got %q, want %q", s.data, want)
+	}
+}
+
+func TestSessionSCTPMaxPending(t *testing.T) {
+	fact := &testSessionFactory{}
+	tracker := NewSessionTracker(fact, SessionTrackerOptions{MaxPendingSCTPMessages: 2})
+	sctp := &layers.SCTP{SrcPort: 1000, DstPort: 2000}
+	sctp.SetInternalPortsForTesting()
+	for i, ssn := range []uint16{0, 2, 3, 4, 1, 5} {
+		tracker.AssembleSCTP(netFlow, sctp, []gopacket.Layer{testSCTPData(uint32(10+i), 1, ssn, "BE", fmt.Sprint(ssn))}, testContext(i))
+	}
+	// 1 is skipped once 3 messages are pending
+	want := []string{">1/0 0", ">1/2 2", ">1/3 3", ">1/4 4", ">1/5 5"}
+	if s := fact.streams[0]; !reflect.DeepEqual(s.data, want) {
+		t.Errorf("got %q, want %q", s.data, want)
+	}
+}
+
+func TestSessionAssemblePacket(t *testing.T) {
+	fact := &testSessionFactory{}
+	tracker := NewSessionTracker(fact, DefaultSessionTrackerOptions)
+	ip := &layers.IPv4{Version: 4, TTL: 64, SrcIP: net.IP{10, 0, 0, 1}, DstIP: net.IP{10, 0, 0, 2}}
+	opts := gopacket.SerializeOptions{FixLengths: true}
+	decode := func(l ...gopacket.SerializableLayer) gopacket.Packet {
+		buf := gopacket.NewSerializeBuffer()
+		if err := gopacket.SerializeLayers(buf, opts, l...); err != nil {
+			t.Fatal(err)
+		}
+		return gopacket.NewPacket(buf.Bytes(), layers.LayerTypeIPv4, gopacket.Default)
+	}
+
+	ip.Protocol = layers.IPProtocolUDP
+	udp := &layers.UDP{SrcPort: 5353, DstPort: 53}
+	if !tracker.Assemble(decode(ip, udp, gopacket.Payload("query"))) {
+		t.Error("UDP packet not tracked")
+	}
+	ip.Protocol = layers.IPProtocolSCTP
+	data := testSCTPData(1, 1, 0, "BE", "odd length").(*layers.SCTPData)
+	if !tracker.Assemble(decode(ip, &layers.SCTP{SrcPort: 1000, DstPort: 2000}, data)) {
+		t.Error("SCTP packet not tracked")
+	}
+	ip.Protocol = layers.IPProtocolICMPv4
+	if tracker.Assemble(decode(ip, &layers.ICMPv4{})) {
+		t.Error("ICMP packet tracked")
+	}
+	want := [][]string{{">query"}, {">1/0 odd length"}}
+	if len(fact.streams) != len(want) {
+		t.Fatalf("got %d streams", len(fact.streams))
+	}
+	for i, s := range fact.streams {
+		if !reflect.DeepEqual(s.data, want[i]) {
+			t.Errorf("stream %d: got %q, want %q", i, s.data, want[i])
+		}
+	}
+}
@@ -21,6 +21,7 @@
 package reassembly
 
 import (
+	"container/list"
 	"encoding/hex"
 	"flag"
 	"fmt"
@@ -419,6 +420,15 @@ type halfconnection struct {
 	queuedPackets  int
 	overlapBytes   int
 	overlapPackets int
+	// for anomalies
+	synSeen    bool
+	wscale     int      // window scale option of the SYN, -1 if none
+	window     int      // last window advertised, -1 if unknown
+	finSeq     Sequence // sequence number of the FIN sent
+	history    []byte   // last bytes given to the Stream, see OverlapHistoryBytes
+	historyEnd Sequence
+	// element of StreamPool.buffered while pages are buffered
+	buffered *list.Element
 }
 
 func (half *halfconnection) String() string {
@@ -450,16 +460,21 @@ type connection struct {
 	key      key // client->server
 	c2s, s2c halfconnection
 	mu       sync.Mutex
+	rstSeen  bool // a RST was seen
 }
 
 func (c *connection) reset(k key, s Stream, ts time.Time) {
 	c.key = k
+	c.rstSeen = false
 	base := halfconnection{
 		nextSeq:  invalidSequence,
 		ackSeq:   invalidSequence,
 		created:  ts,
 		lastSeen: ts,
 		stream:   s,
+		wscale:   -1,
+		window:   -1,
+		finSeq:   invalidSequence,
 	}
 	c.c2s, c.s2c = base, base
 	c.c2s.dir, c.s2c.dir = TCPDirClientToServer, TCPDirServerToClient
@@ -490,6 +505,7 @@ func (c *connection) String() string {
 var DefaultAssemblerOptions = AssemblerOptions{
 	MaxBufferedPagesPerConnection: 0, // unlimited
 	MaxBufferedPagesTotal:         0, // unlimited
+	PageCacheShrinkInterval:       0, // never shrink
 }
 
 // AssemblerOptions controls the behavior of each assembler.  Modify the
@@ -505,6 +521,20 @@ type AssemblerOptions struct {
 	// particular connection, the smallest sequence number will be flushed, along
 	// with any contiguous data.  If <= 0, this is ignored.
 	MaxBufferedPagesPerConnection int
+	// PageCacheShrinkInterval is the interval, in packet time, between
+	// shrinks of the page cache: the free pages not needed to serve the
+	// peak usage of the last interval are released.  FlushAll always
+	// releases all the free pages.  If <= 0, this is ignored and free
+	// pages are kept.
+	PageCacheShrinkInterval time.Duration
+	// OverlapPolicy selects the data kept when segments overlap buffered
+	// data.  Conflicting overlaps are reported to AnomalyStreams.
+	OverlapPolicy OverlapPolicy
+	// OverlapHistoryBytes is the number of bytes given to the Stream kept
+	// for each direction of the connections of AnomalyStreams, to report
+	// retransmissions conflicting with them.  If <= 0, only the overlaps
+	// of buffered data are checked.
+	OverlapHistoryBytes int
 }
 
 // Assembler handles reassembling TCP streams.  It is not safe for
@@ -562,10 +592,12 @@ type AssemblerOptions struct {
 // is done there, then very little allocation is done ever, mostly to handle
 // large increases in bandwidth or numbers of connections.
 //
-// TODO:  The page caches used by an Assembler will grow to the size necessary
-// to handle a workload, and currently will never shrink.  This means that
-// traffic spikes can result in large memory usage which isn't garbage
-// collected when typical traffic levels return.
+// The page caches used by an Assembler grow to the size necessary to handle a
+// workload.  If PageCacheShrinkInterval is set, they are shrunk at that
+// interval so that memory used during traffic spikes can be garbage collected
+// when typical traffic levels return.  The memory used by all the Assemblers of a StreamPool can be
+// bounded with StreamPool.SetMaxBufferedBytes, and monitored with
+// StreamPool.MemoryStats.
 type Assembler struct {
 	AssemblerOptions
 	ret      []byteContainer
@@ -574,6 +606,9 @@ type Assembler struct {
 	cacheLP  livePacket
 	cacheSG  reassemblyObject
 	start    bool
+	// overlapBuf holds the new data of checkOverlap once the original data
+	// is copied over it
+	overlapBuf []byte
 }
 
 // NewAssembler creates a new assembler.  Pass in the StreamPool
@@ -587,7 +622,7 @@ func NewAssembler(pool *StreamPool) *Assembler {
 	pool.mu.Unlock()
 	return &Assembler{
 		ret:              make([]byteContainer, 0, assemblerReturnValueInitialSize),
-		pc:               newPageCache(),
+		pc:               newPageCache(&pool.mem),
 		connPool:         pool,
 		AssemblerOptions: DefaultAssemblerOptions,
 	}
@@ -596,7 +631,7 @@ func NewAssembler(pool *StreamPool) *Assembler {
 // Dump returns a short string describing the page usage of the Assembler
 func (a *Assembler) Dump() string {
 	s := ""
-	s += fmt.Sprintf("pageCache: used: %d:", a.pc.used)
+	s += fmt.Sprintf("pageCache: used: %d, free: %d:", a.pc.used, len(a.pc.free))
 	return s
 }
 
@@ -642,10 +677,15 @@ func (a *Assembler) AssembleWithContext(netFlow gopacket.Flow, t *layers.TCP, ac
 	var half *halfconnection
 	var rev *halfconnection
 
-	a.ret = a.ret[:0]
-	key := key{netFlow, t.TransportFlow()}
 	ci := ac.GetCaptureInfo()
 	timestamp := ci.Timestamp
+	a.pc.maybeShrink(timestamp, a.PageCacheShrinkInterval)
+	if a.connPool.overBudget(8) {
+		a.evictOldest()
+	}
+
+	a.ret = a.ret[:0]
+	key := key{netFlow, t.TransportFlow()}
 
 	conn, half, rev = a.connPool.getConnection(key, false, timestamp, t, ac)
 	if conn == nil {
@@ -656,9 +696,11 @@ func (a *Assembler) AssembleWithContext(netFlow gopacket.Flow, t *layers.TCP, ac
 	}
 	conn.mu.Lock()
 	defer conn.mu.Unlock()
+	defer a.connPool.trackBuffered(conn, half, a.pc)
 	if half.lastSeen.Before(timestamp) {
 		half.lastSeen = timestamp
 	}
+	a.checkAnomalies(conn, half, rev, t, ac)
 	a.start = half.nextSeq == invalidSequence && t.SYN
 	if *debugLog {
 		if half.nextSeq < rev.ackSeq {
@@ -745,8 +787,8 @@ func (a *Assembler) AssembleWithContext(netFlow gopacket.Flow, t *layers.TCP, ac
 //  - new packet overlaps existing queued packets:
 //	a) consider "age" by timestamp (TODO)
 //	b) consider "age" by being present
-//	Then
-//      1) discard new overlapping part
+//	Then, following AssemblerOptions.OverlapPolicy
+//      1) discard new overlapping part (the original data is copied over it)
 //      2) overwrite queued part
 
 func (a *Assembler) checkOverlap(half *halfconnection, queue bool, ac AssemblerContext) {
@@ -755,6 +797,7 @@ func (a *Assembler) checkOverlap(half *halfconnection, queue bool, ac AssemblerC
 	bytes := a.cacheLP.bytes
 	start := a.cacheLP.seq
 	end := start.Add(len(bytes))
+	copied := false
 
 	a.dump("before checkOverlap", half)
 
@@ -786,6 +829,18 @@ func (a *Assembler) checkOverlap(half *halfconnection, queue bool, ac AssemblerC
 			break
 		}
 
+		keepOriginal := !a.OverlapPolicy.newWins(start, end, cur.seq, curEnd)
+		a.checkOverlapConflict(half, start, bytes, cur.seq, cur.bytes, keepOriginal, ac)
+		if keepOriginal {
+			// keep the original data: copy it over the new data, which
+			// then replaces it
+			if !copied {
+				a.overlapBuf = append(a.overlapBuf[:0], bytes...)
+				bytes, copied = a.overlapBuf, true
+			}
+			copyOverlap(bytes, start, cur.bytes, cur.seq)
+		}
+
 		diffStart := start.Difference(cur.seq)
 		diffEnd := end.Difference(curEnd)
 
@@ -927,7 +982,7 @@ func (a *Assembler) dump(text string, half *halfconnection) {
 	}
 }
 
-func (a *Assembler) overlapExisting(half *halfconnection, start, end Sequence, bytes []byte) ([]byte, Sequence) {
+func (a *Assembler) overlapExisting(half *halfconnection, start, end Sequence, bytes []byte, ac AssemblerContext) ([]byte, Sequence) {
 	if half.nextSeq == invalidSequence {
 		// no start yet
 		return bytes, start
@@ -945,6 +1000,10 @@ func (a *Assembler) overlapExisting(half *halfconnection, start, end Sequence, b
 		}
 		half.overlapPackets++
 		half.overlapBytes += diff
+		if len(half.history) > 0 {
+			sent := bytes[:min(diff, e)]
+			a.checkOverlapConflict(half, start, sent, half.historyEnd.Add(-len(half.history)), half.history, true, ac)
+		}
 	}
 	s += diff
 	if s >= e {
@@ -975,7 +1034,7 @@ func (a *Assembler) handleBytes(bytes []byte, seq Sequence, half *halfconnection
 		}
 		a.dump("handleBytes after queue", half)
 	} else {
-		a.cacheLP.bytes, a.cacheLP.seq = a.overlapExisting(half, seq, seq.Add(len(bytes)), a.cacheLP.bytes)
+		a.cacheLP.bytes, a.cacheLP.seq = a.overlapExisting(half, seq, seq.Add(len(bytes)), a.cacheLP.bytes, ac)
 		a.checkOverlap(half, false, ac)
 		if len(a.cacheLP.bytes) != 0 || end || start {
 			a.ret = append(a.ret, &a.cacheLP)
@@ -1105,6 +1164,11 @@ func (a *Assembler) sendToConnection(conn *connection, half *halfconnection, ac
 		log.Printf("sendToConnection\n")
 	}
 	end, nextSeq := a.buildSG(half)
+	if a.OverlapHistoryBytes > 0 {
+		if _, ok := half.stream.(AnomalyStream); ok {
+			a.recordHistory(half, nextSeq)
+		}
+	}
 	half.stream.ReassembledSG(&a.cacheSG, ac)
 	a.cleanSG(half, ac)
 	if end {
@@ -1206,6 +1270,7 @@ func (a *Assembler) closeHalfConnection(conn *connection, half *halfconnection)
 		a.pc.replace(p)
 		half.pages--
 	}
+	a.connPool.trackBuffered(conn, half, nil)
 	if conn.s2c.closed && conn.c2s.closed {
 		if half.stream.ReassemblyComplete(nil) { //FIXME: which context to pass ?
 			a.connPool.remove(conn)
@@ -1267,6 +1332,7 @@ func (a *Assembler) FlushWithOptions(opt FlushOptions) (flushed, closed int) {
 		conn.mu.Lock()
 		for _, half := range []*halfconnection{&conn.s2c, &conn.c2s} {
 			flushed, closed := a.flushClose(conn, half, opt.T, opt.TC)
+			a.connPool.trackBuffered(conn, half, nil)
 			if flushed {
 				flushes++
 			}
@@ -1285,6 +1351,30 @@ func (a *Assembler) FlushWithOptions(opt FlushOptions) (flushed, closed int) {
 	return flushes, closes
 }
 
+// evictOldest flushes the buffered data of the connections buffered by the
+// Assembler, oldest first, until the pool is back under 7/8 of its memory
+// budget.  The pages buffered by other Assemblers are left to them, so that
+// pages are released to the page cache they come from.
+func (a *Assembler) evictOldest() {
+	for a.connPool.overBudget(7) {
+		conn, half := a.connPool.oldestBuffered(a.pc)
+		if conn == nil {
+			return
+		}
+		conn.mu.Lock()
+		// the half may have been flushed, or the connection reused, since
+		if half.buffered != nil && half.buffered.Value.(*bufferedHalf).pc == a.pc {
+			if *debugLog {
+				log.Printf("%v evicting buffered data seen at %v", conn, half.first.seen)
+			}
+			a.skipFlush(conn, half)
+			a.connPool.trackBuffered(conn, half, nil)
+			a.connPool.mem.evictions.Add(1)
+		}
+		conn.mu.Unlock()
+	}
+}
+
 // FlushCloseOlderThan flushes and closes streams older than given time
 func (a *Assembler) FlushCloseOlderThan(t time.Time) (flushed, closed int) {
 	return a.FlushWithOptions(FlushOptions{T: t, TC: t})
@@ -1329,6 +1419,7 @@ func (a *Assembler) FlushAll() (closed int) {
 		}
 		conn.mu.Unlock()
 	}
+	a.pc.shrink()
 	return
 }
 
@@ -1754,6 +1754,96 @@ func TestMemoryShrink(t *testing.T) {
 	}
 }
 
+// bufferPackets sends a SYN on connection port, then n out-of-order packets
+// buffered in one page each.
+func bufferPackets(a *Assembler, port layers.TCPPort, n int, ts time.Time) {
+	tcp := layers.TCP{
+		SrcPort:   port,
+		DstPort:   2,
+		SYN:       true,
+		Seq:       999,
+		BaseLayer: layers.BaseLayer{Payload: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 0}},
+	}
+	tcp.SetInternalPortsForTesting()
+	ctx := assemblerSimpleContext(gopacket.CaptureInfo{Timestamp: ts})
+	a.AssembleWithContext(netFlow, &tcp, &ctx)
+	tcp.SYN = false
+	tcp.Seq += 11 + 100
+	for i := 0; i < n; i++ {
+		ctx := assemblerSimpleContext(gopacket.CaptureInfo{Timestamp: ts.Add(time.Duration(i+1) * time.Millisecond)})
+		a.AssembleWithContext(netFlow, &tcp, &ctx)
+		tcp.Seq += 20
+	}
+}
+
+func TestMemoryBudget(t *testing.T) {
+	tf := &testMemoryFactory{}
+	pool := NewStreamPool(tf)
+	pool.SetMaxBufferedBytes(16 * pageBytes)
+	a1, a2 := NewAssembler(pool), NewAssembler(pool)
+	start := time.Unix(1432538521, 0)
+	pages := func(port layers.TCPPort) int {
+		conn, half, _ := pool.getConnection(key{netFlow, gopacket.NewFlow(layers.EndpointTCPPort, []byte{0, byte(port)}, []byte{0, 2})}, true, start, nil, nil)
+		if conn == nil {
+			t.Fatalf("connection %d was removed", port)
+		}
+		return half.pages
+	}
+	bufferPackets(a1, 10, 10, start)
+	bufferPackets(a2, 11, 6, start.Add(time.Second))
+	if got := pool.MemoryStats(); got.PagesInUse != 16 || got.Evictions != 0 {
+		t.Fatalf("got %+v, want 16 pages in use", got)
+	}
+	// each assembler evicts the oldest connection it buffered
+	bufferPackets(a2, 12, 2, start.Add(2*time.Second))
+	got := pool.MemoryStats()
+	if got.PagesInUse > 16 || got.Evictions == 0 {
+		t.Errorf("got %+v, want at most 16 pages in use and evictions", got)
+	}
+	if p10, p11, p12 := pages(10), pages(11), pages(12); p10 != 10 || p11 == 0 || p11 >= 6 || p12 != 2 {
+		t.Errorf("got %d, %d and %d pages after a2 evictions", p10, p11, p12)
+	}
+	p11 := pages(11)
+	bufferPackets(a1, 13, 4, start.Add(3*time.Second))
+	got = pool.MemoryStats()
+	if got.PagesInUse > 16 {
+		t.Errorf("got %+v, want at most 16 pages in use", got)
+	}
+	if p10, p13 := pages(10), pages(13); p10 == 0 || p10 >= 10 || pages(11) != p11 || p13 != 4 {
+		t.Errorf("got %d, %d and %d pages after a1 evictions", p10, pages(11), p13)
+	}
+	if int64(tf.bytes) != 4*10+got.Evictions*10 {
+		t.Errorf("got %d bytes reassembled, %d evictions", tf.bytes, got.Evictions)
+	}
+	if a1.FlushAll(); pool.MemoryStats().PagesInUse != 0 {
+		t.Errorf("got %+v after FlushAll", pool.MemoryStats())
+	}
+	if pool.buffered.Len() != 0 {
+		t.Errorf("got %d halves still tracked after FlushAll", pool.buffered.Len())
+	}
+}
+
+func TestPageCacheShrink(t *testing.T) {
+	pool := NewStreamPool(&testMemoryFactory{})
+	a := NewAssembler(pool)
+	a.PageCacheShrinkInterval = time.Second
+	start := time.Unix(1432538521, 0)
+	bufferPackets(a, 10, 20, start)
+	a.FlushWithOptions(FlushOptions{T: start.Add(time.Minute)})
+	if got := pool.MemoryStats(); got.PagesInUse != 0 || got.PagesFree != 20 {
+		t.Fatalf("got %+v after flush", got)
+	}
+	// the spike is in the first interval, and kept
+	bufferPackets(a, 11, 0, start.Add(time.Second))
+	if got := pool.MemoryStats(); got.PagesFree != 20 {
+		t.Errorf("got %+v after first interval", got)
+	}
+	bufferPackets(a, 12, 2, start.Add(2*time.Second))
+	if got := pool.MemoryStats(); got.PagesInUse != 2 || got.PagesFree != 0 {
+		t.Errorf("got %+v after second interval", got)
+	}
+}
+
 /*
  * Benchmark tests
  */
@@ -116,6 +116,9 @@ func (t *TCPOptionCheck) Accept(tcp *layers.TCP, ci gopacket.CaptureInfo, dir TC
 // - packet should be received in-order.
 // - no check on sequence number is performed
 // - no RST
+//
+// TCPStateMachine tracks the full state of both endpoints and checks RST
+// and FIN sequence numbers.
 type TCPSimpleFSM struct {
 	dir     TCPFlowDirection
 	state   int
@@ -244,3 +247,23 @@ func (t *TCPSimpleFSM) CheckState(tcp *layers.TCP, dir TCPFlowDirection) bool {
 	}
 	return false
 }
+
+// MarshalBinary returns the state of the state machine, to be saved by a
+// CheckpointStream.  The options are not included.
+func (t *TCPSimpleFSM) MarshalBinary() ([]byte, error) {
+	data := []byte{0, byte(t.state)}
+	if t.dir == TCPDirServerToClient {
+		data[0] = 1
+	}
+	return data, nil
+}
+
+// UnmarshalBinary restores a state returned by MarshalBinary.
+func (t *TCPSimpleFSM) UnmarshalBinary(data []byte) error {
+	if len(data) != 2 || data[0] > 1 || data[1] > TCPStateReset {
+		return fmt.Errorf("invalid TCPSimpleFSM state %v", data)
+	}
+	t.dir = TCPFlowDirection(data[0] == 1)
+	t.state = int(data[1])
+	return nil
+}
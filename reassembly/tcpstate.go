@@ -0,0 +1,508 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package reassembly
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+)
+
+// TCPEndpointState is the state of one endpoint of a TCP connection, as
+// defined by RFC 9293 section 3.3.2.
+type TCPEndpointState uint8
+
+// The TCP endpoint states. TCPEndpointClosed is also the state of an
+// endpoint not seen yet, or whose connection was reset.
+const (
+	TCPEndpointClosed TCPEndpointState = iota
+	TCPEndpointListen
+	TCPEndpointSynSent
+	TCPEndpointSynReceived
+	TCPEndpointEstablished
+	TCPEndpointFinWait1
+	TCPEndpointFinWait2
+	TCPEndpointCloseWait
+	TCPEndpointClosing
+	TCPEndpointLastAck
+	TCPEndpointTimeWait
+)
+
+func (s TCPEndpointState) String() string {
+	switch s {
+	case TCPEndpointClosed:
+		return "CLOSED"
+	case TCPEndpointListen:
+		return "LISTEN"
+	case TCPEndpointSynSent:
+		return "SYN-SENT"
+	case TCPEndpointSynReceived:
+		return "SYN-RECEIVED"
+	case TCPEndpointEstablished:
+		return "ESTABLISHED"
+	case TCPEndpointFinWait1:
+		return "FIN-WAIT-1"
+	case TCPEndpointFinWait2:
+		return "FIN-WAIT-2"
+	case TCPEndpointCloseWait:
+		return "CLOSE-WAIT"
+	case TCPEndpointClosing:
+		return "CLOSING"
+	case TCPEndpointLastAck:
+		return "LAST-ACK"
+	case TCPEndpointTimeWait:
+		return "TIME-WAIT"
+	}
+	return fmt.Sprintf("TCPEndpointState(%d)", uint8(s))
+}
+
+// Errors returned by TCPStateMachine.CheckState. The segment is not taken
+// into account when an error is returned.
+var (
+	// ErrTCPResetOutOfWindow flags a RST whose sequence number is outside
+	// the receive window of its receiver, or that doesn't acknowledge the
+	// SYN of an endpoint in SYN-SENT. Such resets are ignored by the
+	// receiver, and are typical of blind reset injection.
+	ErrTCPResetOutOfWindow = errors.New("RST outside the receive window")
+	// ErrTCPResetNotExact flags a RST inside the receive window whose
+	// sequence number isn't exactly the next expected one. It is only
+	// returned with TCPStateMachineOptions.StrictReset.
+	ErrTCPResetNotExact = errors.New("RST inside the receive window but not at the next expected sequence number")
+	// ErrTCPFinOutOfWindow flags a FIN outside the receive window of its
+	// receiver.
+	ErrTCPFinOutOfWindow = errors.New("FIN outside the receive window")
+	// ErrTCPUnexpectedSegment flags a segment its sender can't send in its
+	// current state, e.g. data before the handshake or after a reset.
+	ErrTCPUnexpectedSegment = errors.New("segment not allowed in the current state")
+)
+
+// TCPStateTransition describes a state change of an endpoint.
+type TCPStateTransition struct {
+	// Endpoint is the endpoint that changed state, designated by the
+	// direction of the packets it sends.
+	Endpoint TCPFlowDirection
+	From, To TCPEndpointState
+	// Reset is set if the change was caused by a RST.
+	Reset bool
+	// CaptureInfo is the capture info of the packet causing the change.
+	CaptureInfo gopacket.CaptureInfo
+}
+
+// TCPStateMachineOptions holds options for TCPStateMachine.
+type TCPStateMachineOptions struct {
+	// SupportMissingEstablishment infers the state of connections whose
+	// handshake was not captured from their first packet.
+	SupportMissingEstablishment bool
+	// StrictReset only accepts a RST carrying the next expected sequence
+	// number, as stacks implementing RFC 5961 do. Otherwise any RST inside
+	// the receive window is accepted.
+	StrictReset bool
+	// OnTransition, if set, is called for every state change.
+	OnTransition func(TCPStateTransition)
+}
+
+// tcpEndpoint holds the state of one endpoint. Sequence numbers are
+// invalidSequence until known.
+type tcpEndpoint struct {
+	state TCPEndpointState
+	// isn is the initial sequence number, sndNxt the sequence number
+	// following the last segment sent and fin the sequence number of the
+	// FIN sent.
+	isn, sndNxt, fin Sequence
+	// rcvNxt is the last acknowledgment sent, and rcvWnd the last window
+	// advertised, after scaling, or -1.
+	rcvNxt Sequence
+	rcvWnd int
+	// scale is the window scale option sent, or -1.
+	scale int
+}
+
+// TCPStateMachine tracks the state of both endpoints of a TCP connection,
+// following RFC 9293, including simultaneous open, half-closed
+// connections and TIME-WAIT. RST and FIN segments are checked against the
+// receive window of their receiver, so that injected resets are flagged
+// and ignored.
+//
+// Every segment is assumed to be received by its receiver. A
+// reassembly.Stream would use it from Accept, and could report the
+// transitions through TCPStateMachineOptions.OnTransition:
+//
+//	func (s *myStream) Accept(tcp *layers.TCP, ci gopacket.CaptureInfo, dir reassembly.TCPFlowDirection, nextSeq reassembly.Sequence, start *bool, ac reassembly.AssemblerContext) bool {
+//		if err := s.fsm.CheckState(tcp, ci, dir); err != nil {
+//			log.Printf("%s: %v (state %s)", s.ident, err, s.fsm)
+//			return false
+//		}
+//		return true
+//	}
+type TCPStateMachine struct {
+	endpoints [2]tcpEndpoint
+	options   TCPStateMachineOptions
+	started   bool
+	ci        gopacket.CaptureInfo
+}
+
+// NewTCPStateMachine creates a TCPStateMachine with both endpoints in
+// TCPEndpointClosed.
+func NewTCPStateMachine(options TCPStateMachineOptions) *TCPStateMachine {
+	t := &TCPStateMachine{options: options}
+	for i := range t.endpoints {
+		t.endpoints[i] = tcpEndpoint{
+			isn:    invalidSequence,
+			sndNxt: invalidSequence,
+			fin:    invalidSequence,
+			rcvNxt: invalidSequence,
+			rcvWnd: -1,
+			scale:  -1,
+		}
+	}
+	return t
+}
+
+func (t *TCPStateMachine) endpoint(dir TCPFlowDirection) *tcpEndpoint {
+	if dir == TCPDirClientToServer {
+		return &t.endpoints[0]
+	}
+	return &t.endpoints[1]
+}
+
+// State returns the state of the endpoint sending packets in direction
+// dir.
+func (t *TCPStateMachine) State(dir TCPFlowDirection) TCPEndpointState {
+	return t.endpoint(dir).state
+}
+
+// Closed reports whether both endpoints are in TCPEndpointClosed or
+// TCPEndpointTimeWait, after the connection was started.
+func (t *TCPStateMachine) Closed() bool {
+	if !t.started {
+		return false
+	}
+	for _, e := range t.endpoints {
+		if e.state != TCPEndpointClosed && e.state != TCPEndpointTimeWait {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *TCPStateMachine) String() string {
+	return fmt.Sprintf("%s/%s", t.endpoints[0].state, t.endpoints[1].state)
+}
+
+// tcpEndpointBinaryLen is the length of an endpoint in the output of
+// TCPStateMachine.MarshalBinary: its state, four sequence numbers, the
+// window and the window scale.
+const tcpEndpointBinaryLen = 1 + 4*8 + 4 + 2
+
+// MarshalBinary returns the state of the state machine, to be saved by a
+// CheckpointStream.  The options are not included.
+func (t *TCPStateMachine) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 1, 1+2*tcpEndpointBinaryLen)
+	if t.started {
+		data[0] = 1
+	}
+	for _, e := range t.endpoints {
+		data = append(data, byte(e.state))
+		for _, s := range []Sequence{e.isn, e.sndNxt, e.fin, e.rcvNxt} {
+			data = binary.BigEndian.AppendUint64(data, uint64(s))
+		}
+		data = binary.BigEndian.AppendUint32(data, uint32(int32(e.rcvWnd)))
+		data = binary.BigEndian.AppendUint16(data, uint16(int16(e.scale)))
+	}
+	return data, nil
+}
+
+// UnmarshalBinary restores a state returned by MarshalBinary.  The options
+// of t are kept.
+func (t *TCPStateMachine) UnmarshalBinary(data []byte) error {
+	if len(data) != 1+2*tcpEndpointBinaryLen || data[0] > 1 {
+		return fmt.Errorf("invalid TCPStateMachine state %v", data)
+	}
+	var endpoints [2]tcpEndpoint
+	for i := range endpoints {
+		b := data[1+i*tcpEndpointBinaryLen:]
+		e := &endpoints[i]
+		if e.state = TCPEndpointState(b[0]); e.state > TCPEndpointTimeWait {
+			return fmt.Errorf("invalid TCPStateMachine state %v", data)
+		}
+		for j, s := range []*Sequence{&e.isn, &e.sndNxt, &e.fin, &e.rcvNxt} {
+			*s = Sequence(binary.BigEndian.Uint64(b[1+j*8:]))
+		}
+		e.rcvWnd = int(int32(binary.BigEndian.Uint32(b[33:])))
+		e.scale = int(int16(binary.BigEndian.Uint16(b[37:])))
+	}
+	t.started = data[0] == 1
+	t.endpoints = endpoints
+	return nil
+}
+
+func (t *TCPStateMachine) set(dir TCPFlowDirection, state TCPEndpointState, reset bool) {
+	e := t.endpoint(dir)
+	if e.state == state {
+		return
+	}
+	from := e.state
+	e.state = state
+	if t.options.OnTransition != nil {
+		t.options.OnTransition(TCPStateTransition{
+			Endpoint:    dir,
+			From:        from,
+			To:          state,
+			Reset:       reset,
+			CaptureInfo: t.ci,
+		})
+	}
+}
+
+// acks reports whether the segment acknowledges the sequence number s.
+func acks(tcp *layers.TCP, s Sequence) bool {
+	return tcp.ACK && s != invalidSequence && s.Difference(Sequence(tcp.Ack)) >= 0
+}
+
+// inWindow reports whether s is in the receive window of e. Without
+// knowledge of the window, any sequence number is accepted.
+func (e *tcpEndpoint) inWindow(s Sequence, allowEnd bool) bool {
+	if e.rcvNxt == invalidSequence || e.rcvWnd < 0 {
+		return true
+	}
+	d := e.rcvNxt.Difference(s)
+	return d == 0 || d > 0 && (d < e.rcvWnd || allowEnd && d == e.rcvWnd)
+}
+
+// CheckState updates the state machine with a segment sent in direction
+// dir. An error is returned, and the segment ignored, if the receiver
+// would not accept the segment or its sender is not allowed to send it.
+func (t *TCPStateMachine) CheckState(tcp *layers.TCP, ci gopacket.CaptureInfo, dir TCPFlowDirection) error {
+	t.ci = ci
+	if !t.started {
+		if err := t.start(tcp, dir); err != nil {
+			return err
+		}
+	}
+	snd, rcv := t.endpoint(dir), t.endpoint(dir.Reverse())
+	seq := Sequence(tcp.Seq)
+
+	if (snd.state == TCPEndpointClosed || snd.state == TCPEndpointListen) && !tcp.SYN && !tcp.RST {
+		return fmt.Errorf("%w: %s sent by %s endpoint", ErrTCPUnexpectedSegment, segmentFlags(tcp), snd.state)
+	}
+
+	if tcp.RST {
+		if err := t.checkReset(tcp, rcv); err != nil {
+			return err
+		}
+		t.set(dir, TCPEndpointClosed, true)
+		t.set(dir.Reverse(), TCPEndpointClosed, true)
+		return nil
+	}
+
+	syn := 0
+	if tcp.SYN {
+		syn = 1
+	}
+	if tcp.FIN {
+		fin := seq.Add(syn + len(tcp.Payload))
+		if fin != snd.fin && !rcv.inWindow(fin, true) {
+			return fmt.Errorf("%w: seq %d, expected %d, window %d", ErrTCPFinOutOfWindow, fin, rcv.rcvNxt, rcv.rcvWnd)
+		}
+	}
+
+	t.updateSender(tcp, snd, rcv)
+	t.sent(tcp, dir, snd, rcv)
+	t.received(tcp, dir.Reverse(), rcv)
+	return nil
+}
+
+// start initializes the state machine with the first segment.
+func (t *TCPStateMachine) start(tcp *layers.TCP, dir TCPFlowDirection) error {
+	if tcp.SYN && !tcp.ACK {
+		t.started = true
+		return nil
+	}
+	if !t.options.SupportMissingEstablishment {
+		return fmt.Errorf("%w: connection starting with %s", ErrTCPUnexpectedSegment, segmentFlags(tcp))
+	}
+	t.started = true
+	rcv := t.endpoint(dir.Reverse())
+	if tcp.SYN {
+		// SYN+ACK: the SYN was missed
+		rcv.isn = Sequence(tcp.Ack).Add(-1)
+		rcv.sndNxt = Sequence(tcp.Ack)
+		t.set(dir.Reverse(), TCPEndpointSynSent, false)
+		t.set(dir, TCPEndpointListen, false)
+		return nil
+	}
+	if tcp.ACK {
+		rcv.sndNxt = Sequence(tcp.Ack)
+	}
+	t.set(dir, TCPEndpointEstablished, false)
+	t.set(dir.Reverse(), TCPEndpointEstablished, false)
+	return nil
+}
+
+func (t *TCPStateMachine) checkReset(tcp *layers.TCP, rcv *tcpEndpoint) error {
+	seq := Sequence(tcp.Seq)
+	if rcv.state == TCPEndpointSynSent {
+		// RFC 9293 3.10.7.3: acceptable if it acknowledges the SYN
+		if rcv.sndNxt == invalidSequence || tcp.ACK && Sequence(tcp.Ack) == rcv.sndNxt {
+			return nil
+		}
+		return fmt.Errorf("%w: ack %d, expected %d", ErrTCPResetOutOfWindow, tcp.Ack, rcv.sndNxt)
+	}
+	if !rcv.inWindow(seq, false) {
+		return fmt.Errorf("%w: seq %d, expected %d, window %d", ErrTCPResetOutOfWindow, seq, rcv.rcvNxt, rcv.rcvWnd)
+	}
+	if t.options.StrictReset && rcv.rcvNxt != invalidSequence && seq != rcv.rcvNxt {
+		return fmt.Errorf("%w: seq %d, expected %d", ErrTCPResetNotExact, seq, rcv.rcvNxt)
+	}
+	return nil
+}
+
+// updateSender records the sequence numbers and window of a segment in
+// its sender.
+func (t *TCPStateMachine) updateSender(tcp *layers.TCP, snd, rcv *tcpEndpoint) {
+	seq := Sequence(tcp.Seq)
+	length := len(tcp.Payload)
+	if tcp.SYN {
+		snd.isn = seq
+		length++
+		for _, o := range tcp.Options {
+			if o.OptionType == layers.TCPOptionKindWindowScale && len(o.OptionData) == 1 {
+				snd.scale = int(o.OptionData[0])
+			}
+		}
+	}
+	if tcp.FIN {
+		snd.fin = seq.Add(length)
+		length++
+	}
+	end := seq.Add(length)
+	if snd.sndNxt == invalidSequence || snd.sndNxt.Difference(end) > 0 {
+		snd.sndNxt = end
+	}
+	if tcp.ACK {
+		ack := Sequence(tcp.Ack)
+		if snd.rcvNxt == invalidSequence || snd.rcvNxt.Difference(ack) > 0 {
+			snd.rcvNxt = ack
+		}
+		snd.rcvWnd = int(tcp.Window)
+		// RFC 7323: the window of SYN segments is never scaled, and
+		// scaling is only used if both endpoints sent the option.
+		if !tcp.SYN && snd.scale >= 0 && rcv.scale >= 0 {
+			snd.rcvWnd <<= uint(snd.scale)
+		}
+	}
+}
+
+// sent updates the state of the sender of a segment.
+func (t *TCPStateMachine) sent(tcp *layers.TCP, dir TCPFlowDirection, snd, rcv *tcpEndpoint) {
+	switch snd.state {
+	case TCPEndpointClosed:
+		if tcp.SYN && !tcp.ACK {
+			t.set(dir, TCPEndpointSynSent, false)
+		} else if tcp.SYN {
+			t.set(dir, TCPEndpointSynReceived, false)
+		}
+	case TCPEndpointListen:
+		if tcp.SYN && tcp.ACK {
+			t.set(dir, TCPEndpointSynReceived, false)
+		} else if tcp.SYN {
+			t.set(dir, TCPEndpointSynSent, false)
+		}
+	case TCPEndpointSynSent:
+		// simultaneous open, answering the peer's SYN
+		if tcp.SYN && tcp.ACK {
+			t.set(dir, TCPEndpointSynReceived, false)
+		}
+	}
+	if tcp.FIN {
+		switch snd.state {
+		case TCPEndpointSynReceived, TCPEndpointEstablished:
+			t.set(dir, TCPEndpointFinWait1, false)
+		case TCPEndpointCloseWait:
+			if rcv.fin == invalidSequence || acks(tcp, rcv.fin.Add(1)) {
+				t.set(dir, TCPEndpointLastAck, false)
+			} else {
+				// the FIN was sent before the peer's FIN was received:
+				// simultaneous close
+				t.set(dir, TCPEndpointClosing, false)
+			}
+		}
+	}
+}
+
+// received updates the state of the receiver of a segment.
+func (t *TCPStateMachine) received(tcp *layers.TCP, dir TCPFlowDirection, rcv *tcpEndpoint) {
+	synAcked := rcv.isn != invalidSequence && acks(tcp, rcv.isn.Add(1))
+	finAcked := rcv.fin != invalidSequence && acks(tcp, rcv.fin.Add(1))
+	switch rcv.state {
+	case TCPEndpointClosed:
+		if tcp.SYN && !tcp.ACK {
+			t.set(dir, TCPEndpointListen, false)
+		}
+	case TCPEndpointSynSent:
+		if tcp.SYN && synAcked {
+			t.set(dir, TCPEndpointEstablished, false)
+		} else if tcp.SYN && !tcp.ACK {
+			// simultaneous open
+			t.set(dir, TCPEndpointSynReceived, false)
+		}
+	case TCPEndpointSynReceived:
+		if synAcked {
+			t.set(dir, TCPEndpointEstablished, false)
+		}
+	}
+	if finAcked {
+		switch rcv.state {
+		case TCPEndpointFinWait1:
+			t.set(dir, TCPEndpointFinWait2, false)
+		case TCPEndpointClosing:
+			t.set(dir, TCPEndpointTimeWait, false)
+		case TCPEndpointLastAck:
+			t.set(dir, TCPEndpointClosed, false)
+		}
+	}
+	if tcp.FIN {
+		switch rcv.state {
+		case TCPEndpointSynReceived, TCPEndpointEstablished:
+			t.set(dir, TCPEndpointCloseWait, false)
+		case TCPEndpointFinWait1:
+			// simultaneous close
+			t.set(dir, TCPEndpointClosing, false)
+		case TCPEndpointFinWait2:
+			t.set(dir, TCPEndpointTimeWait, false)
+		}
+	}
+}
+
+// segmentFlags returns the flags of a segment, for error messages.
+func segmentFlags(tcp *layers.TCP) string {
+	var s string
+	for _, f := range []struct {
+		set  bool
+		name string
+	}{{tcp.SYN, "SYN"}, {tcp.FIN, "FIN"}, {tcp.RST, "RST"}, {tcp.ACK, "ACK"}} {
+		if f.set {
+			if s != "" {
+				s += "+"
+			}
+			s += f.name
+		}
+	}
+	if len(tcp.Payload) > 0 {
+		if s != "" {
+			s += " "
+		}
+		s += fmt.Sprintf("with %d bytes", len(tcp.Payload))
+	}
+	if s == "" {
+		return "empty segment"
+	}
+	return s
+}
@@ -0,0 +1,259 @@
+// Copyright 2026 The GoPacket Authors. All rights reserved.
+//
+// Use of this source code is governed by a BSD-style license
+// that can be found in the LICENSE file in the root of the source
+// tree.
+
+package reassembly
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/gopacket/gopacket"
+	"github.com/gopacket/gopacket/layers"
+)
+
+type testStateMachineSequence struct {
+	tcp layers.TCP
+	// err is the expected error, if any
+	err error
+	// client and server are the expected states after the segment
+	client, server TCPEndpointState
+}
+
+const (
+	testClientPort = layers.TCPPort(54842)
+	testServerPort = layers.TCPPort(80)
+)
+
+func testSegment(client bool, flags string, seq, ack uint32, payload int) layers.TCP {
+	tcp := layers.TCP{
+		SrcPort:   testClientPort,
+		DstPort:   testServerPort,
+		Seq:       seq,
+		Ack:       ack,
+		Window:    1000,
+		BaseLayer: layers.BaseLayer{Payload: make([]byte, payload)},
+	}
+	if !client {
+		tcp.SrcPort, tcp.DstPort = tcp.DstPort, tcp.SrcPort
+	}
+	for _, f := range flags {
+		switch f {
+		case 'S':
+			tcp.SYN = true
+		case 'A':
+			tcp.ACK = true
+		case 'F':
+			tcp.FIN = true
+		case 'R':
+			tcp.RST = true
+		}
+	}
+	return tcp
+}
+
+func testStateMachine(t *testing.T, title string, options TCPStateMachineOptions, s []testStateMachineSequence) *TCPStateMachine {
+	t.Helper()
+	fsm := NewTCPStateMachine(options)
+	for i, test := range s {
+		dir := TCPDirClientToServer
+		if test.tcp.SrcPort != testClientPort {
+			dir = dir.Reverse()
+		}
+		err := fsm.CheckState(&test.tcp, gopacket.CaptureInfo{}, dir)
+		if !errors.Is(err, test.err) {
+			t.Fatalf("%s #%d: got error %v, expected %v", title, i, err, test.err)
+		}
+		if c, s := fsm.State(TCPDirClientToServer), fsm.State(TCPDirServerToClient); c != test.client || s != test.server {
+			t.Fatalf("%s #%d: got state %s/%s, expected %s/%s", title, i, c, s, test.client, test.server)
+		}
+	}
+	return fsm
+}
+
+// handshake is a three-way handshake with client ISN 100 and server ISN 500.
+var handshake = []testStateMachineSequence{
+	{tcp: testSegment(true, "S", 100, 0, 0), client: TCPEndpointSynSent, server: TCPEndpointListen},
+	{tcp: testSegment(false, "SA", 500, 101, 0), client: TCPEndpointEstablished, server: TCPEndpointSynReceived},
+	{tcp: testSegment(true, "A", 101, 501, 0), client: TCPEndpointEstablished, server: TCPEndpointEstablished},
+}
+
+func withHandshake(s ...testStateMachineSequence) []testStateMachineSequence {
+	return append(append([]testStateMachineSequence{}, handshake...), s...)
+}
+
+func TestStateMachineClose(t *testing.T) {
+	fsm := testStateMachine(t, "close", TCPStateMachineOptions{}, withHandshake(
+		testStateMachineSequence{tcp: testSegment(true, "A", 101, 501, 10), client: TCPEndpointEstablished, server: TCPEndpointEstablished},
+		testStateMachineSequence{tcp: testSegment(false, "A", 501, 111, 20), client: TCPEndpointEstablished, server: TCPEndpointEstablished},
+		testStateMachineSequence{tcp: testSegment(true, "FA", 111, 521, 0), client: TCPEndpointFinWait1, server: TCPEndpointCloseWait},
+		testStateMachineSequence{tcp: testSegment(false, "A", 521, 112, 0), client: TCPEndpointFinWait2, server: TCPEndpointCloseWait},
+		// half-closed: the server still sends data
+		testStateMachineSequence{tcp: testSegment(false, "A", 521, 112, 30), client: TCPEndpointFinWait2, server: TCPEndpointCloseWait},
+		testStateMachineSequence{tcp: testSegment(false, "FA", 551, 112, 0), client: TCPEndpointTimeWait, server: TCPEndpointLastAck},
+		testStateMachineSequence{tcp: testSegment(true, "A", 112, 552, 0), client: TCPEndpointTimeWait, server: TCPEndpointClosed},
+	))
+	if !fsm.Closed() {
+		t.Errorf("connection not closed: %s", fsm)
+	}
+	if fsm.String() != "TIME-WAIT/CLOSED" {
+		t.Errorf("got %q", fsm.String())
+	}
+}
+
+func TestStateMachineSimultaneous(t *testing.T) {
+	testStateMachine(t, "open", TCPStateMachineOptions{}, []testStateMachineSequence{
+		{tcp: testSegment(true, "S", 100, 0, 0), client: TCPEndpointSynSent, server: TCPEndpointListen},
+		{tcp: testSegment(false, "S", 500, 0, 0), client: TCPEndpointSynReceived, server: TCPEndpointSynSent},
+		{tcp: testSegment(true, "SA", 100, 501, 0), client: TCPEndpointSynReceived, server: TCPEndpointEstablished},
+		{tcp: testSegment(false, "SA", 500, 101, 0), client: TCPEndpointEstablished, server: TCPEndpointEstablished},
+	})
+	testStateMachine(t, "close", TCPStateMachineOptions{}, withHandshake(
+		testStateMachineSequence{tcp: testSegment(true, "FA", 101, 501, 0), client: TCPEndpointFinWait1, server: TCPEndpointCloseWait},
+		// the server FIN doesn't acknowledge the client FIN
+		testStateMachineSequence{tcp: testSegment(false, "FA", 501, 101, 0), client: TCPEndpointClosing, server: TCPEndpointClosing},
+		testStateMachineSequence{tcp: testSegment(true, "A", 102, 502, 0), client: TCPEndpointClosing, server: TCPEndpointTimeWait},
+		testStateMachineSequence{tcp: testSegment(false, "A", 502, 102, 0), client: TCPEndpointTimeWait, server: TCPEndpointTimeWait},
+	))
+}
+
+func TestStateMachineReset(t *testing.T) {
+	est := TCPEndpointEstablished
+	testStateMachine(t, "in window", TCPStateMachineOptions{}, withHandshake(
+		testStateMachineSequence{tcp: testSegment(false, "R", 5000, 0, 0), err: ErrTCPResetOutOfWindow, client: est, server: est},
+		testStateMachineSequence{tcp: testSegment(false, "R", 400, 0, 0), err: ErrTCPResetOutOfWindow, client: est, server: est},
+		testStateMachineSequence{tcp: testSegment(false, "R", 1501, 0, 0), err: ErrTCPResetOutOfWindow, client: est, server: est},
+		testStateMachineSequence{tcp: testSegment(false, "R", 800, 0, 0), client: TCPEndpointClosed, server: TCPEndpointClosed},
+		// data after the reset
+		testStateMachineSequence{tcp: testSegment(true, "A", 101, 501, 10), err: ErrTCPUnexpectedSegment, client: TCPEndpointClosed, server: TCPEndpointClosed},
+	))
+	testStateMachine(t, "strict", TCPStateMachineOptions{StrictReset: true}, withHandshake(
+		testStateMachineSequence{tcp: testSegment(false, "R", 800, 0, 0), err: ErrTCPResetNotExact, client: est, server: est},
+		testStateMachineSequence{tcp: testSegment(false, "R", 501, 0, 0), client: TCPEndpointClosed, server: TCPEndpointClosed},
+	))
+	testStateMachine(t, "refused", TCPStateMachineOptions{}, []testStateMachineSequence{
+		{tcp: testSegment(true, "S", 100, 0, 0), client: TCPEndpointSynSent, server: TCPEndpointListen},
+		{tcp: testSegment(false, "RA", 0, 100, 0), err: ErrTCPResetOutOfWindow, client: TCPEndpointSynSent, server: TCPEndpointListen},
+		{tcp: testSegment(false, "RA", 0, 101, 0), client: TCPEndpointClosed, server: TCPEndpointClosed},
+	})
+}
+
+func TestStateMachineFinWindow(t *testing.T) {
+	est := TCPEndpointEstablished
+	testStateMachine(t, "fin", TCPStateMachineOptions{}, withHandshake(
+		testStateMachineSequence{tcp: testSegment(true, "FA", 2000, 501, 0), err: ErrTCPFinOutOfWindow, client: est, server: est},
+		testStateMachineSequence{tcp: testSegment(true, "FA", 101, 501, 1000), client: TCPEndpointFinWait1, server: TCPEndpointCloseWait},
+		// retransmission
+		testStateMachineSequence{tcp: testSegment(true, "FA", 101, 501, 1000), client: TCPEndpointFinWait1, server: TCPEndpointCloseWait},
+	))
+}
+
+func TestStateMachineWindowScale(t *testing.T) {
+	syn := testSegment(true, "S", 100, 0, 0)
+	syn.Options = []layers.TCPOption{{OptionType: layers.TCPOptionKindWindowScale, OptionLength: 3, OptionData: []byte{4}}}
+	synAck := testSegment(false, "SA", 500, 101, 0)
+	synAck.Options = []layers.TCPOption{{OptionType: layers.TCPOptionKindWindowScale, OptionLength: 3, OptionData: []byte{2}}}
+	est := TCPEndpointEstablished
+	testStateMachine(t, "scale", TCPStateMachineOptions{}, []testStateMachineSequence{
+		{tcp: syn, client: TCPEndpointSynSent, server: TCPEndpointListen},
+		{tcp: synAck, client: est, server: TCPEndpointSynReceived},
+		{tcp: testSegment(true, "A", 101, 501, 0), client: est, server: est},
+		// window of 1000<<4 bytes
+		{tcp: testSegment(false, "R", 501+16001, 0, 0), err: ErrTCPResetOutOfWindow, client: est, server: est},
+		{tcp: testSegment(false, "R", 501+15999, 0, 0), client: TCPEndpointClosed, server: TCPEndpointClosed},
+	})
+}
+
+func TestStateMachineMissingEstablishment(t *testing.T) {
+	testStateMachine(t, "strict", TCPStateMachineOptions{}, []testStateMachineSequence{
+		{tcp: testSegment(true, "A", 101, 501, 10), err: ErrTCPUnexpectedSegment},
+	})
+	testStateMachine(t, "missing SYN", TCPStateMachineOptions{SupportMissingEstablishment: true}, []testStateMachineSequence{
+		{tcp: testSegment(false, "SA", 500, 101, 0), client: TCPEndpointEstablished, server: TCPEndpointSynReceived},
+		{tcp: testSegment(true, "A", 101, 501, 0), client: TCPEndpointEstablished, server: TCPEndpointEstablished},
+	})
+	testStateMachine(t, "missing handshake", TCPStateMachineOptions{SupportMissingEstablishment: true}, []testStateMachineSequence{
+		{tcp: testSegment(true, "A", 101, 501, 10), client: TCPEndpointEstablished, server: TCPEndpointEstablished},
+		{tcp: testSegment(false, "FA", 501, 111, 0), client: TCPEndpointCloseWait, server: TCPEndpointFinWait1},
+	})
+}
+
+func TestStateMachineTransitions(t *testing.T) {
+	var got []TCPStateTransition
+	testStateMachine(t, "transitions", TCPStateMachineOptions{OnTransition: func(tr TCPStateTransition) {
+		got = append(got, tr)
+	}}, withHandshake(
+		testStateMachineSequence{tcp: testSegment(true, "R", 101, 0, 0), client: TCPEndpointClosed, server: TCPEndpointClosed},
+	))
+	c, s := TCPDirClientToServer, TCPDirServerToClient
+	want := []TCPStateTransition{
+		{Endpoint: c, From: TCPEndpointClosed, To: TCPEndpointSynSent},
+		{Endpoint: s, From: TCPEndpointClosed, To: TCPEndpointListen},
+		{Endpoint: s, From: TCPEndpointListen, To: TCPEndpointSynReceived},
+		{Endpoint: c, From: TCPEndpointSynSent, To: TCPEndpointEstablished},
+		{Endpoint: s, From: TCPEndpointSynReceived, To: TCPEndpointEstablished},
+		{Endpoint: c, From: TCPEndpointEstablished, To: TCPEndpointClosed, Reset: true},
+		{Endpoint: s, From: TCPEndpointEstablished, To: TCPEndpointClosed, Reset: true},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d transitions %v, want %d", len(got), got, len(want))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("#%d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestStateMachineMarshal(t *testing.T) {
+	syn := testSegment(true, "S", 100, 0, 0)
+	syn.Options = []layers.TCPOption{{OptionType: layers.TCPOptionKindWindowScale, OptionLength: 3, OptionData: []byte{4}}}
+	synAck := testSegment(false, "SA", 500, 101, 0)
+	synAck.Options = []layers.TCPOption{{OptionType: layers.TCPOptionKindWindowScale, OptionLength: 3, OptionData: []byte{2}}}
+	est := TCPEndpointEstablished
+	fsm := testStateMachine(t, "marshal", TCPStateMachineOptions{}, []testStateMachineSequence{
+		{tcp: syn, client: TCPEndpointSynSent, server: TCPEndpointListen},
+		{tcp: synAck, client: est, server: TCPEndpointSynReceived},
+		{tcp: testSegment(true, "A", 101, 501, 0), client: est, server: est},
+		{tcp: testSegment(true, "FA", 101, 501, 0), client: TCPEndpointFinWait1, server: TCPEndpointCloseWait},
+	})
+	data, err := fsm.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []TCPStateTransition
+	fsm2 := NewTCPStateMachine(TCPStateMachineOptions{OnTransition: func(tr TCPStateTransition) {
+		got = append(got, tr)
+	}})
+	if err := fsm2.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if fsm2.endpoints != fsm.endpoints || !fsm2.started {
+		t.Fatalf("got %+v, want %+v", fsm2.endpoints, fsm.endpoints)
+	}
+	// the window scale is kept: a window of 1000<<4 bytes
+	tcp := testSegment(false, "R", 501+16001, 0, 0)
+	if err := fsm2.CheckState(&tcp, gopacket.CaptureInfo{}, TCPDirServerToClient); !errors.Is(err, ErrTCPResetOutOfWindow) {
+		t.Errorf("got error %v", err)
+	}
+	tcp = testSegment(false, "A", 501, 102, 0)
+	if err := fsm2.CheckState(&tcp, gopacket.CaptureInfo{}, TCPDirServerToClient); err != nil || fsm2.String() != "FIN-WAIT-2/CLOSE-WAIT" {
+		t.Errorf("got %s, error %v", fsm2, err)
+	}
+	if len(got) != 1 {
+		t.Errorf("got transitions %v", got)
+	}
+
+	for _, data := range [][]byte{nil, data[:len(data)-1], append([]byte{2}, data[1:]...)} {
+		if err := fsm2.UnmarshalBinary(data); err == nil {
+			t.Errorf("no error for %v", data)
+		}
+	}
+	data[1] = byte(TCPEndpointTimeWait + 1)
+	if err := fsm2.UnmarshalBinary(data); err == nil {
+		t.Error("no error for an invalid endpoint state")
+	}
+}
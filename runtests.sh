@@ -1,6 +1,6 @@
 #!/bin/bash
 
-DIRS="afpacket layers pcap pcapgo tcpassembly tcpassembly/tcpreader reassembly routing ip4defrag bytediff macs routing defrag/lcmdefrag"
+DIRS="afpacket layers pcap pcapgo pcapgo/pcaputil tcpassembly tcpassembly/tcpreader reassembly reassembly/httpstream reassembly/flowwriter reassembly/http2stream reassembly/dnsstream reassembly/internal/streamtest routing ip4defrag ip6defrag bytediff macs routing defrag/lcmdefrag"
 set -e
 export CGO_ENABLED=1
 for subdir in $DIRS; do